@@ -0,0 +1,462 @@
+package hastycsv
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"strconv"
+)
+
+// Identifies the function computed by an Aggregation.
+type AggregateFunc int
+
+const (
+	// The number of records, or of non-empty fields if a column is specified.
+	Count AggregateFunc = iota
+	Sum
+	Min
+	Max
+	Avg
+)
+
+var aggregateFuncNames = []string{"count", "sum", "min", "max", "avg"}
+
+// Returns the name of this function, e.g. "sum".
+func (me AggregateFunc) String() string {
+	if me < 0 || int(me) >= len(aggregateFuncNames) {
+		return fmt.Sprintf("AggregateFunc(%d)", int(me))
+	}
+	return aggregateFuncNames[me]
+}
+
+// A value computed for every group by Reader.Aggregate().
+type Aggregation struct {
+	Func AggregateFunc
+
+	// Column is the name of the numeric column that's aggregated.  Empty
+	// fields are ignored.  It may be "" for Count, which then counts records.
+	Column string
+
+	// Name is the aggregation's column name in the output, e.g. "total".  It
+	// defaults to the function applied to the column, e.g. "sum(price)".
+	Name string
+}
+
+// Returns the aggregation's column name in the output.
+func (me Aggregation) name() string {
+	if me.Name != "" {
+		return me.Name
+	} else if me.Column == "" {
+		return me.Func.String()
+	}
+	return fmt.Sprintf("%v(%v)", me.Func, me.Column)
+}
+
+// The result of Reader.Aggregate().
+type AggregateResult struct {
+	GroupBy      []string
+	Aggregations []Aggregation
+
+	// Groups holds a Group per distinct combination of values of the GroupBy
+	// columns, in order of first appearance (within each partition, if groups
+	// were spilled to disk).
+	Groups []Group
+
+	index map[string]int // encoded key => index within Groups
+}
+
+// The aggregated values of one group of records.
+type Group struct {
+	// Key holds the values of the GroupBy columns.
+	Key []string
+
+	// Values holds the value of each aggregation.  Min, Max and Avg are NaN if
+	// the group has no (non-empty) values.
+	Values []float64
+}
+
+// Returns the group with the specified values of the GroupBy columns, or nil
+// if there is no such group.
+func (me *AggregateResult) Lookup(key ...string) *Group {
+	var buf []byte
+	for _, s := range key {
+		buf = append(appendBigEndian32(buf, uint32(len(s))), s...)
+	}
+	if i, exists := me.index[string(buf)]; exists {
+		return &me.Groups[i]
+	}
+	return nil
+}
+
+// Writes the groups to dst as CSV, preceded by a header made up of the GroupBy
+// columns and the aggregations' names, and flushes dst.  NaN values are
+// written as empty fields.
+func (me *AggregateResult) Write(dst *Writer) error {
+	header := append([]string{}, me.GroupBy...)
+	for _, agg := range me.Aggregations {
+		header = append(header, agg.name())
+	}
+	if err := dst.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(header))
+	for _, group := range me.Groups {
+		copy(record, group.Key)
+		for i, v := range group.Values {
+			record[len(group.Key)+i] = ""
+			if !math.IsNaN(v) {
+				record[len(group.Key)+i] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+		if err := dst.Write(record); err != nil {
+			return err
+		}
+	}
+	return dst.Flush()
+}
+
+type aggregateConfig struct {
+	maxGroups int
+	tempDir   string
+}
+
+// Configures Reader.Aggregate().
+type AggregateOption func(cfg *aggregateConfig)
+
+// Returns an AggregateOption that bounds the number of groups held in memory
+// while reading.  Whenever it is exceeded, the partial aggregates are spilled
+// to temporary files, partitioned by group, and the partitions are merged one
+// at a time once the input has been read.
+func WithMaxGroups(groups int) AggregateOption {
+	return func(cfg *aggregateConfig) {
+		cfg.maxGroups = groups
+	}
+}
+
+// Returns an AggregateOption that sets the directory where spilled groups are
+// stored (os.TempDir() by default).
+func WithAggregateTempDir(dir string) AggregateOption {
+	return func(cfg *aggregateConfig) {
+		cfg.tempDir = dir
+	}
+}
+
+// The number of partitions that spilled groups are distributed over.
+const aggregatePartitions = 16
+
+// Reads the records of r and groups them by the values of the groupBy columns,
+// computing the specified aggregations for each group in a single pass.  The
+// columns are identified by name, so HasHeader must be set.  The result can
+// be inspected in memory or written as CSV with AggregateResult.Write().
+func (me *Reader) Aggregate(r io.Reader, groupBy []string, aggregations []Aggregation, options ...AggregateOption) (*AggregateResult, error) {
+	cfg := &aggregateConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	a := &aggregator{
+		reader: me,
+		cfg:    cfg,
+		aggs:   aggregations,
+		result: &AggregateResult{GroupBy: groupBy, Aggregations: aggregations, index: map[string]int{}},
+		table:  map[string]int{},
+	}
+	defer a.removePartitions()
+
+	err := me.Read(r, func(i int, record []Field) error {
+		if a.keyIndexes == nil {
+			if err := a.start(groupBy); err != nil {
+				return err
+			}
+		}
+		if err := a.add(record); err != nil {
+			return err
+		}
+		if cfg.maxGroups > 0 && len(a.groups) > cfg.maxGroups {
+			return a.spill()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if a.partitions == nil {
+		a.finish()
+	} else if err := a.mergePartitions(); err != nil {
+		return nil, err
+	}
+	return a.result, nil
+}
+
+// The partial aggregate of one aggregation of one group.
+type aggregateState struct {
+	count         int64
+	sum, min, max float64
+}
+
+func (me *aggregateState) add(v float64) {
+	if me.count == 0 || v < me.min {
+		me.min = v
+	}
+	if me.count == 0 || v > me.max {
+		me.max = v
+	}
+	me.count++
+	me.sum += v
+}
+
+func (me *aggregateState) merge(other *aggregateState) {
+	if other.count == 0 {
+		return
+	}
+	if me.count == 0 || other.min < me.min {
+		me.min = other.min
+	}
+	if me.count == 0 || other.max > me.max {
+		me.max = other.max
+	}
+	me.count += other.count
+	me.sum += other.sum
+}
+
+func (me *aggregateState) value(fn AggregateFunc) float64 {
+	switch fn {
+	case Count:
+		return float64(me.count)
+	case Sum:
+		return me.sum
+	}
+	if me.count == 0 {
+		return math.NaN()
+	}
+	switch fn {
+	case Min:
+		return me.min
+	case Max:
+		return me.max
+	}
+	return me.sum / float64(me.count)
+}
+
+// The state of a Reader.Aggregate() call.
+type aggregator struct {
+	reader     *Reader
+	cfg        *aggregateConfig
+	aggs       []Aggregation
+	keyIndexes []int
+	aggIndexes []int // the position of each aggregation's column, or -1
+	result     *AggregateResult
+	key        []byte
+
+	// The groups held in memory.
+	table  map[string]int // encoded key => index within groups
+	groups []aggregateGroup
+
+	partitions []*os.File
+}
+
+type aggregateGroup struct {
+	key    []byte // the encoded key
+	states []aggregateState
+}
+
+// Locates the group-by and aggregated columns, given the first record.
+func (me *aggregator) start(groupBy []string) error {
+	header := me.reader.Header()
+	if header == nil {
+		return fmt.Errorf("Can't aggregate by column name: the input has no header")
+	}
+
+	me.keyIndexes = make([]int, len(groupBy))
+	for i, name := range groupBy {
+		if me.keyIndexes[i] = indexOfString(header, name); me.keyIndexes[i] < 0 {
+			return fmt.Errorf(`Column "%v" not found in header`, name)
+		}
+	}
+
+	me.aggIndexes = make([]int, len(me.aggs))
+	for i, agg := range me.aggs {
+		me.aggIndexes[i] = -1
+		if agg.Column == "" && agg.Func != Count {
+			return fmt.Errorf("Aggregation %v requires a column", agg.Func)
+		} else if agg.Column != "" {
+			if me.aggIndexes[i] = indexOfString(header, agg.Column); me.aggIndexes[i] < 0 {
+				return fmt.Errorf(`Column "%v" not found in header`, agg.Column)
+			}
+		}
+	}
+	return nil
+}
+
+func (me *aggregator) add(record []Field) error {
+	me.key = appendKey(me.key[:0], record, me.keyIndexes)
+	g, exists := me.table[string(me.key)]
+	if !exists {
+		g = len(me.groups)
+		me.table[string(me.key)] = g
+		me.groups = append(me.groups, aggregateGroup{
+			key:    append([]byte(nil), me.key...),
+			states: make([]aggregateState, len(me.aggs)),
+		})
+	}
+
+	states := me.groups[g].states
+	for i, index := range me.aggIndexes {
+		if index < 0 {
+			states[i].add(0) // count records
+			continue
+		}
+
+		field := record[index]
+		if field.IsEmpty() {
+			continue
+		}
+		v, err := strconv.ParseFloat(field.unsafeString(), 64)
+		if err != nil {
+			return fmt.Errorf(`Can't aggregate column "%v": "%v" is not numeric`, me.aggs[i].Column, field.String())
+		}
+		states[i].add(v)
+	}
+	return nil
+}
+
+// Appends the groups held in memory to the result, and clears them.
+func (me *aggregator) finish() {
+	for _, g := range me.groups {
+		group := Group{Key: decodeKey(g.key), Values: make([]float64, len(me.aggs))}
+		for i, agg := range me.aggs {
+			group.Values[i] = g.states[i].value(agg.Func)
+		}
+		me.result.index[string(g.key)] = len(me.result.Groups)
+		me.result.Groups = append(me.result.Groups, group)
+	}
+	me.clear()
+}
+
+func (me *aggregator) clear() {
+	me.table = map[string]int{}
+	me.groups = me.groups[:0]
+}
+
+// Writes the groups held in memory to the partition files, and clears them.
+// Each group is stored as its key's length and key, followed by its states.
+func (me *aggregator) spill() error {
+	if me.partitions == nil {
+		for i := 0; i < aggregatePartitions; i++ {
+			f, err := os.CreateTemp(me.cfg.tempDir, "hastycsv-aggregate-*")
+			if err != nil {
+				return err
+			}
+			me.partitions = append(me.partitions, f)
+		}
+	}
+
+	writers := make([]*bufio.Writer, len(me.partitions))
+	for i, f := range me.partitions {
+		writers[i] = bufio.NewWriter(f)
+	}
+
+	h := fnv.New64a()
+	var buf []byte
+	for _, g := range me.groups {
+		h.Reset()
+		h.Write(g.key)
+
+		buf = append(appendBigEndian32(buf[:0], uint32(len(g.key))), g.key...)
+		for _, s := range g.states {
+			buf = appendBigEndian64(buf, uint64(s.count))
+			buf = appendBigEndian64(buf, math.Float64bits(s.sum))
+			buf = appendBigEndian64(buf, math.Float64bits(s.min))
+			buf = appendBigEndian64(buf, math.Float64bits(s.max))
+		}
+		writers[h.Sum64()%uint64(len(writers))].Write(buf)
+	}
+
+	for _, w := range writers {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	me.clear()
+	return nil
+}
+
+// Spills the remaining groups, then merges each partition in memory.
+func (me *aggregator) mergePartitions() error {
+	if err := me.spill(); err != nil {
+		return err
+	}
+
+	for _, f := range me.partitions {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		r := bufio.NewReader(f)
+		states := make([]aggregateState, len(me.aggs))
+		for {
+			var keyLen uint32
+			if err := binary.Read(r, binary.BigEndian, &keyLen); err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+			me.key = append(me.key[:0], make([]byte, keyLen)...)
+			if _, err := io.ReadFull(r, me.key); err != nil {
+				return err
+			}
+			for i := range states {
+				var raw [4]uint64
+				if err := binary.Read(r, binary.BigEndian, &raw); err != nil {
+					return err
+				}
+				states[i] = aggregateState{
+					count: int64(raw[0]),
+					sum:   math.Float64frombits(raw[1]),
+					min:   math.Float64frombits(raw[2]),
+					max:   math.Float64frombits(raw[3]),
+				}
+			}
+
+			g, exists := me.table[string(me.key)]
+			if !exists {
+				g = len(me.groups)
+				me.table[string(me.key)] = g
+				me.groups = append(me.groups, aggregateGroup{
+					key:    append([]byte(nil), me.key...),
+					states: make([]aggregateState, len(me.aggs)),
+				})
+			}
+			for i := range states {
+				me.groups[g].states[i].merge(&states[i])
+			}
+		}
+
+		me.finish()
+	}
+	return nil
+}
+
+func (me *aggregator) removePartitions() {
+	for _, f := range me.partitions {
+		f.Close()
+		os.Remove(f.Name())
+	}
+}
+
+// Returns the fields encoded by appendKey().
+func decodeKey(buf []byte) []string {
+	var fields []string
+	for len(buf) >= 4 {
+		n := binary.BigEndian.Uint32(buf)
+		fields = append(fields, string(buf[4:4+n]))
+		buf = buf[4+n:]
+	}
+	return fields
+}
@@ -0,0 +1,118 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+const aggregateInput = "region,product,price\n" +
+	"east,apple,1.5\n" +
+	"west,pear,2\n" +
+	"east,pear,\n" +
+	"east,apple,3.5\n" +
+	"north,plum,4\n"
+
+func TestAggregate(t *testing.T) {
+	r := NewReader()
+	r.HasHeader = true
+
+	result, err := r.Aggregate(strings.NewReader(aggregateInput), []string{"region"}, []Aggregation{
+		{Func: Count},
+		{Func: Count, Column: "price"},
+		{Func: Sum, Column: "price", Name: "total"},
+		{Func: Min, Column: "price"},
+		{Func: Max, Column: "price"},
+		{Func: Avg, Column: "price"},
+	})
+	require.Nil(t, err)
+
+	require.Equal(t, 3, len(result.Groups))
+	assert.Equal(t, Group{Key: []string{"east"}, Values: []float64{3, 2, 5, 1.5, 3.5, 2.5}}, result.Groups[0])
+	assert.Equal(t, Group{Key: []string{"west"}, Values: []float64{1, 1, 2, 2, 2, 2}}, result.Groups[1])
+	assert.Equal(t, []float64{1, 1, 4, 4, 4, 4}, result.Lookup("north").Values)
+	assert.Nil(t, result.Lookup("south"))
+
+	var out bytes.Buffer
+	require.Nil(t, result.Write(NewWriter(&out)))
+	assert.Equal(t, "region,count,count(price),total,min(price),max(price),avg(price)\n"+
+		"east,3,2,5,1.5,3.5,2.5\n"+
+		"west,1,1,2,2,2,2\n"+
+		"north,1,1,4,4,4,4\n", out.String())
+}
+
+func TestAggregate_multipleColumnsAndEmptyGroups(t *testing.T) {
+	r := NewReader()
+	r.HasHeader = true
+
+	result, err := r.Aggregate(strings.NewReader(aggregateInput), []string{"region", "product"}, []Aggregation{
+		{Func: Sum, Column: "price"},
+		{Func: Avg, Column: "price"},
+	})
+	require.Nil(t, err)
+
+	assert.Equal(t, 4, len(result.Groups))
+	assert.Equal(t, []float64{5, 2.5}, result.Lookup("east", "apple").Values)
+
+	pear := result.Lookup("east", "pear")
+	require.NotNil(t, pear)
+	assert.Equal(t, float64(0), pear.Values[0])
+	assert.True(t, math.IsNaN(pear.Values[1]))
+
+	var out bytes.Buffer
+	require.Nil(t, result.Write(NewWriter(&out)))
+	assert.Contains(t, out.String(), "\neast,pear,0,\n")
+}
+
+func TestAggregate_spill(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	var in strings.Builder
+	in.WriteString("key,value\n")
+	for i := 0; i < 1000; i++ {
+		in.WriteString(string(rune('a'+i%26)) + ",")
+		in.WriteString([]string{"1", "2", "3", "4"}[i%4] + "\n")
+	}
+
+	aggregations := []Aggregation{{Func: Count}, {Func: Sum, Column: "value"}, {Func: Min, Column: "value"}, {Func: Max, Column: "value"}}
+	r := NewReader()
+	r.HasHeader = true
+	expected, err := r.Aggregate(strings.NewReader(in.String()), []string{"key"}, aggregations)
+	require.Nil(t, err)
+
+	spilled, err := r.Aggregate(strings.NewReader(in.String()), []string{"key"}, aggregations, WithMaxGroups(5), WithAggregateTempDir(dir))
+	require.Nil(t, err)
+
+	require.Equal(t, 26, len(spilled.Groups))
+	for _, group := range expected.Groups {
+		actual := spilled.Lookup(group.Key...)
+		require.NotNil(t, actual, group.Key)
+		assert.Equal(t, group.Values, actual.Values, group.Key)
+	}
+
+	// The partitions must have been removed
+	assert.Equal(t, 0, len(listDir(t, dir)))
+}
+
+func TestAggregate_errors(t *testing.T) {
+	r := NewReader()
+	r.HasHeader = true
+
+	_, err := r.Aggregate(strings.NewReader(aggregateInput), []string{"city"}, []Aggregation{{Func: Count}})
+	assert.Contains(t, err.Error(), `Column "city" not found in header`)
+
+	_, err = r.Aggregate(strings.NewReader(aggregateInput), []string{"region"}, []Aggregation{{Func: Sum}})
+	assert.Contains(t, err.Error(), "Aggregation sum requires a column")
+
+	_, err = r.Aggregate(strings.NewReader(aggregateInput), []string{"region"}, []Aggregation{{Func: Sum, Column: "product"}})
+	assert.Contains(t, err.Error(), `Can't aggregate column "product": "apple" is not numeric`)
+
+	r.HasHeader = false
+	_, err = r.Aggregate(strings.NewReader(aggregateInput), []string{"region"}, []Aggregation{{Func: Count}})
+	assert.Contains(t, err.Error(), "the input has no header")
+}
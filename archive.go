@@ -0,0 +1,109 @@
+package hastycsv
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+)
+
+// Reads the CSV members of the tar archive read from r, whose names match the
+// specified glob pattern (see path.Match(); e.g. "*.csv" or "daily/*.csv"),
+// and invokes nextRecord with each of their records along with the member's
+// name.  Records are numbered per member, like ReadFile() numbers them.
+//
+// A gzip-compressed archive (.tar.gz) is decompressed transparently.  Errors
+// are returned as a *FileError naming the offending member.
+func ReadTar(r io.Reader, memberGlob string, comma byte, nextRecord NextFileRecord) error {
+	if _, err := path.Match(memberGlob, ""); err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	tr := tar.NewReader(r)
+	reader := NewReader()
+	reader.Comma = comma
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("Can't read tar archive: %w", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if matched, _ := path.Match(memberGlob, hdr.Name); !matched {
+			continue
+		}
+
+		name := hdr.Name
+		err = reader.Read(tr, func(i int, record []Field) error {
+			return nextRecord(name, i, record)
+		})
+		if err != nil {
+			return &FileError{Path: name, Err: err}
+		}
+	}
+}
+
+// Reads the CSV members of the specified zip file whose names match the
+// specified glob pattern (see path.Match()), in the order in which they're
+// stored, without extracting them to disk.  See ReadTar() for how records and
+// errors are reported.
+func ReadZip(zipFilePath string, memberGlob string, comma byte, nextRecord NextFileRecord) error {
+	if _, err := path.Match(memberGlob, ""); err != nil {
+		return err
+	}
+
+	zr, err := zip.OpenReader(zipFilePath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	reader := NewReader()
+	reader.Comma = comma
+	for _, member := range zr.File {
+		if member.FileInfo().IsDir() {
+			continue
+		}
+		if matched, _ := path.Match(memberGlob, member.Name); !matched {
+			continue
+		}
+
+		if err := readZipMember(reader, member, nextRecord); err != nil {
+			return &FileError{Path: member.Name, Err: err}
+		}
+	}
+
+	return nil
+}
+
+func readZipMember(reader *Reader, member *zip.File, nextRecord NextFileRecord) error {
+	rc, err := member.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	return reader.Read(rc, func(i int, record []Field) error {
+		return nextRecord(member.Name, i, record)
+	})
+}
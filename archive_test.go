@@ -0,0 +1,131 @@
+package hastycsv
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestReadTar(t *testing.T) {
+	archive := makeTar(t, map[string]string{
+		"daily/a.csv":    "mary,35\nbill,40\n",
+		"daily/b.csv":    "joe,50",
+		"daily/notes.md": "not csv",
+	})
+
+	compressed := &bytes.Buffer{}
+	gz := gzip.NewWriter(compressed)
+	gz.Write(archive)
+	require.Nil(t, gz.Close())
+
+	for _, in := range [][]byte{archive, compressed.Bytes()} {
+		received := []string{}
+		err := ReadTar(bytes.NewReader(in), "daily/*.csv", ',', func(name string, i int, record []Field) error {
+			received = append(received, fmt.Sprintf("%v:%v:%v", name, i, record[0].String()))
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"daily/a.csv:1:mary", "daily/a.csv:2:bill", "daily/b.csv:1:joe"}, received)
+	}
+}
+
+func TestReadTar_errors(t *testing.T) {
+	noop := func(name string, i int, record []Field) error { return nil }
+
+	err := ReadTar(bytes.NewReader(makeTar(t, map[string]string{"a.csv": "a,1\nb\n"})), "*.csv", ',', noop)
+	assert.EqualError(t, err, `a.csv: Line 2: Expected []b to contain 2 fields using delimiter ',': "b"`)
+
+	err = ReadTar(bytes.NewReader(bytes.Repeat([]byte("x"), 1024)), "*", ',', noop)
+	assert.NotNil(t, err)
+
+	err = ReadTar(bytes.NewReader(nil), "[", ',', noop)
+	assert.NotNil(t, err)
+}
+
+// Test helper that returns a tar archive holding the specified files, in
+// lexical order of their names.
+func makeTar(t *testing.T, files map[string]string) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, name := range names {
+		require.Nil(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(files[name]))
+		require.Nil(t, err)
+	}
+	require.Nil(t, tw.Close())
+	return buf.Bytes()
+}
+
+func TestReadZip(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bundle.zip")
+	makeZip(t, path, map[string]string{
+		"data/a.csv":  "mary|35\nbill|40\n",
+		"data/b.csv":  "joe|50",
+		"README.txt":  "not csv",
+		"other/c.csv": "x|1",
+	})
+
+	received := []string{}
+	err := ReadZip(path, "data/*.csv", '|', func(name string, i int, record []Field) error {
+		received = append(received, fmt.Sprintf("%v:%v:%v:%v", name, i, record[0].String(), record[1].Uint32()))
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"data/a.csv:1:mary:35", "data/a.csv:2:bill:40", "data/b.csv:1:joe:50"}, received)
+}
+
+func TestReadZip_errors(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+	noop := func(name string, i int, record []Field) error { return nil }
+
+	path := filepath.Join(dir, "bundle.zip")
+	makeZip(t, path, map[string]string{"a.csv": "a,1\nb\n"})
+	err := ReadZip(path, "*.csv", ',', noop)
+	assert.EqualError(t, err, `a.csv: Line 2: Expected []b to contain 2 fields using delimiter ',': "b"`)
+
+	assert.NotNil(t, ReadZip(filepath.Join(dir, "missing.zip"), "*.csv", ',', noop))
+	assert.NotNil(t, ReadZip(path, "[", ',', noop))
+}
+
+// Test helper that creates a zip file holding the specified files, in lexical
+// order of their names.
+func makeZip(t *testing.T, path string, files map[string]string) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	f, err := os.Create(path)
+	require.Nil(t, err)
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		require.Nil(t, err)
+		_, err = w.Write([]byte(files[name]))
+		require.Nil(t, err)
+	}
+	require.Nil(t, zw.Close())
+}
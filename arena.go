@@ -0,0 +1,98 @@
+package hastycsv
+
+import (
+	"unsafe"
+)
+
+// The default size of the blocks allocated by an Arena.
+const DefaultArenaBlockSize = 1 << 20
+
+// An append-only allocator for records that need to be retained after the
+// Next callback returns, e.g. when building a large in-memory dataset.
+//
+// Rather than allocating each retained field separately, an Arena copies
+// records into large blocks, so retaining millions of records causes only a
+// handful of allocations.  All retained records are released at once by
+// Free().
+type Arena struct {
+	blockSize int
+	data      []byte  // current block for field data
+	fields    []Field // current block for []Field slices
+	size      int64
+}
+
+// Returns a new Arena that allocates blocks of the specified size in bytes
+// (DefaultArenaBlockSize if blockSize < 1).
+func NewArena(blockSize int) *Arena {
+	if blockSize < 1 {
+		blockSize = DefaultArenaBlockSize
+	}
+	return &Arena{blockSize: blockSize}
+}
+
+// Returns a copy of record that's stored in this Arena and remains valid until
+// Free() is called.  The copied fields don't belong to a Reader, so their
+// parse errors (e.g. from Uint32()) are not reported.
+func (me *Arena) Retain(record []Field) []Field {
+	dataSize := 0
+	for _, field := range record {
+		dataSize += len(field.data)
+	}
+
+	data := me.allocData(dataSize)
+	fields := me.allocFields(len(record))
+	for i, field := range record {
+		n := copy(data, field.data)
+		fields[i] = Field{data: data[:n:n]}
+		data = data[n:]
+	}
+
+	me.size += int64(dataSize)
+	return fields
+}
+
+// Returns the total number of field bytes retained since the last Free().
+func (me *Arena) Size() int64 {
+	return me.size
+}
+
+// Releases every record retained by this Arena.  Records returned by Retain()
+// must no longer be used.
+func (me *Arena) Free() {
+	me.data, me.fields = nil, nil
+	me.size = 0
+}
+
+func (me *Arena) allocData(n int) []byte {
+	if n > len(me.data) {
+		if n > me.blockSize/4 { // large records get a block of their own
+			return make([]byte, n)
+		}
+		me.data = make([]byte, me.blockSize)
+	}
+	b := me.data[:n:n]
+	me.data = me.data[n:]
+	return b
+}
+
+func (me *Arena) allocFields(n int) []Field {
+	if n > len(me.fields) {
+		blockLen := me.blockSize / int(unsafe.Sizeof(Field{}))
+		if n > blockLen/4 {
+			return make([]Field, n)
+		}
+		me.fields = make([]Field, blockLen)
+	}
+	fields := me.fields[:n:n]
+	me.fields = me.fields[n:]
+	return fields
+}
+
+// Copies record into this Reader's Arena, so that it can be retained after the
+// Next callback returns.
+func (me *Reader) Retain(record []Field) []Field {
+	if me.Arena == nil {
+		me.Arena = NewArena(DefaultArenaBlockSize)
+	}
+	return me.Arena.Retain(record)
+}
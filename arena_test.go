@@ -0,0 +1,56 @@
+package hastycsv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestArena_Retain(t *testing.T) {
+	arena := NewArena(256)
+
+	retained := [][]Field{}
+	for i := 0; i < 100; i++ {
+		record := []Field{makeField(fmt.Sprintf("name%v", i)), makeField(""), makeField(fmt.Sprint(i))}
+		retained = append(retained, arena.Retain(record))
+		record[0].data[0] = 'X'
+	}
+
+	for i, record := range retained {
+		assert.Equal(t, 3, len(record))
+		assert.Equal(t, fmt.Sprintf("name%v", i), record[0].String())
+		assert.True(t, record[1].IsEmpty())
+		assert.Equal(t, uint32(i), record[2].Uint32())
+		assert.Equal(t, 3, cap(record))
+	}
+
+	large := arena.Retain([]Field{makeField(strings.Repeat("z", 1000))})
+	assert.Equal(t, strings.Repeat("z", 1000), large[0].String())
+
+	assert.True(t, arena.Size() > 1000)
+	arena.Free()
+	assert.Equal(t, int64(0), arena.Size())
+}
+
+func TestReader_Retain(t *testing.T) {
+	in := strings.NewReader("name|age\nbill|30\nmary|35\njoe|40")
+	r := NewReader()
+	r.Comma = '|'
+	r.HasHeader = true
+
+	adults := [][]Field{}
+	err := r.Read(in, func(i int, fields []Field) error {
+		if fields[1].Uint32() >= 35 {
+			adults = append(adults, r.Retain(fields))
+		}
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.NotNil(t, r.Arena)
+	assert.Equal(t, 2, len(adults))
+	assert.Equal(t, "mary", adults[0][0].String())
+	assert.Equal(t, "joe", adults[1][0].String())
+	assert.Equal(t, uint32(40), adults[1][1].Uint32())
+}
@@ -0,0 +1,131 @@
+// Package arrowbatch reads CSV input into Apache Arrow record batches.
+//
+// Fields are parsed according to a hastycsv.Schema and appended straight to
+// column-typed Arrow builders, without materializing a Go value per row.  It's
+// a separate module so that the core hastycsv package remains free of
+// third-party dependencies.
+package arrowbatch
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/memory"
+	"github.com/cet001/hastycsv"
+)
+
+// The number of rows per record batch used by Read() if none is specified.
+const DefaultBatchSize = 64 * 1024
+
+// Returns the Arrow schema that corresponds to a hastycsv Schema.  Every field
+// is nullable, since empty CSV fields are read as nulls.  TimeType columns
+// become UTC timestamps with microsecond precision.
+func ArrowSchema(schema *hastycsv.Schema) (*arrow.Schema, error) {
+	fields := make([]arrow.Field, len(schema.Columns))
+	for i, col := range schema.Columns {
+		dataType, err := arrowType(col.Type)
+		if err != nil {
+			return nil, err
+		}
+		fields[i] = arrow.Field{Name: col.Name, Type: dataType, Nullable: true}
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+func arrowType(t hastycsv.ColumnType) (arrow.DataType, error) {
+	switch t {
+	case hastycsv.StringType:
+		return arrow.BinaryTypes.String, nil
+	case hastycsv.Uint32Type:
+		return arrow.PrimitiveTypes.Uint32, nil
+	case hastycsv.Int64Type:
+		return arrow.PrimitiveTypes.Int64, nil
+	case hastycsv.Float32Type:
+		return arrow.PrimitiveTypes.Float32, nil
+	case hastycsv.Float64Type:
+		return arrow.PrimitiveTypes.Float64, nil
+	case hastycsv.BoolType:
+		return arrow.FixedWidthTypes.Boolean, nil
+	case hastycsv.TimeType:
+		return arrow.FixedWidthTypes.Timestamp_us, nil
+	}
+	return nil, fmt.Errorf("Unsupported column type %v", t)
+}
+
+// Reads records from r using reader, parses them according to schema, and
+// passes them to nextBatch as Arrow records of up to batchSize rows
+// (DefaultBatchSize if batchSize < 1).  Memory is allocated from mem
+// (memory.DefaultAllocator if nil).
+//
+// Each record is released once nextBatch returns; call Retain() on it to keep
+// it longer.
+func Read(reader *hastycsv.Reader, r io.Reader, schema *hastycsv.Schema, batchSize int, mem memory.Allocator, nextBatch func(batch arrow.Record) error) error {
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+
+	arrowSchema, err := ArrowSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	builder := array.NewRecordBuilder(mem, arrowSchema)
+	defer builder.Release()
+
+	rows := 0
+	flush := func() error {
+		batch := builder.NewRecord()
+		defer batch.Release()
+		rows = 0
+		return nextBatch(batch)
+	}
+
+	err = reader.ReadWithSchema(r, schema, func(i int, record *hastycsv.Record) error {
+		for col := range schema.Columns {
+			appendValue(builder.Field(col), schema.Columns[col].Type, record, col)
+		}
+
+		if rows++; rows == batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if rows > 0 {
+		return flush()
+	}
+	return nil
+}
+
+// Appends the value of a column of record to the column's builder.
+func appendValue(b array.Builder, t hastycsv.ColumnType, record *hastycsv.Record, col int) {
+	if record.IsNull(col) {
+		b.AppendNull()
+		return
+	}
+
+	switch t {
+	case hastycsv.StringType:
+		b.(*array.StringBuilder).BinaryBuilder.Append(record.Field(col).Bytes())
+	case hastycsv.Uint32Type:
+		b.(*array.Uint32Builder).Append(record.Uint32(col))
+	case hastycsv.Int64Type:
+		b.(*array.Int64Builder).Append(record.Int64(col))
+	case hastycsv.Float32Type:
+		b.(*array.Float32Builder).Append(record.Float32(col))
+	case hastycsv.Float64Type:
+		b.(*array.Float64Builder).Append(record.Float64(col))
+	case hastycsv.BoolType:
+		b.(*array.BooleanBuilder).Append(record.Bool(col))
+	case hastycsv.TimeType:
+		b.(*array.TimestampBuilder).Append(arrow.Timestamp(record.Time(col).UnixMicro()))
+	}
+}
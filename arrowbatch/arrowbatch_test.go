@@ -0,0 +1,80 @@
+package arrowbatch
+
+import (
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/memory"
+	"github.com/cet001/hastycsv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRead(t *testing.T) {
+	in := strings.NewReader("" +
+		"name|age|balance|ratio|score|active|joined\n" +
+		"bill|30|-12|0.5|1.25|true|2020-01-02\n" +
+		"mary||7|0.25|2|false|\n" +
+		"joe|50|8|1|3|true|2021-03-04\n")
+
+	schema := hastycsv.NewSchema(
+		hastycsv.StringColumn("name"),
+		hastycsv.Uint32Column("age"),
+		hastycsv.Int64Column("balance"),
+		hastycsv.Float32Column("ratio"),
+		hastycsv.Float64Column("score"),
+		hastycsv.BoolColumn("active"),
+		hastycsv.TimeColumn("joined", "2006-01-02"),
+	)
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	reader := hastycsv.NewReader()
+	reader.Comma = '|'
+	reader.HasHeader = true
+
+	batches := []arrow.Record{}
+	err := Read(reader, in, schema, 2, mem, func(batch arrow.Record) error {
+		batch.Retain()
+		batches = append(batches, batch)
+		return nil
+	})
+	require.Nil(t, err)
+	defer func() {
+		for _, batch := range batches {
+			batch.Release()
+		}
+	}()
+
+	require.Equal(t, 2, len(batches))
+	assert.Equal(t, int64(2), batches[0].NumRows())
+	assert.Equal(t, int64(1), batches[1].NumRows())
+
+	first := batches[0]
+	assert.Equal(t, "age", first.ColumnName(1))
+	assert.Equal(t, "mary", first.Column(0).(*array.String).Value(1))
+	assert.Equal(t, uint32(30), first.Column(1).(*array.Uint32).Value(0))
+	assert.True(t, first.Column(1).IsNull(1))
+	assert.Equal(t, int64(-12), first.Column(2).(*array.Int64).Value(0))
+	assert.Equal(t, float32(0.25), first.Column(3).(*array.Float32).Value(1))
+	assert.Equal(t, 1.25, first.Column(4).(*array.Float64).Value(0))
+	assert.False(t, first.Column(5).(*array.Boolean).Value(1))
+	assert.Equal(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC).UnixMicro(), int64(first.Column(6).(*array.Timestamp).Value(0)))
+	assert.True(t, first.Column(6).IsNull(1))
+
+	assert.Equal(t, "joe", batches[1].Column(0).(*array.String).Value(0))
+}
+
+func TestRead_errors(t *testing.T) {
+	schema := hastycsv.NewSchema(hastycsv.Uint32Column("age"))
+	noop := func(batch arrow.Record) error { return nil }
+
+	err := Read(hastycsv.NewReader(), strings.NewReader("x\n"), schema, 0, nil, noop)
+	assert.EqualError(t, err, `Line 1, column 1: Can't parse column "age" as uint32: "x"`)
+
+	_, err = ArrowSchema(hastycsv.NewSchema(hastycsv.Column{Name: "bad", Type: hastycsv.ColumnType(99)}))
+	assert.EqualError(t, err, "Unsupported column type ColumnType(99)")
+}
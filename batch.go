@@ -0,0 +1,104 @@
+package hastycsv
+
+import (
+	"fmt"
+	"io"
+)
+
+// Definition of a callback function that receives records in batches from
+// ReadBatches().  lines[j] is the line number of records[j].  ReadBatches()
+// will stop reading the input records if this function returns an error.
+type NextBatch func(lines []int, records [][]Field) error
+
+// Reads records from r and passes them to nextBatch in batches of up to
+// batchSize records, which amortizes the cost of the callback and suits bulk
+// processing such as multi-row database inserts.
+//
+// The records of a batch are copied into an arena that, like the lines and
+// records slices, is reused for the next batch; nothing passed to nextBatch may
+// be retained after it returns.  Since a field parse error can't be traced to a
+// single record, it's reported with the range of lines of its batch.
+func (me *Reader) ReadBatches(r io.Reader, batchSize int, nextBatch NextBatch) error {
+	if batchSize < 1 {
+		return fmt.Errorf("Batch size must be at least 1, but is %v", batchSize)
+	}
+	if err := me.begin(r); err != nil {
+		return err
+	}
+	defer me.end()
+
+	var buf recordBuffer
+	for {
+		record, err := me.next()
+		if err == io.EOF {
+			return buf.flush(me, nextBatch)
+		} else if err != nil {
+			return err
+		}
+
+		buf.add(me.row, record)
+		if len(buf.lines) == batchSize {
+			if err := buf.flush(me, nextBatch); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Holds copies of the records collected by ReadBatches() or ReadGroups() until
+// they're passed to the callback.
+type recordBuffer struct {
+	arena   []byte
+	spans   []int // start and end offset within arena of each buffered field
+	ends    []int // number of buffered fields up to and including each record
+	lines   []int // line number of each buffered record
+	fields  []Field
+	records [][]Field
+}
+
+// Copies the specified record, read from the specified line, into the buffer.
+func (me *recordBuffer) add(line int, record []Field) {
+	for _, field := range record {
+		me.spans = append(me.spans, len(me.arena))
+		me.arena = append(me.arena, field.data...)
+		me.spans = append(me.spans, len(me.arena))
+	}
+	me.ends = append(me.ends, len(me.spans)/2)
+	me.lines = append(me.lines, line)
+}
+
+// Passes the buffered records (if any) to fn, and empties the buffer.  Field
+// parse errors are reported with the range of lines of the buffered records.
+func (me *recordBuffer) flush(r *Reader, fn func(lines []int, records [][]Field) error) error {
+	if len(me.lines) == 0 {
+		return nil
+	}
+
+	// The arena may have been reallocated while the records were collected, so
+	// fields are only pointed at it once they're complete.
+	me.fields, me.records = me.fields[:0], me.records[:0]
+	start := 0
+	for _, end := range me.ends {
+		for k := start; k < end; k++ {
+			data := me.arena[me.spans[2*k]:me.spans[2*k+1]:me.spans[2*k+1]]
+			me.fields = append(me.fields, Field{data: data, reader: r, column: k - start + 1})
+		}
+		start = end
+	}
+	start = 0
+	for _, end := range me.ends {
+		me.records = append(me.records, me.fields[start:end:end])
+		start = end
+	}
+
+	err := fn(me.lines, me.records)
+	if fieldErr := r.fieldError(); fieldErr != nil {
+		err = fieldErr
+	}
+	if err != nil {
+		return atLines(me.lines[0], me.lines[len(me.lines)-1], err)
+	}
+
+	me.arena, me.spans, me.ends, me.lines = me.arena[:0], me.spans[:0], me.ends[:0], me.lines[:0]
+	return nil
+}
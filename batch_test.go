@@ -0,0 +1,69 @@
+package hastycsv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestReader_ReadBatches(t *testing.T) {
+	in := strings.NewReader("name|age\nbill|30\nmary|35\njoe|40\nann|45\nsue|50")
+
+	r := NewReader()
+	r.Comma = '|'
+	r.HasHeader = true
+
+	batches := []string{}
+	err := r.ReadBatches(in, 2, func(lines []int, records [][]Field) error {
+		assert.Equal(t, len(lines), len(records))
+		batch := []string{}
+		for j, record := range records {
+			batch = append(batch, fmt.Sprintf("%v:%v:%v", lines[j], record[0].String(), record[1].Uint32()))
+		}
+		batches = append(batches, strings.Join(batch, ","))
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"2:bill:30,3:mary:35",
+		"4:joe:40,5:ann:45",
+		"6:sue:50",
+	}, batches)
+	assert.Equal(t, []string{"name", "age"}, r.Header())
+}
+
+func TestReader_ReadBatches_errors(t *testing.T) {
+	r := NewReader()
+	r.Comma = '|'
+
+	err := r.ReadBatches(strings.NewReader("a|1"), 0, func(lines []int, records [][]Field) error { return nil })
+	assert.EqualError(t, err, "Batch size must be at least 1, but is 0")
+
+	err = r.ReadBatches(strings.NewReader("a|1\nb|2\nc|3"), 2, func(lines []int, records [][]Field) error {
+		return fmt.Errorf("Insert failed")
+	})
+	assert.EqualError(t, err, "Lines 1-2: Insert failed")
+
+	err = r.ReadBatches(strings.NewReader("a|1\nb|2\nc|x"), 2, func(lines []int, records [][]Field) error {
+		for _, record := range records {
+			record[1].Uint32()
+		}
+		return nil
+	})
+	assert.EqualError(t, err, `Line 3, column 2: Can't parse field as uint32: "x" contains non-numeric character 'x'`)
+
+	err = r.ReadBatches(strings.NewReader("a|x\nb|2"), 2, func(lines []int, records [][]Field) error {
+		records[0][1].Uint32()
+		return nil
+	})
+	assert.EqualError(t, err, `Lines 1-2: Column 2: Can't parse field as uint32: "x" contains non-numeric character 'x'`)
+	assert.True(t, errors.Is(err, ErrParse))
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr))
+
+	err = r.ReadBatches(strings.NewReader("a|1\nb"), 2, func(lines []int, records [][]Field) error { return nil })
+	assert.EqualError(t, err, `Line 2: Expected []b to contain 2 fields using delimiter '|': "b"`)
+}
@@ -0,0 +1,201 @@
+package hastycsv
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+// Converts records parsed according to a Schema into binary messages.
+type RecordEncoder interface {
+	// Appends the encoded form of record to buf and returns the extended
+	// buffer.
+	AppendRecord(buf []byte, record *Record) ([]byte, error)
+}
+
+// Writes records parsed by Reader.ReadWithSchema() to an io.Writer as a stream
+// of binary messages produced by a RecordEncoder (e.g. MsgpackEncoder).
+type BinaryWriter struct {
+	// LengthPrefixed indicates whether each message is preceded by its length
+	// in bytes, encoded as a uvarint.  This is required to read back formats
+	// that aren't self-delimiting, such as protocol buffers.
+	LengthPrefixed bool
+
+	w       *bufio.Writer
+	encoder RecordEncoder
+	buf     []byte
+}
+
+// Returns a new BinaryWriter that writes the messages produced by encoder to
+// w.
+func NewBinaryWriter(w io.Writer, encoder RecordEncoder) *BinaryWriter {
+	return &BinaryWriter{
+		w:       bufio.NewWriterSize(w, 32*1024),
+		encoder: encoder,
+	}
+}
+
+// Encodes record and writes it as a single message.
+func (me *BinaryWriter) WriteRecord(record *Record) error {
+	var err error
+	if me.buf, err = me.encoder.AppendRecord(me.buf[:0], record); err != nil {
+		return err
+	}
+
+	if me.LengthPrefixed {
+		var prefix [binary.MaxVarintLen64]byte
+		me.w.Write(prefix[:binary.PutUvarint(prefix[:], uint64(len(me.buf)))])
+	}
+	_, err = me.w.Write(me.buf)
+	return err
+}
+
+// Writes any buffered data to the underlying io.Writer.
+func (me *BinaryWriter) Flush() error {
+	return me.w.Flush()
+}
+
+// Encodes each record as a MessagePack map from column name to value, or (if
+// AsArray is set) as an array of values in schema column order.
+//
+// Null columns are encoded as nil, and TimeType columns use the MessagePack
+// timestamp extension type.
+type MsgpackEncoder struct {
+	AsArray bool
+}
+
+// Appends the MessagePack encoding of record to buf.
+func (me MsgpackEncoder) AppendRecord(buf []byte, record *Record) ([]byte, error) {
+	columns := record.schema.Columns
+	if me.AsArray {
+		buf = appendMsgpackHeader(buf, len(columns), 0x90, 0xdc, 0xdd)
+	} else {
+		buf = appendMsgpackHeader(buf, len(columns), 0x80, 0xde, 0xdf)
+	}
+
+	for col := range columns {
+		if !me.AsArray {
+			buf = appendMsgpackString(buf, columns[col].Name)
+		}
+
+		if record.IsNull(col) {
+			buf = append(buf, 0xc0)
+			continue
+		}
+
+		switch columns[col].Type {
+		case StringType:
+			buf = appendMsgpackString(buf, record.fields[col].unsafeString())
+		case Uint32Type:
+			buf = appendMsgpackUint(buf, uint64(record.Uint32(col)))
+		case Int64Type:
+			buf = appendMsgpackInt(buf, record.Int64(col))
+		case Float32Type:
+			buf = append(buf, 0xca)
+			buf = appendBigEndian32(buf, math.Float32bits(record.Float32(col)))
+		case Float64Type:
+			buf = append(buf, 0xcb)
+			buf = appendBigEndian64(buf, math.Float64bits(record.Float64(col)))
+		case BoolType:
+			if record.Bool(col) {
+				buf = append(buf, 0xc3)
+			} else {
+				buf = append(buf, 0xc2)
+			}
+		case TimeType:
+			buf = appendMsgpackTime(buf, record.Time(col))
+		}
+	}
+	return buf, nil
+}
+
+// Appends the header of a map or array of n elements, using the fix, 16-bit or
+// 32-bit form depending on n.
+func appendMsgpackHeader(buf []byte, n int, fix byte, code16 byte, code32 byte) []byte {
+	switch {
+	case n < 16:
+		return append(buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		return appendBigEndian16(append(buf, code16), uint16(n))
+	default:
+		return appendBigEndian32(append(buf, code32), uint32(n))
+	}
+}
+
+func appendMsgpackString(buf []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		buf = append(buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		buf = appendBigEndian16(append(buf, 0xda), uint16(n))
+	default:
+		buf = appendBigEndian32(append(buf, 0xdb), uint32(n))
+	}
+	return append(buf, s...)
+}
+
+func appendMsgpackUint(buf []byte, u uint64) []byte {
+	switch {
+	case u < 128:
+		return append(buf, byte(u))
+	case u <= math.MaxUint8:
+		return append(buf, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		return appendBigEndian16(append(buf, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		return appendBigEndian32(append(buf, 0xce), uint32(u))
+	default:
+		return appendBigEndian64(append(buf, 0xcf), u)
+	}
+}
+
+func appendMsgpackInt(buf []byte, i int64) []byte {
+	switch {
+	case i >= 0:
+		return appendMsgpackUint(buf, uint64(i))
+	case i >= -32:
+		return append(buf, byte(i)) // negative fixint
+	case i >= math.MinInt8:
+		return append(buf, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		return appendBigEndian16(append(buf, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		return appendBigEndian32(append(buf, 0xd2), uint32(i))
+	default:
+		return appendBigEndian64(append(buf, 0xd3), uint64(i))
+	}
+}
+
+// Appends t as a MessagePack timestamp (extension type -1), using the smallest
+// of the 32, 64 and 96-bit forms that can hold it.
+func appendMsgpackTime(buf []byte, t time.Time) []byte {
+	sec, nsec := t.Unix(), uint64(t.Nanosecond())
+	switch {
+	case sec>>34 == 0 && nsec == 0 && sec <= math.MaxUint32:
+		buf = append(buf, 0xd6, 0xff)
+		return appendBigEndian32(buf, uint32(sec))
+	case sec>>34 == 0:
+		buf = append(buf, 0xd7, 0xff)
+		return appendBigEndian64(buf, nsec<<34|uint64(sec))
+	default:
+		buf = append(buf, 0xc7, 12, 0xff)
+		buf = appendBigEndian32(buf, uint32(nsec))
+		return appendBigEndian64(buf, uint64(sec))
+	}
+}
+
+func appendBigEndian16(buf []byte, v uint16) []byte {
+	return append(buf, byte(v>>8), byte(v))
+}
+
+func appendBigEndian32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendBigEndian64(buf []byte, v uint64) []byte {
+	return appendBigEndian32(appendBigEndian32(buf, uint32(v>>32)), uint32(v))
+}
@@ -0,0 +1,139 @@
+package hastycsv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMsgpackEncoder(t *testing.T) {
+	schema := NewSchema(
+		StringColumn("s"),
+		Uint32Column("u"),
+		Int64Column("i"),
+		Float32Column("f32"),
+		Float64Column("f64"),
+		BoolColumn("b"),
+		TimeColumn("t", "2006-01-02"),
+	)
+
+	var encoded [][]byte
+	for _, encoder := range []MsgpackEncoder{{}, {AsArray: true}} {
+		buf, err := encodeTestRecord(t, encoder, schema, "ab|300|-2|0.5|1.5|true|1970-01-02")
+		require.Nil(t, err)
+		encoded = append(encoded, buf)
+	}
+
+	values := [][]byte{
+		{0xa2, 'a', 'b'},
+		{0xcd, 0x01, 0x2c},
+		{0xfe},
+		{0xca, 0x3f, 0x00, 0x00, 0x00},
+		{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0},
+		{0xc3},
+		{0xd6, 0xff, 0x00, 0x01, 0x51, 0x80},
+	}
+
+	asMap := []byte{0x87}
+	asArray := []byte{0x97}
+	for k, col := range schema.Columns {
+		asMap = append(append(append(asMap, 0xa0|byte(len(col.Name))), col.Name...), values[k]...)
+		asArray = append(asArray, values[k]...)
+	}
+	assert.Equal(t, asMap, encoded[0])
+	assert.Equal(t, asArray, encoded[1])
+}
+
+func TestMsgpackEncoder_nulls(t *testing.T) {
+	schema := NewSchema(StringColumn("s"), Uint32Column("u"))
+	buf, err := encodeTestRecord(t, MsgpackEncoder{AsArray: true}, schema, "|")
+	require.Nil(t, err)
+	assert.Equal(t, []byte{0x92, 0xc0, 0xc0}, buf)
+}
+
+func TestAppendMsgpackInt(t *testing.T) {
+	testCases := []struct {
+		i        int64
+		expected []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0xcc, 0x80}},
+		{65536, []byte{0xce, 0x00, 0x01, 0x00, 0x00}},
+		{1 << 32, []byte{0xcf, 0, 0, 0, 1, 0, 0, 0, 0}},
+		{-1, []byte{0xff}},
+		{-32, []byte{0xe0}},
+		{-33, []byte{0xd0, 0xdf}},
+		{-129, []byte{0xd1, 0xff, 0x7f}},
+		{-32769, []byte{0xd2, 0xff, 0xff, 0x7f, 0xff}},
+		{-1 << 40, []byte{0xd3, 0xff, 0xff, 0xff, 0x00, 0, 0, 0, 0}},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, appendMsgpackInt(nil, testCase.i), "%v", testCase.i)
+	}
+}
+
+func TestAppendMsgpackString(t *testing.T) {
+	assert.Equal(t, []byte{0xa0}, appendMsgpackString(nil, ""))
+	assert.Equal(t, append([]byte{0xd9, 32}, strings.Repeat("x", 32)...), appendMsgpackString(nil, strings.Repeat("x", 32)))
+	assert.Equal(t, append([]byte{0xda, 0x01, 0x00}, strings.Repeat("x", 256)...), appendMsgpackString(nil, strings.Repeat("x", 256)))
+}
+
+func TestAppendMsgpackTime(t *testing.T) {
+	// timestamp 64
+	ts := time.Unix(1, 5)
+	assert.Equal(t, []byte{0xd7, 0xff, 0, 0, 0, 0x14, 0, 0, 0, 0x01}, appendMsgpackTime(nil, ts))
+
+	// timestamp 96
+	ts = time.Unix(-1, 0)
+	assert.Equal(t, []byte{0xc7, 12, 0xff, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, appendMsgpackTime(nil, ts))
+}
+
+func TestBinaryWriter(t *testing.T) {
+	schema := NewSchema(StringColumn("name"), Uint32Column("age"))
+
+	var out bytes.Buffer
+	w := NewBinaryWriter(&out, MsgpackEncoder{AsArray: true})
+	w.LengthPrefixed = true
+
+	r := NewReader()
+	r.Comma = '|'
+	err := r.ReadWithSchema(strings.NewReader("bill|30\nmary|4"), schema, func(i int, record *Record) error {
+		return w.WriteRecord(record)
+	})
+	require.Nil(t, err)
+	require.Nil(t, w.Flush())
+
+	messages := [][]byte{}
+	data := out.Bytes()
+	for len(data) > 0 {
+		n, k := binary.Uvarint(data)
+		messages = append(messages, data[k:k+int(n)])
+		data = data[k+int(n):]
+	}
+	assert.Equal(t, [][]byte{
+		{0x92, 0xa4, 'b', 'i', 'l', 'l', 30},
+		{0x92, 0xa4, 'm', 'a', 'r', 'y', 4},
+	}, messages)
+}
+
+// Test helper that parses line according to schema and encodes the resulting
+// record.
+func encodeTestRecord(t *testing.T, encoder RecordEncoder, schema *Schema, line string) ([]byte, error) {
+	r := NewReader()
+	r.Comma = '|'
+
+	var buf []byte
+	var encodeErr error
+	err := r.ReadWithSchema(strings.NewReader(line), schema, func(i int, record *Record) error {
+		buf, encodeErr = encoder.AppendRecord(nil, record)
+		return nil
+	})
+	require.Nil(t, err)
+	return buf, encodeErr
+}
@@ -0,0 +1,56 @@
+package hastycsv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// Opens objects in a storage system such as S3, GCS or Azure Blob Storage.
+// hastycsv doesn't depend on any provider's SDK; implementations are supplied
+// by the user (see BlobOpenerFunc).
+type BlobOpener interface {
+	// Open returns a reader for the object identified by uri, e.g.
+	// "s3://bucket/path/data.csv".
+	Open(ctx context.Context, uri string) (io.ReadCloser, error)
+}
+
+// Adapts a function to the BlobOpener interface.
+type BlobOpenerFunc func(ctx context.Context, uri string) (io.ReadCloser, error)
+
+func (me BlobOpenerFunc) Open(ctx context.Context, uri string) (io.ReadCloser, error) {
+	return me(ctx, uri)
+}
+
+// Opens the object identified by uri using opener, and invokes nextRecord with
+// each of its records.  As with ReadFile(), the object is decompressed if a
+// Decompressor is registered for its extension (e.g. "data.csv.gz").
+func ReadFrom(ctx context.Context, opener BlobOpener, uri string, comma byte, nextRecord Next) error {
+	blob, err := opener.Open(ctx, uri)
+	if err != nil {
+		return fmt.Errorf("Can't open %v: %w", uri, err)
+	}
+	defer blob.Close()
+
+	var in io.Reader = blob
+	path := uri
+	if u, err := url.Parse(uri); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	if decompress := decompressorFor(path); decompress != nil {
+		dr, err := decompress(blob)
+		if err != nil {
+			return fmt.Errorf("Can't decompress %v: %w", uri, err)
+		}
+		defer dr.Close()
+		in = dr
+	}
+
+	readAhead := NewReadAheadReader(in, 32*1024)
+	defer readAhead.Close()
+
+	r := NewReader()
+	r.Comma = comma
+	return r.Read(readAhead, nextRecord)
+}
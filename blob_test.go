@@ -0,0 +1,52 @@
+package hastycsv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestReadFrom(t *testing.T) {
+	blobs := map[string][]byte{
+		"mem://bucket/people.csv":    []byte("mary,35\nbill,40\n"),
+		"mem://bucket/people.csv.gz": gzipBytes(t, "mary,35\nbill,40\n"),
+	}
+	closed := 0
+	opener := BlobOpenerFunc(func(ctx context.Context, uri string) (io.ReadCloser, error) {
+		data, ok := blobs[uri]
+		if !ok {
+			return nil, fmt.Errorf("No such object")
+		}
+		return &closeCounter{Reader: bytes.NewReader(data), closed: &closed}, nil
+	})
+
+	for uri := range blobs {
+		names := []string{}
+		err := ReadFrom(context.Background(), opener, uri, ',', func(i int, fields []Field) error {
+			names = append(names, fields[0].String())
+			return nil
+		})
+		assert.Nil(t, err, uri)
+		assert.Equal(t, []string{"mary", "bill"}, names, uri)
+	}
+	assert.Equal(t, 2, closed)
+
+	err := ReadFrom(context.Background(), opener, "mem://bucket/missing.csv", ',', func(i int, fields []Field) error {
+		return nil
+	})
+	assert.EqualError(t, err, "Can't open mem://bucket/missing.csv: No such object")
+}
+
+// Test helper that counts calls to Close().
+type closeCounter struct {
+	io.Reader
+	closed *int
+}
+
+func (me *closeCounter) Close() error {
+	*me.closed++
+	return nil
+}
@@ -0,0 +1,35 @@
+package hastycsv
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+)
+
+// Feeds the raw line of the current record to RowDigest, if set.
+func (me *Reader) hashRow(line []byte) {
+	if me.RowDigest != nil {
+		me.RowDigest.Reset()
+		me.RowDigest.Write(line)
+	}
+}
+
+// Returns nil if the checksum of the input of the most recent read, as
+// computed by Digest, matches expectedHex (a hex-encoded checksum, as found in
+// manifests and `sha256sum` output).  Otherwise, returns an error describing
+// the mismatch.
+func (me *Reader) VerifyDigest(expectedHex string) error {
+	if me.Digest == nil {
+		return fmt.Errorf("Reader has no Digest")
+	}
+
+	expected, err := hex.DecodeString(expectedHex)
+	if err != nil {
+		return fmt.Errorf("Invalid checksum %q: %w", expectedHex, err)
+	}
+
+	if actual := me.Digest.Sum(nil); !bytes.Equal(actual, expected) {
+		return fmt.Errorf("Checksum mismatch: expected %x, got %x", expected, actual)
+	}
+	return nil
+}
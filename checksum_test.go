@@ -0,0 +1,59 @@
+package hastycsv
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestReader_Digest(t *testing.T) {
+	in := "name,age\nbill,30\nmary,35\n"
+	expected := fmt.Sprintf("%x", sha256.Sum256([]byte(in)))
+
+	r := NewReader()
+	r.HasHeader = true
+	r.Digest = sha256.New()
+
+	require.Nil(t, r.Read(strings.NewReader(in), func(i int, fields []Field) error { return nil }))
+	assert.Equal(t, expected, fmt.Sprintf("%x", r.Digest.Sum(nil)))
+	assert.Nil(t, r.VerifyDigest(expected))
+
+	// The digest is reset by every read
+	require.Nil(t, r.ReadBytes([]byte(in), func(i int, fields []Field) error { return nil }))
+	assert.Nil(t, r.VerifyDigest(expected))
+
+	require.Nil(t, r.Read(strings.NewReader("a,b\n"), func(i int, fields []Field) error { return nil }))
+	err := r.VerifyDigest(expected)
+	assert.EqualError(t, err, fmt.Sprintf("Checksum mismatch: expected %v, got %x", expected, sha256.Sum256([]byte("a,b\n"))))
+
+	assert.EqualError(t, r.VerifyDigest("xyz"), `Invalid checksum "xyz": encoding/hex: invalid byte: U+0078 'x'`)
+	assert.EqualError(t, NewReader().VerifyDigest(expected), "Reader has no Digest")
+}
+
+func TestReader_RowDigest(t *testing.T) {
+	in := "bill,30\r\nmary,35\n"
+
+	for _, read := range []func(r *Reader, next Next) error{
+		func(r *Reader, next Next) error { return r.Read(strings.NewReader(in), next) },
+		func(r *Reader, next Next) error { return r.ReadBytes([]byte(in), next) },
+	} {
+		r := NewReader()
+		r.RowDigest = md5.New()
+
+		sums := []string{}
+		err := read(r, func(i int, fields []Field) error {
+			sums = append(sums, fmt.Sprintf("%x", r.RowDigest.Sum(nil)))
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{
+			fmt.Sprintf("%x", md5.Sum([]byte("bill,30"))),
+			fmt.Sprintf("%x", md5.Sum([]byte("mary,35"))),
+		}, sums)
+	}
+}
@@ -0,0 +1,442 @@
+// Command hastycsvgen generates reflection-free record decoders for structs.
+//
+// For each struct type named by the -type flag, hastycsvgen emits a DecodeRow
+// method that decodes a []hastycsv.Field record directly into the struct's
+// fields, without the reflection overhead of hastycsv.Decoder:
+//
+//	//go:generate go run github.com/cet001/hastycsv/cmd/hastycsvgen -type=Car
+//	type Car struct {
+//		Make  string  `csv:"make"`
+//		Year  uint32  `csv:"year"`
+//		MPG   float32 `csv:"mpg"`
+//		Sold  time.Time `csv:"sold,layout=2006-01-02"`
+//	}
+//
+// Columns are mapped to struct fields as hastycsv.Decoder maps them: by the
+// name in the field's `csv:"name"` tag (or the field's own name), or by
+// position if the tags contain column indexes (e.g. `csv:"2"`).  Fields tagged
+// `csv:"-"` are skipped, and the "layout", "required" and "default" tag options
+// are honoured.  The generated NewCarColumns() function resolves the columns
+// of a header once, and DecodeRow() then decodes each record using them:
+//
+//	columns, err := NewCarColumns(reader.Header())
+//	...
+//	err = car.DecodeRow(&columns, fields)
+//
+// Fields whose column is missing or empty are left set to their zero value.
+//
+// Supported field types are string, []byte, bool, all int, uint and float
+// types, and time.Time.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of struct type names; must be set")
+	output := flag.String("output", "", "output file name; default <dir>/<type>_hastycsv.go")
+	flag.Parse()
+
+	if *typeNames == "" {
+		fmt.Fprintln(os.Stderr, "hastycsvgen: -type must be set")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	types := strings.Split(*typeNames, ",")
+	src, err := generateForDir(dir, types)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hastycsvgen: %v\n", err)
+		os.Exit(1)
+	}
+
+	outputFile := *output
+	if outputFile == "" {
+		outputFile = filepath.Join(dir, strings.ToLower(types[0])+"_hastycsv.go")
+	}
+
+	if err := os.WriteFile(outputFile, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "hastycsvgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// Parses the Go package in the specified directory and generates decoders for
+// the specified struct types.
+func generateForDir(dir string, typeNames []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range pkgs {
+		files := []*ast.File{}
+		for _, f := range pkg.Files {
+			files = append(files, f)
+		}
+		return generate(pkg.Name, files, typeNames)
+	}
+
+	return nil, fmt.Errorf("No Go package found in %v", dir)
+}
+
+// A struct field to be decoded, and the column it's decoded from.
+type column struct {
+	field        string
+	name         string
+	position     int    // column index if the tag's name is a number, otherwise -1
+	typ          string // Go type of the field, e.g. "uint32" or "time.Time"
+	layout       string // quoted time layout, for time.Time fields
+	required     bool
+	hasDefault   bool
+	defaultValue string
+}
+
+// Generates the source of a file containing a DecodeRow method for each of
+// the specified struct types.
+func generate(pkgName string, files []*ast.File, typeNames []string) ([]byte, error) {
+	var body bytes.Buffer
+	imports := map[string]bool{"github.com/cet001/hastycsv": true}
+
+	for _, typeName := range typeNames {
+		st, err := findStruct(files, typeName)
+		if err != nil {
+			return nil, err
+		}
+
+		columns, err := structColumns(typeName, st)
+		if err != nil {
+			return nil, err
+		}
+
+		positional, err := isPositional(typeName, columns)
+		if err != nil {
+			return nil, err
+		}
+
+		writeColumns(&body, typeName, columns, positional, imports)
+		writeDecodeRow(&body, typeName, columns, imports)
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by hastycsvgen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %v\n\n", pkgName)
+	fmt.Fprintf(&src, "import (\n")
+	for _, path := range []string{"fmt", "github.com/cet001/hastycsv"} {
+		if imports[path] {
+			fmt.Fprintf(&src, "\t%q\n", path)
+		}
+	}
+	fmt.Fprintf(&src, ")\n")
+	src.Write(body.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("Error formatting generated code: %v", err)
+	}
+	return formatted, nil
+}
+
+func findStruct(files []*ast.File, typeName string) (*ast.StructType, error) {
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if typeSpec.Name.Name != typeName {
+					continue
+				}
+				st, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					return nil, fmt.Errorf("%v is not a struct type", typeName)
+				}
+				return st, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("Type %v not found", typeName)
+}
+
+func structColumns(typeName string, st *ast.StructType) ([]column, error) {
+	columns := []column{}
+
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			return nil, fmt.Errorf("%v: embedded fields are not supported", typeName)
+		}
+
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			tagValue, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			tag = reflect.StructTag(tagValue)
+		}
+
+		for _, name := range field.Names {
+			col, skip := parseTag(name.Name, tag.Get("csv"))
+			if skip || !name.IsExported() {
+				continue
+			}
+
+			col.typ = typeString(field.Type)
+			if !isSupportedType(col.typ) {
+				return nil, fmt.Errorf("%v: field type %v is not supported", typeName, col.typ)
+			}
+			columns = append(columns, col)
+		}
+	}
+
+	return columns, nil
+}
+
+// Parses the `csv` tag of the specified struct field in the same way as
+// hastycsv's parseFieldTag().  Returns true if the field is skipped.
+func parseTag(fieldName string, tag string) (column, bool) {
+	parts := strings.Split(tag, ",")
+	col := column{field: fieldName, name: strings.TrimSpace(parts[0]), position: -1, layout: strconv.Quote(time.RFC3339)}
+	if col.name == "-" && len(parts) == 1 {
+		return col, true
+	}
+	if col.name == "" {
+		col.name = fieldName
+	}
+	if position, err := strconv.Atoi(col.name); err == nil && position >= 0 {
+		col.position = position
+	}
+
+	for _, option := range parts[1:] {
+		option = strings.TrimSpace(option)
+		key, value := option, ""
+		if idx := strings.IndexByte(option, '='); idx != -1 {
+			key, value = option[:idx], option[idx+1:]
+		}
+
+		switch key {
+		case "layout":
+			col.layout = strconv.Quote(value)
+		case "required":
+			col.required = true
+		case "default":
+			col.hasDefault = true
+			col.defaultValue = value
+		}
+	}
+
+	return col, false
+}
+
+// Returns true if the columns are mapped by position rather than by name.  A
+// struct must use either positional or named tags, not both.
+func isPositional(typeName string, columns []column) (bool, error) {
+	positional, named := 0, 0
+	for _, col := range columns {
+		if col.position >= 0 {
+			positional++
+		} else {
+			named++
+		}
+	}
+
+	if positional > 0 && named > 0 {
+		return false, fmt.Errorf("%v: struct mixes positional and named column tags (or untagged fields)", typeName)
+	}
+	return positional > 0, nil
+}
+
+// Returns the source representation of a (simple) type expression.
+func typeString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return typeString(t.X) + "." + t.Sel.Name
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "[]" + typeString(t.Elt)
+		}
+	case *ast.StarExpr:
+		return "*" + typeString(t.X)
+	}
+	return fmt.Sprintf("%T", expr)
+}
+
+func isSupportedType(typ string) bool {
+	switch typ {
+	case "string", "[]byte", "bool", "time.Time",
+		"int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64":
+		return true
+	}
+	return false
+}
+
+// Returns the bit size of an int, uint or float type name.
+func bitSize(typ string) string {
+	digits := strings.TrimLeft(typ, "uintfloa")
+	if digits == "" {
+		return "0"
+	}
+	return digits
+}
+
+// Writes the <type>Columns type, which holds the index of each column within
+// a record, and the New<type>Columns() function that resolves it.
+func writeColumns(w *bytes.Buffer, typeName string, columns []column, positional bool, imports map[string]bool) {
+	fmt.Fprintf(w, "\n// %vColumns holds the index within a record of each column decoded by\n", typeName)
+	fmt.Fprintf(w, "// %v.DecodeRow(), or -1 if the column is missing.\n", typeName)
+	fmt.Fprintf(w, "type %vColumns [%v]int\n", typeName, len(columns))
+
+	if positional {
+		positions := make([]string, len(columns))
+		for i, col := range columns {
+			positions[i] = strconv.Itoa(col.position)
+		}
+
+		fmt.Fprintf(w, "\n// New%vColumns returns the %vColumns of a %v, whose fields are\n", typeName, typeName, typeName)
+		fmt.Fprintf(w, "// mapped to columns by position; the header is ignored.\n")
+		fmt.Fprintf(w, "func New%vColumns(header []string) (%vColumns, error) {\n", typeName, typeName)
+		fmt.Fprintf(w, "return %vColumns{%v}, nil\n}\n", typeName, strings.Join(positions, ", "))
+		return
+	}
+
+	fmt.Fprintf(w, "\n// New%vColumns maps the columns of the specified header (e.g. as returned by\n", typeName)
+	fmt.Fprintf(w, "// hastycsv.Reader.Header()) to the fields of a %v.\n", typeName)
+	fmt.Fprintf(w, "func New%vColumns(header []string) (%vColumns, error) {\n", typeName, typeName)
+	fmt.Fprintf(w, "var columns %vColumns\n", typeName)
+	fmt.Fprintf(w, "for i := range columns {\ncolumns[i] = -1\n}\n\n")
+
+	// Several fields may share a column; the leftmost column of a name wins.
+	names := []string{}
+	indexes := map[string][]string{}
+	for i, col := range columns {
+		if _, ok := indexes[col.name]; !ok {
+			names = append(names, col.name)
+		}
+		indexes[col.name] = append(indexes[col.name], fmt.Sprintf("columns[%v]", i))
+	}
+
+	fmt.Fprintf(w, "for i := len(header) - 1; i >= 0; i-- {\n")
+	fmt.Fprintf(w, "switch header[i] {\n")
+	for _, name := range names {
+		targets := indexes[name]
+		values := strings.TrimSuffix(strings.Repeat("i, ", len(targets)), ", ")
+		fmt.Fprintf(w, "case %q:\n%v = %v\n", name, strings.Join(targets, ", "), values)
+	}
+	fmt.Fprintf(w, "}\n}\n")
+
+	for i, col := range columns {
+		if col.required {
+			imports["fmt"] = true
+			fmt.Fprintf(w, "\nif columns[%v] < 0 {\n", i)
+			fmt.Fprintf(w, "return columns, fmt.Errorf(%q)\n}\n", fmt.Sprintf(`Required column "%v" not found in header`, col.name))
+		}
+	}
+	fmt.Fprintf(w, "\nreturn columns, nil\n}\n")
+}
+
+func writeDecodeRow(w *bytes.Buffer, typeName string, columns []column, imports map[string]bool) {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.name
+	}
+
+	fmt.Fprintf(w, "\n// DecodeRow decodes a record with the columns %v into this %v.\n", strings.Join(names, ", "), typeName)
+	fmt.Fprintf(w, "func (me *%v) DecodeRow(columns *%vColumns, fields []hastycsv.Field) error {\n", typeName, typeName)
+	fmt.Fprintf(w, "*me = %v{}\n", typeName)
+	if len(columns) > 0 {
+		fmt.Fprintf(w, "var f hastycsv.Field\n")
+	}
+
+	for i, col := range columns {
+		fmt.Fprintf(w, "\n// %v\n", col.name)
+		fmt.Fprintf(w, "f = hastycsv.Field{}\n")
+		fmt.Fprintf(w, "if columns[%v] >= 0 && columns[%v] < len(fields) {\nf = fields[columns[%v]]\n}\n", i, i, i)
+		switch {
+		case col.required:
+			imports["fmt"] = true
+			fmt.Fprintf(w, "if f.IsEmpty() {\n")
+			fmt.Fprintf(w, "return fmt.Errorf(%q)\n}\n", fmt.Sprintf(`Column "%v" is required, but the field is empty`, col.name))
+		case col.hasDefault:
+			fmt.Fprintf(w, "if f.IsEmpty() {\nf = %v\n}\n", defaultVar(typeName, col))
+		}
+		fmt.Fprintf(w, "if !f.IsEmpty() {\n")
+
+		if col.typ != "string" && col.typ != "[]byte" {
+			imports["fmt"] = true
+		}
+		errMsg := fmt.Sprintf("fmt.Errorf(`Can't decode column \"%v\": %%v`, err)", col.name)
+		switch col.typ {
+		case "string":
+			fmt.Fprintf(w, "me.%v = f.String()\n", col.field)
+		case "[]byte":
+			fmt.Fprintf(w, "me.%v = append([]byte(nil), f.Bytes()...)\n", col.field)
+		case "bool":
+			fmt.Fprintf(w, "v, err := f.ParseBool()\n")
+			fmt.Fprintf(w, "if err != nil {\nreturn %v\n}\n", errMsg)
+			fmt.Fprintf(w, "me.%v = v\n", col.field)
+		case "time.Time":
+			fmt.Fprintf(w, "v, err := f.ParseTime(%v)\n", col.layout)
+			fmt.Fprintf(w, "if err != nil {\nreturn %v\n}\n", errMsg)
+			fmt.Fprintf(w, "me.%v = v\n", col.field)
+		case "uint32":
+			fmt.Fprintf(w, "v, err := hastycsv.ParseUint32(f.Bytes())\n")
+			fmt.Fprintf(w, "if err != nil {\nreturn %v\n}\n", errMsg)
+			fmt.Fprintf(w, "me.%v = v\n", col.field)
+		case "int", "int8", "int16", "int32", "int64":
+			fmt.Fprintf(w, "v, err := f.ParseInt(%v)\n", bitSize(col.typ))
+			fmt.Fprintf(w, "if err != nil {\nreturn %v\n}\n", errMsg)
+			fmt.Fprintf(w, "me.%v = %v(v)\n", col.field, col.typ)
+		case "uint", "uint8", "uint16", "uint64":
+			fmt.Fprintf(w, "v, err := f.ParseUint(%v)\n", bitSize(col.typ))
+			fmt.Fprintf(w, "if err != nil {\nreturn %v\n}\n", errMsg)
+			fmt.Fprintf(w, "me.%v = %v(v)\n", col.field, col.typ)
+		case "float32", "float64":
+			fmt.Fprintf(w, "v, err := f.ParseFloat(%v)\n", bitSize(col.typ))
+			fmt.Fprintf(w, "if err != nil {\nreturn %v\n}\n", errMsg)
+			fmt.Fprintf(w, "me.%v = %v(v)\n", col.field, col.typ)
+		}
+
+		fmt.Fprintf(w, "}\n")
+	}
+
+	fmt.Fprintf(w, "\nreturn nil\n}\n")
+
+	for _, col := range columns {
+		if col.hasDefault {
+			fmt.Fprintf(w, "\nvar %v = hastycsv.NewField([]byte(%q))\n", defaultVar(typeName, col), col.defaultValue)
+		}
+	}
+}
+
+// Returns the name of the variable holding the default value of a column.
+func defaultVar(typeName string, col column) string {
+	return "hastycsv" + typeName + col.field + "Default"
+}
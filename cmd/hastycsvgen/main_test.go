@@ -0,0 +1,226 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = `package cars
+
+import "time"
+
+type Car struct {
+	Make    string    ` + "`csv:\"make\"`" + `
+	Model   string
+	Year    uint32    ` + "`csv:\"year\"`" + `
+	MPG     float32   ` + "`csv:\"mpg\"`" + `
+	Sold    time.Time ` + "`csv:\"sold,layout=2006-01-02\"`" + `
+	Doors   int8
+	Miles   uint64
+	Price   float64
+	Used    bool
+	VIN     []byte
+	Color   string    ` + "`csv:\"color,default=black\"`" + `
+	Ignored string    ` + "`csv:\"-\"`" + `
+	notes   string
+}
+
+type Boat struct {
+	Name     string    ` + "`csv:\"name\"`" + `
+	Launched time.Time ` + "`csv:\"launched\"`" + `
+}
+
+type Point struct {
+	X int64 ` + "`csv:\"1\"`" + `
+	Y int64 ` + "`csv:\"0\"`" + `
+}
+
+type Plate struct {
+	Number string ` + "`csv:\"number,required\"`" + `
+}
+
+type Mixed struct {
+	A string ` + "`csv:\"0\"`" + `
+	B string
+}
+
+type NotAStruct int
+
+type Unsupported struct {
+	Owners []string
+}
+`
+
+const testMain = `package cars
+
+import (
+	"fmt"
+	"github.com/cet001/hastycsv"
+	"strings"
+)
+
+func main() {
+	in := strings.NewReader("Model|make|year|mpg|sold|Doors|Miles|Price|Used|VIN|Ignored\nCivic|Honda|2017|31.5|2019-06-27|4|12000|18500.5|true|1HGCM|x\nM3|BMW|2015|18.7|||||||\nM3|BMW|x|18.7|||||||")
+
+	r := hastycsv.NewReader()
+	r.Comma = '|'
+	r.HasHeader = true
+	var columns CarColumns
+	err := r.Read(in, func(i int, fields []hastycsv.Field) error {
+		if i == 2 {
+			var err error
+			if columns, err = NewCarColumns(r.Header()); err != nil {
+				return err
+			}
+		}
+
+		var car Car
+		if err := car.DecodeRow(&columns, fields); err != nil {
+			return err
+		}
+		fmt.Printf("%v %v %v %v %v %v %v %v %v %s %v\n", car.Make, car.Model, car.Year, car.MPG, car.Sold.Format("2006-01-02"), car.Doors, car.Miles, car.Price, car.Used, car.VIN, car.Color)
+		return nil
+	})
+	fmt.Println(err)
+
+	pointColumns, _ := NewPointColumns(nil)
+	var p Point
+	fmt.Println(p.DecodeRow(&pointColumns, []hastycsv.Field{hastycsv.NewField([]byte("2")), hastycsv.NewField([]byte("1"))}), p.X, p.Y)
+
+	_, err = NewPlateColumns([]string{"make"})
+	fmt.Println(err)
+}
+`
+
+func TestGenerate(t *testing.T) {
+	src, err := generate("cars", parseTestSource(t), []string{"Car"})
+	require.Nil(t, err)
+
+	code := string(src)
+	assert.Contains(t, code, "// Code generated by hastycsvgen; DO NOT EDIT.")
+	assert.Contains(t, code, "func NewCarColumns(header []string) (CarColumns, error) {")
+	assert.Contains(t, code, "func (me *Car) DecodeRow(columns *CarColumns, fields []hastycsv.Field) error {")
+	assert.Contains(t, code, `case "make":`)
+	assert.Contains(t, code, `case "Model":`)
+	assert.Contains(t, code, "hastycsv.ParseUint32(f.Bytes())")
+	assert.Contains(t, code, `f.ParseTime("2006-01-02")`)
+	assert.Contains(t, code, "f.ParseInt(8)")
+	assert.NotContains(t, code, "Ignored")
+	assert.NotContains(t, code, "notes")
+	assert.NotContains(t, code, "string(b)")
+}
+
+func TestGenerate_positional(t *testing.T) {
+	src, err := generate("cars", parseTestSource(t), []string{"Point"})
+	require.Nil(t, err)
+
+	code := string(src)
+	assert.Contains(t, code, "return PointColumns{1, 0}, nil")
+	assert.Contains(t, code, "f.ParseInt(64)")
+}
+
+func TestGenerate_errors(t *testing.T) {
+	files := parseTestSource(t)
+
+	_, err := generate("cars", files, []string{"Missing"})
+	assert.EqualError(t, err, "Type Missing not found")
+
+	_, err = generate("cars", files, []string{"NotAStruct"})
+	assert.EqualError(t, err, "NotAStruct is not a struct type")
+
+	_, err = generate("cars", files, []string{"Unsupported"})
+	assert.EqualError(t, err, "Unsupported: field type []string is not supported")
+
+	_, err = generate("cars", files, []string{"Mixed"})
+	assert.EqualError(t, err, "Mixed: struct mixes positional and named column tags (or untagged fields)")
+}
+
+// Generates a decoder, compiles it together with a small program that uses
+// it, and checks the program's output.
+func TestGenerate_compiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compilation test in short mode")
+	}
+
+	tmpDir := writeTestModule(t)
+	require.Nil(t, os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte(testMain), 0644))
+
+	src, err := generateForDir(tmpDir, []string{"Car", "Point", "Plate"})
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(filepath.Join(tmpDir, "car_hastycsv.go"), src, 0644))
+
+	// Turn the package into a runnable program.
+	for _, name := range []string{"car.go", "main.go", "car_hastycsv.go"} {
+		path := filepath.Join(tmpDir, name)
+		b, err := os.ReadFile(path)
+		require.Nil(t, err)
+		require.Nil(t, os.WriteFile(path, []byte(strings.Replace(string(b), "package cars", "package main", 1)), 0644))
+	}
+
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = tmpDir
+	out, err := cmd.CombinedOutput()
+	require.Nil(t, err, string(out))
+
+	assert.Equal(t, "Honda Civic 2017 31.5 2019-06-27 4 12000 18500.5 true 1HGCM black\n"+
+		"BMW M3 2015 18.7 0001-01-01 0 0 0 false  black\n"+
+		"Line 4: Can't decode column \"year\": \"x\" contains non-numeric character 'x'\n"+
+		"<nil> 1 2\n"+
+		"Required column \"number\" not found in header\n", string(out))
+}
+
+// Generates the decoders of two types into separate files of the same
+// package, as two go:generate directives would, and checks that the package
+// builds.
+func TestGenerate_separateFiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compilation test in short mode")
+	}
+
+	tmpDir := writeTestModule(t)
+	for _, typeName := range []string{"Car", "Boat"} {
+		src, err := generateForDir(tmpDir, []string{typeName})
+		require.Nil(t, err)
+		require.Nil(t, os.WriteFile(filepath.Join(tmpDir, strings.ToLower(typeName)+"_hastycsv.go"), src, 0644))
+	}
+
+	boatSrc, err := os.ReadFile(filepath.Join(tmpDir, "boat_hastycsv.go"))
+	require.Nil(t, err)
+	assert.Contains(t, string(boatSrc), `f.ParseTime("2006-01-02T15:04:05Z07:00")`)
+
+	cmd := exec.Command("go", "build", ".")
+	cmd.Dir = tmpDir
+	out, err := cmd.CombinedOutput()
+	require.Nil(t, err, string(out))
+}
+
+// Test helper
+func writeTestModule(t *testing.T) string {
+	repoDir, err := filepath.Abs("../..")
+	require.Nil(t, err)
+
+	tmpDir := t.TempDir()
+	goMod := "module cars\n\ngo 1.18\n\nrequire github.com/cet001/hastycsv v0.0.0\n\nreplace github.com/cet001/hastycsv => " + repoDir + "\n"
+	goSum, err := os.ReadFile(filepath.Join(repoDir, "go.sum"))
+	require.Nil(t, err)
+
+	require.Nil(t, os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte(goMod), 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(tmpDir, "go.sum"), goSum, 0644))
+	require.Nil(t, os.WriteFile(filepath.Join(tmpDir, "car.go"), []byte(testSource), 0644))
+	return tmpDir
+}
+
+// Test helper
+func parseTestSource(t *testing.T) []*ast.File {
+	f, err := parser.ParseFile(token.NewFileSet(), "car.go", testSource, 0)
+	require.Nil(t, err)
+	return []*ast.File{f}
+}
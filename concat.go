@@ -0,0 +1,148 @@
+package hastycsv
+
+type concatConfig struct {
+	reader  *Reader
+	reorder bool
+}
+
+// Configures Concat().
+type ConcatOption func(cfg *concatConfig)
+
+// Returns a ConcatOption that makes Concat() parse its input files using the
+// specified Reader, e.g. to enable QuoteFallback.  HasHeader is set on it
+// regardless.  By default, a Reader returned by NewReader() with the Writer's
+// Comma delimiter is used.
+func WithConcatReader(reader *Reader) ConcatOption {
+	return func(cfg *concatConfig) {
+		cfg.reader = reader
+	}
+}
+
+// Returns a ConcatOption that makes Concat() accept files whose header lists
+// the same columns as the first file's header in a different order; their
+// records are reordered to match the first file's columns.
+func WithReorderColumns() ConcatOption {
+	return func(cfg *concatConfig) {
+		cfg.reorder = true
+	}
+}
+
+// Writes the records of the specified CSV files (see OpenFile()), one file
+// after the other, to dst.  Every file must start with a header: the first
+// file's header is written once, and the headers of the other files are
+// dropped.  A file whose header differs from the first file's header fails
+// with a *FileError wrapping a *HeaderOrderError, unless WithReorderColumns()
+// is used and the file merely lists the same columns in a different order.
+// dst is flushed once all files have been written.
+func Concat(dst *Writer, paths []string, options ...ConcatOption) error {
+	cfg := &concatConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+	if cfg.reader == nil {
+		cfg.reader = NewReader()
+		cfg.reader.Comma = dst.Comma
+	}
+	cfg.reader.HasHeader = true
+
+	c := &concatenator{dst: dst, reader: cfg.reader, reorder: cfg.reorder}
+	for _, path := range paths {
+		if err := c.concatFile(path); err != nil {
+			return &FileError{Path: path, Err: err}
+		}
+	}
+	return dst.Flush()
+}
+
+// The state of a Concat() call.
+type concatenator struct {
+	dst     *Writer
+	reader  *Reader
+	reorder bool
+	header  []string // the first file's header
+
+	// The position within the current file's records of each of the first
+	// file's columns, if its columns are in a different order.
+	positions  []int
+	projection []Field
+}
+
+func (me *concatenator) concatFile(path string) error {
+	f, err := OpenFile(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reconciled := false
+	err = me.reader.Read(f, func(i int, record []Field) error {
+		if !reconciled {
+			reconciled = true
+			if err := me.reconcile(me.reader.Header()); err != nil {
+				return err
+			}
+		}
+
+		if me.positions == nil {
+			return me.dst.writeLine(me.reader.raw, len(record))
+		}
+		for i, pos := range me.positions {
+			me.projection[i] = record[pos]
+		}
+		return me.dst.WriteFields(me.projection)
+	})
+	if err != nil {
+		return err
+	}
+
+	if header := me.reader.Header(); !reconciled && header != nil {
+		// The file has a header but no records.
+		return me.reconcile(header)
+	}
+	return nil
+}
+
+// Compares the header of the current file with that of the first file (or
+// writes it, if this is the first file), and prepares the reordering of the
+// file's records if needed.
+func (me *concatenator) reconcile(header []string) error {
+	me.positions = nil
+	if me.header == nil {
+		me.header = header
+		return me.dst.Write(header)
+	}
+
+	if equalStrings(header, me.header) {
+		return nil
+	}
+
+	if me.reorder && len(header) == len(me.header) {
+		positions := make([]int, len(me.header))
+		found := true
+		for i, name := range me.header {
+			if positions[i] = indexOfString(header, name); positions[i] < 0 {
+				found = false
+				break
+			}
+		}
+		if found {
+			me.positions = positions
+			me.projection = make([]Field, len(positions))
+			return nil
+		}
+	}
+
+	return &HeaderOrderError{Expected: me.header, Actual: header}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package hastycsv
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConcat(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	paths := writeConcatFiles(t, dir, "id,name\n1,bill\n", "id,name\n", "id,name\n2,mary\n3,joe")
+
+	var buf bytes.Buffer
+	assert.Nil(t, Concat(NewWriter(&buf), paths))
+	assert.Equal(t, "id,name\n1,bill\n2,mary\n3,joe\n", buf.String())
+}
+
+func TestConcat_headerMismatch(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	paths := writeConcatFiles(t, dir, "id,name\n1,bill\n", "name,id\nmary,2\n")
+
+	var buf bytes.Buffer
+	err := Concat(NewWriter(&buf), paths)
+	assert.EqualError(t, err, paths[1]+`: Line 2: Header doesn't match the expected columns: column 1 is "name", expected "id"; column 2 is "id", expected "name"`)
+
+	var fileErr *FileError
+	require.True(t, errors.As(err, &fileErr))
+	assert.Equal(t, paths[1], fileErr.Path)
+	var orderErr *HeaderOrderError
+	assert.True(t, errors.As(err, &orderErr))
+
+	// Reorder the columns instead
+	buf.Reset()
+	assert.Nil(t, Concat(NewWriter(&buf), paths, WithReorderColumns()))
+	assert.Equal(t, "id,name\n1,bill\n2,mary\n", buf.String())
+
+	// A different set of columns can't be reordered
+	paths = writeConcatFiles(t, dir, "id,name\n1,bill\n", "name,age\nmary,35\n")
+	err = Concat(NewWriter(&buf), paths, WithReorderColumns())
+	assert.True(t, errors.As(err, &orderErr))
+}
+
+func TestConcat_reader(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	paths := writeConcatFiles(t, dir, "id|name\n1|bill\n", "id|name\n2|mary\n")
+
+	r := NewReader()
+	r.Comma = '|'
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Comma = '|'
+	assert.Nil(t, Concat(w, paths, WithConcatReader(r)))
+	assert.Equal(t, "id|name\n1|bill\n2|mary\n", buf.String())
+}
+
+// Test helper
+func writeConcatFiles(t *testing.T, dir string, contents ...string) []string {
+	paths := make([]string, len(contents))
+	for i, content := range contents {
+		paths[i] = filepath.Join(dir, string(rune('a'+i))+".csv")
+		require.Nil(t, ioutil.WriteFile(paths[i], []byte(content), 0644))
+	}
+	return paths
+}
@@ -0,0 +1,176 @@
+package hastycsv
+
+import (
+	"io"
+	"runtime"
+	"sync"
+)
+
+// A record copied by ReadConcurrent()'s parsing goroutine for a worker.
+type concurrentJob struct {
+	seq    int // position of the record among all records passed to workers
+	row    int
+	fields []Field
+}
+
+// The outcome of processing a concurrentJob.
+type concurrentResult[T any] struct {
+	seq   int
+	row   int
+	value T
+	err   error
+	skip  bool // process returned ErrSkipRow
+}
+
+// Reads records from r using reader and passes each one to process, which is
+// run on the specified number of worker goroutines (runtime.NumCPU() if
+// workers < 1).  Parsing stays on the calling goroutine, so this suits
+// callbacks that are much slower than parsing, e.g. ones that make network
+// requests.
+//
+// Each record is copied before it's handed to a worker, so process may retain
+// its fields.  process must be safe for concurrent use.
+//
+// The values returned by process are passed to emit (unless emit is nil) from
+// a single goroutine.  If ordered is true, emit receives them in input order;
+// otherwise it receives them as soon as they're ready.
+//
+// Reading stops at the first error returned by process or emit (or raised by
+// a field of the record being processed), and that error is returned.  If
+// process returns ErrSkipRow, the record is skipped and its value isn't
+// emitted.
+func ReadConcurrent[T any](reader *Reader, r io.Reader, workers int, ordered bool, process func(i int, fields []Field) (T, error), emit func(i int, value T) error) error {
+	if err := reader.begin(r); err != nil {
+		return err
+	}
+	defer reader.end()
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		once     sync.Once
+		firstErr error
+		done     = make(chan struct{}) // closed by fail()
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			close(done)
+		})
+	}
+
+	jobs := make(chan concurrentJob, workers)
+	results := make(chan concurrentResult[T], workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Collects the parse errors raised by the fields of this worker's records.
+			worker := &Reader{}
+			for job := range jobs {
+				for k := range job.fields {
+					job.fields[k].reader = worker
+				}
+				worker.row = job.row
+				worker.fields = job.fields
+				worker.header = reader.header
+				worker.clearFieldErr()
+
+				value, err := process(job.row, job.fields)
+				result := concurrentResult[T]{seq: job.seq, row: job.row, value: value, err: worker.recordError(err), skip: err == ErrSkipRow}
+				select {
+				case results <- result:
+				case <-done:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	emitted := make(chan struct{})
+	go func() {
+		defer close(emitted)
+
+		emitResult := func(result concurrentResult[T]) {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if result.err != nil {
+				fail(result.err)
+			} else if emit != nil && !result.skip {
+				if err := emit(result.row, result.value); err != nil {
+					fail(atLine(result.row, err))
+				}
+			}
+		}
+
+		pending := map[int]concurrentResult[T]{}
+		nextSeq := 0
+		for result := range results {
+			if !ordered {
+				emitResult(result)
+				continue
+			}
+
+			pending[result.seq] = result
+			for {
+				result, ok := pending[nextSeq]
+				if !ok {
+					break
+				}
+				delete(pending, nextSeq)
+				nextSeq++
+				emitResult(result)
+			}
+		}
+	}()
+
+	for seq := 0; ; seq++ {
+		fields, err := reader.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			fail(err)
+			break
+		}
+
+		job := concurrentJob{seq: seq, row: reader.row, fields: copyFields(fields)}
+		select {
+		case jobs <- job:
+			continue
+		case <-done:
+		}
+		break
+	}
+	close(jobs)
+	<-emitted
+
+	return firstErr
+}
+
+// Returns a copy of the specified fields that's backed by a single new buffer.
+func copyFields(fields []Field) []Field {
+	size := 0
+	for _, field := range fields {
+		size += len(field.data)
+	}
+
+	buf := make([]byte, 0, size)
+	copied := make([]Field, len(fields))
+	for i, field := range fields {
+		start := len(buf)
+		buf = append(buf, field.data...)
+		copied[i].data = buf[start:len(buf):len(buf)]
+		copied[i].column = field.column
+	}
+	return copied
+}
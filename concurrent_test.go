@@ -0,0 +1,131 @@
+package hastycsv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadConcurrent_ordered(t *testing.T) {
+	in := makeConcurrentTestInput(200)
+
+	r := NewReader()
+	r.Comma = '|'
+	rows := []int{}
+	names := []string{}
+	err := ReadConcurrent(r, strings.NewReader(in), 8, true, func(i int, fields []Field) (Field, error) {
+		// Make later records finish first
+		time.Sleep(time.Duration(200-i) * time.Microsecond)
+		return fields[0], nil
+	}, func(i int, name Field) error {
+		rows = append(rows, i)
+		names = append(names, name.String())
+		return nil
+	})
+
+	assert.Nil(t, err)
+	expectedRows := make([]int, 200)
+	for i := range expectedRows {
+		expectedRows[i] = i + 1
+	}
+	assert.Equal(t, expectedRows, rows)
+	assert.Equal(t, "name1", names[0])
+	assert.Equal(t, "name200", names[199])
+}
+
+func TestReadConcurrent_skipRow(t *testing.T) {
+	in := makeConcurrentTestInput(10)
+
+	r := NewReader()
+	r.Comma = '|'
+	rows := []int{}
+	err := ReadConcurrent(r, strings.NewReader(in), 4, true, func(i int, fields []Field) (int, error) {
+		if i%2 == 0 {
+			return 0, ErrSkipRow
+		}
+		return i, nil
+	}, func(i int, value int) error {
+		rows = append(rows, value)
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 3, 5, 7, 9}, rows)
+}
+
+func TestReadConcurrent_unordered(t *testing.T) {
+	in := makeConcurrentTestInput(100)
+
+	r := NewReader()
+	r.Comma = '|'
+	var mu sync.Mutex
+	processed := []string{}
+	err := ReadConcurrent(r, strings.NewReader(in), 0, false, func(i int, fields []Field) (uint32, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		processed = append(processed, fields[0].String())
+		return fields[1].Uint32(), nil
+	}, nil)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 100, len(processed))
+	sort.Strings(processed)
+	assert.Equal(t, "name1", processed[0])
+}
+
+func TestReadConcurrent_errors(t *testing.T) {
+	in := makeConcurrentTestInput(100)
+
+	r := NewReader()
+	r.Comma = '|'
+	err := ReadConcurrent(r, strings.NewReader(in), 4, true, func(i int, fields []Field) (int, error) {
+		if i == 42 {
+			return 0, fmt.Errorf("Lookup failed")
+		}
+		return i, nil
+	}, nil)
+	assert.EqualError(t, err, "Line 42: Lookup failed")
+
+	err = ReadConcurrent(r, strings.NewReader(in), 4, true, func(i int, fields []Field) (int, error) {
+		return i, nil
+	}, func(i int, value int) error {
+		if value == 7 {
+			return fmt.Errorf("Emit failed")
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "Line 7: Emit failed")
+
+	err = ReadConcurrent(r, strings.NewReader("a|1\nb|x\nc|3"), 2, false, func(i int, fields []Field) (uint32, error) {
+		return fields[1].Uint32(), nil
+	}, nil)
+	assert.EqualError(t, err, `Line 2, column 2: Can't parse field as uint32: "x" contains non-numeric character 'x'`)
+
+	err = ReadConcurrent(r, strings.NewReader("a|1\nb"), 2, false, func(i int, fields []Field) (int, error) {
+		return i, nil
+	}, nil)
+	assert.EqualError(t, err, `Line 2: Expected []b to contain 2 fields using delimiter '|': "b"`)
+}
+
+func TestCopyFields(t *testing.T) {
+	fields := []Field{makeField("abc"), makeField(""), makeField("de")}
+	copied := copyFields(fields)
+	fields[0].data[0] = 'x'
+
+	assert.Equal(t, "abc", copied[0].String())
+	assert.Equal(t, "", copied[1].String())
+	assert.Equal(t, "de", copied[2].String())
+}
+
+// Test helper that returns n "name<i>|<i>" records.
+func makeConcurrentTestInput(n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("name%v|%v", i+1, i+1)
+	}
+	return strings.Join(lines, "\n")
+}
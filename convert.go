@@ -0,0 +1,93 @@
+package hastycsv
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Copies src to dst, changing the field delimiter from fromDelim to toDelim
+// (see Reader.Convert()).  Quoted fields in src are supported.
+func Convert(src io.Reader, dst io.Writer, fromDelim, toDelim byte) error {
+	r := NewReader()
+	r.Comma = fromDelim
+	r.QuoteFallback = true
+	return r.Convert(src, dst, toDelim)
+}
+
+// Copies the records of src, delimited by Comma, to dst, delimited by toDelim
+// instead.  Unlike a Writer, which rejects such values, Convert() quotes the
+// fields that contain toDelim, a double quote or a line break, as specified by
+// RFC 4180, so that the output can be read back (e.g. by a Reader with
+// QuoteFallback set).  Records that need no quoting are written as their raw
+// line, with the delimiters swapped.  If HasHeader is set, the header is
+// converted too.
+func (me *Reader) Convert(src io.Reader, dst io.Writer, toDelim byte) error {
+	w := bufio.NewWriterSize(dst, 32*1024)
+	special := []byte{'"', '\r', '\n', toDelim}
+	var line []byte
+
+	writeRecord := func(record []Field) {
+		line = line[:0]
+		for i := range record {
+			if i > 0 {
+				line = append(line, toDelim)
+			}
+			line = appendQuotedField(line, record[i].data, special)
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+
+	headerWritten := false
+	writeHeader := func() {
+		if !headerWritten && me.header != nil {
+			header := make([]Field, len(me.header))
+			for i, name := range me.header {
+				header[i] = NewField([]byte(name))
+			}
+			writeRecord(header)
+		}
+		headerWritten = true
+	}
+
+	err := me.Read(src, func(i int, record []Field) error {
+		writeHeader()
+		if bytes.ContainsAny(me.raw, string(special)) {
+			writeRecord(record)
+			return nil
+		}
+
+		line = append(line[:0], me.raw...)
+		for j, b := range line {
+			if b == me.Comma {
+				line[j] = toDelim
+			}
+		}
+		w.Write(line)
+		return w.WriteByte('\n')
+	})
+	if err != nil {
+		return err
+	}
+
+	writeHeader()
+	return w.Flush()
+}
+
+// Appends value to buf, enclosed in double quotes (with its own double quotes
+// doubled) if it contains any of the special bytes.
+func appendQuotedField(buf, value, special []byte) []byte {
+	if !bytes.ContainsAny(value, string(special)) {
+		return append(buf, value...)
+	}
+
+	buf = append(buf, '"')
+	for _, b := range value {
+		if b == '"' {
+			buf = append(buf, '"')
+		}
+		buf = append(buf, b)
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,41 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	in := "id|name|note\r\n1|Smith, John|ok\n2|bill|say \"hi\"\n3|\"a|b\"|x\n"
+
+	var buf bytes.Buffer
+	require.Nil(t, Convert(strings.NewReader(in), &buf, '|', ','))
+	assert.Equal(t, "id,name,note\n1,\"Smith, John\",ok\n2,bill,\"say \"\"hi\"\"\"\n3,a|b,x\n", buf.String())
+
+	// The output can be read back
+	r := NewReader()
+	r.QuoteFallback = true
+	var names []string
+	err := r.Read(&buf, func(i int, record []Field) error {
+		names = append(names, record[1].String())
+		return nil
+	})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"name", "Smith, John", "bill", "a|b"}, names)
+}
+
+func TestReader_Convert(t *testing.T) {
+	r := NewReader()
+	r.HasHeader = true
+
+	var buf bytes.Buffer
+	require.Nil(t, r.Convert(strings.NewReader("a,b\n1,x\ty\n"), &buf, '\t'))
+	assert.Equal(t, "a\tb\n1\t\"x\ty\"\n", buf.String())
+
+	buf.Reset()
+	require.Nil(t, r.Convert(strings.NewReader("a,b\n"), &buf, '\t'))
+	assert.Equal(t, "a\tb\n", buf.String())
+}
@@ -0,0 +1,39 @@
+package hastycsv
+
+import (
+	"io"
+)
+
+// Definition of a callback function that transforms the records copied by
+// Copy().  Return the (possibly modified) record to have it written, or a nil
+// record to drop it.  Returning an error stops the copy.
+type Transform func(i int, record []Field) ([]Field, error)
+
+// Streams the records of src to dst, passing each one through the (optional)
+// transform function first.  src is parsed using dst's Comma delimiter, and
+// dst is flushed once all records have been copied.
+//
+// The record passed to the transform function may be modified in place or
+// replaced altogether; use NewField() to create fields with new values.
+func Copy(dst *Writer, src io.Reader, transform Transform) error {
+	r := NewReader()
+	r.Comma = dst.Comma
+
+	err := r.Read(src, func(i int, record []Field) error {
+		if transform != nil {
+			var err error
+			if record, err = transform(i, record); err != nil {
+				return err
+			}
+			if record == nil {
+				return nil
+			}
+		}
+		return dst.WriteFields(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	return dst.Flush()
+}
@@ -0,0 +1,51 @@
+package hastycsv
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestCopy(t *testing.T) {
+	in := strings.NewReader("a|1\nb|2\nc|3")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Comma = '|'
+
+	assert.Nil(t, Copy(w, in, nil))
+	assert.Equal(t, "a|1\nb|2\nc|3\n", buf.String())
+}
+
+func TestCopy_transform(t *testing.T) {
+	in := strings.NewReader("BILL,30\nmary,17\nJane,45")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	err := Copy(w, in, func(i int, record []Field) ([]Field, error) {
+		if record[1].Uint32() < 18 {
+			return nil, nil // drop minors
+		}
+		return []Field{record[0].ToLower(), record[1], NewField([]byte("adult"))}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "bill,30,adult\njane,45,adult\n", buf.String())
+}
+
+func TestCopy_transformError(t *testing.T) {
+	in := strings.NewReader("a\nb\nc")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	err := Copy(w, in, func(i int, record []Field) ([]Field, error) {
+		if i == 2 {
+			return nil, fmt.Errorf("Abort!")
+		}
+		return record, nil
+	})
+	assert.EqualError(t, err, "Line 2: Abort!")
+}
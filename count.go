@@ -0,0 +1,37 @@
+package hastycsv
+
+import (
+	"bytes"
+	"io"
+)
+
+// Returns the number of newline-delimited records in r, including a header
+// record if there is one.  A final line that isn't terminated by a newline is
+// counted as a record.  Records are not split into fields, so this is much
+// faster than counting records with Read().
+func CountRecords(r io.Reader) (int64, error) {
+	lineBuffer := lineBufferPool.Get().(*[]byte)
+	defer lineBufferPool.Put(lineBuffer)
+	buf := *lineBuffer
+
+	count := int64(0)
+	endsWithNewline := true
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			count += int64(bytes.Count(buf[:n], []byte{'\n'}))
+			endsWithNewline = buf[n-1] == '\n'
+		}
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+
+	if !endsWithNewline {
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,37 @@
+package hastycsv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestCountRecords(t *testing.T) {
+	testCases := map[string]int64{
+		"":                0,
+		"a":               1,
+		"a\n":             1,
+		"a\r\nb\r\n":      2,
+		"a\nb\nc":         3,
+		"\n\n":            2,
+		"id,name\n1,bill": 2,
+	}
+
+	for in, expectedCount := range testCases {
+		count, err := CountRecords(iotest.HalfReader(strings.NewReader(in)))
+		assert.Nil(t, err)
+		assert.Equal(t, expectedCount, count, "in=%q", in)
+	}
+
+	count, err := CountRecords(strings.NewReader(strings.Repeat("1,2,3\n", 100000)))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100000), count)
+}
+
+func TestCountRecords_error(t *testing.T) {
+	_, err := CountRecords(io.MultiReader(strings.NewReader("a\nb\n"), iotest.ErrReader(fmt.Errorf("Disk error"))))
+	assert.EqualError(t, err, "Disk error")
+}
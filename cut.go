@@ -0,0 +1,67 @@
+package hastycsv
+
+import (
+	"fmt"
+	"io"
+)
+
+// Streams the records of src to dst, keeping only the specified columns in
+// the specified order, like csvcut.  Columns are identified by name, so the
+// first record of src must be a header; the header is written too, cut down
+// to the selected columns.  Fields are written as is, without being copied.
+func Cut(dst *Writer, src io.Reader, columns ...string) error {
+	return cut(dst, src, func(header []Field) ([]int, error) {
+		indexes := make([]int, len(columns))
+		for i, name := range columns {
+			if indexes[i] = fieldIndex(header, name); indexes[i] < 0 {
+				return nil, fmt.Errorf(`Column "%v" not found in header`, name)
+			}
+		}
+		return indexes, nil
+	})
+}
+
+// Same as Cut(), except that columns are identified by their (0-based) index,
+// and the first record isn't treated as a header.
+func CutIndexes(dst *Writer, src io.Reader, indexes ...int) error {
+	return cut(dst, src, func(first []Field) ([]int, error) {
+		for _, index := range indexes {
+			if index < 0 || index >= len(first) {
+				return nil, fmt.Errorf("Column index %v is out of range for records with %v fields", index, len(first))
+			}
+		}
+		return indexes, nil
+	})
+}
+
+// Copies src to dst, keeping only the columns returned by selectColumns,
+// which is called with the first record.
+func cut(dst *Writer, src io.Reader, selectColumns func(first []Field) ([]int, error)) error {
+	var indexes []int
+	var projection []Field
+	return Copy(dst, src, func(i int, record []Field) ([]Field, error) {
+		if indexes == nil {
+			var err error
+			if indexes, err = selectColumns(record); err != nil {
+				return nil, err
+			}
+			projection = make([]Field, len(indexes))
+		}
+
+		for i, index := range indexes {
+			projection[i] = record[index]
+		}
+		return projection, nil
+	})
+}
+
+// Returns the index of the header field whose value is name, or -1 if there is
+// no such field.
+func fieldIndex(header []Field, name string) int {
+	for i := range header {
+		if header[i].unsafeString() == name {
+			return i
+		}
+	}
+	return -1
+}
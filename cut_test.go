@@ -0,0 +1,33 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestCut(t *testing.T) {
+	in := strings.NewReader("id,name,age,zip\n1,bill,30,12345\n2,mary,35,54321\n")
+
+	var buf bytes.Buffer
+	assert.Nil(t, Cut(NewWriter(&buf), in, "zip", "name"))
+	assert.Equal(t, "zip,name\n12345,bill\n54321,mary\n", buf.String())
+
+	buf.Reset()
+	err := Cut(NewWriter(&buf), strings.NewReader("id,name\n1,bill\n"), "name", "age")
+	assert.EqualError(t, err, `Line 1: Column "age" not found in header`)
+}
+
+func TestCutIndexes(t *testing.T) {
+	in := strings.NewReader("1|bill|30\n2|mary|35\n")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Comma = '|'
+	assert.Nil(t, CutIndexes(w, in, 2, 0, 2))
+	assert.Equal(t, "30|1|30\n35|2|35\n", buf.String())
+
+	err := CutIndexes(NewWriter(&buf), strings.NewReader("1,bill\n"), 2)
+	assert.EqualError(t, err, "Line 1: Column index 2 is out of range for records with 2 fields")
+}
@@ -0,0 +1,415 @@
+package hastycsv
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// Reads CSV records and decodes each one into a struct of type T.
+//
+// Decoder embeds a Reader, so its delimiter and other settings are configured
+// in the same way (e.g. decoder.Comma = '|').  NewDecoder sets HasHeader to
+// true, since columns are mapped to struct fields via the column names found
+// in the header record.
+//
+// Each exported struct field is mapped to the column whose name matches the
+// field's `csv:"name"` tag, or the field's own name if it has no tag.  Fields
+// tagged `csv:"-"` and fields whose column isn't present in the header are
+// left untouched.
+//
+// Alternatively, for input that has no header, fields can be mapped to columns
+// by position: a field tagged `csv:"2"` is decoded from the third field of
+// each record (positions are zero-based, like the indexes of a record's
+// []Field).  A struct must use either positional or named tags, not both.
+// NewDecoder sets HasHeader to false for structs that use positional tags;
+// set it back to true if the input does have a header that must be skipped.
+//
+// The fields of embedded structs are mapped as if they were fields of the
+// outer struct.  The fields of a nested (named) struct field are mapped to
+// columns whose names are prefixed with the struct field's name and a dot,
+// e.g. a City field within an `csv:"address"` struct field is mapped to the
+// "address.City" column (or "address.city", if City is tagged `csv:"city"`).
+//
+// The following tag options are supported:
+//
+//	layout=...  time.Parse() layout of a time.Time field (time.RFC3339 by default)
+//	required    the column must be present in the header, and its fields must
+//	            not be empty
+//	default=... value that's decoded in place of an empty field, or if the
+//	            column is not present in the header
+//
+// Option values cannot contain commas.
+//
+// Supported field types are strings, bools, all int, uint and float types,
+// time.Time, types whose pointer implements encoding.TextUnmarshaler, and
+// pointers to any of these.  UnmarshalText() receives the field's raw bytes,
+// which must be copied if they are retained.  An empty field without a
+// default value leaves the struct field set to its zero value (nil, for
+// pointers).
+type Decoder[T any] struct {
+	*Reader
+
+	// RowCountHint is the expected number of records in the input.  If set,
+	// ReadAll() pre-allocates room for this many records.
+	RowCountHint int
+}
+
+type decoderColumn struct {
+	name         string
+	column       int   // index of the column within the record, or -1 if it's missing
+	fieldIndex   []int // index sequence of the (possibly nested) struct field
+	decode       func(field Field, v reflect.Value) error
+	required     bool
+	hasDefault   bool
+	defaultValue Field
+}
+
+// Returns a new Decoder whose Delimiter is set to the comma character (',').
+// HasHeader is set to true, unless T's fields are mapped to columns by
+// position.
+func NewDecoder[T any]() *Decoder[T] {
+	r := NewReader()
+	r.HasHeader = true
+	if t := reflect.TypeOf((*T)(nil)).Elem(); t.Kind() == reflect.Struct {
+		if positional, err := isPositionalStruct(t); err == nil && positional {
+			r.HasHeader = false
+		}
+	}
+	return &Decoder[T]{Reader: r}
+}
+
+// Reads records from r and invokes fn with each decoded record.
+//
+// To avoid allocations, v points to the same value for every record; copy
+// *v if it needs to be retained after fn returns.  Reading stops if fn
+// returns an error.
+func (me *Decoder[T]) Read(r io.Reader, fn func(i int, v *T) error) error {
+	if err := me.checkType(); err != nil {
+		return err
+	}
+
+	var columns []decoderColumn
+	var v T
+	return me.Reader.Read(r, func(i int, record []Field) error {
+		if err := me.decode(record, &columns, &v); err != nil {
+			return err
+		}
+		return fn(i, &v)
+	})
+}
+
+// Returns an Iterator that reads records from r one at a time, as an
+// alternative to the callback-based Read().  The Decoder must not be used for
+// anything else until the Iterator is exhausted.
+func (me *Decoder[T]) Iter(r io.Reader) *Iterator[T] {
+	it := &Iterator[T]{d: me}
+	if it.err = me.checkType(); it.err == nil {
+		it.err = me.begin(r)
+	}
+	return it
+}
+
+func (me *Decoder[T]) checkType() error {
+	if t := reflect.TypeOf((*T)(nil)).Elem(); t.Kind() != reflect.Struct {
+		return fmt.Errorf("Can't decode into %v: not a struct", t)
+	}
+	return nil
+}
+
+// Decodes a record into *v.  The mapping of columns to struct fields is
+// established when the first record is decoded, and stored in *columns.
+func (me *Decoder[T]) decode(record []Field, columns *[]decoderColumn, v *T) error {
+	rv := reflect.ValueOf(v).Elem()
+
+	if *columns == nil {
+		var err error
+		if *columns, err = me.columns(rv.Type()); err != nil {
+			return err
+		}
+	}
+
+	var zero T
+	*v = zero
+	for i := range *columns {
+		col := &(*columns)[i]
+
+		var field Field
+		if col.column >= 0 && col.column < len(record) {
+			field = record[col.column]
+		}
+
+		if field.IsEmpty() {
+			if col.required {
+				return fmt.Errorf(`Column "%v" is required, but the field is empty`, col.name)
+			}
+			if !col.hasDefault {
+				continue
+			}
+			field = col.defaultValue
+		}
+
+		if err := col.decode(field, rv.FieldByIndex(col.fieldIndex)); err != nil {
+			return fmt.Errorf(`Can't decode column "%v": %w`, col.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Reads all records from r and returns them decoded into a slice.
+func (me *Decoder[T]) ReadAll(r io.Reader) ([]T, error) {
+	var values []T
+	if me.RowCountHint > 0 {
+		values = make([]T, 0, me.RowCountHint)
+	}
+
+	err := me.Read(r, func(i int, v *T) error {
+		values = append(values, *v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Maps the fields of the specified struct type to the columns of the header
+// (or to column positions, if the struct's tags specify positions).
+func (me *Decoder[T]) columns(t reflect.Type) ([]decoderColumn, error) {
+	positional, err := isPositionalStruct(t)
+	if err != nil {
+		return nil, err
+	}
+
+	header := me.Header()
+	if header == nil && !positional {
+		return nil, fmt.Errorf("Can't decode %v: HasHeader must be set to map columns to struct fields", t)
+	}
+
+	columnIndexes := make(map[string]int, len(header))
+	for i, name := range header {
+		if _, exists := columnIndexes[name]; !exists {
+			columnIndexes[name] = i
+		}
+	}
+
+	columns := []decoderColumn{}
+	for _, sf := range flattenStruct(t) {
+		if positional {
+			// Positional tags refer to a column by index rather than by name.
+			columnIndexes = map[string]int{sf.name: sf.tag.position}
+		}
+
+		decode, err := valueDecoder(sf.typ, sf.tag)
+		if err != nil {
+			return nil, fmt.Errorf("Can't decode field %v.%v: %w", t, sf.path, err)
+		}
+
+		col := decoderColumn{name: sf.name, column: -1, fieldIndex: sf.index, decode: decode}
+		_, col.required = sf.tag.option("required")
+		if defaultValue, ok := sf.tag.option("default"); ok {
+			col.hasDefault = true
+			col.defaultValue = NewField([]byte(defaultValue))
+			if err := decode(col.defaultValue, reflect.New(sf.typ).Elem()); err != nil {
+				return nil, fmt.Errorf(`Invalid default value for field %v.%v: %w`, t, sf.path, err)
+			}
+		}
+
+		if column, ok := columnIndexes[sf.name]; ok {
+			col.column = column
+		} else if col.required {
+			return nil, fmt.Errorf(`Required column "%v" not found in header`, sf.name)
+		} else if !col.hasDefault {
+			continue
+		}
+
+		columns = append(columns, col)
+	}
+
+	return columns, nil
+}
+
+// Returns true if the fields of the specified struct type are mapped to
+// columns by position (i.e. their tags contain column indexes rather than
+// column names).
+func isPositionalStruct(t reflect.Type) (bool, error) {
+	positional, named := 0, 0
+	for _, sf := range flattenStruct(t) {
+		if sf.tag.position >= 0 {
+			positional++
+		} else {
+			named++
+		}
+	}
+
+	if positional > 0 && named > 0 {
+		return false, fmt.Errorf("Can't decode %v: struct mixes positional and named column tags (or untagged fields)", t)
+	}
+	return positional > 0, nil
+}
+
+// A pull-based iterator over decoded records.  See Decoder.Iter().
+type Iterator[T any] struct {
+	d       *Decoder[T]
+	columns []decoderColumn
+	v       T
+	err     error
+}
+
+// Returns the next decoded record, or io.EOF once all records have been read.
+// Once an error has been returned, every subsequent call returns it again.
+//
+// Like Decoder.Read(), the returned pointer points to the same value on every
+// call; copy it if it needs to be retained.
+func (me *Iterator[T]) Next() (*T, error) {
+	if me.err != nil {
+		return nil, me.err
+	}
+
+	record, err := me.d.next()
+	if err == nil {
+		// Errors are associated with the line as Read() does.
+		err = me.d.recordError(me.d.decode(record, &me.columns, &me.v))
+	}
+
+	if err != nil {
+		me.err = err
+		me.d.end()
+		return nil, err
+	}
+	return &me.v, nil
+}
+
+// Returns the line number of the record most recently returned by Next().
+func (me *Iterator[T]) Line() int {
+	return me.d.row
+}
+
+// Returns a function that parses a Field into a value of the specified type.
+func valueDecoder(t reflect.Type, tag fieldTag) (func(field Field, v reflect.Value) error, error) {
+	if t.Kind() == reflect.Ptr {
+		decodeElem, err := valueDecoder(t.Elem(), tag)
+		if err != nil {
+			return nil, err
+		}
+		return func(field Field, v reflect.Value) error {
+			if field.IsEmpty() {
+				return nil
+			}
+			elem := reflect.New(t.Elem())
+			if err := decodeElem(field, elem.Elem()); err != nil {
+				return err
+			}
+			v.Set(elem)
+			return nil
+		}, nil
+	}
+
+	var decode func(field Field, v reflect.Value) error
+
+	if t == timeType {
+		layout := time.RFC3339
+		if tagLayout, ok := tag.option("layout"); ok {
+			layout = tagLayout
+		}
+		decode = func(field Field, v reflect.Value) error {
+			tm, err := parseTime(field, layout)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(tm))
+			return nil
+		}
+	} else if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		decode = func(field Field, v reflect.Value) error {
+			return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText(field.data)
+		}
+	} else {
+		switch t.Kind() {
+		case reflect.String:
+			decode = func(field Field, v reflect.Value) error {
+				v.SetString(field.String())
+				return nil
+			}
+		case reflect.Bool:
+			decode = func(field Field, v reflect.Value) error {
+				b, err := strconv.ParseBool(field.unsafeString())
+				if err != nil {
+					return fmt.Errorf(`Can't parse "%v" as bool`, field.unsafeString())
+				}
+				v.SetBool(b)
+				return nil
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			bitSize := t.Bits()
+			decode = func(field Field, v reflect.Value) error {
+				i, err := strconv.ParseInt(field.unsafeString(), 10, bitSize)
+				if err != nil {
+					return fmt.Errorf(`Can't parse "%v" as %v`, field.unsafeString(), t)
+				}
+				v.SetInt(i)
+				return nil
+			}
+		case reflect.Uint32:
+			decode = func(field Field, v reflect.Value) error {
+				i, err := ParseUint32(field.data)
+				if err != nil {
+					return err
+				}
+				v.SetUint(uint64(i))
+				return nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint64:
+			bitSize := t.Bits()
+			decode = func(field Field, v reflect.Value) error {
+				i, err := strconv.ParseUint(field.unsafeString(), 10, bitSize)
+				if err != nil {
+					return fmt.Errorf(`Can't parse "%v" as %v`, field.unsafeString(), t)
+				}
+				v.SetUint(i)
+				return nil
+			}
+		case reflect.Float32, reflect.Float64:
+			bitSize := t.Bits()
+			decode = func(field Field, v reflect.Value) error {
+				f, err := strconv.ParseFloat(field.unsafeString(), bitSize)
+				if err != nil {
+					return fmt.Errorf(`Can't parse "%v" as %v`, field.unsafeString(), t)
+				}
+				v.SetFloat(f)
+				return nil
+			}
+		default:
+			return nil, fmt.Errorf("Unsupported type %v", t)
+		}
+	}
+
+	// Empty fields leave the struct field set to its zero value.
+	return func(field Field, v reflect.Value) error {
+		if field.IsEmpty() {
+			return nil
+		}
+		return decode(field, v)
+	}, nil
+}
+
+// Reads comma-delimited records (starting with a header record) from r and
+// invokes fn with each record decoded into a struct of type T.  See Decoder
+// for how columns are mapped to struct fields.
+func ReadInto[T any](r io.Reader, fn func(i int, v *T) error) error {
+	return NewDecoder[T]().Read(r, fn)
+}
+
+// Reads all comma-delimited records (starting with a header record) from r and
+// returns them decoded into a slice of structs of type T.  See Decoder for how
+// columns are mapped to struct fields.
+func ReadAll[T any](r io.Reader) ([]T, error) {
+	return NewDecoder[T]().ReadAll(r)
+}
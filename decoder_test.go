@@ -0,0 +1,360 @@
+package hastycsv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type decoderTestPerson struct {
+	Name     string    `csv:"name"`
+	Age      uint32    `csv:"age"`
+	Weight   float32   `csv:"weight"`
+	Score    float64   `csv:"score"`
+	Rank     int       `csv:"rank"`
+	Born     time.Time `csv:"born,layout=2006-01-02"`
+	Nickname *string   `csv:"nickname"`
+	Active   bool
+	Ignored  string `csv:"-"`
+	Missing  string `csv:"not_in_header"`
+}
+
+func TestNewDecoder(t *testing.T) {
+	d := NewDecoder[decoderTestPerson]()
+	assert.Equal(t, byte(','), d.Comma)
+	assert.True(t, d.HasHeader)
+}
+
+func TestDecoder_Read(t *testing.T) {
+	in := strings.NewReader(`Active|nickname|born|rank|score|weight|age|name|Ignored
+true|billy|1990-03-04|-3|1234.5|154.5|30|bill|x
+false||1985-12-31|7|0.25|125.1|35|mary|y`)
+
+	d := NewDecoder[decoderTestPerson]()
+	d.Comma = '|'
+
+	persons := []decoderTestPerson{}
+	lineNums := []int{}
+	err := d.Read(in, func(i int, p *decoderTestPerson) error {
+		persons = append(persons, *p)
+		lineNums = append(lineNums, i)
+		return nil
+	})
+	assert.Nil(t, err)
+
+	nickname := "billy"
+	assert.Equal(t, []decoderTestPerson{
+		{Name: "bill", Age: 30, Weight: 154.5, Score: 1234.5, Rank: -3, Born: time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC), Nickname: &nickname, Active: true},
+		{Name: "mary", Age: 35, Weight: 125.1, Score: 0.25, Rank: 7, Born: time.Date(1985, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}, persons)
+	assert.Equal(t, []int{2, 3}, lineNums)
+	assert.Equal(t, []string{"Active", "nickname", "born", "rank", "score", "weight", "age", "name", "Ignored"}, d.Header())
+}
+
+func TestDecoder_Read_emptyFields(t *testing.T) {
+	type Record struct {
+		A uint32
+		B float64
+		C *int
+		D bool
+	}
+
+	in := strings.NewReader("A,B,C,D\n,,,")
+	err := ReadInto(in, func(i int, r *Record) error {
+		assert.Equal(t, Record{}, *r)
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func TestDecoder_Read_parseError(t *testing.T) {
+	in := strings.NewReader("name,age\nbill,30\nmary,abc")
+	err := ReadInto(in, func(i int, p *decoderTestPerson) error { return nil })
+	assert.EqualError(t, err, `Line 3: Can't decode column "age": "abc" contains non-numeric character 'a'`)
+}
+
+func TestDecoder_Read_callbackError(t *testing.T) {
+	in := strings.NewReader("name,age\nbill,30\nmary,35")
+	err := ReadInto(in, func(i int, p *decoderTestPerson) error {
+		return fmt.Errorf("Abort!")
+	})
+	assert.EqualError(t, err, "Line 2: Abort!")
+}
+
+func TestDecoder_Read_unsupportedTypes(t *testing.T) {
+	type Unsupported struct {
+		Values []int
+	}
+
+	err := ReadInto(strings.NewReader("Values\n1"), func(i int, v *Unsupported) error { return nil })
+	assert.NotNil(t, err)
+
+	err = ReadInto(strings.NewReader("a\n1"), func(i int, v *int) error { return nil })
+	assert.NotNil(t, err)
+}
+
+func TestDecoder_Read_noHeader(t *testing.T) {
+	d := NewDecoder[decoderTestPerson]()
+	d.HasHeader = false
+	err := d.Read(strings.NewReader("bill,30"), func(i int, p *decoderTestPerson) error { return nil })
+	assert.NotNil(t, err)
+}
+
+func TestDecoder_ReadAll(t *testing.T) {
+	type Car struct {
+		Make  string  `csv:"make"`
+		Model string  `csv:"model"`
+		Year  uint32  `csv:"year"`
+		MPG   float32 `csv:"mpg"`
+	}
+
+	d := NewDecoder[Car]()
+	d.Comma = '|'
+	d.RowCountHint = 10
+	cars, err := d.ReadAll(strings.NewReader("make|model|year|mpg\nHonda|Acura NSX|2017|18.1\nBMW|M3|2015|18.7"))
+	assert.Nil(t, err)
+	assert.Equal(t, []Car{
+		{"Honda", "Acura NSX", 2017, 18.1},
+		{"BMW", "M3", 2015, 18.7},
+	}, cars)
+	assert.Equal(t, 10, cap(cars))
+}
+
+func TestReadAll(t *testing.T) {
+	type Record struct {
+		ID   uint32 `csv:"id"`
+		Name string `csv:"name"`
+	}
+
+	records, err := ReadAll[Record](strings.NewReader("id,name\n1,a\n2,b\n3,c"))
+	assert.Nil(t, err)
+	assert.Equal(t, []Record{{1, "a"}, {2, "b"}, {3, "c"}}, records)
+
+	records, err = ReadAll[Record](strings.NewReader("id,name"))
+	assert.Nil(t, err)
+	assert.Empty(t, records)
+
+	records, err = ReadAll[Record](strings.NewReader("id,name\n1,a\nx,b"))
+	assert.EqualError(t, err, `Line 3: Can't decode column "id": "x" contains non-numeric character 'x'`)
+	assert.Nil(t, records)
+}
+
+func TestDecoder_Iter(t *testing.T) {
+	type Record struct {
+		ID   uint32 `csv:"id"`
+		Name string `csv:"name"`
+	}
+
+	d := NewDecoder[Record]()
+	d.Comma = '|'
+	it := d.Iter(strings.NewReader("id|name\n1|a\n2|b\n3|c"))
+
+	records := []Record{}
+	lines := []int{}
+	for {
+		r, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		records = append(records, *r)
+		lines = append(lines, it.Line())
+	}
+
+	assert.Equal(t, []Record{{1, "a"}, {2, "b"}, {3, "c"}}, records)
+	assert.Equal(t, []int{2, 3, 4}, lines)
+
+	// The iterator stays exhausted.
+	_, err := it.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecoder_Iter_error(t *testing.T) {
+	type Record struct {
+		ID uint32 `csv:"id"`
+	}
+
+	it := NewDecoder[Record]().Iter(strings.NewReader("id\n1\nx\n3"))
+
+	r, err := it.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), r.ID)
+
+	r, err = it.Next()
+	assert.Nil(t, r)
+	assert.EqualError(t, err, `Line 3: Can't decode column "id": "x" contains non-numeric character 'x'`)
+
+	_, err2 := it.Next()
+	assert.Equal(t, err, err2)
+
+	readErr := NewDecoder[Record]().Read(strings.NewReader("id\n1\nx\n3"), func(i int, r *Record) error { return nil })
+	assert.Equal(t, readErr, err)
+
+	_, err = NewDecoder[int]().Iter(strings.NewReader("id\n1")).Next()
+	assert.NotNil(t, err)
+}
+
+type decoderTestColor int
+
+func (me *decoderTestColor) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "red":
+		*me = 1
+	case "green":
+		*me = 2
+	default:
+		return fmt.Errorf(`Unknown color "%s"`, text)
+	}
+	return nil
+}
+
+type decoderTestDecimal struct {
+	Units int64
+	Cents int64
+}
+
+func (me *decoderTestDecimal) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d.%d", &me.Units, &me.Cents)
+	return err
+}
+
+func TestDecoder_Read_textUnmarshaler(t *testing.T) {
+	type Record struct {
+		Color    decoderTestColor    `csv:"color"`
+		Price    decoderTestDecimal  `csv:"price"`
+		Discount *decoderTestDecimal `csv:"discount"`
+	}
+
+	records, err := ReadAll[Record](strings.NewReader("color,price,discount\nred,12.34,1.50\ngreen,0.99,"))
+	assert.Nil(t, err)
+	assert.Equal(t, []Record{
+		{Color: 1, Price: decoderTestDecimal{12, 34}, Discount: &decoderTestDecimal{1, 50}},
+		{Color: 2, Price: decoderTestDecimal{0, 99}},
+	}, records)
+
+	_, err = ReadAll[Record](strings.NewReader("color,price,discount\nblue,1.00,"))
+	assert.EqualError(t, err, `Line 2: Can't decode column "color": Unknown color "blue"`)
+}
+
+type decoderTestAuditInfo struct {
+	CreatedBy string `csv:"created_by"`
+	Version   uint32 `csv:"version"`
+}
+
+type decoderTestAddress struct {
+	Street string `csv:"street"`
+	City   string `csv:"city"`
+	Geo    struct {
+		Lat float64 `csv:"lat"`
+		Lon float64 `csv:"lon"`
+	} `csv:"geo"`
+}
+
+func TestDecoder_Read_nestedStructs(t *testing.T) {
+	type Customer struct {
+		decoderTestAuditInfo
+		Name     string             `csv:"name"`
+		Home     decoderTestAddress `csv:"home"`
+		Work     decoderTestAddress
+		Internal decoderTestAddress `csv:"-"`
+	}
+
+	in := strings.NewReader(`name,home.street,home.city,home.geo.lat,home.geo.lon,Work.city,created_by,version
+bill,1 Main St,Springfield,39.8,-89.6,Chicago,admin,3`)
+
+	customers, err := ReadAll[Customer](in)
+	assert.Nil(t, err)
+
+	expected := Customer{Name: "bill"}
+	expected.CreatedBy = "admin"
+	expected.Version = 3
+	expected.Home.Street = "1 Main St"
+	expected.Home.City = "Springfield"
+	expected.Home.Geo.Lat = 39.8
+	expected.Home.Geo.Lon = -89.6
+	expected.Work.City = "Chicago"
+	assert.Equal(t, []Customer{expected}, customers)
+}
+
+func TestDecoder_Read_requiredAndDefault(t *testing.T) {
+	type Record struct {
+		Name    string  `csv:"name,required"`
+		Age     uint32  `csv:"age,default=18"`
+		Score   float64 `csv:"score,default=0.5"`
+		Country string  `csv:"country,default=US"`
+		Nick    *string `csv:"nick,default=none"`
+	}
+
+	records, err := ReadAll[Record](strings.NewReader("name,age,score\nbill,30,\nmary,,0.75"))
+	assert.Nil(t, err)
+
+	none := "none"
+	assert.Equal(t, []Record{
+		{Name: "bill", Age: 30, Score: 0.5, Country: "US", Nick: &none},
+		{Name: "mary", Age: 18, Score: 0.75, Country: "US", Nick: &none},
+	}, records)
+
+	_, err = ReadAll[Record](strings.NewReader("name,age\nbill,30\n,40"))
+	assert.EqualError(t, err, `Line 3: Column "name" is required, but the field is empty`)
+
+	_, err = ReadAll[Record](strings.NewReader("age\n30"))
+	assert.EqualError(t, err, `Line 2: Required column "name" not found in header`)
+}
+
+func TestDecoder_Read_invalidDefault(t *testing.T) {
+	type Record struct {
+		Age uint32 `csv:"age,default=abc"`
+	}
+
+	_, err := ReadAll[Record](strings.NewReader("age\n30"))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Invalid default value for field hastycsv.Record.Age")
+}
+
+func TestDecoder_Read_positionalTags(t *testing.T) {
+	type Car struct {
+		Make string  `csv:"0"`
+		Year uint32  `csv:"2,required"`
+		MPG  float32 `csv:"3,default=20"`
+		Skip string  `csv:"-"`
+	}
+
+	d := NewDecoder[Car]()
+	assert.False(t, d.HasHeader)
+	d.Comma = '|'
+
+	cars, err := d.ReadAll(strings.NewReader("Honda|Acura NSX|2017|18.1\nBMW|M3|2015|"))
+	assert.Nil(t, err)
+	assert.Equal(t, []Car{{"Honda", 2017, 18.1, ""}, {"BMW", 2015, 20, ""}}, cars)
+
+	// Skip a header record, while still mapping columns by position.
+	d.HasHeader = true
+	cars, err = d.ReadAll(strings.NewReader("make|model|year|mpg\nHonda|Acura NSX|2017|18.1"))
+	assert.Nil(t, err)
+	assert.Equal(t, []Car{{"Honda", 2017, 18.1, ""}}, cars)
+
+	// Position beyond the end of the record.
+	type Wide struct {
+		A string `csv:"0"`
+		Z string `csv:"10,required"`
+	}
+	_, err = ReadAll[Wide](strings.NewReader("a,b"))
+	assert.EqualError(t, err, `Line 1: Column "10" is required, but the field is empty`)
+}
+
+func TestDecoder_Read_mixedTags(t *testing.T) {
+	type Mixed struct {
+		A string `csv:"0"`
+		B string `csv:"b"`
+	}
+
+	d := NewDecoder[Mixed]()
+	assert.True(t, d.HasHeader)
+	_, err := d.ReadAll(strings.NewReader("a,b\n1,2"))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "mixes positional and named column tags")
+}
@@ -0,0 +1,103 @@
+package hastycsv
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// The magic bytes at the start of every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Definition of a function that returns a reader that decompresses r.
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+// Decompressors by lowercase file extension (including the leading dot).
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = map[string]Decompressor{".gz": newGzipReader}
+)
+
+func newGzipReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// Registers a Decompressor for files with the specified extension (e.g.
+// ".bz2" or "xz"), which ReadFile() will then decompress transparently.
+// Extensions are matched case-insensitively.  Registering an extension again
+// replaces its Decompressor; the ".gz" Decompressor is registered by default.
+func RegisterDecompressor(ext string, fn Decompressor) {
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+	decompressors[strings.ToLower(ext)] = fn
+}
+
+// Returns the Decompressor registered for the extension of path, or nil.
+func decompressorFor(path string) Decompressor {
+	decompressorsMu.RLock()
+	defer decompressorsMu.RUnlock()
+	return decompressors[strings.ToLower(filepath.Ext(path))]
+}
+
+// Opens the specified file for reading, as done by ReadFile().  If a
+// Decompressor is registered for the file's extension, or if the file starts
+// with gzip's magic bytes, the returned reader decompresses it.
+func OpenFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Peek rather than seek, so that pipes and FIFOs can be read too.
+	br := bufio.NewReader(f)
+	magic, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		f.Close()
+		return nil, err
+	}
+
+	decompress := decompressorFor(path)
+	if decompress == nil && bytes.Equal(magic, gzipMagic) {
+		decompress = newGzipReader
+	}
+
+	if decompress != nil {
+		dr, err := decompress(br)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("Can't decompress %v: %w", path, err)
+		}
+		return &openedFile{Reader: dr, decompressor: dr, file: f}, nil
+	}
+
+	return &openedFile{Reader: br, file: f}, nil
+}
+
+// A file that's read through a buffer, and possibly a decompressor.
+type openedFile struct {
+	io.Reader
+	decompressor io.Closer // nil if the file isn't compressed
+	file         *os.File
+}
+
+// Closes both the decompressor, if any, and the file.
+func (me *openedFile) Close() error {
+	var err error
+	if me.decompressor != nil {
+		err = me.decompressor.Close()
+	}
+	if fileErr := me.file.Close(); err == nil {
+		err = fileErr
+	}
+	return err
+}
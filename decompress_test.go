@@ -0,0 +1,106 @@
+package hastycsv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFile_gzip(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	compressed := &bytes.Buffer{}
+	gz := gzip.NewWriter(compressed)
+	gz.Write([]byte("mary,jones,35\nbill,anderson,40\n"))
+	require.Nil(t, gz.Close())
+
+	// Detected by extension or by magic bytes
+	for _, name := range []string{"people.csv.gz", "people.csv.GZ", "people.csv"} {
+		path := filepath.Join(dir, name)
+		require.Nil(t, ioutil.WriteFile(path, compressed.Bytes(), 0644))
+
+		names := []string{}
+		err := ReadFile(path, ',', func(i int, rec []Field) error {
+			names = append(names, rec[0].String())
+			return nil
+		})
+		assert.Nil(t, err, name)
+		assert.Equal(t, []string{"mary", "bill"}, names, name)
+	}
+}
+
+func TestReadFile_notGzip(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "fake.csv.gz")
+	require.Nil(t, ioutil.WriteFile(path, []byte("a,b\n"), 0644))
+	err := ReadFile(path, ',', func(i int, rec []Field) error { return nil })
+	assert.NotNil(t, err)
+
+	// Files shorter than the gzip magic are read as-is.
+	path = filepath.Join(dir, "tiny.csv")
+	require.Nil(t, ioutil.WriteFile(path, []byte("a"), 0644))
+	values := []string{}
+	err = ReadFile(path, ',', func(i int, rec []Field) error {
+		values = append(values, rec[0].String())
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, values)
+}
+
+func TestRegisterDecompressor(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	// A toy "codec" that stores the input reversed
+	RegisterDecompressor("REV", func(r io.Reader) (io.ReadCloser, error) {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+			data[i], data[j] = data[j], data[i]
+		}
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	})
+	defer func() {
+		decompressorsMu.Lock()
+		delete(decompressors, ".rev")
+		decompressorsMu.Unlock()
+	}()
+
+	path := filepath.Join(dir, "people.csv.rev")
+	require.Nil(t, ioutil.WriteFile(path, []byte("\n04,llib\n53,yram"), 0644))
+
+	names := []string{}
+	err := ReadFile(path, ',', func(i int, rec []Field) error {
+		names = append(names, rec[0].String())
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mary", "bill"}, names)
+
+	RegisterDecompressor(".fail", func(r io.Reader) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("Unsupported codec version")
+	})
+	defer func() {
+		decompressorsMu.Lock()
+		delete(decompressors, ".fail")
+		decompressorsMu.Unlock()
+	}()
+
+	path = filepath.Join(dir, "people.csv.fail")
+	require.Nil(t, ioutil.WriteFile(path, []byte("x"), 0644))
+	err = ReadFile(path, ',', func(i int, rec []Field) error { return nil })
+	assert.EqualError(t, err, fmt.Sprintf("Can't decompress %v: Unsupported codec version", path))
+}
@@ -0,0 +1,47 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package hastycsv
+
+import (
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestReadFile_fifo(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	for _, compressed := range []bool{false, true} {
+		path := filepath.Join(dir, "people.fifo")
+		require.Nil(t, syscall.Mkfifo(path, 0600))
+
+		go func(compressed bool) {
+			f, err := os.OpenFile(path, os.O_WRONLY, 0)
+			if err != nil {
+				return
+			}
+			defer f.Close()
+			if compressed {
+				gz := gzip.NewWriter(f)
+				defer gz.Close()
+				gz.Write([]byte("mary,jones,35\nbill,anderson,40\n"))
+			} else {
+				f.Write([]byte("mary,jones,35\nbill,anderson,40\n"))
+			}
+		}(compressed)
+
+		names := []string{}
+		err := ReadFile(path, ',', func(i int, rec []Field) error {
+			names = append(names, rec[0].String())
+			return nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"mary", "bill"}, names)
+		require.Nil(t, os.Remove(path))
+	}
+}
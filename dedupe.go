@@ -0,0 +1,232 @@
+package hastycsv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+type dedupeConfig struct {
+	reader     *Reader
+	keepLast   bool
+	sorted     bool
+	filterSize int
+}
+
+// Configures Dedupe().
+type DedupeOption func(cfg *dedupeConfig)
+
+// Returns a DedupeOption that makes Dedupe() parse its input using the
+// specified Reader, e.g. to set its Comma delimiter.  By default, a Reader
+// returned by NewReader() with the Writer's Comma delimiter and HasHeader set
+// to true is used.
+func WithDedupeReader(reader *Reader) DedupeOption {
+	return func(cfg *dedupeConfig) {
+		cfg.reader = reader
+	}
+}
+
+// Returns a DedupeOption that makes Dedupe() keep the last record of each key
+// instead of the first.  Unless the input is sorted (see WithSortedInput()),
+// this holds every kept record in memory until the input has been read.
+func WithKeepLast() DedupeOption {
+	return func(cfg *dedupeConfig) {
+		cfg.keepLast = true
+	}
+}
+
+// Returns a DedupeOption that declares the input to be sorted (or at least
+// grouped) by the key columns, e.g. by Sort().  Dedupe() then only compares
+// each record with the previous one, which takes no memory per key.
+func WithSortedInput() DedupeOption {
+	return func(cfg *dedupeConfig) {
+		cfg.sorted = true
+	}
+}
+
+// Returns a DedupeOption that bounds the memory used to track keys to the
+// specified number of bytes, by tracking them with a Bloom filter (see
+// Reader.DuplicateFilterSize).  This is approximate: some records with unique
+// keys are mistaken for duplicates and dropped.  It can't be combined with
+// WithKeepLast() unless the input is sorted.
+func WithDedupeFilterSize(bytes int) DedupeOption {
+	return func(cfg *dedupeConfig) {
+		cfg.filterSize = bytes
+	}
+}
+
+// Streams the records of src to dst, keeping only the first record of each
+// key, i.e. of each combination of values of the specified key columns.  The
+// first record of src must be a header, which is written too.  Records are
+// written as their raw line, in input order, and dst is flushed once all
+// records have been written.
+//
+// Keys are tracked by their 64-bit hash, so a record is only mistaken for a
+// duplicate in the (extremely unlikely) case of a hash collision.
+func Dedupe(dst *Writer, src io.Reader, keyColumns []string, options ...DedupeOption) error {
+	cfg := &dedupeConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+	if cfg.reader == nil {
+		cfg.reader = NewReader()
+		cfg.reader.Comma = dst.Comma
+		cfg.reader.HasHeader = true
+	}
+	if cfg.keepLast && !cfg.sorted && cfg.filterSize > 0 {
+		return fmt.Errorf("Can't keep the last record of each key using a Bloom filter")
+	}
+
+	d := &deduper{dst: dst, reader: cfg.reader, cfg: cfg}
+	d.keys.reset(cfg.filterSize)
+
+	err := cfg.reader.Read(src, func(i int, record []Field) error {
+		if d.indexes == nil {
+			if err := d.start(keyColumns, len(record)); err != nil {
+				return err
+			}
+		}
+		return d.add(i, record)
+	})
+	if err != nil {
+		return err
+	}
+	if d.indexes == nil && cfg.reader.Header() != nil {
+		// The input holds no records, so start() wasn't called.
+		if err := d.start(keyColumns, len(cfg.reader.Header())); err != nil {
+			return err
+		}
+	}
+
+	if err := d.finish(); err != nil {
+		return err
+	}
+	return dst.Flush()
+}
+
+// The state of a Dedupe() call.
+type deduper struct {
+	dst        *Writer
+	reader     *Reader
+	cfg        *dedupeConfig
+	indexes    []int // the position of each key column within records
+	fieldCount int
+	key        []byte // the encoded key of the current record
+
+	keys rowSet // the keys seen so far, if the input isn't sorted
+
+	// If the input is sorted, the key and (if keeping the last record) the
+	// line of the previous record.
+	prevKey  []byte
+	prevLine []byte
+	hasPrev  bool
+
+	// If keeping the last record of unsorted input, the kept records, and the
+	// index within kept of each key.
+	arena *Arena
+	kept  []keptRecord
+	slots map[string]int
+}
+
+type keptRecord struct {
+	line int
+	raw  []byte
+}
+
+// Resolves the positions of the key columns and writes the header, given the
+// number of fields of the records.
+func (me *deduper) start(keyColumns []string, fieldCount int) error {
+	header := me.reader.Header()
+	if header == nil {
+		return fmt.Errorf("Can't dedupe by column name: the input has no header")
+	}
+
+	me.fieldCount = fieldCount
+	me.indexes = make([]int, len(keyColumns))
+	for i, name := range keyColumns {
+		if me.indexes[i] = indexOfString(header, name); me.indexes[i] < 0 {
+			return fmt.Errorf(`Column "%v" not found in header`, name)
+		}
+	}
+	return me.dst.Write(header)
+}
+
+func (me *deduper) add(line int, record []Field) error {
+	me.key = appendKey(me.key[:0], record, me.indexes)
+	raw := me.reader.raw
+
+	switch {
+	case me.cfg.sorted:
+		if me.hasPrev && bytes.Equal(me.key, me.prevKey) {
+			if me.cfg.keepLast {
+				me.prevLine = append(me.prevLine[:0], raw...)
+			}
+			return nil
+		}
+		if me.cfg.keepLast && me.hasPrev {
+			if err := me.dst.writeLine(me.prevLine, me.fieldCount); err != nil {
+				return err
+			}
+		}
+		me.prevKey = append(me.prevKey[:0], me.key...)
+		me.hasPrev = true
+		if me.cfg.keepLast {
+			me.prevLine = append(me.prevLine[:0], raw...)
+			return nil
+		}
+		return me.dst.writeLine(raw, me.fieldCount)
+
+	case me.cfg.keepLast:
+		if me.slots == nil {
+			me.slots = map[string]int{}
+			me.arena = NewArena(DefaultArenaBlockSize)
+		}
+		kept := keptRecord{line: line, raw: me.arena.Retain([]Field{{data: raw}})[0].data}
+		if slot, exists := me.slots[string(me.key)]; exists {
+			me.kept[slot] = kept
+		} else {
+			me.slots[string(me.key)] = len(me.kept)
+			me.kept = append(me.kept, kept)
+		}
+		return nil
+
+	default:
+		if me.keys.add(me.key) {
+			return nil
+		}
+		return me.dst.writeLine(raw, me.fieldCount)
+	}
+}
+
+// Writes the records that are held until the end of the input.
+func (me *deduper) finish() error {
+	if me.cfg.sorted {
+		if me.cfg.keepLast && me.hasPrev {
+			return me.dst.writeLine(me.prevLine, me.fieldCount)
+		}
+		return nil
+	}
+
+	sort.Slice(me.kept, func(i, j int) bool {
+		return me.kept[i].line < me.kept[j].line
+	})
+	for _, kept := range me.kept {
+		if err := me.dst.writeLine(kept.raw, me.fieldCount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Appends the encoding of the specified fields of record to buf, such that two
+// records have the same encoding only if those fields are equal.
+func appendKey(buf []byte, record []Field, indexes []int) []byte {
+	for _, index := range indexes {
+		// Length-prefix each field so that e.g. ("ab", "c") and ("a", "bc")
+		// have different keys.
+		data := record[index].data
+		buf = append(appendBigEndian32(buf, uint32(len(data))), data...)
+	}
+	return buf
+}
@@ -0,0 +1,73 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestDedupe(t *testing.T) {
+	in := "id,region,qty\na,east,1\nb,east,2\na,west,3\na,east,4\nb,east,5\n"
+
+	dedupe := func(keyColumns []string, options ...DedupeOption) string {
+		var buf bytes.Buffer
+		assert.Nil(t, Dedupe(NewWriter(&buf), strings.NewReader(in), keyColumns, options...))
+		return buf.String()
+	}
+
+	assert.Equal(t, "id,region,qty\na,east,1\nb,east,2\n", dedupe([]string{"id"}))
+	assert.Equal(t, "id,region,qty\na,east,1\nb,east,2\na,west,3\n", dedupe([]string{"id", "region"}))
+	assert.Equal(t, "id,region,qty\na,west,3\na,east,4\nb,east,5\n", dedupe([]string{"id", "region"}, WithKeepLast()))
+	assert.Equal(t, "id,region,qty\na,east,1\nb,east,2\n", dedupe([]string{"id"}, WithDedupeFilterSize(1024)))
+}
+
+func TestDedupe_sortedInput(t *testing.T) {
+	in := "id;qty\na;1\na;2\nb;3\nc;4\nc;5\nc;6\n"
+
+	r := NewReader()
+	r.Comma = ';'
+	r.HasHeader = true
+
+	for _, tc := range []struct {
+		options  []DedupeOption
+		expected string
+	}{
+		{[]DedupeOption{WithSortedInput()}, "id;qty\na;1\nb;3\nc;4\n"},
+		{[]DedupeOption{WithSortedInput(), WithKeepLast()}, "id;qty\na;2\nb;3\nc;6\n"},
+	} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.Comma = ';'
+
+		options := append(tc.options, WithDedupeReader(r))
+		assert.Nil(t, Dedupe(w, strings.NewReader(in), []string{"id"}, options...))
+		assert.Equal(t, tc.expected, buf.String())
+	}
+}
+
+func TestDedupe_headerOnly(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, Dedupe(NewWriter(&buf), strings.NewReader("id,name\n"), []string{"id"}))
+	assert.Equal(t, "id,name\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, Dedupe(NewWriter(&buf), strings.NewReader(""), []string{"id"}))
+	assert.Equal(t, "", buf.String())
+
+	err := Dedupe(NewWriter(&buf), strings.NewReader("id,name\n"), []string{"c"})
+	assert.EqualError(t, err, `Column "c" not found in header`)
+}
+
+func TestDedupe_errors(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := Dedupe(NewWriter(&buf), strings.NewReader("a,b\n1,2\n"), []string{"c"})
+	assert.EqualError(t, err, `Line 2: Column "c" not found in header`)
+
+	err = Dedupe(NewWriter(&buf), strings.NewReader("a,b\n1,2\n"), []string{"a"}, WithKeepLast(), WithDedupeFilterSize(1024))
+	assert.EqualError(t, err, "Can't keep the last record of each key using a Bloom filter")
+
+	err = Dedupe(NewWriter(&buf), strings.NewReader("1,2\n"), []string{"a"}, WithDedupeReader(NewReader()))
+	assert.EqualError(t, err, "Line 1: Can't dedupe by column name: the input has no header")
+}
@@ -0,0 +1,44 @@
+package hastycsv
+
+import (
+	"io"
+)
+
+// A column appended to every record by DeriveColumns().
+type DerivedColumn struct {
+	// Name is the column's name in the header.
+	Name string
+
+	// Compute returns the column's value for a record, given the record's
+	// original fields.  The returned slice is written before the next call,
+	// so it may be a buffer that is reused from one record to the next.
+	Compute func(record []Field) []byte
+}
+
+// Streams the records of src to dst, appending the specified computed columns
+// to each one (see DeriveColumns()).  The first record of src must be a
+// header, which is written with the columns' names appended.
+func Derive(dst *Writer, src io.Reader, columns ...DerivedColumn) error {
+	return Copy(dst, src, DeriveColumns(true, columns...))
+}
+
+// Returns a Transform for Copy() that appends the specified computed columns
+// to each record.  If hasHeader is true, the first record is treated as a
+// header, and the columns' names are appended to it instead.
+func DeriveColumns(hasHeader bool, columns ...DerivedColumn) Transform {
+	var extended []Field
+	return func(i int, record []Field) ([]Field, error) {
+		extended = append(extended[:0], record...)
+		if hasHeader && i == 1 {
+			for _, col := range columns {
+				extended = append(extended, NewField([]byte(col.Name)))
+			}
+			return extended, nil
+		}
+
+		for _, col := range columns {
+			extended = append(extended, NewField(col.Compute(record)))
+		}
+		return extended, nil
+	}
+}
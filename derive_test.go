@@ -0,0 +1,42 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDerive(t *testing.T) {
+	in := strings.NewReader("name,price,qty\napple,1.5,4\npear,2,3\n")
+
+	var buf []byte
+	total := DerivedColumn{Name: "total", Compute: func(record []Field) []byte {
+		buf = strconv.AppendFloat(buf[:0], float64(record[1].Float32())*float64(record[2].Uint32()), 'f', -1, 64)
+		return buf
+	}}
+	upper := DerivedColumn{Name: "upper", Compute: func(record []Field) []byte {
+		return bytes.ToUpper(record[0].Bytes())
+	}}
+
+	var out bytes.Buffer
+	assert.Nil(t, Derive(NewWriter(&out), in, total, upper))
+	assert.Equal(t, "name,price,qty,total,upper\napple,1.5,4,6,APPLE\npear,2,3,6,PEAR\n", out.String())
+}
+
+func TestDeriveColumns(t *testing.T) {
+	in := strings.NewReader("bill|30\nmary|17\n")
+	adult := DerivedColumn{Compute: func(record []Field) []byte {
+		if record[1].Uint32() >= 18 {
+			return []byte("yes")
+		}
+		return []byte("no")
+	}}
+
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	w.Comma = '|'
+	assert.Nil(t, Copy(w, in, DeriveColumns(false, adult)))
+	assert.Equal(t, "bill|30|yes\nmary|17|no\n", out.String())
+}
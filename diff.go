@@ -0,0 +1,321 @@
+package hastycsv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Identifies how a record differs between the inputs of Diff().
+type DiffKind int
+
+const (
+	// The record's key only appears in the new input.
+	Added DiffKind = iota
+
+	// The record's key only appears in the old input.
+	Removed
+
+	// The record's key appears in both inputs, with different values.
+	Changed
+)
+
+var diffKindNames = []string{"added", "removed", "changed"}
+
+// Returns the name of this kind of difference, e.g. "added".
+func (me DiffKind) String() string {
+	if me < 0 || int(me) >= len(diffKindNames) {
+		return fmt.Sprintf("DiffKind(%d)", int(me))
+	}
+	return diffKindNames[me]
+}
+
+// A record that differs between the inputs of Diff().
+type Difference struct {
+	Kind DiffKind
+	Key  string
+
+	// Old and New are the record in the old and new input, respectively, or
+	// nil if the record is Added or Removed.  Like the fields passed to a Next
+	// callback, they are only valid until the DiffFunc returns.
+	Old, New []Field
+
+	// OldLine and NewLine are the record's line numbers in each input, or 0.
+	OldLine, NewLine int
+
+	// Columns holds the names of the columns whose values changed, if Kind is
+	// Changed.  It's only valid until the DiffFunc returns too.
+	Columns []string
+}
+
+// Definition of a callback function that receives the differences found by
+// Diff().  Returning an error stops the comparison.
+type DiffFunc func(d *Difference) error
+
+// Counts the records compared by Diff().
+type DiffSummary struct {
+	Added, Removed, Changed, Unchanged int
+}
+
+type diffConfig struct {
+	oldReader, newReader *Reader
+	unsorted             bool
+	tempDir              string
+}
+
+// Configures Diff().
+type DiffOption func(cfg *diffConfig)
+
+// Returns a DiffOption that makes Diff() parse its old and new inputs using
+// the specified Readers, e.g. to set their Comma delimiter.  By default,
+// Readers returned by NewReader() are used.  HasHeader is always set.
+func WithDiffReaders(oldReader, newReader *Reader) DiffOption {
+	return func(cfg *diffConfig) {
+		cfg.oldReader, cfg.newReader = oldReader, newReader
+	}
+}
+
+// Returns a DiffOption that makes Diff() sort its inputs by key before
+// comparing them, using Sort() with temporary files in the specified directory
+// (os.TempDir() if ""), so that memory use remains bounded.
+func WithUnsortedDiffInputs(tempDir string) DiffOption {
+	return func(cfg *diffConfig) {
+		cfg.unsorted = true
+		cfg.tempDir = tempDir
+	}
+}
+
+// Compares the records of the old and new inputs by the value of their key
+// column, calling onDiff for each record that was added, removed or changed,
+// and returns the number of records of each kind.  A record is changed if any
+// of the columns that appear in both headers has a different value; columns
+// that only appear in one header are ignored.
+//
+// Both inputs must start with a header and be sorted by their key column in
+// ascending byte order (see Sort()), unless WithUnsortedDiffInputs() is used.
+// They are then streamed side by side, so only the current record of each
+// input is held in memory.  Keys must be unique within each input.
+func Diff(oldSrc, newSrc io.Reader, keyColumn string, onDiff DiffFunc, options ...DiffOption) (DiffSummary, error) {
+	cfg := &diffConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+	if cfg.oldReader == nil {
+		cfg.oldReader = NewReader()
+	}
+	if cfg.newReader == nil {
+		cfg.newReader = NewReader()
+	}
+	cfg.oldReader.HasHeader = true
+	cfg.newReader.HasHeader = true
+
+	if cfg.unsorted {
+		var err error
+		if oldSrc, err = sortDiffInput(cfg.oldReader, oldSrc, keyColumn, cfg.tempDir); err != nil {
+			return DiffSummary{}, fmt.Errorf("Old input: %w", err)
+		}
+		defer removeTempFile(oldSrc.(*os.File))
+		if newSrc, err = sortDiffInput(cfg.newReader, newSrc, keyColumn, cfg.tempDir); err != nil {
+			return DiffSummary{}, fmt.Errorf("New input: %w", err)
+		}
+		defer removeTempFile(newSrc.(*os.File))
+	}
+
+	oldSide := &diffSide{name: "Old input", src: oldSrc, reader: cfg.oldReader, keyColumn: keyColumn}
+	newSide := &diffSide{name: "New input", src: newSrc, reader: cfg.newReader, keyColumn: keyColumn}
+	for _, side := range []*diffSide{oldSide, newSide} {
+		if err := side.reader.begin(side.src); err != nil {
+			return DiffSummary{}, err
+		}
+		defer side.reader.end()
+		if err := side.advance(); err != nil {
+			return DiffSummary{}, err
+		}
+	}
+
+	d := &differ{old: oldSide, new: newSide, onDiff: onDiff}
+	if err := d.run(); err != nil {
+		return DiffSummary{}, err
+	}
+	return d.summary, nil
+}
+
+// One of the inputs of a Diff(), positioned at its current record.
+type diffSide struct {
+	name      string
+	src       io.Reader
+	reader    *Reader
+	keyColumn string
+	keyIndex  int
+	record    []Field // nil once the input is exhausted
+	key       []byte  // the key of the current record
+	prevKey   []byte
+	started   bool
+}
+
+// Reads the next record, and checks that the keys are in ascending order.
+func (me *diffSide) advance() error {
+	record, err := me.reader.next()
+	if err == io.EOF {
+		me.record = nil
+		if !me.started {
+			// The input has no records; it must still have the key column.
+			return me.locate()
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("%v: %w", me.name, err)
+	}
+
+	if !me.started {
+		if err := me.locate(); err != nil {
+			return err
+		}
+	}
+	me.record = record
+	me.prevKey = append(me.prevKey[:0], me.key...)
+	me.key = append(me.key[:0], record[me.keyIndex].data...)
+
+	if me.started {
+		if c := bytes.Compare(me.prevKey, me.key); c == 0 {
+			return fmt.Errorf(`%v: Line %v: Duplicate key "%v"`, me.name, me.reader.row, string(me.key))
+		} else if c > 0 {
+			return fmt.Errorf(`%v: Line %v: Key "%v" is out of order; the input must be sorted by "%v"`, me.name, me.reader.row, string(me.key), me.keyColumn)
+		}
+	}
+	me.started = true
+	return nil
+}
+
+// Finds the key column in the header.
+func (me *diffSide) locate() error {
+	me.started = true
+	header := me.reader.Header()
+	if header == nil {
+		return fmt.Errorf("%v: Missing header", me.name)
+	}
+	if me.keyIndex = indexOfString(header, me.keyColumn); me.keyIndex < 0 {
+		return fmt.Errorf(`%v: Column "%v" not found in header`, me.name, me.keyColumn)
+	}
+	return nil
+}
+
+// The state of a Diff() call.
+type differ struct {
+	old, new *diffSide
+	onDiff   DiffFunc
+	summary  DiffSummary
+
+	columns    []string // the columns that appear in both headers
+	oldIndexes []int
+	newIndexes []int
+	diff       Difference
+}
+
+func (me *differ) run() error {
+	oldHeader, newHeader := me.old.reader.Header(), me.new.reader.Header()
+	for i, name := range oldHeader {
+		if j := indexOfString(newHeader, name); j >= 0 {
+			me.columns = append(me.columns, name)
+			me.oldIndexes = append(me.oldIndexes, i)
+			me.newIndexes = append(me.newIndexes, j)
+		}
+	}
+
+	for me.old.record != nil || me.new.record != nil {
+		c := 0
+		if me.old.record == nil {
+			c = 1
+		} else if me.new.record == nil {
+			c = -1
+		} else {
+			c = bytes.Compare(me.old.key, me.new.key)
+		}
+
+		me.diff = Difference{Columns: me.diff.Columns[:0]}
+		switch {
+		case c < 0:
+			me.summary.Removed++
+			me.diff.Kind, me.diff.Key = Removed, string(me.old.key)
+			me.diff.Old, me.diff.OldLine = me.old.record, me.old.reader.row
+			if err := me.emit(me.old); err != nil {
+				return err
+			}
+
+		case c > 0:
+			me.summary.Added++
+			me.diff.Kind, me.diff.Key = Added, string(me.new.key)
+			me.diff.New, me.diff.NewLine = me.new.record, me.new.reader.row
+			if err := me.emit(me.new); err != nil {
+				return err
+			}
+
+		default:
+			for i, name := range me.columns {
+				if !bytes.Equal(me.old.record[me.oldIndexes[i]].data, me.new.record[me.newIndexes[i]].data) {
+					me.diff.Columns = append(me.diff.Columns, name)
+				}
+			}
+			if len(me.diff.Columns) == 0 {
+				me.summary.Unchanged++
+			} else {
+				me.summary.Changed++
+				me.diff.Kind, me.diff.Key = Changed, string(me.old.key)
+				me.diff.Old, me.diff.OldLine = me.old.record, me.old.reader.row
+				me.diff.New, me.diff.NewLine = me.new.record, me.new.reader.row
+				if err := me.callback(); err != nil {
+					return err
+				}
+			}
+			if err := me.old.advance(); err != nil {
+				return err
+			}
+			if err := me.new.advance(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Reports the current difference, and advances side.
+func (me *differ) emit(side *diffSide) error {
+	if err := me.callback(); err != nil {
+		return err
+	}
+	return side.advance()
+}
+
+func (me *differ) callback() error {
+	if me.onDiff == nil {
+		return nil
+	}
+	return me.onDiff(&me.diff)
+}
+
+// Sorts src by keyColumn into a temporary file, and returns the file,
+// positioned at its start.
+func sortDiffInput(reader *Reader, src io.Reader, keyColumn, tempDir string) (io.Reader, error) {
+	f, err := os.CreateTemp(tempDir, "hastycsv-diff-*")
+	if err != nil {
+		return nil, err
+	}
+
+	w := NewWriter(f)
+	w.Comma = reader.Comma
+	err = Sort(w, src, []SortKey{{Column: StringColumn(keyColumn)}}, WithSortReader(reader), WithSortTempDir(tempDir))
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		removeTempFile(f)
+		return nil, err
+	}
+	return f, nil
+}
+
+func removeTempFile(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
@@ -0,0 +1,72 @@
+package hastycsv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	oldIn := "id,name,age,legacy\n1,bill,30,x\n2,mary,35,y\n4,jane,45,z\n5,fred,50,w\n"
+	newIn := "id,age,name\n1,30,bill\n3,22,tom\n4,46,janet\n5,50,fred\n6,60,ann\n"
+
+	var diffs []string
+	summary, err := Diff(strings.NewReader(oldIn), strings.NewReader(newIn), "id", func(d *Difference) error {
+		diffs = append(diffs, fmt.Sprintf("%v %v %v old=%v new=%v %v", d.Kind, d.Key, d.Columns, d.OldLine, d.NewLine, len(d.Old)+len(d.New)))
+		return nil
+	})
+
+	require.Nil(t, err)
+	assert.Equal(t, DiffSummary{Added: 2, Removed: 1, Changed: 1, Unchanged: 2}, summary)
+	assert.Equal(t, []string{
+		"removed 2 [] old=3 new=0 4",
+		"added 3 [] old=0 new=3 3",
+		"changed 4 [name age] old=4 new=4 7",
+		"added 6 [] old=0 new=6 3",
+	}, diffs)
+}
+
+func TestDiff_unsorted(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	oldIn := "id|v\nc|1\na|1\nb|1\n"
+	newIn := "id|v\nb|2\nd|1\na|1\n"
+
+	oldReader, newReader := NewReader(), NewReader()
+	oldReader.Comma, newReader.Comma = '|', '|'
+
+	var diffs []string
+	summary, err := Diff(strings.NewReader(oldIn), strings.NewReader(newIn), "id", func(d *Difference) error {
+		diffs = append(diffs, d.Kind.String()+" "+d.Key)
+		return nil
+	}, WithDiffReaders(oldReader, newReader), WithUnsortedDiffInputs(dir))
+
+	require.Nil(t, err)
+	assert.Equal(t, DiffSummary{Added: 1, Removed: 1, Changed: 1, Unchanged: 1}, summary)
+	assert.Equal(t, []string{"changed b", "removed c", "added d"}, diffs)
+	assert.Equal(t, 0, len(listDir(t, dir)))
+}
+
+func TestDiff_errors(t *testing.T) {
+	_, err := Diff(strings.NewReader("id\n2\n1\n"), strings.NewReader("id\n1\n"), "id", nil)
+	assert.EqualError(t, err, `Old input: Line 3: Key "1" is out of order; the input must be sorted by "id"`)
+
+	_, err = Diff(strings.NewReader("id\n1\n"), strings.NewReader("id\n1\n1\n"), "id", nil)
+	assert.EqualError(t, err, `New input: Line 3: Duplicate key "1"`)
+
+	_, err = Diff(strings.NewReader("id,name\n1\n"), strings.NewReader("id\n1\n"), "id", nil)
+	assert.True(t, errors.Is(err, ErrFieldCount))
+
+	_, err = Diff(strings.NewReader("id\n1\n"), strings.NewReader("key\n"), "id", nil)
+	assert.EqualError(t, err, `New input: Column "id" not found in header`)
+
+	_, err = Diff(strings.NewReader("id\n1\n"), strings.NewReader("id\n2\n"), "id", func(d *Difference) error {
+		return fmt.Errorf("Stop")
+	})
+	assert.EqualError(t, err, "Stop")
+}
@@ -0,0 +1,96 @@
+package hastycsv
+
+import (
+	"hash/maphash"
+)
+
+// Identifies how Read() handles records whose line is identical to that of an
+// earlier record.
+type DuplicateRowPolicy int
+
+const (
+	// Don't look for duplicate rows.
+	AllowDuplicateRows DuplicateRowPolicy = iota
+
+	// Pass duplicate rows to the callback, but count them (see
+	// Reader.DuplicateRowCount()).
+	CountDuplicateRows
+
+	// Count duplicate rows, and don't pass them to the callback.
+	SkipDuplicateRows
+)
+
+// The number of bits set in the Bloom filter for each row.
+const duplicateFilterHashes = 4
+
+// Detects rows that were already read, by hashing each row's raw line.  Lines
+// are identified by their 64-bit hash, so a row is mistaken for a duplicate
+// only in the (extremely unlikely) case of a hash collision.  If a Bloom
+// filter is used, memory stays bounded but false positives become likelier as
+// the filter fills up.
+type rowSet struct {
+	hash       maphash.Hash
+	seen       map[uint64]struct{}
+	filter     []uint64 // the Bloom filter's bits, if memory is bounded
+	duplicates int
+}
+
+// Prepares the set for a new read.  If filterSize is positive, rows are
+// tracked with a Bloom filter of filterSize bytes.
+func (me *rowSet) reset(filterSize int) {
+	me.seen = nil
+	me.filter = nil
+	me.duplicates = 0
+	if filterSize > 0 {
+		me.filter = make([]uint64, (filterSize+7)/8)
+	} else {
+		me.seen = map[uint64]struct{}{}
+	}
+}
+
+// Adds line to the set, and returns true if it was already there.
+func (me *rowSet) add(line []byte) bool {
+	me.hash.Reset()
+	me.hash.Write(line)
+	h := me.hash.Sum64()
+
+	duplicate := true
+	if me.filter == nil {
+		if _, duplicate = me.seen[h]; !duplicate {
+			me.seen[h] = struct{}{}
+		}
+	} else {
+		// Derive the filter's bit positions from two halves of the hash
+		// (Kirsch-Mitzenmacher double hashing).
+		bits := uint64(len(me.filter)) * 64
+		h1, h2 := h&0xffffffff, h>>32
+		for i := uint64(0); i < duplicateFilterHashes; i++ {
+			bit := (h1 + i*h2) % bits
+			word, mask := bit/64, uint64(1)<<(bit%64)
+			if me.filter[word]&mask == 0 {
+				duplicate = false
+				me.filter[word] |= mask
+			}
+		}
+	}
+
+	if duplicate {
+		me.duplicates++
+	}
+	return duplicate
+}
+
+// Returns true if the record whose raw line is line should be skipped as a
+// duplicate, counting it if DuplicateRows is set.
+func (me *Reader) skipDuplicate(line []byte) bool {
+	if me.DuplicateRows == AllowDuplicateRows {
+		return false
+	}
+	return me.dupes.add(line) && me.DuplicateRows == SkipDuplicateRows
+}
+
+// Returns the number of duplicate rows found by the most recent read, if
+// DuplicateRows was set.
+func (me *Reader) DuplicateRowCount() int {
+	return me.dupes.duplicates
+}
@@ -0,0 +1,65 @@
+package hastycsv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestReader_DuplicateRows(t *testing.T) {
+	in := "name,age\nbill,30\nmary,35\nbill,30\nbill,31\nmary,35\n"
+
+	r := NewReader()
+	r.HasHeader = true
+
+	read := func() []int {
+		received := []int{}
+		err := r.Read(strings.NewReader(in), func(i int, fields []Field) error {
+			received = append(received, i)
+			return nil
+		})
+		assert.Nil(t, err)
+		return received
+	}
+
+	assert.Equal(t, []int{2, 3, 4, 5, 6}, read())
+	assert.Equal(t, 0, r.DuplicateRowCount())
+
+	r.DuplicateRows = CountDuplicateRows
+	assert.Equal(t, []int{2, 3, 4, 5, 6}, read())
+	assert.Equal(t, 2, r.DuplicateRowCount())
+
+	r.DuplicateRows = SkipDuplicateRows
+	assert.Equal(t, []int{2, 3, 5}, read())
+	assert.Equal(t, 2, r.DuplicateRowCount())
+
+	received := []int{}
+	err := r.ReadBytes([]byte(in), func(i int, fields []Field) error {
+		received = append(received, i)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 3, 5}, received)
+
+	r.DuplicateFilterSize = 1024
+	assert.Equal(t, []int{2, 3, 5}, read())
+	assert.Equal(t, 2, r.DuplicateRowCount())
+}
+
+func TestRowSet_filter(t *testing.T) {
+	rows := &rowSet{}
+	rows.reset(2 * 1000)
+
+	falsePositives := 0
+	for i := 0; i < 1000; i++ {
+		if rows.add([]byte(fmt.Sprintf("row %v", i))) {
+			falsePositives++
+		}
+	}
+	assert.True(t, falsePositives < 20, "%v false positives", falsePositives)
+
+	for i := 0; i < 1000; i++ {
+		assert.True(t, rows.add([]byte(fmt.Sprintf("row %v", i))))
+	}
+}
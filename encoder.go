@@ -0,0 +1,282 @@
+package hastycsv
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Writes structs as CSV records, one record per struct.
+//
+// Each exported struct field becomes a column.  The column name defaults to
+// the field's name and can be overridden with a `csv:"name"` struct tag;
+// fields tagged `csv:"-"` are skipped.  The following tag options control how
+// individual fields are formatted:
+//
+//	fmt=f|e|g   strconv.FormatFloat() format of a float field
+//	prec=N      strconv.FormatFloat() precision of a float field
+//	layout=...  time.Format() layout of a time.Time field
+//
+// Supported field types are strings, bools, all int, uint and float types,
+// time.Time, types that implement encoding.TextMarshaler, and pointers to any
+// of these (a nil pointer is written as an empty field).
+type Encoder struct {
+	// FloatFormat is the default strconv.FormatFloat() format of float fields.
+	// It is set to 'f' by NewEncoder.
+	FloatFormat byte
+
+	// FloatPrecision is the default strconv.FormatFloat() precision of float
+	// fields.  It is set to -1 (the smallest number of digits necessary to
+	// represent the value exactly) by NewEncoder.
+	FloatPrecision int
+
+	// TimeLayout is the default layout of time.Time fields.
+	// It is set to time.RFC3339 by NewEncoder.
+	TimeLayout string
+
+	// WriteHeader indicates whether the first call to Encode() writes a header
+	// record containing the column names.
+	// It is set to true by NewEncoder.
+	WriteHeader bool
+
+	w             *Writer
+	typ           reflect.Type
+	columns       []encoderColumn
+	buf           []byte
+	headerWritten bool
+}
+
+type encoderColumn struct {
+	name   string
+	index  int // index of the struct field
+	encode func(buf []byte, v reflect.Value) ([]byte, error)
+}
+
+// Returns a new Encoder that writes records to the specified Writer.
+func NewEncoder(w *Writer) *Encoder {
+	return &Encoder{
+		FloatFormat:    'f',
+		FloatPrecision: -1,
+		TimeLayout:     time.RFC3339,
+		WriteHeader:    true,
+		w:              w,
+	}
+}
+
+// Writes the specified struct (or pointer to a struct) as a single record.
+// All values passed to the same Encoder must be of the same type.
+func (me *Encoder) Encode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return fmt.Errorf("Can't encode a nil %v", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+
+	if err := me.prepare(rv.Type()); err != nil {
+		return err
+	}
+
+	if me.WriteHeader && !me.headerWritten {
+		if err := me.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	me.buf = me.buf[:0]
+	for i := range me.columns {
+		col := &me.columns[i]
+		if i > 0 {
+			me.buf = append(me.buf, me.w.Comma)
+		}
+
+		start := len(me.buf)
+		buf, err := col.encode(me.buf, rv.Field(col.index))
+		if err != nil {
+			return fmt.Errorf("Can't encode field %v: %w", col.name, err)
+		}
+		me.buf = buf
+
+		if err := me.w.checkField(i, Field{data: me.buf[start:]}.unsafeString()); err != nil {
+			return err
+		}
+	}
+
+	return me.w.writeLine(me.buf, len(me.columns))
+}
+
+// Writes each element of the specified slice (or array) of structs as a
+// record.
+func (me *Encoder) EncodeAll(slice interface{}) error {
+	rv := reflect.ValueOf(slice)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return fmt.Errorf("Expected a slice of structs, but got %T", slice)
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		if err := me.Encode(rv.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (me *Encoder) writeHeader() error {
+	header := make([]string, len(me.columns))
+	for i, col := range me.columns {
+		header[i] = col.name
+	}
+
+	me.headerWritten = true
+	return me.w.Write(header)
+}
+
+// Builds the column layout of the specified struct type the first time it's
+// encountered.
+func (me *Encoder) prepare(t reflect.Type) error {
+	if me.typ != nil {
+		if t != me.typ {
+			return fmt.Errorf("Can't encode %v with an Encoder that is encoding %v", t, me.typ)
+		}
+		return nil
+	}
+
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("Can't encode %v: not a struct", t)
+	}
+
+	columns := []encoderColumn{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := parseFieldTag(sf)
+		if tag.skip {
+			continue
+		}
+
+		encode, err := me.valueEncoder(sf.Type, tag)
+		if err != nil {
+			return fmt.Errorf("Can't encode field %v.%v: %w", t, sf.Name, err)
+		}
+		columns = append(columns, encoderColumn{name: tag.name, index: i, encode: encode})
+	}
+
+	me.typ = t
+	me.columns = columns
+	return nil
+}
+
+// Returns a function that appends the text representation of a value of the
+// specified type to a byte slice.
+func (me *Encoder) valueEncoder(t reflect.Type, tag fieldTag) (func(buf []byte, v reflect.Value) ([]byte, error), error) {
+	if t.Kind() == reflect.Ptr {
+		encodeElem, err := me.valueEncoder(t.Elem(), tag)
+		if err != nil {
+			return nil, err
+		}
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			if v.IsNil() {
+				return buf, nil
+			}
+			return encodeElem(buf, v.Elem())
+		}, nil
+	}
+
+	if t == timeType {
+		layout := me.TimeLayout
+		if tagLayout, ok := tag.option("layout"); ok {
+			layout = tagLayout
+		}
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			return v.Interface().(time.Time).AppendFormat(buf, layout), nil
+		}, nil
+	}
+
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			if !v.Type().Implements(textMarshalerType) {
+				if !v.CanAddr() {
+					tmp := reflect.New(v.Type()).Elem()
+					tmp.Set(v)
+					v = tmp
+				}
+				v = v.Addr()
+			}
+			text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+			return append(buf, text...), err
+		}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			return append(buf, v.String()...), nil
+		}, nil
+	case reflect.Bool:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			return strconv.AppendBool(buf, v.Bool()), nil
+		}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			return strconv.AppendInt(buf, v.Int(), 10), nil
+		}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			return strconv.AppendUint(buf, v.Uint(), 10), nil
+		}, nil
+	case reflect.Float32, reflect.Float64:
+		format, prec, err := me.floatFormat(tag)
+		if err != nil {
+			return nil, err
+		}
+		bitSize := t.Bits()
+		return func(buf []byte, v reflect.Value) ([]byte, error) {
+			return strconv.AppendFloat(buf, v.Float(), format, prec, bitSize), nil
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Unsupported type %v", t)
+}
+
+// Returns the float format and precision of a field, taking its tag options
+// into account.
+func (me *Encoder) floatFormat(tag fieldTag) (byte, int, error) {
+	format, prec := me.FloatFormat, me.FloatPrecision
+
+	if tagFormat, ok := tag.option("fmt"); ok {
+		if len(tagFormat) != 1 {
+			return 0, 0, fmt.Errorf(`Invalid float format "%v"`, tagFormat)
+		}
+		format = tagFormat[0]
+	}
+
+	if tagPrec, ok := tag.option("prec"); ok {
+		p, err := strconv.Atoi(tagPrec)
+		if err != nil {
+			return 0, 0, fmt.Errorf(`Invalid float precision "%v"`, tagPrec)
+		}
+		prec = p
+	}
+
+	return format, prec, nil
+}
+
+// Writes the specified slice of structs to w as CSV: a header record followed
+// by one record per struct.
+func Marshal(w io.Writer, comma byte, slice interface{}) error {
+	csvWriter := NewWriter(w)
+	csvWriter.Comma = comma
+
+	if err := NewEncoder(csvWriter).EncodeAll(slice); err != nil {
+		return err
+	}
+	return csvWriter.Flush()
+}
@@ -0,0 +1,137 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+type encoderTestPerson struct {
+	Name     string    `csv:"name"`
+	Age      uint32    `csv:"age"`
+	Weight   float32   `csv:"weight,prec=1"`
+	Score    float64   `csv:"score,fmt=e,prec=2"`
+	Born     time.Time `csv:"born,layout=2006-01-02"`
+	Nickname *string   `csv:"nickname"`
+	Active   bool
+	Ignored  string `csv:"-"`
+	internal string
+}
+
+func TestNewEncoder(t *testing.T) {
+	e := NewEncoder(NewWriter(&bytes.Buffer{}))
+	assert.Equal(t, byte('f'), e.FloatFormat)
+	assert.Equal(t, -1, e.FloatPrecision)
+	assert.Equal(t, time.RFC3339, e.TimeLayout)
+	assert.True(t, e.WriteHeader)
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	nickname := "billy"
+	persons := []encoderTestPerson{
+		{Name: "bill", Age: 30, Weight: 154.55, Score: 1234.5, Born: time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC), Nickname: &nickname, Active: true, Ignored: "x", internal: "y"},
+		{Name: "mary", Age: 35, Weight: 125, Score: 0.25, Born: time.Date(1985, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Comma = '|'
+	e := NewEncoder(w)
+	assert.Nil(t, e.Encode(persons[0]))
+	assert.Nil(t, e.Encode(&persons[1]))
+	assert.Nil(t, w.Flush())
+
+	assert.Equal(t, strings.Join([]string{
+		"name|age|weight|score|born|nickname|Active",
+		"bill|30|154.6|1.23e+03|1990-03-04|billy|true",
+		"mary|35|125.0|2.50e-01|1985-12-31||false",
+		"",
+	}, "\n"), buf.String())
+}
+
+func TestEncoder_Encode_defaultFormats(t *testing.T) {
+	type Record struct {
+		F32 float32
+		F64 float64
+		T   time.Time
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	e := NewEncoder(w)
+	e.WriteHeader = false
+	assert.Nil(t, e.Encode(Record{F32: 0.1, F64: 2.5, T: time.Date(2019, 6, 27, 10, 30, 0, 0, time.UTC)}))
+
+	e = NewEncoder(w)
+	e.WriteHeader = false
+	e.FloatPrecision = 3
+	e.TimeLayout = "20060102"
+	assert.Nil(t, e.Encode(Record{F32: 0.1, F64: 2.5, T: time.Date(2019, 6, 27, 10, 30, 0, 0, time.UTC)}))
+	assert.Nil(t, w.Flush())
+
+	assert.Equal(t, "0.1,2.5,2019-06-27T10:30:00Z\n0.100,2.500,20190627\n", buf.String())
+}
+
+type encoderTestColor int
+
+func (me encoderTestColor) MarshalText() ([]byte, error) {
+	return []byte([]string{"red", "green", "blue"}[me]), nil
+}
+
+type encoderTestID [2]byte
+
+func (me *encoderTestID) MarshalText() ([]byte, error) {
+	return []byte{'#', me[0], me[1]}, nil
+}
+
+func TestEncoder_Encode_textMarshaler(t *testing.T) {
+	type Record struct {
+		Color encoderTestColor
+		ID    encoderTestID
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, Marshal(&buf, ',', []Record{{Color: 2, ID: encoderTestID{'a', 'b'}}}))
+	assert.Equal(t, "Color,ID\nblue,#ab\n", buf.String())
+}
+
+func TestEncoder_Encode_errors(t *testing.T) {
+	type Unsupported struct {
+		Values []int
+	}
+	type Other struct {
+		Name string
+	}
+
+	e := NewEncoder(NewWriter(&bytes.Buffer{}))
+	assert.NotNil(t, e.Encode("not a struct"))
+	assert.NotNil(t, e.Encode((*Other)(nil)))
+	assert.NotNil(t, e.Encode(Unsupported{}))
+
+	e = NewEncoder(NewWriter(&bytes.Buffer{}))
+	assert.Nil(t, e.Encode(Other{Name: "a"}))
+	assert.NotNil(t, e.Encode(encoderTestPerson{}), "type mismatch")
+	assert.NotNil(t, e.Encode(Other{Name: "a,b"}), "field contains delimiter")
+}
+
+func TestMarshal(t *testing.T) {
+	type Car struct {
+		Make  string  `csv:"make"`
+		Model string  `csv:"model"`
+		Year  int     `csv:"year"`
+		MPG   float32 `csv:"mpg"`
+	}
+
+	cars := []Car{
+		{"Honda", "Acura NSX", 2017, 18.1},
+		{"BMW", "M3", 2015, 18.7},
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, Marshal(&buf, '|', cars))
+	assert.Equal(t, "make|model|year|mpg\nHonda|Acura NSX|2017|18.1\nBMW|M3|2015|18.7\n", buf.String())
+
+	assert.NotNil(t, Marshal(&buf, '|', cars[0]), "not a slice")
+}
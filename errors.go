@@ -0,0 +1,475 @@
+package hastycsv
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sentinel errors wrapped by the errors of this package, so that callers can
+// tell kinds of failures apart with errors.Is(), e.g. to route ragged records
+// and unparseable values differently, while treating any other error as an
+// I/O failure.
+var (
+	// A record doesn't contain the expected number of fields.  See
+	// FieldCountError.
+	ErrFieldCount = errors.New("Wrong number of fields")
+
+	// A field can't be parsed.  See ParseError.
+	ErrParse = errors.New("Can't parse field")
+
+	// A line exceeds Reader.MaxLineLength.
+	ErrLineTooLong = errors.New("Line too long")
+
+	// A line is shorter than Reader.MinLineLength.
+	ErrLineTooShort = errors.New("Line too short")
+
+	// A Reader's or Writer's Comma delimiter is invalid.
+	ErrBadDelimiter = errors.New("Invalid delimiter")
+
+	// The input can't be read, e.g. due to an I/O failure.  See ReadError.
+	ErrRead = errors.New("Can't read input")
+)
+
+// An error that wraps one of the sentinel errors above, with its own message.
+type sentinelError struct {
+	sentinel error
+	msg      string
+}
+
+func (me *sentinelError) Error() string {
+	return me.msg
+}
+
+func (me *sentinelError) Unwrap() error {
+	return me.sentinel
+}
+
+// Returned when the input can't be read.  It wraps the error returned by the
+// underlying io.Reader, which can be retrieved with errors.Is() or
+// errors.As(), e.g. to tell a network timeout from a malformed record.
+type ReadError struct {
+	Err error
+}
+
+func (me *ReadError) Error() string {
+	return fmt.Sprintf("Error scanning input: %v", me.Err)
+}
+
+func (me *ReadError) Unwrap() error {
+	return me.Err
+}
+
+// Makes errors.Is(err, ErrRead) true.
+func (me *ReadError) Is(target error) bool {
+	return target == ErrRead
+}
+
+// Identifies why a field couldn't be parsed, so that the hot path can record
+// a parse error without formatting (or allocating) a message.
+type parseErrorCode uint8
+
+const (
+	noParseError parseErrorCode = iota
+	errTooLong
+	errNonNumeric
+	errOverflow
+)
+
+// Returns the message describing why data couldn't be parsed as a uint32.
+func parseErrorMessage(code parseErrorCode, data []byte) string {
+	switch code {
+	case errTooLong:
+		return fmt.Sprintf(`"%v" is too long to be parsed as a uint32`, string(data))
+	case errNonNumeric:
+		for _, ch := range data {
+			if ch < '0' || ch > '9' {
+				return fmt.Sprintf(`"%v" contains non-numeric character '%v'`, string(data), string(ch))
+			}
+		}
+		return fmt.Sprintf(`"%v" is not numeric`, string(data))
+	case errOverflow:
+		return fmt.Sprintf(`"%v" overflows uint32`, string(data))
+	}
+	return fmt.Sprintf(`"%v" can't be parsed`, string(data))
+}
+
+// Describes a field that couldn't be parsed.  While a record is being
+// processed, the Reader keeps this as plain data (with raw pointing into the
+// record); it's only turned into a ParseError once the record is done.
+type fieldError struct {
+	typ   string // the type that the field was parsed as, e.g. "uint32"
+	col   int    // the 1-based position of the field within the record, or 0 if unknown
+	code  parseErrorCode
+	raw   []byte
+	cause error // set instead of code by parsers that return errors, e.g. strconv
+}
+
+// Identifies why a field couldn't be parsed.
+type ParseErrorKind uint8
+
+const (
+	// The field isn't valid for its type, e.g. "abc" for a float32.
+	InvalidSyntax ParseErrorKind = iota
+
+	// The field has too many digits to be parsed as a uint32.
+	TooLong
+
+	// The field contains a character that isn't a digit.
+	NonNumeric
+
+	// The field's value is out of range for its type.
+	Overflow
+)
+
+var parseErrorKindNames = []string{"invalid syntax", "too long", "non-numeric", "overflow"}
+
+// Returns a description of this kind of parse error, e.g. "overflow".
+func (me ParseErrorKind) String() string {
+	if int(me) >= len(parseErrorKindNames) {
+		return fmt.Sprintf("ParseErrorKind(%d)", int(me))
+	}
+	return parseErrorKindNames[me]
+}
+
+// Returns the ParseErrorKind that corresponds to a parseErrorCode, or to the
+// error returned by a parser such as strconv.ParseFloat().
+func parseErrorKind(code parseErrorCode, cause error) ParseErrorKind {
+	switch code {
+	case errTooLong:
+		return TooLong
+	case errNonNumeric:
+		return NonNumeric
+	case errOverflow:
+		return Overflow
+	}
+	if errors.Is(cause, strconv.ErrRange) {
+		return Overflow
+	}
+	return InvalidSyntax
+}
+
+// Returned when a field can't be parsed, whether by a Field method such as
+// Uint32() (once the Next callback returns) or according to a Schema.  Use
+// errors.As() to retrieve it, e.g. to report the offending column.
+type ParseError struct {
+	Line       int    // the line number of the record, or 0 if unknown
+	Column     int    // the (1-based) position of the field within the record, or 0 if unknown
+	ColumnName string // the name of the field's column, if known
+	Type       string // the type that the field was parsed as, e.g. "uint32"
+	Kind       ParseErrorKind
+	Raw        []byte // a copy of the field
+	Err        error  // the error returned by the underlying parser (e.g. a *strconv.NumError), if any
+
+	schema bool // whether the field was parsed according to a Schema
+}
+
+func (me *ParseError) Error() string {
+	msg, column := me.message(), me.column()
+	switch {
+	case me.Line > 0 && column != "":
+		return fmt.Sprintf("Line %v, %v: %v", me.Line, column, msg)
+	case me.Line > 0:
+		return fmt.Sprintf("Line %v: %v", me.Line, msg)
+	case column != "":
+		return fmt.Sprintf("%v%v: %v", strings.ToUpper(column[:1]), column[1:], msg)
+	}
+	return msg
+}
+
+// Returns the error message, without its location.
+func (me *ParseError) message() string {
+	switch {
+	case me.schema:
+		return fmt.Sprintf(`Can't parse column "%v" as %v: "%v"`, me.ColumnName, me.Type, string(me.Raw))
+	case me.Err != nil:
+		return me.Err.Error()
+	}
+	return fmt.Sprintf("Can't parse field as %v: %v", me.Type, parseErrorMessage(me.errorCode(), me.Raw))
+}
+
+// Returns the column of the field, e.g. `column 4 ("price")`, or "" if it's
+// unknown.
+func (me *ParseError) column() string {
+	if me.Column <= 0 {
+		return ""
+	}
+	column := fmt.Sprintf("column %v", me.Column)
+	if me.ColumnName != "" && !me.schema { // a schema's message names the column already
+		column += fmt.Sprintf(` ("%v")`, me.ColumnName)
+	}
+	return column
+}
+
+func (me *ParseError) Unwrap() error {
+	return me.Err
+}
+
+// Makes errors.Is(err, ErrParse) true.
+func (me *ParseError) Is(target error) bool {
+	return target == ErrParse
+}
+
+// Returned instead of a ParseError when Reader.CollectFieldErrors is set and
+// several fields of a record can't be parsed, so that all of them are reported
+// at once.  Its elements are in the order in which the fields were parsed.
+type ParseErrors []*ParseError
+
+func (me ParseErrors) Error() string {
+	parts := make([]string, len(me))
+	for i, err := range me {
+		parts[i] = err.message()
+		if column := err.column(); column != "" {
+			parts[i] = column + ": " + parts[i]
+		}
+	}
+
+	msg := fmt.Sprintf("%v fields can't be parsed: %v", len(me), strings.Join(parts, "; "))
+	if len(me) > 0 && me[0].Line > 0 {
+		return fmt.Sprintf("Line %v: %v", me[0].Line, msg)
+	}
+	return msg
+}
+
+// Makes errors.Is(err, ErrParse) true.
+func (me ParseErrors) Is(target error) bool {
+	return target == ErrParse
+}
+
+// Makes errors.As() retrieve the first ParseError.
+func (me ParseErrors) As(target interface{}) bool {
+	if p, ok := target.(**ParseError); ok && len(me) > 0 {
+		*p = me[0]
+		return true
+	}
+	return false
+}
+
+// Returns the parseErrorCode that corresponds to this error's Kind.
+func (me *ParseError) errorCode() parseErrorCode {
+	switch me.Kind {
+	case TooLong:
+		return errTooLong
+	case NonNumeric:
+		return errNonNumeric
+	case Overflow:
+		return errOverflow
+	}
+	return noParseError
+}
+
+// Returns err associated with the specified line: a *ParseError gets its Line
+// set, while other errors are wrapped in a lineError.
+func atLine(line int, err error) error {
+	switch e := err.(type) {
+	case *ParseError:
+		if e.Line == 0 {
+			e.Line = line
+			return e
+		}
+	case ParseErrors:
+		for _, parseErr := range e {
+			parseErr.Line = line
+		}
+		return e
+	case *PanicError:
+		return e
+	}
+	return &lineError{line: line, err: err}
+}
+
+// Returns err associated with the specified range of lines, e.g. those of a
+// batch of records.  Unlike atLine(), a *ParseError is wrapped rather than
+// given a Line, since it can't be traced to a single line.
+func atLines(first int, last int, err error) error {
+	if first == last {
+		return atLine(first, err)
+	} else if _, ok := err.(*PanicError); ok {
+		return err
+	}
+	return &lineError{line: first, last: last, err: err}
+}
+
+// An error that's associated with a line (or range of lines) of the input.
+// Its message is only formatted by Error().
+type lineError struct {
+	line int
+	last int // the last line of a range, or 0 for a single line
+	err  error
+}
+
+func (me *lineError) Error() string {
+	if me.last > 0 {
+		return fmt.Sprintf("Lines %v-%v: %v", me.line, me.last, me.err)
+	}
+	return fmt.Sprintf("Line %v: %v", me.line, me.err)
+}
+
+func (me *lineError) Unwrap() error {
+	return me.err
+}
+
+// Returned when a record doesn't contain the expected number of fields, i.e.
+// the number of fields of the first record.  Use errors.As() to retrieve it,
+// e.g. to route ragged rows to a quarantine file.
+type FieldCountError struct {
+	Line      int    // the line number of the record
+	Expected  int    // the expected number of fields
+	Actual    int    // the number of fields found
+	Delimiter byte   // the Comma delimiter that the record was split with
+	Raw       []byte // a copy of the record's raw line
+
+	rawLength int // the Reader's ErrorRawLength
+}
+
+func (me *FieldCountError) Error() string {
+	return fmt.Sprintf(`Line %v: Expected []b to contain %v fields using delimiter '%v'%v`, me.Line, me.Expected, string(me.Delimiter), quoteRaw(me.Raw, me.rawLength))
+}
+
+// Returned when a Next callback panics while processing a record, if
+// Reader.RecoverPanics is set.  If the panic's value is an error (e.g. a
+// runtime.Error), it's wrapped.
+type PanicError struct {
+	Line  int         // the line number of the record
+	Value interface{} // the value passed to panic()
+	Raw   []byte      // a copy of the record's raw line, if any
+	Stack []byte      // the stack trace of the goroutine that panicked
+
+	rawLength int // the Reader's ErrorRawLength
+}
+
+func (me *PanicError) Error() string {
+	var raw string
+	if me.Raw != nil {
+		raw = quoteRaw(me.Raw, me.rawLength)
+	}
+	return fmt.Sprintf("Line %v: Callback panicked: %v%v", me.Line, me.Value, raw)
+}
+
+func (me *PanicError) Unwrap() error {
+	err, _ := me.Value.(error)
+	return err
+}
+
+// Returns raw formatted for inclusion at the end of an error message, e.g.
+// `: "a,b"`, limited according to maxLength (see Reader.ErrorRawLength).
+func quoteRaw(raw []byte, maxLength int) string {
+	switch {
+	case maxLength < 0:
+		return ""
+	case maxLength > 0 && len(raw) > maxLength:
+		return fmt.Sprintf(`: "%v..."`, string(raw[:maxLength]))
+	}
+	return fmt.Sprintf(`: "%v"`, string(raw))
+}
+
+// Makes errors.Is(err, ErrFieldCount) true.
+func (me *FieldCountError) Is(target error) bool {
+	return target == ErrFieldCount
+}
+
+// Returns a FieldCountError for the current record, whose raw line is raw and
+// which contains actual fields.
+func (me *Reader) fieldCountError(raw []byte, actual int) error {
+	return &FieldCountError{
+		Line:      me.row,
+		Expected:  len(me.fields),
+		Actual:    actual,
+		Delimiter: me.Comma,
+		Raw:       append([]byte(nil), raw...),
+		rawLength: me.ErrorRawLength,
+	}
+}
+
+// Returned when a header doesn't list exactly the expected columns, in the
+// same order: by Reader.ReadWithSchema() when Schema.StrictHeader is set, and
+// by Concat() when a file's header differs from the first file's.  Its
+// message lists every position where the two differ.
+type HeaderOrderError struct {
+	Expected []string // the expected column names
+	Actual   []string // the header
+}
+
+func (me *HeaderOrderError) Error() string {
+	var diffs []string
+	for i := 0; i < len(me.Expected) || i < len(me.Actual); i++ {
+		switch {
+		case i >= len(me.Actual):
+			diffs = append(diffs, fmt.Sprintf(`column %v is missing, expected "%v"`, i+1, me.Expected[i]))
+		case i >= len(me.Expected):
+			diffs = append(diffs, fmt.Sprintf(`column %v "%v" is unexpected`, i+1, me.Actual[i]))
+		case me.Actual[i] != me.Expected[i]:
+			diffs = append(diffs, fmt.Sprintf(`column %v is "%v", expected "%v"`, i+1, me.Actual[i], me.Expected[i]))
+		}
+	}
+	return "Header doesn't match the expected columns: " + strings.Join(diffs, "; ")
+}
+
+// Records the first field parse error of the current record, raised by the
+// field at the specified (1-based) column.  raw is not copied, so this doesn't
+// allocate.
+func (me *Reader) setFieldErr(typ string, col int, code parseErrorCode, raw []byte, cause error) {
+	if !me.hasFieldErr {
+		me.fieldErr = fieldError{typ: typ, col: col, code: code, raw: raw, cause: cause}
+		me.hasFieldErr = true
+	} else if me.CollectFieldErrors {
+		// Fields that are parsed more than once are only reported once.
+		if col > 0 {
+			if col == me.fieldErr.col {
+				return
+			}
+			for _, fe := range me.moreFieldErrs {
+				if fe.col == col {
+					return
+				}
+			}
+		}
+		me.moreFieldErrs = append(me.moreFieldErrs, fieldError{typ: typ, col: col, code: code, raw: raw, cause: cause})
+	}
+}
+
+// Returns the field parse error recorded for the current record as a
+// *ParseError (with its contents copied, so that it remains valid), or nil if
+// there is none.  If several errors were collected, they're returned as
+// ParseErrors.
+func (me *Reader) fieldError() error {
+	if !me.hasFieldErr {
+		return nil
+	}
+	if len(me.moreFieldErrs) == 0 {
+		return me.parseError(&me.fieldErr)
+	}
+
+	errs := ParseErrors{me.parseError(&me.fieldErr)}
+	for i := range me.moreFieldErrs {
+		errs = append(errs, me.parseError(&me.moreFieldErrs[i]))
+	}
+	return errs
+}
+
+// Returns a ParseError for a recorded field parse error.
+func (me *Reader) parseError(fe *fieldError) *ParseError {
+	var name string
+	if fe.col > 0 && fe.col <= len(me.header) {
+		name = me.header[fe.col-1]
+	}
+	return &ParseError{
+		Column:     fe.col,
+		ColumnName: name,
+		Type:       fe.typ,
+		Kind:       parseErrorKind(fe.code, fe.cause),
+		Raw:        append([]byte(nil), fe.raw...),
+		Err:        fe.cause,
+	}
+}
+
+// Forgets the field parse error recorded for the current record.
+func (me *Reader) clearFieldErr() {
+	me.fieldErr = fieldError{}
+	me.hasFieldErr = false
+	for i := range me.moreFieldErrs {
+		me.moreFieldErrs[i] = fieldError{} // don't keep the record's fields alive
+	}
+	me.moreFieldErrs = me.moreFieldErrs[:0]
+}
@@ -0,0 +1,353 @@
+package hastycsv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestField_parseError_noAllocations(t *testing.T) {
+	field := makeField("12x45")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		field.reader.clearFieldErr()
+		field.Uint32()
+	})
+
+	assert.Equal(t, 0.0, allocs)
+	assert.EqualError(t, field.reader.fieldError(), `Can't parse field as uint32: "12x45" contains non-numeric character 'x'`)
+}
+
+func TestReader_fieldError(t *testing.T) {
+	r := NewReader()
+	assert.Nil(t, r.fieldError())
+
+	raw := []byte("99999999999")
+	r.setFieldErr("uint32", 0, errTooLong, raw, nil)
+	r.setFieldErr("uint32", 0, errOverflow, []byte("5000000000"), nil) // only the first error is kept
+
+	err := r.fieldError()
+	raw[0] = 'X'
+	assert.EqualError(t, err, `Can't parse field as uint32: "99999999999" is too long to be parsed as a uint32`)
+
+	r.clearFieldErr()
+	assert.Nil(t, r.fieldError())
+
+	_, cause := strconv.ParseFloat("abc", 32)
+	r.setFieldErr("float32", 0, noParseError, []byte("abc"), cause)
+	assert.True(t, errors.Is(r.fieldError(), strconv.ErrSyntax))
+}
+
+func TestLineError(t *testing.T) {
+	cause := fmt.Errorf("Bad record")
+	err := &lineError{line: 42, err: cause}
+
+	assert.EqualError(t, err, "Line 42: Bad record")
+	assert.True(t, errors.Is(err, cause))
+
+	err = &lineError{line: 42, last: 44, err: cause}
+	assert.EqualError(t, err, "Lines 42-44: Bad record")
+	assert.True(t, errors.Is(err, cause))
+}
+
+func TestFieldCountError(t *testing.T) {
+	r := NewReader()
+	r.Comma = '|'
+	err := r.Read(strings.NewReader("a|b|c\nd|e\nf|g|h"), func(i int, fields []Field) error { return nil })
+
+	var countErr *FieldCountError
+	require.True(t, errors.As(err, &countErr))
+	assert.Equal(t, &FieldCountError{Line: 2, Expected: 3, Actual: 2, Delimiter: '|', Raw: []byte("d|e")}, countErr)
+	assert.EqualError(t, err, `Line 2: Expected []b to contain 3 fields using delimiter '|': "d|e"`)
+}
+
+func TestFieldCountError_wrapped(t *testing.T) {
+	dir := makeTempDir(t)
+	path := filepath.Join(dir, "ragged.csv")
+	require.Nil(t, ioutil.WriteFile(path, []byte("a,b\nc\n"), 0644))
+
+	err := ProcessFiles([]string{path}, ',', 1, func(path string, i int, record []Field) error { return nil })
+
+	fileErrs, ok := err.(FileErrors)
+	require.True(t, ok)
+
+	var countErr *FieldCountError
+	require.True(t, errors.As(fileErrs[0], &countErr))
+	assert.Equal(t, "c", string(countErr.Raw))
+}
+
+func TestParseError(t *testing.T) {
+	r := NewReader()
+	err := r.Read(strings.NewReader("1,2\n3,99999999999\n"), func(i int, record []Field) error {
+		record[1].Uint32()
+		return nil
+	})
+
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 2, parseErr.Line)
+	assert.Equal(t, "uint32", parseErr.Type)
+	assert.Equal(t, TooLong, parseErr.Kind)
+	assert.Equal(t, "99999999999", string(parseErr.Raw))
+	assert.EqualError(t, err, `Line 2, column 2: Can't parse field as uint32: "99999999999" is too long to be parsed as a uint32`)
+
+	err = r.Read(strings.NewReader("1,abc\n"), func(i int, record []Field) error {
+		record[1].Float32()
+		return nil
+	})
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, InvalidSyntax, parseErr.Kind)
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+	assert.EqualError(t, err, `Line 1, column 2: strconv.ParseFloat: parsing "abc": invalid syntax`)
+}
+
+func TestParseError_schema(t *testing.T) {
+	schema := NewSchema(StringColumn("name"), Int64Column("count"))
+	err := NewReader().ReadWithSchema(strings.NewReader("a,1\nb,99999999999999999999\n"), schema, func(i int, record *Record) error {
+		return nil
+	})
+
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 2, parseErr.Line)
+	assert.Equal(t, "count", parseErr.ColumnName)
+	assert.Equal(t, "int64", parseErr.Type)
+	assert.Equal(t, Overflow, parseErr.Kind)
+	assert.True(t, errors.Is(err, strconv.ErrRange))
+	assert.EqualError(t, err, `Line 2, column 2: Can't parse column "count" as int64: "99999999999999999999"`)
+}
+
+func TestParseError_column(t *testing.T) {
+	// Empty fields are told apart by their position, not their value
+	err := NewReader().Read(strings.NewReader("a,,,d\n"), func(i int, record []Field) error {
+		record[2].Float32()
+		return nil
+	})
+
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 3, parseErr.Column)
+	assert.EqualError(t, err, `Line 1, column 3: strconv.ParseFloat: parsing "": invalid syntax`)
+
+	// Fields that don't belong to the current record have no known column
+	field := makeField("x")
+	field.Uint32()
+	assert.EqualError(t, field.reader.fieldError(), `Can't parse field as uint32: "x" contains non-numeric character 'x'`)
+}
+
+func TestParseError_columnName(t *testing.T) {
+	r := NewReader()
+	r.HasHeader = true
+	err := r.Read(strings.NewReader("sku,unit_price\na,1.5\nb,n/a\n"), func(i int, record []Field) error {
+		record[1].Float32()
+		return nil
+	})
+
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, 2, parseErr.Column)
+	assert.Equal(t, "unit_price", parseErr.ColumnName)
+	assert.EqualError(t, err, `Line 3, column 2 ("unit_price"): strconv.ParseFloat: parsing "n/a": invalid syntax`)
+
+	// Schema errors name the column in their message
+	parseErr = &ParseError{Column: 2, ColumnName: "unit_price", Type: "float32", Raw: []byte("n/a"), schema: true}
+	assert.EqualError(t, parseErr, `Column 2: Can't parse column "unit_price" as float32: "n/a"`)
+}
+
+func TestSentinelErrors(t *testing.T) {
+	read := func(r *Reader, in string) error {
+		return r.Read(strings.NewReader(in), func(i int, record []Field) error {
+			record[0].Uint32()
+			return nil
+		})
+	}
+
+	err := read(NewReader(), "1,2\n3\n")
+	assert.True(t, errors.Is(err, ErrFieldCount))
+	assert.False(t, errors.Is(err, ErrParse))
+
+	err = read(NewReader(), "1,2\nx,3\n")
+	assert.True(t, errors.Is(err, ErrParse))
+	assert.False(t, errors.Is(err, ErrFieldCount))
+
+	_, err = ParseUint32([]byte("x"))
+	assert.True(t, errors.Is(err, ErrParse))
+	assert.EqualError(t, err, `"x" contains non-numeric character 'x'`)
+
+	r := NewReader()
+	r.MaxLineLength = 3
+	assert.True(t, errors.Is(read(r, "1,2\n10,2\n"), ErrLineTooLong))
+	r.MaxLineLength, r.MinLineLength = 0, 4
+	assert.True(t, errors.Is(read(r, "1,2\n"), ErrLineTooShort))
+
+	r = NewReader()
+	r.Comma = '\n'
+	assert.True(t, errors.Is(read(r, "1\n"), ErrBadDelimiter))
+
+	var buf strings.Builder
+	w := NewWriter(&buf)
+	w.Comma = '\r'
+	assert.True(t, errors.Is(w.Write([]string{"a"}), ErrBadDelimiter))
+
+	diskErr := fmt.Errorf("Disk error")
+	err = NewReader().Read(io.MultiReader(strings.NewReader("1,2\n"), iotest.ErrReader(diskErr)), func(i int, record []Field) error { return nil })
+	assert.True(t, errors.Is(err, ErrRead))
+	assert.True(t, errors.Is(err, diskErr))
+	assert.False(t, errors.Is(err, ErrParse))
+	assert.EqualError(t, err, "Error scanning input: Disk error")
+
+	var readErr *ReadError
+	require.True(t, errors.As(err, &readErr))
+	assert.Equal(t, diskErr, readErr.Err)
+}
+
+func TestReader_ErrorRawLength(t *testing.T) {
+	in := "a|b|c\nsecret-value-1234|e\n"
+	read := func(r *Reader) error {
+		return r.Read(strings.NewReader(in), func(i int, fields []Field) error { return nil })
+	}
+
+	r := NewReader()
+	r.Comma = '|'
+	r.ErrorRawLength = 6
+	err := read(r)
+	assert.EqualError(t, err, `Line 2: Expected []b to contain 3 fields using delimiter '|': "secret..."`)
+
+	// The typed error still holds the whole line
+	var countErr *FieldCountError
+	require.True(t, errors.As(err, &countErr))
+	assert.Equal(t, "secret-value-1234|e", string(countErr.Raw))
+
+	r.ErrorRawLength = -1
+	assert.EqualError(t, read(r), `Line 2: Expected []b to contain 3 fields using delimiter '|'`)
+
+	r.ErrorRawLength = 100
+	assert.EqualError(t, read(r), `Line 2: Expected []b to contain 3 fields using delimiter '|': "secret-value-1234|e"`)
+
+	r = NewReader()
+	r.QuoteFallback = true
+	r.ErrorRawLength = 4
+	err = r.Read(strings.NewReader("\"abc\"x,def\n"), func(i int, fields []Field) error { return nil })
+	assert.EqualError(t, err, `Line 1: Field 0 has unexpected character 'x' after its closing quote: ""abc..."`)
+}
+
+func TestReader_ErrLineLastRaw(t *testing.T) {
+	r := NewReader()
+	err := r.Read(strings.NewReader("a,1\nb,2\nc,x\nd,4\n"), func(i int, record []Field) error {
+		record[1].Uint32()
+		return nil
+	})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, err, r.Err())
+	assert.Equal(t, 3, r.Line())
+	assert.Equal(t, "c,x", string(r.LastRaw()))
+
+	err = r.ReadBytes([]byte("a,1\nb\n"), func(i int, record []Field) error { return nil })
+	assert.True(t, errors.Is(r.Err(), ErrFieldCount))
+	assert.Equal(t, 2, r.Line())
+	assert.Equal(t, "b", string(r.LastRaw()))
+
+	r.MaxLineLength = 3
+	err = r.Read(strings.NewReader("a,1\nb,22\n"), func(i int, record []Field) error { return nil })
+	assert.EqualError(t, r.Err(), "Line 2: Length of 4 bytes exceeds the maximum of 3")
+	assert.Equal(t, 2, r.Line())
+	assert.Equal(t, "b,22", string(r.LastRaw()))
+
+	// A successful read clears the error
+	assert.Nil(t, r.Read(strings.NewReader("a,1\r\nb,2\r\n"), func(i int, record []Field) error { return nil }))
+	assert.Nil(t, r.Err())
+	assert.Equal(t, 2, r.Line())
+	assert.Equal(t, "b,2", string(r.LastRaw()))
+}
+
+func TestReader_CollectFieldErrors(t *testing.T) {
+	r := NewReader()
+	r.HasHeader = true
+	r.CollectFieldErrors = true
+
+	err := r.Read(strings.NewReader("id,qty,price\n1,2,3.5\n2,x,n/a\n"), func(i int, record []Field) error {
+		record[0].Uint32()
+		record[1].Uint32()
+		record[1].Uint32() // reported once
+		record[2].Float32()
+		return nil
+	})
+
+	var parseErrs ParseErrors
+	require.True(t, errors.As(err, &parseErrs))
+	require.Equal(t, 2, len(parseErrs))
+	assert.Equal(t, "qty", parseErrs[0].ColumnName)
+	assert.Equal(t, 3, parseErrs[1].Line)
+	assert.Equal(t, "price", parseErrs[1].ColumnName)
+	assert.True(t, errors.Is(err, ErrParse))
+	assert.EqualError(t, err, `Line 3: 2 fields can't be parsed: `+
+		`column 2 ("qty"): Can't parse field as uint32: "x" contains non-numeric character 'x'; `+
+		`column 3 ("price"): strconv.ParseFloat: parsing "n/a": invalid syntax`)
+
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, "qty", parseErr.ColumnName)
+
+	// A single error is still reported as a ParseError
+	err = r.Read(strings.NewReader("id,qty\n1,x\n"), func(i int, record []Field) error {
+		record[1].Uint32()
+		return nil
+	})
+	assert.EqualError(t, err, `Line 2, column 2 ("qty"): Can't parse field as uint32: "x" contains non-numeric character 'x'`)
+}
+
+func TestReader_CollectFieldErrors_schema(t *testing.T) {
+	r := NewReader()
+	r.CollectFieldErrors = true
+
+	schema := NewSchema(Uint32Column("a"), StringColumn("b"), Int64Column("c"))
+	err := r.ReadWithSchema(strings.NewReader("1,x,2\ny,x,z\n"), schema, func(i int, record *Record) error { return nil })
+	assert.EqualError(t, err, `Line 2: 2 fields can't be parsed: column 1: Can't parse column "a" as uint32: "y"; column 3: Can't parse column "c" as int64: "z"`)
+}
+
+func TestReader_RecoverPanics(t *testing.T) {
+	r := NewReader()
+	r.RecoverPanics = true
+
+	err := r.Read(strings.NewReader("a,1\nb,2\n"), func(i int, record []Field) error {
+		if i == 2 {
+			var m map[string]int
+			m["x"] = 1
+		}
+		return nil
+	})
+
+	var panicErr *PanicError
+	require.True(t, errors.As(err, &panicErr))
+	assert.Equal(t, 2, panicErr.Line)
+	assert.Equal(t, "b,2", string(panicErr.Raw))
+	assert.NotEmpty(t, panicErr.Stack)
+	assert.EqualError(t, err, `Line 2: Callback panicked: assignment to entry in nil map: "b,2"`)
+
+	var runtimeErr runtime.Error
+	assert.True(t, errors.As(err, &runtimeErr))
+
+	r.ErrorRawLength = -1
+	err = r.ReadBytes([]byte("a,1\n"), func(i int, record []Field) error {
+		panic("Oops")
+	})
+	assert.EqualError(t, err, `Line 1: Callback panicked: Oops`)
+
+	// Without RecoverPanics, the panic propagates
+	r.RecoverPanics = false
+	assert.Panics(t, func() {
+		r.ReadBytes([]byte("a,1\n"), func(i int, record []Field) error {
+			panic("Oops")
+		})
+	})
+}
@@ -0,0 +1,123 @@
+package hastycsv
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Definition of a callback function that receives the records of the files
+// read by ProcessFiles() or ReadFiles().  path identifies the file that record
+// belongs to.
+type NextFileRecord func(path string, i int, record []Field) error
+
+// An error that occurred while reading one of the files passed to
+// ProcessFiles().
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (me *FileError) Error() string {
+	return fmt.Sprintf("%v: %v", me.Path, me.Err)
+}
+
+func (me *FileError) Unwrap() error {
+	return me.Err
+}
+
+// The errors of the files that ProcessFiles() failed to read, in the order in
+// which their paths were passed to ProcessFiles().
+type FileErrors []*FileError
+
+func (me FileErrors) Error() string {
+	messages := make([]string, len(me))
+	for i, err := range me {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%v file(s) failed: %v", len(me), strings.Join(messages, "; "))
+}
+
+// Reads the specified CSV files (see ReadFile()) on the specified number of
+// worker goroutines (runtime.NumCPU() if workers < 1), each file with its own
+// Reader.
+//
+// nextRecord is invoked concurrently for records of different files, so it
+// must be safe for concurrent use; the records of each file are delivered in
+// order.  An error (whether raised while reading a file or returned by
+// nextRecord) stops reading that file only.  If any file fails, a FileErrors
+// is returned.
+func ProcessFiles(paths []string, comma byte, workers int, nextRecord NextFileRecord) error {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	errs := make([]*FileError, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range jobs {
+				path := paths[k]
+				err := ReadFile(path, comma, func(i int, record []Field) error {
+					return nextRecord(path, i, record)
+				})
+				if err != nil {
+					errs[k] = &FileError{Path: path, Err: err}
+				}
+			}
+		}()
+	}
+
+	for k := range paths {
+		jobs <- k
+	}
+	close(jobs)
+	wg.Wait()
+
+	var failed FileErrors
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if failed != nil {
+		return failed
+	}
+	return nil
+}
+
+// Reads the CSV files that match the specified glob pattern (see
+// filepath.Match()) one after the other, in lexical order, which suits sharded
+// datasets such as part-0000.csv ... part-0999.csv.
+//
+// Records are numbered continuously across files: i is the record's position
+// within the concatenation of all files, while path identifies the file it
+// came from.  The first error stops reading and is returned as a *FileError.
+func ReadFiles(pattern string, comma byte, nextRecord NextFileRecord) error {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	} else if len(paths) == 0 {
+		return fmt.Errorf(`No files match "%v"`, pattern)
+	}
+
+	recordsBefore := 0
+	for _, path := range paths {
+		lines := 0
+		err := ReadFile(path, comma, func(i int, record []Field) error {
+			lines = i
+			return nextRecord(path, recordsBefore+i, record)
+		})
+		if err != nil {
+			return &FileError{Path: path, Err: err}
+		}
+		recordsBefore += lines
+	}
+
+	return nil
+}
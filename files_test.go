@@ -0,0 +1,118 @@
+package hastycsv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestProcessFiles(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	paths := []string{}
+	for i := 0; i < 20; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("shard%02d.csv", i))
+		require.Nil(t, ioutil.WriteFile(path, []byte(fmt.Sprintf("%v|a\n%v|b\n", i, i)), 0644))
+		paths = append(paths, path)
+	}
+
+	var mu sync.Mutex
+	totals := map[string]uint32{}
+	lines := map[string][]int{}
+	err := ProcessFiles(paths, '|', 4, func(path string, i int, record []Field) error {
+		mu.Lock()
+		defer mu.Unlock()
+		totals[path] += record[0].Uint32()
+		lines[path] = append(lines[path], i)
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 20, len(totals))
+	for i, path := range paths {
+		assert.Equal(t, uint32(2*i), totals[path])
+		assert.Equal(t, []int{1, 2}, lines[path])
+	}
+}
+
+func TestProcessFiles_errors(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	good := filepath.Join(dir, "good.csv")
+	bad := filepath.Join(dir, "bad.csv")
+	missing := filepath.Join(dir, "missing.csv")
+	require.Nil(t, ioutil.WriteFile(good, []byte("1,a\n2,b\n"), 0644))
+	require.Nil(t, ioutil.WriteFile(bad, []byte("1,a\n2\n"), 0644))
+
+	var mu sync.Mutex
+	count := 0
+	err := ProcessFiles([]string{missing, good, bad}, ',', 0, func(path string, i int, record []Field) error {
+		mu.Lock()
+		defer mu.Unlock()
+		count++
+		return nil
+	})
+
+	assert.Equal(t, 3, count)
+	var fileErrs FileErrors
+	require.True(t, errors.As(err, &fileErrs))
+	require.Equal(t, 2, len(fileErrs))
+	assert.Equal(t, missing, fileErrs[0].Path)
+	assert.True(t, errors.Is(fileErrs[0], os.ErrNotExist))
+	assert.Equal(t, bad, fileErrs[1].Path)
+	assert.Equal(t, `Line 2: Expected []b to contain 2 fields using delimiter ',': "2"`, fileErrs[1].Err.Error())
+	assert.Contains(t, err.Error(), "2 file(s) failed: ")
+}
+
+func TestReadFiles(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	for i, content := range []string{"a,1\nb,2\n", "c,3\n", "d,4\ne,5"} {
+		path := filepath.Join(dir, fmt.Sprintf("part-%04d.csv", i))
+		require.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "other.txt"), []byte("x,9\n"), 0644))
+
+	received := []string{}
+	err := ReadFiles(filepath.Join(dir, "part-*.csv"), ',', func(path string, i int, record []Field) error {
+		received = append(received, fmt.Sprintf("%v:%v:%v", filepath.Base(path), i, record[0].String()))
+		assert.Equal(t, uint32(i), record[1].Uint32())
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"part-0000.csv:1:a",
+		"part-0000.csv:2:b",
+		"part-0001.csv:3:c",
+		"part-0002.csv:4:d",
+		"part-0002.csv:5:e",
+	}, received)
+}
+
+func TestReadFiles_errors(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	noop := func(path string, i int, record []Field) error { return nil }
+
+	err := ReadFiles(filepath.Join(dir, "*.csv"), ',', noop)
+	assert.EqualError(t, err, fmt.Sprintf(`No files match "%v"`, filepath.Join(dir, "*.csv")))
+
+	err = ReadFiles("[", ',', noop)
+	assert.NotNil(t, err)
+
+	path := filepath.Join(dir, "bad.csv")
+	require.Nil(t, ioutil.WriteFile(path, []byte("a,1\nb\n"), 0644))
+	err = ReadFiles(filepath.Join(dir, "*.csv"), ',', noop)
+	assert.EqualError(t, err, path+`: Line 2: Expected []b to contain 2 fields using delimiter ',': "b"`)
+}
@@ -0,0 +1,60 @@
+package hastycsv
+
+import (
+	"bufio"
+	"io"
+)
+
+// Definition of a callback function that selects the records copied by
+// Filter().  Return true to keep the record.
+type Predicate func(i int, record []Field) bool
+
+// Copies the records of src for which pred returns true to dst, using a
+// default Reader (see Reader.Filter()).
+func Filter(src io.Reader, dst io.Writer, pred Predicate) error {
+	return NewReader().Filter(src, dst, pred)
+}
+
+// Copies the records of src for which pred returns true to dst.  Records are
+// written as their raw line, byte for byte (except for the line terminator,
+// which is always "\n"), so they needn't be re-encoded.  Fields that pred
+// modifies in place (e.g. with Field.ToLower()) are modified in the output
+// too.  If HasHeader is set, the header is written ahead of the records.
+func (me *Reader) Filter(src io.Reader, dst io.Writer, pred Predicate) error {
+	w := bufio.NewWriterSize(dst, 32*1024)
+	headerWritten := false
+
+	err := me.Read(src, func(i int, record []Field) error {
+		if !headerWritten {
+			headerWritten = true
+			me.writeHeader(w)
+		}
+		if pred(i, record) {
+			w.Write(me.raw)
+			return w.WriteByte('\n')
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !headerWritten {
+		me.writeHeader(w)
+	}
+	return w.Flush()
+}
+
+// Writes the header, if any, to w as a line of delimited column names.
+func (me *Reader) writeHeader(w *bufio.Writer) {
+	if me.header == nil {
+		return
+	}
+	for i, name := range me.header {
+		if i > 0 {
+			w.WriteByte(me.Comma)
+		}
+		w.WriteString(name)
+	}
+	w.WriteByte('\n')
+}
@@ -0,0 +1,42 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	in := strings.NewReader("bill,30,x\r\nmary,17,y\njane,45,  z \n")
+
+	var buf bytes.Buffer
+	err := Filter(in, &buf, func(i int, record []Field) bool {
+		return record[1].Uint32() >= 18
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "bill,30,x\njane,45,  z \n", buf.String())
+}
+
+func TestReader_Filter(t *testing.T) {
+	r := NewReader()
+	r.Comma = '|'
+	r.HasHeader = true
+
+	var buf bytes.Buffer
+	err := r.Filter(strings.NewReader("name|state\nbill|CA\nmary|NY\n"), &buf, func(i int, record []Field) bool {
+		return record[1].String() == "CA"
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "name|state\nbill|CA\n", buf.String())
+
+	// The header is written even if there are no records
+	buf.Reset()
+	err = r.Filter(strings.NewReader("name|state\n"), &buf, func(i int, record []Field) bool { return true })
+	assert.Nil(t, err)
+	assert.Equal(t, "name|state\n", buf.String())
+
+	err = r.Filter(strings.NewReader("name|state\nbill|CA\nmary\n"), &buf, func(i int, record []Field) bool { return true })
+	assert.EqualError(t, err, `Line 3: Expected []b to contain 2 fields using delimiter '|': "mary"`)
+}
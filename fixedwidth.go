@@ -0,0 +1,94 @@
+package hastycsv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Reads records whose fields occupy fixed byte widths, as found in mainframe
+// and banking exports.  Records are passed to the same Next callback as
+// Reader.Read(), so the same Field accessors apply.
+type FixedWidthReader struct {
+	// Widths are the byte widths of the columns, from left to right.
+	Widths []int
+
+	// TrimSpace removes the padding spaces around each field.
+	// It is set to true by NewFixedWidthReader.
+	TrimSpace bool
+
+	// ErrorRawLength limits how much of an offending line is quoted in error
+	// messages, as Reader.ErrorRawLength does.
+	ErrorRawLength int
+
+	reader Reader
+}
+
+// Returns a new FixedWidthReader for columns of the specified byte widths.
+func NewFixedWidthReader(widths ...int) *FixedWidthReader {
+	return &FixedWidthReader{
+		Widths:    widths,
+		TrimSpace: true,
+	}
+}
+
+// Reads records from r and invokes nextRecord with each one.
+//
+// A line that ends before the last column (e.g. because trailing padding was
+// stripped) yields truncated or empty fields, while a line that's longer than
+// the sum of the widths is an error.  As with Reader.Read(), the []Field is
+// reused for every record.
+func (me *FixedWidthReader) Read(r io.Reader, nextRecord Next) error {
+	lineWidth := 0
+	for i, width := range me.Widths {
+		if width < 1 {
+			return fmt.Errorf("Width of column %v must be at least 1, but is %v", i+1, width)
+		}
+		lineWidth += width
+	}
+	if lineWidth == 0 {
+		return fmt.Errorf("No column widths specified")
+	}
+
+	reader := &me.reader
+	if err := reader.begin(r); err != nil {
+		return err
+	}
+	defer reader.end()
+	reader.initFields(len(me.Widths))
+
+	for {
+		line, err := reader.lines.readLine()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return &ReadError{err}
+		}
+		reader.row++
+
+		if len(line) > lineWidth {
+			return fmt.Errorf("Line %v: Expected at most %v bytes, but found %v%v", reader.row, lineWidth, len(line), quoteRaw(line, me.ErrorRawLength))
+		}
+		me.split(line, reader.fields)
+
+		if err := reader.recordError(nextRecord(reader.row, reader.fields)); err != nil {
+			return err
+		}
+	}
+}
+
+// Splits a line into fields according to Widths.
+func (me *FixedWidthReader) split(line []byte, fields []Field) {
+	for i, width := range me.Widths {
+		if width > len(line) {
+			width = len(line)
+		}
+
+		data := line[:width]
+		if me.TrimSpace {
+			data = bytes.Trim(data, " ")
+		}
+		fields[i].data = data
+		line = line[width:]
+	}
+}
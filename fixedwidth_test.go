@@ -0,0 +1,67 @@
+package hastycsv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestFixedWidthReader_Read(t *testing.T) {
+	in := strings.NewReader("" +
+		"0001bill      030   12.5\r\n" +
+		"0002mary      035\n" +
+		"0003jo")
+
+	r := NewFixedWidthReader(4, 10, 3, 7)
+	received := []string{}
+	err := r.Read(in, func(i int, fields []Field) error {
+		assert.Equal(t, 4, len(fields))
+		received = append(received, fmt.Sprintf("%v:%v:%q:%v:%q", i, fields[0].Uint32(), fields[1].String(), fields[2].Uint32(), fields[3].String()))
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		`1:1:"bill":30:"12.5"`,
+		`2:2:"mary":35:""`,
+		`3:3:"jo":0:""`,
+	}, received)
+}
+
+func TestFixedWidthReader_Read_noTrim(t *testing.T) {
+	r := NewFixedWidthReader(3, 3)
+	r.TrimSpace = false
+
+	values := []string{}
+	err := r.Read(strings.NewReader("ab  c "), func(i int, fields []Field) error {
+		values = append(values, fields[0].String(), fields[1].String())
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ab ", " c "}, values)
+}
+
+func TestFixedWidthReader_Read_errors(t *testing.T) {
+	noop := func(i int, fields []Field) error { return nil }
+
+	assert.EqualError(t, NewFixedWidthReader().Read(strings.NewReader("x"), noop), "No column widths specified")
+	assert.EqualError(t, NewFixedWidthReader(2, 0).Read(strings.NewReader("x"), noop), "Width of column 2 must be at least 1, but is 0")
+
+	err := NewFixedWidthReader(2, 2).Read(strings.NewReader("abcd\nabcde"), noop)
+	assert.EqualError(t, err, `Line 2: Expected at most 4 bytes, but found 5: "abcde"`)
+
+	fr := NewFixedWidthReader(2, 2)
+	fr.ErrorRawLength = 3
+	err = fr.Read(strings.NewReader("abcde"), noop)
+	assert.EqualError(t, err, `Line 1: Expected at most 4 bytes, but found 5: "abc..."`)
+	fr.ErrorRawLength = -1
+	err = fr.Read(strings.NewReader("abcde"), noop)
+	assert.EqualError(t, err, `Line 1: Expected at most 4 bytes, but found 5`)
+
+	err = NewFixedWidthReader(2, 2).Read(strings.NewReader("ab12\ncdxx"), func(i int, fields []Field) error {
+		fields[1].Uint32()
+		return nil
+	})
+	assert.EqualError(t, err, `Line 2, column 2: Can't parse field as uint32: "xx" contains non-numeric character 'x'`)
+}
@@ -0,0 +1,201 @@
+package hastycsv
+
+import (
+	"container/heap"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+)
+
+// The number of occurrences of a value, as counted by FrequencyCount().
+type ValueCount struct {
+	Value string
+	Count int
+}
+
+type frequencyConfig struct {
+	sketchWidth, sketchDepth int
+}
+
+// Configures FrequencyCount().
+type FrequencyOption func(cfg *frequencyConfig)
+
+// Returns a FrequencyOption that makes FrequencyCount() count values
+// approximately, using a count-min sketch with the specified number of
+// counters per row (width) and rows (depth), so that memory use doesn't grow
+// with the number of distinct values.  Counts may be overestimated, by at most
+// about 2n/width with probability 1-(1/2)^depth for n records; e.g. a width of
+// 1<<16 and a depth of 4 keep a 1M-record column's counts within about 30.
+func WithSketch(width, depth int) FrequencyOption {
+	return func(cfg *frequencyConfig) {
+		cfg.sketchWidth = width
+		cfg.sketchDepth = depth
+	}
+}
+
+// Returns the topN most frequent values of the specified (0-based) column of
+// r, using a default Reader (see Reader.FrequencyCount()).
+func FrequencyCount(r io.Reader, col int, topN int, options ...FrequencyOption) ([]ValueCount, error) {
+	return NewReader().FrequencyCount(r, col, topN, options...)
+}
+
+// Returns the topN most frequent values of the specified (0-based) column of
+// r, by decreasing count (ties are ordered by value), or all of its values if
+// topN <= 0.  Values are counted exactly by default, holding every distinct
+// value in memory; see WithSketch() for an approximate mode suited to columns
+// with huge numbers of distinct values.  Set HasHeader to skip the header.
+func (me *Reader) FrequencyCount(r io.Reader, col int, topN int, options ...FrequencyOption) ([]ValueCount, error) {
+	cfg := &frequencyConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	var counter interface {
+		add(value []byte)
+		counts() []ValueCount
+	}
+	if cfg.sketchWidth > 0 && cfg.sketchDepth > 0 {
+		if topN <= 0 {
+			return nil, fmt.Errorf("Approximate frequency counts require topN to be set")
+		}
+		counter = newSketchCounter(cfg.sketchWidth, cfg.sketchDepth, topN)
+	} else {
+		counter = exactCounter{}
+	}
+
+	err := me.Read(r, func(i int, record []Field) error {
+		if col < 0 || col >= len(record) {
+			return fmt.Errorf("Column index %v is out of range for records with %v fields", col, len(record))
+		}
+		counter.add(record[col].data)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := counter.counts()
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	if topN > 0 && len(counts) > topN {
+		counts = counts[:topN]
+	}
+	return counts, nil
+}
+
+// Counts every distinct value exactly.
+type exactCounter map[string]int
+
+func (me exactCounter) add(value []byte) {
+	me[string(value)]++
+}
+
+func (me exactCounter) counts() []ValueCount {
+	counts := make([]ValueCount, 0, len(me))
+	for value, count := range me {
+		counts = append(counts, ValueCount{Value: value, Count: count})
+	}
+	return counts
+}
+
+// Estimates counts with a count-min sketch, and tracks the values with the
+// highest estimates as candidates for the top N.
+type sketchCounter struct {
+	width      uint64
+	rows       [][]int
+	topN       int
+	candidates candidateHeap
+	index      map[string]*candidate
+}
+
+func newSketchCounter(width, depth, topN int) *sketchCounter {
+	rows := make([][]int, depth)
+	for i := range rows {
+		rows[i] = make([]int, width)
+	}
+	return &sketchCounter{width: uint64(width), rows: rows, topN: topN, index: map[string]*candidate{}}
+}
+
+func (me *sketchCounter) add(value []byte) {
+	h := fnv.New64a()
+	h.Write(value)
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32|1
+
+	// Increment the value's counter in every row, and estimate its count as the
+	// smallest of them.
+	estimate := 0
+	for i, row := range me.rows {
+		c := &row[(h1+uint64(i)*h2)%me.width]
+		*c++
+		if i == 0 || *c < estimate {
+			estimate = *c
+		}
+	}
+
+	if c, exists := me.index[string(value)]; exists {
+		c.Count = estimate
+		heap.Fix(&me.candidates, c.pos)
+		return
+	}
+	if len(me.candidates) < me.topN {
+		c := &candidate{ValueCount: ValueCount{Value: string(value), Count: estimate}}
+		me.index[c.Value] = c
+		heap.Push(&me.candidates, c)
+	} else if estimate > me.candidates[0].Count {
+		// Replace the candidate with the lowest estimate.
+		c := me.candidates[0]
+		delete(me.index, c.Value)
+		c.ValueCount = ValueCount{Value: string(value), Count: estimate}
+		me.index[c.Value] = c
+		heap.Fix(&me.candidates, 0)
+	}
+}
+
+func (me *sketchCounter) counts() []ValueCount {
+	counts := make([]ValueCount, len(me.candidates))
+	for i, c := range me.candidates {
+		counts[i] = c.ValueCount
+	}
+	return counts
+}
+
+// A value tracked by a sketchCounter, and its position within the heap.
+type candidate struct {
+	ValueCount
+	pos int
+}
+
+// A min-heap of candidate values, by count.
+type candidateHeap []*candidate
+
+func (me candidateHeap) Len() int {
+	return len(me)
+}
+
+func (me candidateHeap) Less(i, j int) bool {
+	return me[i].Count < me[j].Count
+}
+
+func (me candidateHeap) Swap(i, j int) {
+	me[i], me[j] = me[j], me[i]
+	me[i].pos, me[j].pos = i, j
+}
+
+func (me *candidateHeap) Push(x interface{}) {
+	c := x.(*candidate)
+	c.pos = len(*me)
+	*me = append(*me, c)
+}
+
+func (me *candidateHeap) Pop() interface{} {
+	old := *me
+	c := old[len(old)-1]
+	*me = old[:len(old)-1]
+	return c
+}
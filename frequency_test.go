@@ -0,0 +1,61 @@
+package hastycsv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestFrequencyCount(t *testing.T) {
+	in := "1,red\n2,blue\n3,red\n4,green\n5,blue\n6,red\n7,amber\n8,green\n"
+
+	counts, err := FrequencyCount(strings.NewReader(in), 1, 3)
+	require.Nil(t, err)
+	assert.Equal(t, []ValueCount{{"red", 3}, {"blue", 2}, {"green", 2}}, counts)
+
+	counts, err = FrequencyCount(strings.NewReader(in), 1, 0)
+	require.Nil(t, err)
+	assert.Equal(t, 4, len(counts))
+	assert.Equal(t, ValueCount{"amber", 1}, counts[3])
+
+	_, err = FrequencyCount(strings.NewReader(in), 2, 3)
+	assert.EqualError(t, err, "Line 1: Column index 2 is out of range for records with 2 fields")
+}
+
+func TestReader_FrequencyCount(t *testing.T) {
+	r := NewReader()
+	r.HasHeader = true
+	r.Comma = '|'
+
+	counts, err := r.FrequencyCount(strings.NewReader("color|n\nred|1\nred|2\n"), 0, 10)
+	require.Nil(t, err)
+	assert.Equal(t, []ValueCount{{"red", 2}}, counts)
+}
+
+func TestFrequencyCount_sketch(t *testing.T) {
+	// 1000 rare values, and a few frequent ones
+	var b strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&b, "%v,rare%v\n", i, i)
+		if i%10 == 0 {
+			b.WriteString("x,common\n")
+		}
+		if i%20 == 0 {
+			b.WriteString("x,frequent\n")
+		}
+	}
+	in := b.String()
+
+	counts, err := FrequencyCount(strings.NewReader(in), 1, 2, WithSketch(1<<12, 4))
+	require.Nil(t, err)
+	require.Equal(t, 2, len(counts))
+	assert.Equal(t, "common", counts[0].Value)
+	assert.InDelta(t, 100, counts[0].Count, 5)
+	assert.Equal(t, "frequent", counts[1].Value)
+	assert.InDelta(t, 50, counts[1].Count, 5)
+
+	_, err = FrequencyCount(strings.NewReader(in), 1, 0, WithSketch(1<<12, 4))
+	assert.EqualError(t, err, "Approximate frequency counts require topN to be set")
+}
@@ -0,0 +1,80 @@
+package hastycsv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// Definition of a function that tests the raw bytes of a field, for Grep().
+type Matcher func(value []byte) bool
+
+// Returns a Matcher for fields equal to pattern.
+func FieldEquals(pattern string) Matcher {
+	p := []byte(pattern)
+	return func(value []byte) bool {
+		return bytes.Equal(value, p)
+	}
+}
+
+// Returns a Matcher for fields that contain pattern.
+func FieldContains(pattern string) Matcher {
+	p := []byte(pattern)
+	return func(value []byte) bool {
+		return bytes.Contains(value, p)
+	}
+}
+
+// Returns a Matcher for fields that match the regular expression re (which
+// may match part of the field, unless it's anchored with ^ and $).
+func FieldMatches(re *regexp.Regexp) Matcher {
+	return re.Match
+}
+
+// Returns a Matcher for the fields that this Matcher rejects.
+func (me Matcher) Not() Matcher {
+	return func(value []byte) bool {
+		return !me(value)
+	}
+}
+
+// Copies the records of src whose specified (0-based) column matches to dst,
+// using a default Reader (see Reader.Grep()).
+func Grep(src io.Reader, dst io.Writer, col int, match Matcher) error {
+	return NewReader().Grep(src, dst, col, match)
+}
+
+// Copies the records of src whose specified (0-based) column matches to dst.
+// Unlike piping the input through grep, only the specified column is tested,
+// so values in other columns can't cause false positives.  Fields are tested
+// on their raw bytes, and records are written as their raw line, like
+// Filter().  If HasHeader is set, the header is written ahead of the records.
+func (me *Reader) Grep(src io.Reader, dst io.Writer, col int, match Matcher) error {
+	w := bufio.NewWriterSize(dst, 32*1024)
+	headerWritten := false
+
+	err := me.Read(src, func(i int, record []Field) error {
+		if !headerWritten {
+			headerWritten = true
+			if col < 0 || col >= len(record) {
+				return fmt.Errorf("Column index %v is out of range for records with %v fields", col, len(record))
+			}
+			me.writeHeader(w)
+		}
+		if match(record[col].data) {
+			w.Write(me.raw)
+			return w.WriteByte('\n')
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !headerWritten {
+		me.writeHeader(w)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,42 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGrep(t *testing.T) {
+	in := "bill,CA,x\r\nmary,NY,CA\njane,CA ,y\nfred,WA,z\n"
+
+	var buf bytes.Buffer
+	assert.Nil(t, Grep(strings.NewReader(in), &buf, 1, FieldEquals("CA")))
+	assert.Equal(t, "bill,CA,x\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, Grep(strings.NewReader(in), &buf, 1, FieldContains("CA")))
+	assert.Equal(t, "bill,CA,x\njane,CA ,y\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, Grep(strings.NewReader(in), &buf, 0, FieldMatches(regexp.MustCompile(`^(bill|fred)$`))))
+	assert.Equal(t, "bill,CA,x\nfred,WA,z\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, Grep(strings.NewReader(in), &buf, 1, FieldContains("CA").Not()))
+	assert.Equal(t, "mary,NY,CA\nfred,WA,z\n", buf.String())
+
+	err := Grep(strings.NewReader(in), &buf, 3, FieldEquals("CA"))
+	assert.EqualError(t, err, "Line 1: Column index 3 is out of range for records with 3 fields")
+}
+
+func TestReader_Grep(t *testing.T) {
+	r := NewReader()
+	r.Comma = '|'
+	r.HasHeader = true
+
+	var buf bytes.Buffer
+	assert.Nil(t, r.Grep(strings.NewReader("name|state\nbill|CA\nmary|NY\n"), &buf, 1, FieldEquals("TX")))
+	assert.Equal(t, "name|state\n", buf.String())
+}
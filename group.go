@@ -0,0 +1,64 @@
+package hastycsv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Definition of a callback function that receives the groups of records read
+// by ReadGroups().  key is the value of the key column shared by the records,
+// and lines[j] is the line number of records[j].  ReadGroups() will stop
+// reading the input records if this function returns an error.
+type NextGroup func(key []byte, lines []int, records [][]Field) error
+
+// Reads records from r and passes each run of consecutive records that share
+// the same value in the (0-based) key column to nextGroup as a single group,
+// e.g. the events of a session, or the rows of an entity.  The input should be
+// sorted (or at least grouped) by the key column, as a key that reappears later
+// on starts a new group.
+//
+// Like ReadBatches(), the records of a group are copied into an arena that is
+// reused for the next group, so nothing passed to nextGroup may be retained
+// after it returns, and a field parse error is reported with the range of
+// lines of its group.  A group is held in memory in its entirety.
+func (me *Reader) ReadGroups(r io.Reader, keyCol int, nextGroup NextGroup) error {
+	if err := me.begin(r); err != nil {
+		return err
+	}
+	defer me.end()
+
+	var (
+		key []byte
+		buf recordBuffer
+	)
+	emit := func(lines []int, records [][]Field) error {
+		return nextGroup(key, lines, records)
+	}
+	flush := func() error {
+		return buf.flush(me, emit)
+	}
+
+	for {
+		record, err := me.next()
+		if err == io.EOF {
+			return flush()
+		} else if err != nil {
+			return err
+		}
+
+		if keyCol < 0 || keyCol >= len(record) {
+			return fmt.Errorf("Line %v: Column index %v is out of range for records with %v fields", me.row, keyCol, len(record))
+		}
+		if len(buf.lines) > 0 && !bytes.Equal(record[keyCol].data, key) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if len(buf.lines) == 0 {
+			key = append(key[:0], record[keyCol].data...)
+		}
+
+		buf.add(me.row, record)
+	}
+}
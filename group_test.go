@@ -0,0 +1,71 @@
+package hastycsv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestReader_ReadGroups(t *testing.T) {
+	in := strings.NewReader("user|event\nbill|login\nbill|view\nbill|logout\nmary|login\njoe|login\njoe|view\nbill|login")
+
+	r := NewReader()
+	r.Comma = '|'
+	r.HasHeader = true
+
+	groups := []string{}
+	err := r.ReadGroups(in, 0, func(key []byte, lines []int, records [][]Field) error {
+		assert.Equal(t, len(lines), len(records))
+		events := []string{}
+		for j, record := range records {
+			assert.Equal(t, string(key), record[0].String())
+			events = append(events, fmt.Sprintf("%v:%v", lines[j], record[1].String()))
+		}
+		groups = append(groups, string(key)+"="+strings.Join(events, ","))
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"bill=2:login,3:view,4:logout",
+		"mary=5:login",
+		"joe=6:login,7:view",
+		"bill=8:login",
+	}, groups)
+}
+
+func TestReader_ReadGroups_errors(t *testing.T) {
+	r := NewReader()
+	r.Comma = '|'
+
+	err := r.ReadGroups(strings.NewReader("a|1\na|2\nb|3"), 0, func(key []byte, lines []int, records [][]Field) error {
+		return fmt.Errorf("Failed")
+	})
+	assert.EqualError(t, err, "Lines 1-2: Failed")
+
+	err = r.ReadGroups(strings.NewReader("a|1\nb|x"), 0, func(key []byte, lines []int, records [][]Field) error {
+		records[0][1].Uint32()
+		return nil
+	})
+	assert.EqualError(t, err, `Line 2, column 2: Can't parse field as uint32: "x" contains non-numeric character 'x'`)
+
+	err = r.ReadGroups(strings.NewReader("a|x\na|2"), 0, func(key []byte, lines []int, records [][]Field) error {
+		records[0][1].Uint32()
+		return nil
+	})
+	assert.EqualError(t, err, `Lines 1-2: Column 2: Can't parse field as uint32: "x" contains non-numeric character 'x'`)
+	assert.True(t, errors.Is(err, ErrParse))
+	var parseErr *ParseError
+	assert.True(t, errors.As(err, &parseErr))
+
+	err = r.ReadGroups(strings.NewReader("a|1"), 2, func(key []byte, lines []int, records [][]Field) error { return nil })
+	assert.EqualError(t, err, "Line 1: Column index 2 is out of range for records with 2 fields")
+
+	// A reader that finds no records never calls the callback
+	err = r.ReadGroups(strings.NewReader(""), 0, func(key []byte, lines []int, records [][]Field) error {
+		return fmt.Errorf("Unexpected")
+	})
+	assert.Nil(t, err)
+}
@@ -5,37 +5,45 @@
 package hastycsv
 
 import (
-	"bufio"
 	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"math"
 	"os"
+	"runtime/debug"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 	"unsafe"
 )
 
-// Needed by ParseUint32() for better performance.
-var base10exp = []uint64{
-	1, 10, 100, 1000, 10000, 100000, 1000000, 10000000, 100000000,
-	1000000000,
-	10000000000,
-	100000000000,
-	1000000000000,
-	10000000000000,
-	100000000000000,
-	1000000000000000,
-	10000000000000000,
-	100000000000000000,
-	100000000000000000,
-	1000000000000000000,
-}
+// Pools the buffers that a Reader needs for the duration of a read, so that
+// reading many small inputs doesn't allocate them over and over.
+var (
+	fieldsPool     sync.Pool // *[]Field
+	lineBufferPool = sync.Pool{
+		New: func() interface{} {
+			buf := make([]byte, 64*1024)
+			return &buf
+		},
+	}
+)
 
 // Definition of a callback function that serves as a sequential record iterator.
 // Read() and ReadFile() will stop reading the input records if this function
-// returns an error.
+// returns an error, unless that error is ErrSkipRow.
 type Next func(i int, record []Field) error
 
+// Returned by a Next callback (or one that wraps it, such as a NextRecord) to
+// discard the current record and keep reading, rather than stop.  Any parse
+// errors raised by the record's fields are discarded along with it.
+var ErrSkipRow = errors.New("Skip row")
+
 // Reads records from a CSV-encoded file or io.Reader.
 type Reader struct {
 	// Comma is the field delimiter.
@@ -43,9 +51,100 @@ type Reader struct {
 	// Comma cannot be \r or \n.
 	Comma byte
 
-	fields []Field
-	row    int
-	err    error
+	// HasHeader indicates that the first record is a header containing the
+	// column names.  The header is not passed to the Next callback; it can be
+	// retrieved with Header() instead.  Records are still numbered by their
+	// position in the input, so the first record passed to the callback is
+	// record 2.
+	HasHeader bool
+
+	// Arena receives the records copied by Retain().  If nil, Retain() creates
+	// an Arena with the default block size.
+	Arena *Arena
+
+	// CollectProfile enables the collection of timings during Read(), which
+	// can be retrieved afterwards with Profile().  Timing each record adds a
+	// small overhead.
+	CollectProfile bool
+
+	// TraceRegions wraps the I/O, splitting and callback stages of Read() in
+	// runtime/trace regions, which show up in `go tool trace` when tracing is
+	// enabled.
+	TraceRegions bool
+
+	// QuoteFallback enables an RFC 4180 compatibility mode: lines that contain
+	// a double quote are parsed by a slower, RFC 4180-compliant splitter that
+	// handles quoted fields (including embedded delimiters, escaped quotes and
+	// line breaks), while all other lines keep using the fast splitter.
+	// Quoted fields spanning several lines are only supported by Read() and
+	// the functions built on it.
+	QuoteFallback bool
+
+	// Digest, if set, is reset at the start of every read and receives every
+	// byte of the input (e.g. sha256.New()), so that the input's checksum is
+	// available from Digest.Sum() once the read is done.  See VerifyDigest().
+	Digest hash.Hash
+
+	// RowDigest, if set, is reset and fed the raw line of every record just
+	// before the record is passed to the callback, which can get the record's
+	// checksum from RowDigest.Sum().  The line terminator is not included.
+	RowDigest hash.Hash
+
+	// DuplicateRows enables the detection of records whose line is identical
+	// to that of an earlier record (AllowDuplicateRows by default).  Rows are
+	// tracked by their 64-bit hash, which takes roughly 20 bytes of memory per
+	// distinct row.
+	DuplicateRows DuplicateRowPolicy
+
+	// DuplicateFilterSize, if positive, bounds the memory used to detect
+	// duplicate rows to this many bytes, by tracking rows with a Bloom filter.
+	// This is approximate: some unique rows are mistaken for duplicates, more
+	// so as the filter fills up (about 1 in 40 with 1 byte per row, 1 in 400
+	// with 2 bytes per row).
+	DuplicateFilterSize int
+
+	// MinLineLength and MaxLineLength, if positive, are the bounds on the
+	// length in bytes of each record's line (excluding the line terminator,
+	// and the header).  A line outside of them stops the read before it is
+	// split, which catches truncated or concatenated lines early.
+	MinLineLength int
+	MaxLineLength int
+
+	// CollectFieldErrors makes a record whose fields can't be parsed (e.g. by
+	// Field.Uint32()) report every such field at once, as ParseErrors, rather
+	// than only the first one.  Columns parsed according to a Schema are
+	// collected too.  The record is otherwise handled as it would be for a
+	// single error.
+	CollectFieldErrors bool
+
+	// ErrorRawLength limits how much of an offending line is quoted in error
+	// messages, which may otherwise be huge or leak sensitive data: 0 quotes
+	// the whole line, a positive value truncates it to that many bytes
+	// followed by "...", and a negative value leaves it out.  Typed errors such
+	// as FieldCountError still hold the whole line.
+	ErrorRawLength int
+
+	// RecoverPanics makes Read(), ReadBytes() and ReadRows() recover a panic
+	// raised by the Next callback, and return it as a PanicError holding the
+	// line number and raw line of the record being processed, rather than let
+	// it crash the process.
+	RecoverPanics bool
+
+	lines         lineReader
+	lineBuffer    *[]byte
+	raw           []byte // the raw line of the current record
+	lastRaw       []byte // a copy of raw, kept once the read is done
+	err           error  // the error returned by the most recent read
+	fields        []Field
+	header        []string
+	row           int
+	fieldErr      fieldError
+	hasFieldErr   bool
+	moreFieldErrs []fieldError // the errors after the first, if CollectFieldErrors is set
+	profile       Profile
+	quoting       quoteState
+	validation    ValidationReport
+	dupes         rowSet
 }
 
 // Returns a new Reader whose Delimiter is set to the comma character (',').
@@ -55,74 +154,404 @@ func NewReader() *Reader {
 	}
 }
 
-func (me *Reader) Read(r io.Reader, nextRecord Next) error {
+// Reads records from r and invokes nextRecord with each one.
+//
+// Apart from whatever nextRecord itself allocates, reading a record performs
+// no allocations: the []Field passed to nextRecord is reused for every record,
+// and both it and the line buffer are drawn from pools shared by all Readers.
+// Consequently, the []Field must not be retained once nextRecord returns;
+// records that need to outlive the callback can be copied with Retain().
+func (me *Reader) Read(r io.Reader, nextRecord Next) (err error) {
+	defer func() {
+		me.err = err
+	}()
+	if me.RecoverPanics {
+		nextRecord = me.recovering(nextRecord)
+	}
+
+	if me.CollectProfile || me.TraceRegions {
+		return me.readProfiled(r, nextRecord)
+	}
+
+	if err := me.begin(r); err != nil {
+		return err
+	}
+	defer me.end()
+
+	for {
+		fields, err := me.next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := me.recordError(nextRecord(me.row, fields)); err != nil {
+			return err
+		}
+	}
+}
+
+// Prepares this Reader to read records from r using next().
+func (me *Reader) begin(r io.Reader) error {
+	if err := me.reset(); err != nil {
+		return err
+	}
+
+	if me.Digest != nil {
+		me.Digest.Reset()
+		r = io.TeeReader(r, me.Digest)
+	}
+
+	me.lineBuffer = lineBufferPool.Get().(*[]byte)
+	me.lines.init(r, *me.lineBuffer)
+	return nil
+}
+
+// Returns the buffers used during a read to their pools.  The header remains
+// available.
+func (me *Reader) end() {
+	// raw may point into the line buffer, so copy it before the buffer can be
+	// handed to another Reader.
+	me.lastRaw = append(me.lastRaw[:0], me.raw...)
+	me.raw = nil
+
+	if me.lineBuffer != nil {
+		// Keep the line buffer if it had to grow to hold a long line.
+		if buf := me.lines.buf; cap(buf) <= maxPooledLineBufferSize {
+			*me.lineBuffer = buf
+			lineBufferPool.Put(me.lineBuffer)
+		}
+		me.lineBuffer = nil
+	}
+	me.lines = lineReader{}
+
+	if me.fields != nil {
+		fields := me.fields[:cap(me.fields)]
+		for i := range fields {
+			fields[i] = Field{}
+		}
+		fieldsPool.Put(&fields)
+		me.fields = nil
+	}
+}
+
+// Validates the Reader's configuration and clears the state left behind by a
+// previous read.
+func (me *Reader) reset() error {
 	if me.Comma == '\r' || me.Comma == '\n' {
-		return fmt.Errorf(`Comma delimiter cannot be \r or \n`)
+		return &sentinelError{ErrBadDelimiter, `Comma delimiter cannot be \r or \n`}
 	}
 
-	var fields []Field
-	isFirstRecord := true
-	delim := me.Comma
+	me.end()
+	me.header = nil
 	me.row = 0
+	me.quoting.continuationLines = 0
+	me.clearFieldErr()
+	if me.DuplicateRows != AllowDuplicateRows {
+		me.dupes.reset(me.DuplicateFilterSize)
+	} else {
+		me.dupes = rowSet{}
+	}
+	return nil
+}
 
-	lineScanner := bufio.NewScanner(r)
-	for lineScanner.Scan() {
-		b := lineScanner.Bytes()
+// Reads and splits the next record, skipping the header record if HasHeader
+// is set.  Returns io.EOF once all records have been read.
+func (me *Reader) next() ([]Field, error) {
+	for {
+		if me.quoting.continuationLines > 0 {
+			// The previous record spanned several lines.
+			me.row += me.quoting.continuationLines
+			me.quoting.continuationLines = 0
+		}
 
-		if isFirstRecord {
-			// Infer number of fields from the first row and initialize the []fields buffer
-			fieldCount := bytes.Count(b, []byte{delim}) + 1
+		line, err := me.lines.readLine()
+		if err == io.EOF {
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, &ReadError{err}
+		}
 
-			fields = make([]Field, fieldCount)
-			for i := 0; i < fieldCount; i++ {
-				field := &fields[i]
-				field.reader = me
+		if me.QuoteFallback && bytes.IndexByte(line, '"') >= 0 {
+			if line, err = me.joinQuotedLines(line); err != nil {
+				return nil, err
 			}
-			isFirstRecord = false
 		}
 
-		me.row++
+		me.raw = line
+		me.hashRow(line)
+		isRecord, err := me.splitLine(line)
+		if err != nil {
+			return nil, err
+		} else if isRecord && !me.skipDuplicate(line) {
+			return me.fields, nil
+		}
+	}
+}
 
-		if err := splitBytes(b, delim, fields); err != nil {
-			return fmt.Errorf("Line %v: %v: \"%v\"", me.row, err, string(b))
+// Parses the lines contained in data, which is expected to end at a line
+// boundary (a final line without a trailing newline is fine), and invokes
+// nextRecord for each record.  Like lineReader, a carriage return that
+// precedes a newline is dropped.
+func (me *Reader) parseLines(data []byte, nextRecord Next) error {
+	for len(data) > 0 {
+		var line []byte
+		if idx := bytes.IndexByte(data, '\n'); idx >= 0 {
+			line, data = data[:idx], data[idx+1:]
+		} else {
+			line, data = data, nil
+		}
+		if n := len(line); n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
 		}
 
-		callbackErr := nextRecord(me.row, fields)
+		me.raw = line
+		me.hashRow(line)
+		isRecord, err := me.splitLine(line)
+		if err != nil {
+			return err
+		} else if !isRecord || me.skipDuplicate(line) {
+			continue
+		}
 
-		if me.err != nil {
-			return fmt.Errorf("Line %v: %v", me.row, me.err)
-		} else if callbackErr != nil {
-			return fmt.Errorf("Line %v: %v", me.row, callbackErr)
+		if err := me.recordError(nextRecord(me.row, me.fields)); err != nil {
+			return err
 		}
 	}
 
-	if me.err != nil {
-		return fmt.Errorf("Line %v: %v", me.row, me.err)
+	return nil
+}
+
+// Splits a line into this Reader's fields buffer, which is initialized using
+// the first line's field count.  Returns false if the line is the header.
+func (me *Reader) splitLine(b []byte) (bool, error) {
+	delim := me.Comma
+
+	if err := me.checkLineLength(b); err != nil {
+		return false, err
+	}
+
+	if me.QuoteFallback && bytes.IndexByte(b, '"') >= 0 {
+		return me.splitQuotedLine(b)
+	}
+
+	if me.fields == nil {
+		// Infer number of fields from the first row and initialize the []fields buffer
+		me.initFields(bytes.Count(b, []byte{delim}) + 1)
+	}
+
+	me.row++
+
+	if err := splitBytes(b, delim, me.fields); err != nil {
+		return false, me.fieldCountError(b, bytes.Count(b, []byte{delim})+1)
+	}
+
+	return !me.takeHeader(), nil
+}
+
+// Returns an error if the line of the next record is shorter than
+// MinLineLength or longer than MaxLineLength.
+func (me *Reader) checkLineLength(b []byte) error {
+	if me.HasHeader && me.header == nil {
+		return nil
+	}
+
+	if me.MinLineLength > 0 && len(b) < me.MinLineLength {
+		me.row++
+		return &sentinelError{ErrLineTooShort, fmt.Sprintf("Line %v: Length of %v bytes is below the minimum of %v", me.row, len(b), me.MinLineLength)}
+	} else if me.MaxLineLength > 0 && len(b) > me.MaxLineLength {
+		me.row++
+		return &sentinelError{ErrLineTooLong, fmt.Sprintf("Line %v: Length of %v bytes exceeds the maximum of %v", me.row, len(b), me.MaxLineLength)}
 	}
+	return nil
+}
 
-	if err := lineScanner.Err(); err != nil {
-		return fmt.Errorf("Error scanning input: %v", err)
+// If HasHeader is set and the header hasn't been read yet, stores the current
+// record as the header and returns true.
+func (me *Reader) takeHeader() bool {
+	if !me.HasHeader || me.header != nil {
+		return false
 	}
 
+	me.header = make([]string, len(me.fields))
+	for i := range me.fields {
+		me.header[i] = me.fields[i].String()
+	}
+	return true
+}
+
+// Initializes the fields buffer that records are split into.
+func (me *Reader) initFields(fieldCount int) {
+	if pooled, _ := fieldsPool.Get().(*[]Field); pooled != nil && cap(*pooled) >= fieldCount {
+		me.fields = (*pooled)[:fieldCount]
+	} else {
+		if pooled != nil {
+			fieldsPool.Put(pooled)
+		}
+		me.fields = make([]Field, fieldCount)
+	}
+	for i := 0; i < fieldCount; i++ {
+		field := &me.fields[i]
+		field.reader = me
+		field.column = i + 1
+	}
+}
+
+// Returns the error that stops reading once the Next callback has processed
+// the current record: either a field parse error, or the callback's own
+// error.  Returns nil if the callback returned ErrSkipRow.
+func (me *Reader) recordError(callbackErr error) error {
+	if callbackErr == ErrSkipRow {
+		me.clearFieldErr()
+		return nil
+	} else if err := me.fieldError(); err != nil {
+		return atLine(me.row, err)
+	} else if callbackErr != nil {
+		return atLine(me.row, callbackErr)
+	}
 	return nil
 }
 
+// Returns a Next callback that invokes nextRecord, and returns a PanicError
+// if it panics.
+func (me *Reader) recovering(nextRecord Next) Next {
+	return func(i int, record []Field) (err error) {
+		defer func() {
+			if value := recover(); value != nil {
+				err = &PanicError{
+					Line:      i,
+					Value:     value,
+					Raw:       append([]byte(nil), me.raw...),
+					Stack:     debug.Stack(),
+					rawLength: me.ErrorRawLength,
+				}
+			}
+		}()
+		return nextRecord(i, record)
+	}
+}
+
+// Like Read(), but parses records directly from data, which holds the entire
+// input.  Nothing is copied: each Field's byte slice points into data (and
+// in-place operations such as Field.ToLower() modify data).
+func (me *Reader) ReadBytes(data []byte, nextRecord Next) (err error) {
+	defer func() {
+		me.err = err
+	}()
+	if me.RecoverPanics {
+		nextRecord = me.recovering(nextRecord)
+	}
+
+	if err := me.reset(); err != nil {
+		return err
+	}
+	defer me.end()
+
+	if me.Digest != nil {
+		me.Digest.Reset()
+		me.Digest.Write(data)
+	}
+	return me.parseLines(data, nextRecord)
+}
+
+// Definition of a callback function that serves as a sequential record iterator
+// for ReadMaps().
+type NextMap func(i int, record map[string]Field) error
+
+// Like Read(), but passes each record to the callback as a map from column
+// name to Field.  Requires HasHeader to be set.
+//
+// The same map is reused for every record, so it must not be retained by the
+// callback.  If several columns share the same name, the map holds the
+// leftmost one.
+func (me *Reader) ReadMaps(r io.Reader, nextRecord NextMap) error {
+	if !me.HasHeader {
+		return fmt.Errorf("ReadMaps() requires HasHeader to be set")
+	}
+
+	var record map[string]Field
+	return me.Read(r, func(i int, fields []Field) error {
+		if record == nil {
+			record = make(map[string]Field, len(fields))
+		}
+
+		for j := len(fields) - 1; j >= 0; j-- {
+			record[me.header[j]] = fields[j]
+		}
+		return nextRecord(i, record)
+	})
+}
+
+// Returns the column names read from the header record if HasHeader is true,
+// or nil if the header hasn't been read (yet).
+func (me *Reader) Header() []string {
+	return me.header
+}
+
+// Returns the error that stopped the most recent Read() or ReadBytes() (or a
+// function built on them, such as ReadWithSchema()), or nil if it read all of
+// its input.
+func (me *Reader) Err() error {
+	return me.err
+}
+
+// Returns the line number of the last line read by the most recent read, i.e.
+// the line at which it stopped if it failed.
+func (me *Reader) Line() int {
+	return me.row
+}
+
+// Returns the raw line of the last record read by the most recent read (e.g.
+// the record that its callback rejected), without the line terminator.  The
+// returned slice is only valid until the next read.
+func (me *Reader) LastRaw() []byte {
+	return me.lastRaw
+}
+
+// Reads records from the specified CSV file.  Files with a .gz extension, or
+// that start with gzip's magic bytes, are decompressed transparently.
 func ReadFile(csvFilePath string, comma byte, nextRecord Next) error {
-	f, err := os.Open(csvFilePath)
+	f, err := OpenFile(csvFilePath)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
+	in := NewReadAheadReader(f, 32*1024)
+	defer in.Close()
+
 	r := NewReader()
 	r.Comma = comma
-	return r.Read(bufio.NewReaderSize(f, 32*1024), nextRecord)
+	return r.Read(in, nextRecord)
+}
+
+// Reads records from standard input, e.g. for writing Unix-style filters.
+//
+// Unlike ReadFile(), no data is read ahead: each record is passed to
+// nextRecord as soon as its line has arrived, so this works with interactive
+// input and with pipes that deliver data slowly.  A final line that lacks a
+// trailing newline is read as a regular record.
+func ReadStdin(comma byte, nextRecord Next) error {
+	r := NewReader()
+	r.Comma = comma
+	return r.Read(os.Stdin, nextRecord)
 }
 
 // Represents a field (encoded as a UTF-8 string) within a CSV record.
 type Field struct {
 	reader *Reader
 	data   []byte
+	column int // the 1-based position of the field within its record, or 0 if unknown
+}
+
+// Returns a new Field backed by the specified bytes, e.g. for replacing a value
+// within a record that's passed to Copy().
+//
+// A Field created this way doesn't belong to a Reader, so its parse errors
+// (e.g. from Uint32()) are not reported.
+func NewField(data []byte) Field {
+	return Field{data: data}
 }
 
 // Returns true if this field is empty.
@@ -140,6 +569,19 @@ func (me Field) String() string {
 	return string(me.data)
 }
 
+// Implements encoding.TextMarshaler.  Returns a copy of this field's bytes, so
+// the result remains valid after the Reader moves on to the next record.
+func (me Field) MarshalText() ([]byte, error) {
+	return append([]byte{}, me.data...), nil
+}
+
+// Passes this field's bytes to v's UnmarshalText() method, e.g. to parse it
+// into a net.IP or a big.Int.  Per the encoding.TextUnmarshaler contract, v must
+// copy the bytes if it needs to retain them.
+func (me Field) FillFromText(v encoding.TextUnmarshaler) error {
+	return v.UnmarshalText(me.data)
+}
+
 // Interprets this field as an ASCII string and performs an in-place conversion
 // to lowercase.
 func (me Field) ToLower() Field {
@@ -154,11 +596,9 @@ func (me Field) ToLower() Field {
 
 // Parses this field as a Uint32.
 func (me Field) Uint32() uint32 {
-	i, err := ParseUint32(me.data)
-	if err != nil {
-		if me.reader.err == nil {
-			me.reader.err = fmt.Errorf(`Can't parse field as uint32: %v`, err)
-		}
+	i, code := parseUint32(me.data)
+	if code != noParseError && me.reader != nil {
+		me.reader.setFieldErr("uint32", me.column, code, me.data, nil)
 	}
 
 	return i
@@ -168,35 +608,143 @@ func (me Field) Uint32() uint32 {
 func (me Field) Float32() float32 {
 	f, err := strconv.ParseFloat(me.unsafeString(), 32)
 	if err != nil {
-		if me.reader.err == nil {
-			me.reader.err = err
+		if me.reader != nil {
+			me.reader.setFieldErr("float32", me.column, noParseError, me.data, err)
 		}
 		return 0
 	}
 	return float32(f)
 }
 
+// Parses this field as an integer of the specified bit size (0 for int), like
+// strconv.ParseInt(), but without converting the field to a string first.
+// Unlike Uint32(), the error is returned rather than reported by the Reader.
+func (me Field) ParseInt(bitSize int) (int64, error) {
+	i, err := strconv.ParseInt(me.unsafeString(), 10, bitSize)
+	return i, me.detachError(err)
+}
+
+// Like ParseInt(), but parses this field as an unsigned integer.
+func (me Field) ParseUint(bitSize int) (uint64, error) {
+	u, err := strconv.ParseUint(me.unsafeString(), 10, bitSize)
+	return u, me.detachError(err)
+}
+
+// Like ParseInt(), but parses this field as a floating-point number of the
+// specified bit size (32 or 64).
+func (me Field) ParseFloat(bitSize int) (float64, error) {
+	f, err := strconv.ParseFloat(me.unsafeString(), bitSize)
+	return f, me.detachError(err)
+}
+
+// Like ParseInt(), but parses this field as a bool, as strconv.ParseBool() does.
+func (me Field) ParseBool() (bool, error) {
+	b, err := strconv.ParseBool(me.unsafeString())
+	return b, me.detachError(err)
+}
+
+// Parses this field as a time.Time using the specified time.Parse() layout.
+// The field is only copied if the layout contains a zone name ("MST"), which
+// the parsed time would otherwise retain.
+func (me Field) ParseTime(layout string) (time.Time, error) {
+	if strings.Contains(layout, "MST") {
+		return time.Parse(layout, me.String())
+	}
+	t, err := time.Parse(layout, me.unsafeString())
+	if err != nil {
+		// Parse a copy again, since the error retains the field.
+		return time.Parse(layout, me.String())
+	}
+	return t, nil
+}
+
+// Returns err, with the field's text that a *strconv.NumError holds replaced
+// by a copy, since it points to the field's reused bytes.
+func (me Field) detachError(err error) error {
+	if numErr, ok := err.(*strconv.NumError); ok {
+		numErr.Num = me.String()
+	}
+	return err
+}
+
 // ParseUint32() parses an ascii byte array into a uint32 value.
+//
+// Digits are converted 8 (or 4) at a time using SWAR ("SIMD within a
+// register") arithmetic on 64-bit words, which avoids a branch and a multiply
+// per character.
 func ParseUint32(data []byte) (uint32, error) {
+	v, code := parseUint32(data)
+	if code != noParseError {
+		return 0, &sentinelError{ErrParse, parseErrorMessage(code, data)}
+	}
+	return v, nil
+}
+
+// Like ParseUint32(), but reports failures with a parseErrorCode instead of
+// an error, which avoids allocations.
+func parseUint32(data []byte) (uint32, parseErrorCode) {
 	d := len(data)
 	if d > 10 { // 2^32 is 10 digits long
-		return 0, fmt.Errorf(`"%v" is too long to be parsed as a uint32`, string(data))
+		return 0, errTooLong
 	}
 
 	v := uint64(0)
-	for _, ch := range data {
+	i := 0
+	if d >= 8 {
+		chunk := binary.LittleEndian.Uint64(data)
+		if !isEightDigits(chunk) {
+			return 0, errNonNumeric
+		}
+		v, i = parseEightDigits(chunk), 8
+	} else if d >= 4 {
+		chunk := binary.LittleEndian.Uint32(data)
+		if !isFourDigits(chunk) {
+			return 0, errNonNumeric
+		}
+		v, i = parseFourDigits(chunk), 4
+	}
+
+	for ; i < d; i++ {
+		ch := data[i]
 		if ch < '0' || ch > '9' {
-			return 0, fmt.Errorf(`"%v" contains non-numeric character '%v'`, string(data), string(ch))
+			return 0, errNonNumeric
 		}
-		d--
-		v += uint64(ch-'0') * base10exp[d]
+		v = v*10 + uint64(ch-'0')
 	}
 
 	if v > math.MaxUint32 {
-		return 0, fmt.Errorf(`"%v" overflows uint32`, string(data))
+		return 0, errOverflow
 	}
 
-	return uint32(v), nil
+	return uint32(v), noParseError
+}
+
+// Returns true if each byte of the little-endian word x is an ascii digit.
+// Adding 6 to a digit ('0'-'9' = 0x30-0x39) leaves its high nibble at 3, while
+// any other byte ends up with a different high nibble either before or after
+// the addition.
+func isEightDigits(x uint64) bool {
+	return (x&0xF0F0F0F0F0F0F0F0)|(((x+0x0606060606060606)&0xF0F0F0F0F0F0F0F0)>>4) == 0x3333333333333333
+}
+
+// Like isEightDigits(), but for a 4-byte word.
+func isFourDigits(x uint32) bool {
+	return (x&0xF0F0F0F0)|(((x+0x06060606)&0xF0F0F0F0)>>4) == 0x33333333
+}
+
+// Converts the 8 ascii digits of the little-endian word x (the first digit
+// being the least significant byte) into their value, by repeatedly combining
+// adjacent pairs of 1-, 2-, and then 4-digit values.
+func parseEightDigits(x uint64) uint64 {
+	x = ((x & 0x0F0F0F0F0F0F0F0F) * (10<<8 + 1)) >> 8
+	x = ((x & 0x00FF00FF00FF00FF) * (100<<16 + 1)) >> 16
+	return ((x & 0x0000FFFF0000FFFF) * (10000<<32 + 1)) >> 32
+}
+
+// Like parseEightDigits(), but for a 4-byte word.
+func parseFourDigits(x uint32) uint64 {
+	x = ((x & 0x0F0F0F0F) * (10<<8 + 1)) >> 8
+	return uint64(((x & 0x00FF00FF) * (100<<16 + 1)) >> 16)
 }
 
 // Returns the string representation of this Field without creating a memory allocation.
@@ -2,16 +2,19 @@ package hastycsv
 
 import (
 	"bytes"
+	"encoding"
 	"encoding/csv"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewReader(t *testing.T) {
@@ -50,6 +53,81 @@ func TestReader_Read(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestReader_Read_hasHeader(t *testing.T) {
+	in := strings.NewReader("name|age\nbill|30\nmary|35")
+
+	r := NewReader()
+	r.Comma = '|'
+	r.HasHeader = true
+	assert.Nil(t, r.Header())
+
+	receivedValues := []string{}
+	err := r.Read(in, func(i int, fields []Field) error {
+		receivedValues = append(receivedValues, fmt.Sprintf("%v:%v", i, fields[0].String()))
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2:bill", "3:mary"}, receivedValues)
+	assert.Equal(t, []string{"name", "age"}, r.Header())
+}
+
+func TestReader_ReadBytes(t *testing.T) {
+	data := []byte("name|age\r\nBILL|30\nmary|35")
+
+	r := NewReader()
+	r.Comma = '|'
+	r.HasHeader = true
+
+	receivedValues := []string{}
+	err := r.ReadBytes(data, func(i int, fields []Field) error {
+		receivedValues = append(receivedValues, fmt.Sprintf("%v:%v:%v", i, fields[0].ToLower().String(), fields[1].Uint32()))
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2:bill:30", "3:mary:35"}, receivedValues)
+	assert.Equal(t, []string{"name", "age"}, r.Header())
+	assert.Equal(t, "name|age\r\nbill|30\nmary|35", string(data))
+
+	err = r.ReadBytes([]byte("a|1\nb|x"), func(i int, fields []Field) error {
+		fields[1].Uint32()
+		return nil
+	})
+	assert.EqualError(t, err, `Line 2, column 2 ("1"): Can't parse field as uint32: "x" contains non-numeric character 'x'`)
+}
+
+func TestReader_ReadMaps(t *testing.T) {
+	in := strings.NewReader("name|age|name\nbill|30|x\nmary|35|y")
+
+	r := NewReader()
+	r.Comma = '|'
+	r.HasHeader = true
+
+	receivedValues := []string{}
+	err := r.ReadMaps(in, func(i int, record map[string]Field) error {
+		assert.Equal(t, 2, len(record))
+		receivedValues = append(receivedValues, fmt.Sprintf("%v:%v:%v", i, record["name"].String(), record["age"].Uint32()))
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"2:bill:30", "3:mary:35"}, receivedValues)
+}
+
+func TestReader_ReadMaps_errors(t *testing.T) {
+	r := NewReader()
+	err := r.ReadMaps(strings.NewReader("a\n1"), func(i int, record map[string]Field) error { return nil })
+	assert.EqualError(t, err, "ReadMaps() requires HasHeader to be set")
+
+	r.HasHeader = true
+	err = r.ReadMaps(strings.NewReader("a\nx"), func(i int, record map[string]Field) error {
+		record["a"].Uint32()
+		return nil
+	})
+	assert.EqualError(t, err, "Line 2, column 1 (\"a\"): Can't parse field as uint32: \"x\" contains non-numeric character 'x'")
+}
+
 func TestReader_Read_abortReading(t *testing.T) {
 	records := []string{
 		"a0|b0|c0",
@@ -75,6 +153,34 @@ func TestReader_Read_abortReading(t *testing.T) {
 	assert.Equal(t, []string{"a0", "a1", "a2"}, receivedValues)
 }
 
+func TestReader_Read_skipRow(t *testing.T) {
+	in := strings.NewReader("a0|1\na1|x\na2|2\na3|3")
+
+	r := NewReader()
+	r.Comma = '|'
+	receivedValues := []string{}
+	err := r.Read(in, func(i int, fields []Field) error {
+		n := fields[1].Uint32()
+		if fields[0].String() == "a1" || n == 3 {
+			// Discards the parse error of "x" too
+			return ErrSkipRow
+		}
+		receivedValues = append(receivedValues, fields[0].String())
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a0", "a2"}, receivedValues)
+
+	err = r.ReadBytes([]byte("a0|1\na1|2"), func(i int, fields []Field) error {
+		if i == 1 {
+			return ErrSkipRow
+		}
+		return fmt.Errorf("Abort!")
+	})
+	assert.EqualError(t, err, "Line 2: Abort!")
+}
+
 func TestReader_Read_InvalidComma(t *testing.T) {
 	r := NewReader()
 	in := strings.NewReader(`10|20|30`)
@@ -100,7 +206,39 @@ Mary|25|130.5`)
 		return nil
 	})
 
-	assert.EqualError(t, err, "Line 1: Can't parse field as uint32: \"123xyz\" contains non-numeric character 'x'")
+	assert.EqualError(t, err, "Line 1, column 2: Can't parse field as uint32: \"123xyz\" contains non-numeric character 'x'")
+}
+
+func TestReader_Read_lineLength(t *testing.T) {
+	r := NewReader()
+	r.HasHeader = true
+	r.MinLineLength = 6
+	r.MaxLineLength = 8
+
+	count := 0
+	next := func(i int, fields []Field) error {
+		count++
+		return nil
+	}
+
+	assert.Nil(t, r.Read(strings.NewReader("name,age\nbill,30\nmaryan,3\n"), next))
+	assert.Equal(t, 2, count)
+
+	err := r.Read(strings.NewReader("name,age\nbill,30\nbo,3\n"), next)
+	assert.EqualError(t, err, "Line 3: Length of 4 bytes is below the minimum of 6")
+
+	err = r.ReadBytes([]byte("n,a\nbill,30\nbill,3030\n"), next)
+	assert.EqualError(t, err, "Line 3: Length of 9 bytes exceeds the maximum of 8")
+}
+
+func TestNewField(t *testing.T) {
+	field := NewField([]byte("123"))
+	assert.Equal(t, "123", field.String())
+	assert.Equal(t, uint32(123), field.Uint32())
+
+	// Parse errors of detached fields are silently ignored.
+	assert.Equal(t, uint32(0), NewField([]byte("x")).Uint32())
+	assert.Equal(t, float32(0), NewField([]byte("x")).Float32())
 }
 
 func TestField_IsEmpty(t *testing.T) {
@@ -150,6 +288,31 @@ func TestField_String(t *testing.T) {
 	}
 }
 
+func TestField_MarshalText(t *testing.T) {
+	field := makeField("abc")
+	var marshaler encoding.TextMarshaler = field
+
+	text, err := marshaler.MarshalText()
+	require.Nil(t, err)
+	assert.Equal(t, []byte("abc"), text)
+
+	text[0] = 'x'
+	assert.Equal(t, "abc", field.String(), "MarshalText() must return a copy")
+
+	text, err = makeField("").MarshalText()
+	require.Nil(t, err)
+	assert.Equal(t, []byte{}, text)
+}
+
+func TestField_FillFromText(t *testing.T) {
+	var ip net.IP
+	require.Nil(t, makeField("192.168.0.1").FillFromText(&ip))
+	assert.Equal(t, "192.168.0.1", ip.String())
+
+	err := makeField("not-an-ip").FillFromText(&ip)
+	assert.EqualError(t, err, "invalid IP address: not-an-ip")
+}
+
 func TestField_Uint32(t *testing.T) {
 	testValues := map[string]uint32{
 		"0":          0,
@@ -164,7 +327,7 @@ func TestField_Uint32(t *testing.T) {
 	for testValue, expectedValue := range testValues {
 		field := makeField(testValue)
 		actualValue := field.Uint32()
-		assert.Nil(t, field.reader.err)
+		assert.Nil(t, field.reader.fieldError())
 		assert.Equal(t, expectedValue, actualValue)
 	}
 }
@@ -185,7 +348,7 @@ func TestField_Uint32_parseError(t *testing.T) {
 	for _, badlyFormattedInt := range badlyFormattedInts {
 		field := makeField(badlyFormattedInt)
 		assert.Equal(t, uint32(0), field.Uint32())
-		assert.NotNil(t, field.reader.err, `value="%v"`, badlyFormattedInt)
+		assert.NotNil(t, field.reader.fieldError(), `value="%v"`, badlyFormattedInt)
 	}
 }
 
@@ -202,7 +365,7 @@ func TestField_Float32(t *testing.T) {
 	for testValue, expectedValue := range testValues {
 		field := makeField(testValue)
 		actualValue := field.Float32()
-		assert.Nil(t, field.reader.err)
+		assert.Nil(t, field.reader.fieldError())
 		assert.Equal(t, expectedValue, actualValue)
 	}
 }
@@ -218,10 +381,53 @@ func TestField_Float32_parseError(t *testing.T) {
 	for _, badlyFormattedFloat := range badlyFormattedFloats {
 		field := makeField(badlyFormattedFloat)
 		assert.Equal(t, float32(0), field.Float32())
-		assert.NotNil(t, field.reader.err)
+		assert.NotNil(t, field.reader.fieldError())
 	}
 }
 
+func TestField_ParseX(t *testing.T) {
+	i, err := NewField([]byte("-42")).ParseInt(8)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-42), i)
+
+	u, err := NewField([]byte("42")).ParseUint(64)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(42), u)
+
+	f, err := NewField([]byte("1.25")).ParseFloat(64)
+	assert.Nil(t, err)
+	assert.Equal(t, 1.25, f)
+
+	b, err := NewField([]byte("true")).ParseBool()
+	assert.Nil(t, err)
+	assert.True(t, b)
+
+	tm, err := NewField([]byte("2019-06-27")).ParseTime("2006-01-02")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2019, 6, 27, 0, 0, 0, 0, time.UTC), tm)
+
+	// Errors don't point to the field's bytes
+	data := []byte("300")
+	_, err = NewField(data).ParseInt(8)
+	copy(data, "xxx")
+	assert.EqualError(t, err, `strconv.ParseInt: parsing "300": value out of range`)
+
+	data = []byte("2019-13-27")
+	_, err = NewField(data).ParseTime("2006-01-02")
+	copy(data, "xxxxxxxxxx")
+	assert.EqualError(t, err, `parsing time "2019-13-27": month out of range`)
+}
+
+func TestField_ParseX_allocations(t *testing.T) {
+	field := NewField([]byte("12345"))
+	allocs := testing.AllocsPerRun(100, func() {
+		field.ParseInt(64)
+		field.ParseUint(64)
+		field.ParseFloat(64)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
 func TestReadFile(t *testing.T) {
 	// Create a temp csv file and add a header plus 2 records.
 	tmpCsvFile, err := ioutil.TempFile("", "TestReadRecords")
@@ -258,6 +464,41 @@ func TestReadFile_nonexistentFile(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestReadStdin(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	require.Nil(t, err)
+	defer pr.Close()
+
+	origStdin := os.Stdin
+	os.Stdin = pr
+	defer func() { os.Stdin = origStdin }()
+
+	// Each record must be delivered before the next line is written.
+	delivered := make(chan string)
+	go func() {
+		defer pw.Close()
+		for _, chunk := range []string{"a|1\n", "b|2\n", "c|3"} {
+			pw.WriteString(chunk)
+			if chunk != "c|3" {
+				<-delivered
+			}
+		}
+	}()
+
+	records := []string{}
+	err = ReadStdin('|', func(i int, fields []Field) error {
+		record := fmt.Sprintf("%v:%v=%v", i, fields[0].String(), fields[1].Uint32())
+		records = append(records, record)
+		if i < 3 {
+			delivered <- record
+		}
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1:a=1", "2:b=2", "3:c=3"}, records)
+}
+
 func TestSplitBytes(t *testing.T) {
 	testData := []string{
 		"",
@@ -313,6 +554,11 @@ func TestParseUint32(t *testing.T) {
 		{Input: "", ExpectedOutput: uint32(0)},
 		{Input: "1", ExpectedOutput: uint32(1)},
 		{Input: "4294967295", ExpectedOutput: uint32(4294967295)},
+		{Input: "1234", ExpectedOutput: uint32(1234)},
+		{Input: "0012345", ExpectedOutput: uint32(12345)},
+		{Input: "12345678", ExpectedOutput: uint32(12345678)},
+		{Input: "987654321", ExpectedOutput: uint32(987654321)},
+		{Input: "0000000007", ExpectedOutput: uint32(7)},
 		// Error paths
 		{Input: "4294967296", ExpectedErr: "overflows uint32"},
 		{Input: "9999999999", ExpectedErr: "overflows uint32"},
@@ -321,6 +567,10 @@ func TestParseUint32(t *testing.T) {
 		{Input: "-1", ExpectedErr: `"-1" contains non-numeric character '-'`},
 		{Input: "1.2345", ExpectedErr: `"1.2345" contains non-numeric character '.'`},
 		{Input: "xyz", ExpectedErr: `"xyz" contains non-numeric character 'x'`},
+		{Input: "12:4", ExpectedErr: `"12:4" contains non-numeric character ':'`},
+		{Input: "123456/8", ExpectedErr: `"123456/8" contains non-numeric character '/'`},
+		{Input: "12345678x", ExpectedErr: `"12345678x" contains non-numeric character 'x'`},
+		{Input: "1234 ", ExpectedErr: `"1234 " contains non-numeric character ' '`},
 	}
 
 	for i, testCase := range testCases {
@@ -497,3 +747,34 @@ func createCsvRecords() *bytes.Buffer {
 
 	return buf
 }
+
+func TestReader_Read_allocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("skipping allocation test with the race detector enabled")
+	}
+
+	r := NewReader()
+	r.Comma = '|'
+
+	allocsPerRead := func(recordCount int) float64 {
+		buf := &bytes.Buffer{}
+		for i := 0; i < recordCount; i++ {
+			fmt.Fprintf(buf, "%v|name%v|%v\n", i, i, float32(i)/2)
+		}
+		data := buf.Bytes()
+		in := bytes.NewReader(data)
+
+		return testing.AllocsPerRun(10, func() {
+			in.Reset(data)
+			r.Read(in, func(i int, fields []Field) error {
+				fields[0].Uint32()
+				fields[1].Bytes()
+				fields[2].Float32()
+				return nil
+			})
+		})
+	}
+
+	// Allocations per Read() call must not depend on the number of records.
+	assert.Equal(t, allocsPerRead(100), allocsPerRead(10000))
+}
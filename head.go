@@ -0,0 +1,99 @@
+package hastycsv
+
+import (
+	"bufio"
+	"io"
+)
+
+// Copies the header and the first n records of src to dst, using a default
+// Reader with HasHeader set (see Reader.Head()).
+func Head(src io.Reader, dst io.Writer, n int) error {
+	r := NewReader()
+	r.HasHeader = true
+	return r.Head(src, dst, n)
+}
+
+// Copies the first n records of src to dst, preceded by the header if
+// HasHeader is set.  Reading stops after the n-th record, so the rest of src
+// isn't consumed.  Records are written as their raw line, like Filter().
+func (me *Reader) Head(src io.Reader, dst io.Writer, n int) error {
+	if err := me.begin(src); err != nil {
+		return err
+	}
+	defer me.end()
+
+	w := bufio.NewWriterSize(dst, 32*1024)
+	headerWritten := false
+	for count := 0; count < n; count++ {
+		if _, err := me.next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if !headerWritten {
+			headerWritten = true
+			me.writeHeader(w)
+		}
+		w.Write(me.raw)
+		w.WriteByte('\n')
+	}
+
+	if !headerWritten {
+		if n <= 0 {
+			// Read the header, which would otherwise be left unread.
+			if _, err := me.next(); err != nil && err != io.EOF {
+				return err
+			}
+		}
+		me.writeHeader(w)
+	}
+	return w.Flush()
+}
+
+// Copies the header and the last n records of src to dst, using a default
+// Reader with HasHeader set (see Reader.Tail()).
+func Tail(src io.Reader, dst io.Writer, n int) error {
+	r := NewReader()
+	r.HasHeader = true
+	return r.Tail(src, dst, n)
+}
+
+// Copies the last n records of src to dst, preceded by the header if
+// HasHeader is set.  All of src is read, but only the raw lines of the last n
+// records are held in memory, in a ring buffer.
+func (me *Reader) Tail(src io.Reader, dst io.Writer, n int) error {
+	if n < 0 {
+		n = 0
+	}
+	ring := make([][]byte, n)
+	count := 0
+
+	err := me.Read(src, func(i int, record []Field) error {
+		if n > 0 {
+			slot := count % n
+			ring[slot] = append(ring[slot][:0], me.raw...)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriterSize(dst, 32*1024)
+	me.writeHeader(w)
+	oldest := 0
+	if count > n {
+		// The ring is full: its oldest line is the one overwritten next.
+		if n > 0 {
+			oldest = count % n
+		}
+		count = n
+	}
+	for i := 0; i < count; i++ {
+		w.Write(ring[(oldest+i)%n])
+		w.WriteByte('\n')
+	}
+	return w.Flush()
+}
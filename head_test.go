@@ -0,0 +1,72 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestHead(t *testing.T) {
+	in := "name,age\nbill,30\nmary,17\njane,45\n"
+
+	var buf bytes.Buffer
+	require.Nil(t, Head(strings.NewReader(in), &buf, 2))
+	assert.Equal(t, "name,age\nbill,30\nmary,17\n", buf.String())
+
+	buf.Reset()
+	require.Nil(t, Head(strings.NewReader(in), &buf, 10))
+	assert.Equal(t, in, buf.String())
+
+	buf.Reset()
+	require.Nil(t, Head(strings.NewReader(in), &buf, 0))
+	assert.Equal(t, "name,age\n", buf.String())
+
+	// Records past the n-th aren't read, so they may be malformed
+	buf.Reset()
+	require.Nil(t, Head(strings.NewReader("name,age\nbill,30\nmary\n"), &buf, 1))
+	assert.Equal(t, "name,age\nbill,30\n", buf.String())
+}
+
+func TestReader_Head(t *testing.T) {
+	r := NewReader()
+	r.Comma = '|'
+
+	var buf bytes.Buffer
+	require.Nil(t, r.Head(strings.NewReader("bill|30\r\nmary|17\njane|45\n"), &buf, 2))
+	assert.Equal(t, "bill|30\nmary|17\n", buf.String())
+}
+
+func TestTail(t *testing.T) {
+	in := "name,age\nbill,30\nmary,17\njane,45\nfred,50\n"
+
+	var buf bytes.Buffer
+	require.Nil(t, Tail(strings.NewReader(in), &buf, 2))
+	assert.Equal(t, "name,age\njane,45\nfred,50\n", buf.String())
+
+	buf.Reset()
+	require.Nil(t, Tail(strings.NewReader(in), &buf, 3))
+	assert.Equal(t, "name,age\nmary,17\njane,45\nfred,50\n", buf.String())
+
+	buf.Reset()
+	require.Nil(t, Tail(strings.NewReader(in), &buf, 10))
+	assert.Equal(t, in, buf.String())
+
+	buf.Reset()
+	require.Nil(t, Tail(strings.NewReader(in), &buf, 0))
+	assert.Equal(t, "name,age\n", buf.String())
+
+	buf.Reset()
+	err := Tail(strings.NewReader("name,age\nbill,30\nmary\n"), &buf, 1)
+	assert.Contains(t, err.Error(), "Line 3:")
+}
+
+func TestReader_Tail(t *testing.T) {
+	r := NewReader()
+	r.Comma = '|'
+
+	var buf bytes.Buffer
+	require.Nil(t, r.Tail(strings.NewReader("bill|30\nmary|17\njane|45\n"), &buf, 1))
+	assert.Equal(t, "jane|45\n", buf.String())
+}
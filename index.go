@@ -0,0 +1,226 @@
+package hastycsv
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// The interval used by BuildIndex() if none is specified.
+const DefaultIndexInterval = 1000
+
+// Identifies the binary encoding of an Index written by Index.WriteTo().
+var indexMagic = []byte("HCSVIDX1")
+
+// Holds the byte offsets of every Nth line of a CSV input, allowing records to
+// be located without rescanning the input (see ReadRow()), and the input to be
+// divided into line-aligned ranges for parallel processing.
+//
+// Line numbers start at 1 and match the record numbers passed to the Next
+// callback (so when the input has a header, it's line 1).
+type Index struct {
+	// Interval is the number of lines between consecutive offsets.
+	Interval int
+
+	// Offsets[k] is the byte offset of line k*Interval + 1.
+	Offsets []int64
+
+	// Lines is the total number of lines in the input.
+	Lines int
+
+	// Size is the size of the input in bytes.
+	Size int64
+}
+
+// Scans r and returns an Index of the byte offsets of every interval-th line
+// (DefaultIndexInterval if interval < 1).
+func BuildIndex(r io.Reader, interval int) (*Index, error) {
+	if interval < 1 {
+		interval = DefaultIndexInterval
+	}
+
+	lineBuffer := lineBufferPool.Get().(*[]byte)
+	defer lineBufferPool.Put(lineBuffer)
+
+	var lines lineReader
+	lines.init(r, *lineBuffer)
+
+	index := &Index{Interval: interval}
+	for {
+		if _, err := lines.readLine(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, &ReadError{err}
+		}
+
+		if index.Lines%interval == 0 {
+			index.Offsets = append(index.Offsets, lines.offset)
+		}
+		index.Lines++
+	}
+	index.Size = lines.consumed
+
+	return index, nil
+}
+
+// Returns the byte offset of the closest indexed line at or before the
+// specified line, along with that line's number.  Reading from that offset and
+// skipping line-n lines leads to the specified line.
+func (me *Index) Lookup(line int) (offset int64, n int, err error) {
+	if line < 1 || line > me.Lines {
+		return 0, 0, fmt.Errorf("Line %v is out of range [1, %v]", line, me.Lines)
+	}
+
+	k := (line - 1) / me.Interval
+	return me.Offsets[k], k*me.Interval + 1, nil
+}
+
+// Writes a compact binary encoding of this Index to w, which can be read back
+// with ReadIndex().  Offsets are delta-encoded as varints, so an Index of a
+// file with short lines takes only a couple of bytes per offset.
+func (me *Index) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, binary.MaxVarintLen64)
+	written := int64(0)
+
+	write := func(b []byte) {
+		n, _ := bw.Write(b)
+		written += int64(n)
+	}
+	writeUvarint := func(v uint64) {
+		write(buf[:binary.PutUvarint(buf, v)])
+	}
+
+	write(indexMagic)
+	writeUvarint(uint64(me.Interval))
+	writeUvarint(uint64(me.Lines))
+	writeUvarint(uint64(me.Size))
+	writeUvarint(uint64(len(me.Offsets)))
+	prev := int64(0)
+	for _, offset := range me.Offsets {
+		writeUvarint(uint64(offset - prev))
+		prev = offset
+	}
+
+	return written, bw.Flush()
+}
+
+// Reads an Index that was written by Index.WriteTo().
+func ReadIndex(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(indexMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != string(indexMagic) {
+		return nil, fmt.Errorf("Input is not a hastycsv index")
+	}
+
+	values := make([]uint64, 4)
+	for i := range values {
+		v, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("Can't read index: %w", err)
+		}
+		values[i] = v
+	}
+
+	index := &Index{Interval: int(values[0]), Lines: int(values[1]), Size: int64(values[2])}
+	if index.Interval < 1 || values[3] != uint64((index.Lines+index.Interval-1)/index.Interval) {
+		return nil, fmt.Errorf("Can't read index: inconsistent header")
+	}
+
+	index.Offsets = make([]int64, values[3])
+	prev := int64(0)
+	for i := range index.Offsets {
+		delta, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, fmt.Errorf("Can't read index: %w", err)
+		}
+		prev += int64(delta)
+		index.Offsets[i] = prev
+	}
+
+	return index, nil
+}
+
+// Provides random access to the records of a CSV input by line number, using
+// an Index to jump close to the requested lines.
+type IndexedReader struct {
+	// Comma is the field delimiter.
+	// It is set to comma (',') by NewIndexedReader.
+	Comma byte
+
+	r      io.ReaderAt
+	index  *Index
+	reader Reader
+}
+
+// Returns a new IndexedReader over r, whose size in bytes is size.  If index
+// is nil, one is built by scanning r (see BuildIndex()); otherwise it must
+// have been built from the same input.
+func NewIndexedReader(r io.ReaderAt, size int64, index *Index) (*IndexedReader, error) {
+	if index == nil {
+		var err error
+		if index, err = BuildIndex(io.NewSectionReader(r, 0, size), DefaultIndexInterval); err != nil {
+			return nil, err
+		}
+	} else if index.Size != size {
+		return nil, fmt.Errorf("Index was built from an input of %v bytes, but the input has %v bytes", index.Size, size)
+	}
+
+	return &IndexedReader{Comma: ',', r: r, index: index}, nil
+}
+
+// Returns the Index used by this IndexedReader.
+func (me *IndexedReader) Index() *Index {
+	return me.index
+}
+
+// Returns a copy of the record on the specified line.
+func (me *IndexedReader) ReadRow(line int) ([]Field, error) {
+	var record []Field
+	err := me.ReadRows(line, line, func(i int, fields []Field) error {
+		record = copyFields(fields)
+		return nil
+	})
+	return record, err
+}
+
+// Reads the records on lines from through to (inclusive) and invokes
+// nextRecord with each one, as Reader.Read() would.  The number of fields per
+// record is inferred from line from.
+func (me *IndexedReader) ReadRows(from int, to int, nextRecord Next) error {
+	if to < from || to > me.index.Lines {
+		return fmt.Errorf("Invalid line range [%v, %v]: the input has %v lines", from, to, me.index.Lines)
+	}
+	offset, line, err := me.index.Lookup(from)
+	if err != nil {
+		return err
+	}
+
+	me.reader.Comma = me.Comma
+	if err := me.reader.begin(io.NewSectionReader(me.r, offset, me.index.Size-offset)); err != nil {
+		return err
+	}
+	defer me.reader.end()
+
+	// Skip the lines between the indexed line and the first requested line.
+	me.reader.row = line - 1
+	for me.reader.row+1 < from {
+		if _, err := me.reader.lines.readLine(); err != nil {
+			return &ReadError{err}
+		}
+		me.reader.row++
+	}
+
+	for me.reader.row < to {
+		fields, err := me.reader.next()
+		if err != nil {
+			return err
+		}
+		if err := me.reader.recordError(nextRecord(me.reader.row, fields)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package hastycsv
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestBuildIndex(t *testing.T) {
+	in := "id,name\r\n1,bill\n2,mary\n3,joe\n4,ann"
+
+	index, err := BuildIndex(strings.NewReader(in), 2)
+	require.Nil(t, err)
+	assert.Equal(t, &Index{Interval: 2, Offsets: []int64{0, 16, 29}, Lines: 5, Size: int64(len(in))}, index)
+	assert.True(t, strings.HasPrefix(in[16:], "2,mary"))
+	assert.True(t, strings.HasPrefix(in[29:], "4,ann"))
+
+	index, err = BuildIndex(strings.NewReader(""), 0)
+	require.Nil(t, err)
+	assert.Equal(t, &Index{Interval: DefaultIndexInterval}, index)
+}
+
+func TestIndex_Lookup(t *testing.T) {
+	index := &Index{Interval: 10, Offsets: []int64{0, 100, 200}, Lines: 25}
+
+	offset, line, err := index.Lookup(1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, 1, line)
+
+	offset, line, err = index.Lookup(25)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(200), offset)
+	assert.Equal(t, 21, line)
+
+	_, _, err = index.Lookup(26)
+	assert.EqualError(t, err, "Line 26 is out of range [1, 25]")
+	_, _, err = index.Lookup(0)
+	assert.NotNil(t, err)
+}
+
+func TestIndex_WriteTo(t *testing.T) {
+	buf := &bytes.Buffer{}
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(buf, "%v,name%v\n", i, i)
+	}
+	index, err := BuildIndex(bytes.NewReader(buf.Bytes()), 100)
+	require.Nil(t, err)
+
+	encoded := &bytes.Buffer{}
+	n, err := index.WriteTo(encoded)
+	require.Nil(t, err)
+	assert.Equal(t, int64(encoded.Len()), n)
+
+	decoded, err := ReadIndex(encoded)
+	require.Nil(t, err)
+	assert.Equal(t, index, decoded)
+}
+
+func TestReadIndex_errors(t *testing.T) {
+	_, err := ReadIndex(strings.NewReader("not an index"))
+	assert.EqualError(t, err, "Input is not a hastycsv index")
+
+	_, err = ReadIndex(strings.NewReader("HCSVIDX1\x02"))
+	assert.EqualError(t, err, "Can't read index: EOF")
+
+	_, err = ReadIndex(strings.NewReader("HCSVIDX1\x02\x05\x10\x09"))
+	assert.EqualError(t, err, "Can't read index: inconsistent header")
+}
+
+func TestIndexedReader_ReadRow(t *testing.T) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("id|name\n")
+	for i := 2; i <= 1000; i++ {
+		fmt.Fprintf(buf, "%v|name%v\r\n", i, i)
+	}
+	in := bytes.NewReader(buf.Bytes())
+
+	r, err := NewIndexedReader(in, in.Size(), nil)
+	require.Nil(t, err)
+	r.Comma = '|'
+	assert.Equal(t, 1000, r.Index().Lines)
+
+	for _, line := range []int{2, 500, 999, 1000} {
+		record, err := r.ReadRow(line)
+		require.Nil(t, err)
+		assert.Equal(t, uint32(line), record[0].Uint32())
+		assert.Equal(t, fmt.Sprintf("name%v", line), record[1].String())
+	}
+
+	header, err := r.ReadRow(1)
+	require.Nil(t, err)
+	assert.Equal(t, "id", header[0].String())
+
+	_, err = r.ReadRow(1001)
+	assert.EqualError(t, err, "Invalid line range [1001, 1001]: the input has 1000 lines")
+	_, err = r.ReadRow(0)
+	assert.EqualError(t, err, "Line 0 is out of range [1, 1000]")
+}
+
+func TestIndexedReader_ReadRows(t *testing.T) {
+	in := strings.NewReader("1,a\n2,b\n3,c\n4,d\n5,e\n6,f")
+	index, err := BuildIndex(in, 2)
+	require.Nil(t, err)
+
+	r, err := NewIndexedReader(in, in.Size(), index)
+	require.Nil(t, err)
+
+	received := []string{}
+	err = r.ReadRows(3, 5, func(i int, fields []Field) error {
+		received = append(received, fmt.Sprintf("%v:%v", i, fields[1].String()))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3:c", "4:d", "5:e"}, received)
+
+	err = r.ReadRows(4, 6, func(i int, fields []Field) error {
+		return fmt.Errorf("Stop")
+	})
+	assert.EqualError(t, err, "Line 4: Stop")
+
+	err = r.ReadRows(5, 4, func(i int, fields []Field) error { return nil })
+	assert.NotNil(t, err)
+
+	_, err = NewIndexedReader(in, in.Size()+1, index)
+	assert.EqualError(t, err, "Index was built from an input of 23 bytes, but the input has 24 bytes")
+}
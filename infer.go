@@ -0,0 +1,153 @@
+package hastycsv
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Time layouts that InferSchema() tries when checking whether a column
+// contains times.
+var inferTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"01/02/2006",
+	"02-Jan-2006",
+}
+
+// The result of InferSchema().
+type InferredSchema struct {
+	// The narrowest type that each column's sampled values fit into, in
+	// order of preference: Uint32Type, Int64Type, Float64Type, TimeType and
+	// StringType.
+	Schema *Schema
+
+	// Nullable[i] is true if at least one sampled field of column i was empty.
+	Nullable []bool
+}
+
+// Per-column state of the type inference.
+type columnInference struct {
+	uint32OK  bool
+	int64OK   bool
+	float64OK bool
+	layouts   []string // time layouts that parsed every value so far
+	nullable  bool
+	hasValues bool
+}
+
+// Scans up to sampleRows records of r (all of them, if sampleRows <= 0) and
+// infers the type of each column, as a starting point for a Schema
+// definition.  Column names are taken from the header if HasHeader is set,
+// and are otherwise named "column1", "column2", etc.
+func (me *Reader) InferSchema(r io.Reader, sampleRows int) (*InferredSchema, error) {
+	if err := me.begin(r); err != nil {
+		return nil, err
+	}
+	defer me.end()
+
+	var columns []columnInference
+	for n := 0; sampleRows <= 0 || n < sampleRows; n++ {
+		fields, err := me.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if columns == nil {
+			columns = make([]columnInference, len(fields))
+			for i := range columns {
+				columns[i] = columnInference{uint32OK: true, int64OK: true, float64OK: true, layouts: inferTimeLayouts}
+			}
+		}
+
+		for i, field := range fields {
+			columns[i].add(field)
+		}
+	}
+
+	names := me.header
+	if names == nil {
+		names = make([]string, len(columns))
+		for i := range names {
+			names[i] = fmt.Sprintf("column%v", i+1)
+		}
+	}
+
+	inferred := &InferredSchema{
+		Schema:   NewSchema(),
+		Nullable: make([]bool, len(names)),
+	}
+	for i, name := range names {
+		col := Column{Name: name, Type: StringType}
+		if i < len(columns) {
+			col.Type, col.Layout = columns[i].result()
+			inferred.Nullable[i] = columns[i].nullable
+		}
+		inferred.Schema.Columns = append(inferred.Schema.Columns, col)
+	}
+
+	return inferred, nil
+}
+
+// Reads up to sampleRows comma-delimited records from r (which must start with
+// a header record) and infers the type of each column.  See
+// Reader.InferSchema().
+func InferSchema(r io.Reader, sampleRows int) (*InferredSchema, error) {
+	reader := NewReader()
+	reader.HasHeader = true
+	return reader.InferSchema(r, sampleRows)
+}
+
+func (me *columnInference) add(field Field) {
+	if field.IsEmpty() {
+		me.nullable = true
+		return
+	}
+	me.hasValues = true
+
+	s := field.unsafeString()
+	if me.uint32OK {
+		_, err := ParseUint32(field.data)
+		me.uint32OK = err == nil
+	}
+	if me.int64OK && !me.uint32OK {
+		_, err := strconv.ParseInt(s, 10, 64)
+		me.int64OK = err == nil
+	}
+	if me.float64OK && !me.int64OK {
+		_, err := strconv.ParseFloat(s, 64)
+		me.float64OK = err == nil
+	}
+
+	if len(me.layouts) > 0 {
+		viable := me.layouts[:0:0]
+		for _, layout := range me.layouts {
+			if _, err := time.Parse(layout, s); err == nil {
+				viable = append(viable, layout)
+			}
+		}
+		me.layouts = viable
+	}
+}
+
+// Returns the narrowest column type that fits all values seen so far.
+func (me *columnInference) result() (ColumnType, string) {
+	switch {
+	case !me.hasValues:
+		return StringType, ""
+	case me.uint32OK:
+		return Uint32Type, ""
+	case me.int64OK:
+		return Int64Type, ""
+	case me.float64OK:
+		return Float64Type, ""
+	case len(me.layouts) > 0:
+		return TimeType, me.layouts[0]
+	}
+	return StringType, ""
+}
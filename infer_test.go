@@ -0,0 +1,61 @@
+package hastycsv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestReader_InferSchema(t *testing.T) {
+	in := strings.NewReader(`1|-5|1.5|2019-06-27|x|10|2019-06-27T10:00:00Z|
+2|7|2|2019-06-28|y||2019-06-27T11:00:00Z|
+3|4294967296|3e2|2019-06-29|3|30|2019-06-27 12:00:00|`)
+
+	r := NewReader()
+	r.Comma = '|'
+	inferred, err := r.InferSchema(in, 0)
+	assert.Nil(t, err)
+
+	assert.Equal(t, NewSchema(
+		Uint32Column("column1"),
+		Int64Column("column2"),
+		Float64Column("column3"),
+		TimeColumn("column4", "2006-01-02"),
+		StringColumn("column5"),
+		Uint32Column("column6"),
+		StringColumn("column7"), // mixed time layouts
+		StringColumn("column8"), // all empty
+	), inferred.Schema)
+	assert.Equal(t, []bool{false, false, false, false, false, true, false, true}, inferred.Nullable)
+}
+
+func TestReader_InferSchema_sampleRows(t *testing.T) {
+	in := strings.NewReader("1,a\n2,b\n-3,c")
+
+	inferred, err := NewReader().InferSchema(in, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, NewSchema(Uint32Column("column1"), StringColumn("column2")), inferred.Schema)
+}
+
+func TestInferSchema(t *testing.T) {
+	in := strings.NewReader("id,price,sold\n1,9.99,2019-06-27T10:00:00Z\n2,,2019-06-28T10:00:00Z")
+
+	inferred, err := InferSchema(in, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, NewSchema(
+		Uint32Column("id"),
+		Float64Column("price"),
+		TimeColumn("sold", "2006-01-02T15:04:05Z07:00"),
+	), inferred.Schema)
+	assert.Equal(t, []bool{false, true, false}, inferred.Nullable)
+
+	// Header only
+	inferred, err = InferSchema(strings.NewReader("a,b"), 100)
+	assert.Nil(t, err)
+	assert.Equal(t, NewSchema(StringColumn("a"), StringColumn("b")), inferred.Schema)
+}
+
+func TestInferSchema_error(t *testing.T) {
+	_, err := InferSchema(strings.NewReader("a,b\n1"), 100)
+	assert.NotNil(t, err)
+}
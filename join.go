@@ -0,0 +1,264 @@
+package hastycsv
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// Identifies which records Join() writes.
+type JoinType int
+
+const (
+	// Write only the probe records that match at least one build record.
+	InnerJoin JoinType = iota
+
+	// Write every probe record; those without a match are padded with empty
+	// fields.
+	LeftJoin
+)
+
+type joinConfig struct {
+	reader   *Reader
+	joinType JoinType
+}
+
+// Configures Join().
+type JoinOption func(cfg *joinConfig)
+
+// Returns a JoinOption that makes Join() parse both of its inputs using the
+// specified Reader, e.g. to set its Comma delimiter.  By default, a Reader
+// returned by NewReader() with the Writer's Comma delimiter and HasHeader set
+// to true is used.
+func WithJoinReader(reader *Reader) JoinOption {
+	return func(cfg *joinConfig) {
+		cfg.reader = reader
+	}
+}
+
+// Returns a JoinOption that sets the type of join (InnerJoin by default).
+func WithJoinType(joinType JoinType) JoinOption {
+	return func(cfg *joinConfig) {
+		cfg.joinType = joinType
+	}
+}
+
+// Joins the records of two inputs on their key columns and writes the combined
+// records to dst: each probe record is followed by the fields of a matching
+// build record, except for its key column.  The build input, which should be
+// the smaller of the two, is loaded into a hash table, and the probe input is
+// streamed.  A probe record that matches several build records is written once
+// per match, in build input order.
+//
+// Both inputs must start with a header, in which the key columns are looked
+// up by name; the combined header is written first.  Keys are compared by
+// their parsed value according to the key columns' types, so e.g. "007" and
+// "7" match as Uint32Type keys.  Empty keys never match.  dst is flushed once
+// all records have been written.
+func Join(dst *Writer, probe io.Reader, build io.Reader, probeKey, buildKey Column, options ...JoinOption) error {
+	cfg := &joinConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+	if cfg.reader == nil {
+		cfg.reader = NewReader()
+		cfg.reader.Comma = dst.Comma
+		cfg.reader.HasHeader = true
+	}
+
+	table, err := buildJoinTable(cfg.reader, build, buildKey)
+	if err != nil {
+		return fmt.Errorf("Build input: %w", err)
+	}
+
+	key := newJoinKey(probeKey)
+	var combined, padding []Field
+	start := func() error {
+		header := cfg.reader.Header()
+		if err := key.locate(header); err != nil {
+			return err
+		}
+		if err := dst.Write(append(append([]string{}, header...), table.header...)); err != nil {
+			return err
+		}
+		combined = make([]Field, len(header)+len(table.header))
+		padding = make([]Field, len(table.header))
+		return nil
+	}
+
+	err = cfg.reader.Read(probe, func(i int, record []Field) error {
+		if combined == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+
+		buf, ok, err := key.encode(record)
+		if err != nil {
+			return err
+		}
+
+		copy(combined, record)
+		row, matched := -1, false
+		if ok {
+			row, matched = table.heads[string(buf)]
+		}
+		for ; matched && row >= 0; row = table.rows[row].next {
+			copy(combined[len(record):], table.rows[row].fields)
+			if err := dst.WriteFields(combined); err != nil {
+				return err
+			}
+		}
+
+		if !matched && cfg.joinType == LeftJoin {
+			copy(combined[len(record):], padding)
+			return dst.WriteFields(combined)
+		}
+		return nil
+	})
+	if err == nil && combined == nil {
+		// The probe input has no records.
+		err = start()
+	}
+	if err != nil {
+		return err
+	}
+
+	return dst.Flush()
+}
+
+// The build input of a Join(), indexed by key.
+type joinTable struct {
+	header []string // the build header, without the key column
+	rows   []joinRow
+	heads  map[string]int // key => index of the key's first row
+	tails  map[string]int // key => index of the key's last row
+}
+
+// A build record (without its key field), and the index of the next record
+// with the same key (or -1).
+type joinRow struct {
+	fields []Field
+	next   int
+}
+
+func buildJoinTable(reader *Reader, build io.Reader, buildKey Column) (*joinTable, error) {
+	table := &joinTable{heads: map[string]int{}, tails: map[string]int{}}
+	arena := NewArena(DefaultArenaBlockSize)
+	key := newJoinKey(buildKey)
+	var fields []Field // the current record without its key field
+
+	located := false
+	locate := func() error {
+		located = true
+		header := reader.Header()
+		if err := key.locate(header); err != nil {
+			return err
+		}
+		table.header = append(append([]string{}, header[:key.index]...), header[key.index+1:]...)
+		return nil
+	}
+
+	err := reader.Read(build, func(i int, record []Field) error {
+		if !located {
+			if err := locate(); err != nil {
+				return err
+			}
+		}
+
+		buf, ok, err := key.encode(record)
+		if err != nil || !ok {
+			return err
+		}
+
+		fields = append(append(fields[:0], record[:key.index]...), record[key.index+1:]...)
+		table.rows = append(table.rows, joinRow{fields: arena.Retain(fields), next: -1})
+		row := len(table.rows) - 1
+		if tail, exists := table.tails[string(buf)]; exists {
+			table.rows[tail].next = row
+		} else {
+			table.heads[string(buf)] = row
+		}
+		table.tails[string(buf)] = row
+		return nil
+	})
+	if err == nil && !located {
+		// The build input has no records.
+		err = locate()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// The key column of one of the inputs of a Join().
+type joinKey struct {
+	column Column
+	index  int
+	record Record // used to parse the key
+	buf    []byte
+}
+
+func newJoinKey(column Column) *joinKey {
+	return &joinKey{
+		column: column,
+		record: Record{
+			schema: &Schema{Columns: []Column{column}},
+			fields: make([]Field, 1),
+			values: make([]value, 1),
+		},
+	}
+}
+
+// Finds the key column in header.
+func (me *joinKey) locate(header []string) error {
+	if header == nil {
+		return fmt.Errorf(`Can't join on column "%v": the input has no header`, me.column.Name)
+	}
+	if me.index = indexOfString(header, me.column.Name); me.index < 0 {
+		return fmt.Errorf(`Column "%v" not found in header`, me.column.Name)
+	}
+	return nil
+}
+
+// Parses the key of record and returns its canonical encoding, which is only
+// valid until the next call.  Returns false if the key is empty.
+func (me *joinKey) encode(record []Field) ([]byte, bool, error) {
+	me.record.fields[0] = record[me.index]
+	if err := me.record.parse(0); err != nil {
+		err.(*ParseError).Column = me.index + 1
+		return nil, false, err
+	}
+
+	v := &me.record.values[0]
+	if v.null {
+		return nil, false, nil
+	}
+
+	buf := me.buf[:0]
+	switch me.column.Type {
+	case Uint32Type:
+		buf = appendBigEndian32(buf, v.u)
+	case Int64Type:
+		buf = appendBigEndian64(buf, uint64(v.i))
+	case Float32Type, Float64Type:
+		f := v.f
+		if f == 0 {
+			f = 0 // -0 and +0 are equal
+		}
+		buf = appendBigEndian64(buf, math.Float64bits(f))
+	case BoolType:
+		if v.b {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+	case TimeType:
+		buf = appendBigEndian32(appendBigEndian64(buf, uint64(v.t.Unix())), uint32(v.t.Nanosecond()))
+	default:
+		buf = append(buf, record[me.index].data...)
+	}
+	me.buf = buf
+	return buf, true, nil
+}
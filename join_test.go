@@ -0,0 +1,66 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestJoin(t *testing.T) {
+	orders := "order,customer,total\n1,007,10\n2,8,20\n3,9,30\n4,,40\n"
+	customers := "id,name\n7,bill\n8,mary\n7,william\n,nobody\n"
+
+	join := func(options ...JoinOption) string {
+		var buf bytes.Buffer
+		err := Join(NewWriter(&buf), strings.NewReader(orders), strings.NewReader(customers),
+			Uint32Column("customer"), Uint32Column("id"), options...)
+		assert.Nil(t, err)
+		return buf.String()
+	}
+
+	assert.Equal(t, "order,customer,total,name\n1,007,10,bill\n1,007,10,william\n2,8,20,mary\n", join())
+	assert.Equal(t, "order,customer,total,name\n1,007,10,bill\n1,007,10,william\n2,8,20,mary\n3,9,30,\n4,,40,\n", join(WithJoinType(LeftJoin)))
+}
+
+func TestJoin_stringKeys(t *testing.T) {
+	r := NewReader()
+	r.Comma = '|'
+	r.HasHeader = true
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Comma = '|'
+
+	err := Join(w, strings.NewReader("code|qty\n007|1\nabc|2\n"), strings.NewReader("desc|code|price\nwidget|abc|9.99\nknob|7|1.50\n"),
+		StringColumn("code"), StringColumn("code"), WithJoinReader(r))
+	assert.Nil(t, err)
+	assert.Equal(t, "code|qty|desc|price\nabc|2|widget|9.99\n", buf.String())
+
+	// A build input without records still contributes its columns
+	buf.Reset()
+	err = Join(w, strings.NewReader("code|qty\n007|1\n"), strings.NewReader("desc|code|price\n"),
+		StringColumn("code"), StringColumn("code"), WithJoinReader(r), WithJoinType(LeftJoin))
+	assert.Nil(t, err)
+	assert.Equal(t, "code|qty|desc|price\n007|1||\n", buf.String())
+}
+
+func TestJoin_emptyProbe(t *testing.T) {
+	var buf bytes.Buffer
+	err := Join(NewWriter(&buf), strings.NewReader("id,dept\n"), strings.NewReader("id,name\n1,bill\n"), Uint32Column("id"), Uint32Column("id"))
+	assert.Nil(t, err)
+	assert.Equal(t, "id,dept,name\n", buf.String())
+
+	err = Join(NewWriter(&buf), strings.NewReader("a,b\n"), strings.NewReader("id,name\n1,bill\n"), Uint32Column("c"), Uint32Column("id"))
+	assert.EqualError(t, err, `Column "c" not found in header`)
+}
+
+func TestJoin_errors(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := Join(NewWriter(&buf), strings.NewReader("a,b\n1,2\n"), strings.NewReader("id,name\nx,bill\n"), Uint32Column("a"), Uint32Column("id"))
+	assert.EqualError(t, err, `Build input: Line 2, column 1: Can't parse column "id" as uint32: "x"`)
+
+	err = Join(NewWriter(&buf), strings.NewReader("a,b\n1,2\n"), strings.NewReader("id,name\n1,bill\n"), Uint32Column("c"), Uint32Column("id"))
+	assert.EqualError(t, err, `Line 2: Column "c" not found in header`)
+}
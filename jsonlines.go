@@ -0,0 +1,166 @@
+package hastycsv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Converts the comma-delimited records read from r into JSON Lines written to
+// w: one JSON object per record, keyed by column name, with string values.
+// If header is nil, the first record of r is a header that provides the
+// column names; otherwise header names the columns of r, which has no header
+// record.  See Reader.ToJSONLines() for typed values.
+func ToJSONLines(r io.Reader, header []string, w io.Writer) error {
+	reader := NewReader()
+	if header == nil {
+		reader.HasHeader = true
+		return reader.ToJSONLines(r, nil, w)
+	}
+
+	schema := NewSchema()
+	for _, name := range header {
+		schema.Columns = append(schema.Columns, StringColumn(name))
+	}
+	return reader.ToJSONLines(r, schema, w)
+}
+
+// Converts the records read from r into JSON Lines written to w: one JSON
+// object per record, whose keys are the names of the schema's columns and
+// whose values are typed according to the schema.  Numeric and bool columns
+// become JSON numbers and booleans, TimeType columns become RFC 3339 strings,
+// and empty fields become null.
+//
+// If schema is nil, every column is converted as a StringType column named
+// after the header (or "column1", "column2", etc. if there is no header).
+func (me *Reader) ToJSONLines(r io.Reader, schema *Schema, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, 32*1024)
+	var buf []byte
+	var keys [][]byte
+
+	nextRecord := func(i int, record *Record) error {
+		if keys == nil {
+			for _, col := range record.Schema().Columns {
+				keys = append(keys, appendJSONString(nil, col.Name))
+			}
+		}
+
+		buf = append(buf[:0], '{')
+		for col := range keys {
+			if col > 0 {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, keys[col]...)
+			buf = append(buf, ':')
+			buf = appendJSONValue(buf, record, col)
+		}
+		buf = append(buf, '}', '\n')
+
+		_, err := bw.Write(buf)
+		return err
+	}
+
+	var err error
+	if schema != nil {
+		err = me.ReadWithSchema(r, schema, nextRecord)
+	} else {
+		var record *Record
+		err = me.Read(r, func(i int, fields []Field) error {
+			if record == nil {
+				schema := NewSchema()
+				for col := range fields {
+					name := fmt.Sprintf("column%v", col+1)
+					if me.header != nil {
+						name = me.header[col]
+					}
+					schema.Columns = append(schema.Columns, StringColumn(name))
+				}
+				record = &Record{schema: schema, values: make([]value, len(fields))}
+			}
+			record.fields = fields
+			for col := range fields {
+				if err := record.parse(col); err != nil {
+					return err
+				}
+			}
+			return nextRecord(i, record)
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// Appends the JSON representation of a column's value to buf.
+func appendJSONValue(buf []byte, record *Record, col int) []byte {
+	if record.IsNull(col) {
+		return append(buf, "null"...)
+	}
+
+	switch record.Schema().Columns[col].Type {
+	case Uint32Type:
+		return strconv.AppendUint(buf, uint64(record.Uint32(col)), 10)
+	case Int64Type:
+		return strconv.AppendInt(buf, record.Int64(col), 10)
+	case Float32Type, Float64Type:
+		bitSize := 64
+		if record.Schema().Columns[col].Type == Float32Type {
+			bitSize = 32
+		}
+		f := record.Float64(col)
+		if math.IsNaN(f) || math.IsInf(f, 0) { // not representable in JSON
+			return append(buf, "null"...)
+		}
+		return strconv.AppendFloat(buf, f, 'g', -1, bitSize)
+	case BoolType:
+		return strconv.AppendBool(buf, record.Bool(col))
+	case TimeType:
+		return appendJSONString(buf, record.Time(col).Format(time.RFC3339Nano))
+	default:
+		return appendJSONString(buf, record.Field(col).unsafeString())
+	}
+}
+
+const hexDigits = "0123456789abcdef"
+
+// Appends s to buf as a quoted JSON string.  Invalid UTF-8 is replaced by
+// U+FFFD, like encoding/json does.
+func appendJSONString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf = append(buf, '\\', c)
+			case c == '\n':
+				buf = append(buf, '\\', 'n')
+			case c == '\r':
+				buf = append(buf, '\\', 'r')
+			case c == '\t':
+				buf = append(buf, '\\', 't')
+			case c < 0x20:
+				buf = append(buf, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			default:
+				buf = append(buf, c)
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, "\uFFFD"...)
+		} else {
+			buf = append(buf, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,82 @@
+package hastycsv
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestToJSONLines(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := ToJSONLines(strings.NewReader("name,note\nbill,\"quoted\"\\ \tx\nmary,"), nil, out)
+	require.Nil(t, err)
+	assert.Equal(t, `{"name":"bill","note":"\"quoted\"\\ \tx"}`+"\n"+`{"name":"mary","note":null}`+"\n", out.String())
+
+	out.Reset()
+	err = ToJSONLines(strings.NewReader("bill,30\n"), []string{"name", "age"}, out)
+	require.Nil(t, err)
+	assert.Equal(t, `{"name":"bill","age":"30"}`+"\n", out.String())
+}
+
+func TestReader_ToJSONLines(t *testing.T) {
+	in := strings.NewReader("" +
+		"name|age|balance|ratio|active|joined|ignored\n" +
+		"bill|30|-12|0.5|true|2020-01-02|x\n" +
+		"mary||7|NaN|false||y\n")
+
+	schema := NewSchema(
+		StringColumn("name"),
+		Uint32Column("age"),
+		Int64Column("balance"),
+		Float64Column("ratio"),
+		BoolColumn("active"),
+		TimeColumn("joined", "2006-01-02"),
+	)
+
+	r := NewReader()
+	r.Comma = '|'
+	r.HasHeader = true
+	out := &bytes.Buffer{}
+	require.Nil(t, r.ToJSONLines(in, schema, out))
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Equal(t, 2, len(lines))
+	assert.Equal(t, `{"name":"bill","age":30,"balance":-12,"ratio":0.5,"active":true,"joined":"2020-01-02T00:00:00Z"}`, lines[0])
+	assert.Equal(t, `{"name":"mary","age":null,"balance":7,"ratio":null,"active":false,"joined":null}`, lines[1])
+
+	for _, line := range lines {
+		var v map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &v), line)
+	}
+
+	err := r.ToJSONLines(strings.NewReader("name|age\nbill|x\n"), NewSchema(Uint32Column("age")), out)
+	assert.EqualError(t, err, `Line 2, column 2: Can't parse column "age" as uint32: "x"`)
+}
+
+func TestReader_ToJSONLines_noHeader(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.Nil(t, NewReader().ToJSONLines(strings.NewReader("a,b\n"), nil, out))
+	assert.Equal(t, `{"column1":"a","column2":"b"}`+"\n", out.String())
+}
+
+func TestAppendJSONString(t *testing.T) {
+	testCases := map[string]string{
+		"":            `""`,
+		"abc":         `"abc"`,
+		"\"\\":        `"\"\\"`,
+		"a\nb\rc\x01": `"a\nb\rc\u0001"`,
+		"héllo 世界":    `"héllo 世界"`,
+		"bad\xffutf8": "\"bad\uFFFDutf8\"",
+	}
+
+	for in, expected := range testCases {
+		actual := string(appendJSONString(nil, in))
+		assert.Equal(t, expected, actual)
+
+		var decoded string
+		assert.Nil(t, json.Unmarshal([]byte(actual), &decoded))
+	}
+}
@@ -0,0 +1,99 @@
+package hastycsv
+
+import (
+	"bytes"
+	"io"
+)
+
+// Line buffers larger than this are not returned to lineBufferPool.
+const maxPooledLineBufferSize = 1 << 20
+
+// Splits an input stream into lines.  Unlike bufio.Scanner, lines of any
+// length are supported: the buffer grows as needed to hold the longest line.
+// Lines are returned as slices of the buffer, so no bytes are copied except
+// when unconsumed data is moved to the front of the buffer before a refill.
+type lineReader struct {
+	r        io.Reader
+	buf      []byte
+	start    int   // start of the unconsumed data in buf
+	searched int   // end of the unconsumed data already searched for a newline
+	end      int   // end of the valid data in buf
+	consumed int64 // input offset of buf[start]
+	offset   int64 // input offset of the line most recently returned by readLine()
+	err      error // error returned by r, e.g. io.EOF
+}
+
+// Prepares this lineReader to read from r using the specified buffer.
+func (me *lineReader) init(r io.Reader, buf []byte) {
+	*me = lineReader{r: r, buf: buf[:cap(buf)]}
+}
+
+// Returns the next line, without its line terminator ("\n" or "\r\n").  The
+// line is only valid until the next call.  Returns io.EOF once all lines have
+// been read, or any other error returned by the underlying reader.
+func (me *lineReader) readLine() ([]byte, error) {
+	for {
+		if idx := bytes.IndexByte(me.buf[me.searched:me.end], '\n'); idx >= 0 {
+			lineEnd := me.searched + idx
+			return me.consume(lineEnd, lineEnd+1), nil
+		}
+		me.searched = me.end
+
+		if me.err == io.EOF {
+			if me.start < me.end { // final line without a line terminator
+				return me.consume(me.end, me.end), nil
+			}
+			return nil, io.EOF
+		} else if me.err != nil {
+			return nil, me.err
+		}
+
+		me.fill()
+	}
+}
+
+// Returns buf[start:lineEnd] as a line (minus a trailing '\r'), and marks the
+// input up to next as consumed.
+func (me *lineReader) consume(lineEnd int, next int) []byte {
+	line := me.buf[me.start:lineEnd]
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+
+	me.offset = me.consumed
+	me.consumed += int64(next - me.start)
+	me.start, me.searched = next, next
+	return line
+}
+
+// Reads more data into the buffer, first making room by moving unconsumed data
+// to the front of the buffer, or by growing the buffer if it's full of
+// unconsumed data.
+func (me *lineReader) fill() {
+	if me.start > 0 {
+		n := copy(me.buf, me.buf[me.start:me.end])
+		me.searched -= me.start
+		me.start, me.end = 0, n
+	}
+	if me.end == len(me.buf) {
+		size := 2 * len(me.buf)
+		if size == 0 {
+			size = 4096
+		}
+		buf := make([]byte, size)
+		copy(buf, me.buf[:me.end])
+		me.buf = buf
+	}
+
+	for i := 0; i < 100; i++ {
+		n, err := me.r.Read(me.buf[me.end:])
+		me.end += n
+		if err != nil {
+			me.err = err
+			return
+		} else if n > 0 {
+			return
+		}
+	}
+	me.err = io.ErrNoProgress
+}
@@ -0,0 +1,77 @@
+package hastycsv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLineReader_readLine(t *testing.T) {
+	in := "abc\r\n\ndefg\nlast"
+	for _, bufSize := range []int{0, 1, 2, 64} {
+		var lr lineReader
+		lr.init(iotest.HalfReader(strings.NewReader(in)), make([]byte, bufSize))
+
+		lines := []string{}
+		offsets := []int64{}
+		for {
+			line, err := lr.readLine()
+			if err == io.EOF {
+				break
+			}
+			assert.Nil(t, err)
+			lines = append(lines, string(line))
+			offsets = append(offsets, lr.offset)
+		}
+
+		assert.Equal(t, []string{"abc", "", "defg", "last"}, lines, "bufSize=%v", bufSize)
+		assert.Equal(t, []int64{0, 5, 6, 11}, offsets, "bufSize=%v", bufSize)
+	}
+}
+
+func TestLineReader_readLine_longLine(t *testing.T) {
+	longLine := strings.Repeat("x", 200*1024)
+
+	var lr lineReader
+	lr.init(strings.NewReader(longLine+"\nshort\n"), make([]byte, 64*1024))
+
+	line, err := lr.readLine()
+	assert.Nil(t, err)
+	assert.Equal(t, longLine, string(line))
+
+	line, err = lr.readLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "short", string(line))
+
+	_, err = lr.readLine()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestLineReader_readLine_error(t *testing.T) {
+	var lr lineReader
+	lr.init(io.MultiReader(strings.NewReader("a\nb"), iotest.ErrReader(fmt.Errorf("Disk error"))), nil)
+
+	line, err := lr.readLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "a", string(line))
+
+	_, err = lr.readLine()
+	assert.EqualError(t, err, "Disk error")
+}
+
+func TestReader_Read_longLine(t *testing.T) {
+	longValue := strings.Repeat("y", 100*1024)
+	in := strings.NewReader("a,b\n" + longValue + ",c\n")
+
+	values := []string{}
+	err := NewReader().Read(in, func(i int, fields []Field) error {
+		values = append(values, fields[0].String())
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", longValue}, values)
+}
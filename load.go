@@ -0,0 +1,255 @@
+package hastycsv
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The number of rows inserted per INSERT statement by LoadIntoDB() if none is
+// specified.
+const DefaultLoadBatchSize = 500
+
+// Identifies how LoadIntoDB() handles records whose fields can't be parsed.
+type LoadErrorPolicy int
+
+const (
+	// Stop loading at the first record that can't be parsed, roll back
+	// everything inserted so far, and return the record's error.
+	AbortOnError LoadErrorPolicy = iota
+
+	// Skip records that can't be parsed and load the rest.  The errors of the
+	// skipped records are returned in LoadResult.Skipped.
+	SkipInvalidRecords
+)
+
+// Identifies the bind parameter syntax of a database driver.
+type PlaceholderStyle int
+
+const (
+	// "?" placeholders, as used by MySQL and SQLite.
+	QuestionPlaceholders PlaceholderStyle = iota
+
+	// "$1", "$2", ... placeholders, as used by PostgreSQL.
+	DollarPlaceholders
+)
+
+type loadConfig struct {
+	reader       *Reader
+	batchSize    int
+	errorPolicy  LoadErrorPolicy
+	placeholders PlaceholderStyle
+}
+
+// Configures LoadIntoDB().
+type LoadOption func(cfg *loadConfig)
+
+// Returns a LoadOption that makes LoadIntoDB() parse its input using the
+// specified Reader, e.g. to set its Comma delimiter.  By default, a Reader
+// returned by NewReader() with HasHeader set to true is used.
+func WithLoadReader(reader *Reader) LoadOption {
+	return func(cfg *loadConfig) {
+		cfg.reader = reader
+	}
+}
+
+// Returns a LoadOption that sets the maximum number of rows inserted per
+// INSERT statement.  Keep rows * (number of schema columns) within the
+// driver's limit on bind parameters per statement.
+func WithLoadBatchSize(rows int) LoadOption {
+	return func(cfg *loadConfig) {
+		cfg.batchSize = rows
+	}
+}
+
+// Returns a LoadOption that sets how records that can't be parsed are handled
+// (AbortOnError by default).
+func WithLoadErrorPolicy(policy LoadErrorPolicy) LoadOption {
+	return func(cfg *loadConfig) {
+		cfg.errorPolicy = policy
+	}
+}
+
+// Returns a LoadOption that sets the bind parameter syntax used in the INSERT
+// statements (QuestionPlaceholders by default).
+func WithPlaceholderStyle(style PlaceholderStyle) LoadOption {
+	return func(cfg *loadConfig) {
+		cfg.placeholders = style
+	}
+}
+
+// Describes the outcome of LoadIntoDB().
+type LoadResult struct {
+	// The number of rows inserted.
+	Inserted int64
+
+	// The errors of the records skipped under the SkipInvalidRecords policy.
+	Skipped []error
+}
+
+// Reads records from r, parses them according to schema, and inserts them into
+// the specified table of db.  Rows are batched into multi-row INSERT
+// statements, e.g. "INSERT INTO people (name, age) VALUES (?, ?), (?, ?)",
+// whose column names are those of the schema.  The table and column names are
+// inserted into the statements verbatim, so quote them if necessary.
+//
+// All rows are inserted in a single transaction.  If anything fails (including
+// a record that can't be parsed under the AbortOnError policy), the
+// transaction is rolled back and the error is returned; the returned
+// LoadResult then describes the rows that were rolled back.
+//
+// Null (empty) fields are inserted as NULL.  Float32 values are inserted as
+// float64s, and uint32 values as int64s.
+func LoadIntoDB(ctx context.Context, db *sql.DB, table string, schema *Schema, r io.Reader, options ...LoadOption) (*LoadResult, error) {
+	cfg := loadConfig{batchSize: DefaultLoadBatchSize}
+	for _, option := range options {
+		option(&cfg)
+	}
+	if cfg.reader == nil {
+		cfg.reader = NewReader()
+		cfg.reader.HasHeader = true
+	}
+	if cfg.batchSize < 1 {
+		cfg.batchSize = DefaultLoadBatchSize
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LoadResult{}
+	if err := loadRows(ctx, tx, table, schema, r, &cfg, result); err != nil {
+		tx.Rollback()
+		return result, err
+	}
+	return result, tx.Commit()
+}
+
+// Inserts the records of r into table using tx.
+func loadRows(ctx context.Context, tx *sql.Tx, table string, schema *Schema, r io.Reader, cfg *loadConfig, result *LoadResult) error {
+	columns := len(schema.Columns)
+	args := make([]interface{}, 0, cfg.batchSize*columns)
+
+	var batchStmt *sql.Stmt // prepared on first use for full batches
+	defer func() {
+		if batchStmt != nil {
+			batchStmt.Close()
+		}
+	}()
+
+	flush := func() error {
+		if len(args) == 0 {
+			return nil
+		}
+
+		rows := len(args) / columns
+		var err error
+		if rows == cfg.batchSize {
+			if batchStmt == nil {
+				if batchStmt, err = tx.PrepareContext(ctx, insertStatement(table, schema, rows, cfg.placeholders)); err != nil {
+					return err
+				}
+			}
+			_, err = batchStmt.ExecContext(ctx, args...)
+		} else {
+			_, err = tx.ExecContext(ctx, insertStatement(table, schema, rows, cfg.placeholders), args...)
+		}
+		if err != nil {
+			return err
+		}
+
+		result.Inserted += int64(rows)
+		args = args[:0]
+		return nil
+	}
+
+	var onParseError func(err error) error
+	if cfg.errorPolicy == SkipInvalidRecords {
+		onParseError = func(err error) error {
+			result.Skipped = append(result.Skipped, err)
+			return nil
+		}
+	}
+
+	err := cfg.reader.readWithSchema(r, schema, onParseError, func(i int, record *Record) error {
+		for col := 0; col < columns; col++ {
+			args = append(args, loadValue(record, col))
+		}
+		if len(args) == cfg.batchSize*columns {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return flush()
+}
+
+// Returns an INSERT statement with bind parameters for the specified number of
+// rows.
+func insertStatement(table string, schema *Schema, rows int, placeholders PlaceholderStyle) string {
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(table)
+	sb.WriteString(" (")
+	for i, col := range schema.Columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(col.Name)
+	}
+	sb.WriteString(") VALUES ")
+
+	param := 0
+	for row := 0; row < rows; row++ {
+		if row > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteByte('(')
+		for i := range schema.Columns {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			param++
+			if placeholders == DollarPlaceholders {
+				sb.WriteByte('$')
+				sb.WriteString(strconv.Itoa(param))
+			} else {
+				sb.WriteByte('?')
+			}
+		}
+		sb.WriteByte(')')
+	}
+	return sb.String()
+}
+
+// Returns the value of the specified column of record as a database/sql
+// argument.
+func loadValue(record *Record, col int) interface{} {
+	if record.IsNull(col) {
+		return nil
+	}
+
+	switch t := record.schema.Columns[col].Type; t {
+	case StringType:
+		return record.String(col)
+	case Uint32Type:
+		return int64(record.Uint32(col))
+	case Int64Type:
+		return record.Int64(col)
+	case Float32Type, Float64Type:
+		return record.Float64(col)
+	case BoolType:
+		return record.Bool(col)
+	case TimeType:
+		return record.Time(col)
+	default:
+		panic(fmt.Sprintf("Unsupported column type %v", t))
+	}
+}
@@ -0,0 +1,203 @@
+package hastycsv
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadIntoDB(t *testing.T) {
+	db, log := openLoadTestDB(t)
+
+	in := "" +
+		"name,age,score,joined,extra\n" +
+		"bill,30,1.5,2020-01-02,x\n" +
+		"mary,,2,2021-03-04,y\n" +
+		"joe,50,,2022-05-06,z\n"
+	schema := NewSchema(StringColumn("name"), Uint32Column("age"), Float64Column("score"), TimeColumn("joined", "2006-01-02"))
+
+	result, err := LoadIntoDB(context.Background(), db, "people", schema, strings.NewReader(in), WithLoadBatchSize(2))
+	require.Nil(t, err)
+	assert.Equal(t, int64(3), result.Inserted)
+	assert.Equal(t, 0, len(result.Skipped))
+
+	assert.Equal(t, []string{
+		"BEGIN",
+		"INSERT INTO people (name, age, score, joined) VALUES (?, ?, ?, ?), (?, ?, ?, ?)" +
+			fmt.Sprint([]interface{}{"bill", int64(30), 1.5, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), "mary", nil, 2.0, time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)}),
+		"INSERT INTO people (name, age, score, joined) VALUES (?, ?, ?, ?)" +
+			fmt.Sprint([]interface{}{"joe", int64(50), nil, time.Date(2022, 5, 6, 0, 0, 0, 0, time.UTC)}),
+		"COMMIT",
+	}, log.entries())
+}
+
+func TestLoadIntoDB_reusesBatchStatement(t *testing.T) {
+	db, log := openLoadTestDB(t)
+
+	in := "a\n1\n2\n3\n4\n"
+	result, err := LoadIntoDB(context.Background(), db, "t", NewSchema(Int64Column("a")), strings.NewReader(in), WithLoadBatchSize(2))
+	require.Nil(t, err)
+	assert.Equal(t, int64(4), result.Inserted)
+	assert.Equal(t, 1, log.prepares)
+	assert.Equal(t, 4, len(log.entries())) // BEGIN, 2 INSERTs, COMMIT
+}
+
+func TestLoadIntoDB_dollarPlaceholders(t *testing.T) {
+	db, log := openLoadTestDB(t)
+
+	reader := NewReader()
+	reader.Comma = '|'
+	_, err := LoadIntoDB(context.Background(), db, `"t"`, NewSchema(StringColumn("a"), BoolColumn("b")), strings.NewReader("x|true\ny|false"),
+		WithLoadReader(reader), WithPlaceholderStyle(DollarPlaceholders))
+	require.Nil(t, err)
+	assert.Equal(t, `INSERT INTO "t" (a, b) VALUES ($1, $2), ($3, $4)[x true y false]`, log.entries()[1])
+}
+
+func TestLoadIntoDB_abortOnError(t *testing.T) {
+	db, log := openLoadTestDB(t)
+
+	in := "a\n1\n2\nx\n4\n"
+	result, err := LoadIntoDB(context.Background(), db, "t", NewSchema(Uint32Column("a")), strings.NewReader(in), WithLoadBatchSize(2))
+	assert.EqualError(t, err, `Line 4, column 1: Can't parse column "a" as uint32: "x"`)
+	assert.Equal(t, int64(2), result.Inserted)
+	assert.Equal(t, "ROLLBACK", log.entries()[len(log.entries())-1])
+}
+
+func TestLoadIntoDB_skipInvalidRecords(t *testing.T) {
+	db, log := openLoadTestDB(t)
+
+	in := "a\n1\nx\n3\ny\n"
+	result, err := LoadIntoDB(context.Background(), db, "t", NewSchema(Uint32Column("a")), strings.NewReader(in), WithLoadErrorPolicy(SkipInvalidRecords))
+	require.Nil(t, err)
+	assert.Equal(t, int64(2), result.Inserted)
+	require.Equal(t, 2, len(result.Skipped))
+	assert.EqualError(t, result.Skipped[0], `Line 3, column 1: Can't parse column "a" as uint32: "x"`)
+	assert.EqualError(t, result.Skipped[1], `Line 5, column 1: Can't parse column "a" as uint32: "y"`)
+	assert.Equal(t, []string{"BEGIN", "INSERT INTO t (a) VALUES (?), (?)[1 3]", "COMMIT"}, log.entries())
+}
+
+func TestLoadIntoDB_execError(t *testing.T) {
+	db, log := openLoadTestDB(t)
+	log.failExec = fmt.Errorf("Disk full")
+
+	_, err := LoadIntoDB(context.Background(), db, "t", NewSchema(StringColumn("a")), strings.NewReader("a\nx\n"))
+	assert.EqualError(t, err, "Disk full")
+	assert.Equal(t, "ROLLBACK", log.entries()[len(log.entries())-1])
+}
+
+// A database/sql driver that records the statements executed through it.
+type loadTestDriver struct{}
+
+var (
+	loadTestLogsMu sync.Mutex
+	loadTestLogs   = map[string]*loadTestLog{}
+)
+
+func init() {
+	sql.Register("hastycsv-loadtest", loadTestDriver{})
+}
+
+type loadTestLog struct {
+	mu       sync.Mutex
+	log      []string
+	prepares int
+	failExec error
+}
+
+func (me *loadTestLog) add(entry string) {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	me.log = append(me.log, entry)
+}
+
+func (me *loadTestLog) entries() []string {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	return append([]string(nil), me.log...)
+}
+
+func (loadTestDriver) Open(name string) (driver.Conn, error) {
+	loadTestLogsMu.Lock()
+	defer loadTestLogsMu.Unlock()
+	return &loadTestConn{log: loadTestLogs[name]}, nil
+}
+
+type loadTestConn struct {
+	log *loadTestLog
+}
+
+func (me *loadTestConn) Prepare(query string) (driver.Stmt, error) {
+	me.log.mu.Lock()
+	me.log.prepares++
+	me.log.mu.Unlock()
+	return &loadTestStmt{log: me.log, query: query}, nil
+}
+
+func (me *loadTestConn) Close() error {
+	return nil
+}
+
+func (me *loadTestConn) Begin() (driver.Tx, error) {
+	me.log.add("BEGIN")
+	return loadTestTx{log: me.log}, nil
+}
+
+type loadTestTx struct {
+	log *loadTestLog
+}
+
+func (me loadTestTx) Commit() error {
+	me.log.add("COMMIT")
+	return nil
+}
+
+func (me loadTestTx) Rollback() error {
+	me.log.add("ROLLBACK")
+	return nil
+}
+
+type loadTestStmt struct {
+	log   *loadTestLog
+	query string
+}
+
+func (me *loadTestStmt) Close() error {
+	return nil
+}
+
+func (me *loadTestStmt) NumInput() int {
+	return -1
+}
+
+func (me *loadTestStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if me.log.failExec != nil {
+		return nil, me.log.failExec
+	}
+	me.log.add(me.query + fmt.Sprint(args))
+	return driver.RowsAffected(1), nil
+}
+
+func (me *loadTestStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, fmt.Errorf("Not supported")
+}
+
+// Test helper that opens a database whose driver records the statements
+// executed through it.
+func openLoadTestDB(t *testing.T) (*sql.DB, *loadTestLog) {
+	log := &loadTestLog{}
+	loadTestLogsMu.Lock()
+	loadTestLogs[t.Name()] = log
+	loadTestLogsMu.Unlock()
+
+	db, err := sql.Open("hastycsv-loadtest", t.Name())
+	require.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db, log
+}
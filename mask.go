@@ -0,0 +1,99 @@
+package hastycsv
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"unicode/utf8"
+)
+
+// Definition of a function that masks a value for MaskColumns(): it appends
+// the masked form of value to dst and returns the extended buffer.
+type Masker func(dst, value []byte) []byte
+
+// Returns a Masker that replaces values with the hex-encoded HMAC-SHA256 of
+// the value under key.  Equal values yield equal hashes, so masked columns can
+// still be joined and counted, but values can't be recovered (or guessed by
+// hashing candidates) without the key.
+func HashMask(key []byte) Masker {
+	var mac hash.Hash
+	var sum []byte
+	return func(dst, value []byte) []byte {
+		if mac == nil {
+			mac = hmac.New(sha256.New, key)
+		}
+		mac.Reset()
+		mac.Write(value)
+		sum = mac.Sum(sum[:0])
+
+		n := len(dst)
+		dst = append(dst, make([]byte, hex.EncodedLen(len(sum)))...)
+		hex.Encode(dst[n:], sum)
+		return dst
+	}
+}
+
+// Returns a Masker that keeps the first n characters (runes) of values.
+func TruncateMask(n int) Masker {
+	return func(dst, value []byte) []byte {
+		end := 0
+		for i := 0; i < n && end < len(value); i++ {
+			_, size := utf8.DecodeRune(value[end:])
+			end += size
+		}
+		return append(dst, value[:end]...)
+	}
+}
+
+// Returns a Masker that replaces values with replacement, e.g. "REDACTED".
+func RedactMask(replacement string) Masker {
+	return func(dst, value []byte) []byte {
+		return append(dst, replacement...)
+	}
+}
+
+// The masking of chosen columns by MaskColumns().
+type ColumnMask struct {
+	// Columns holds the (0-based) indexes of the masked columns.
+	Columns []int
+
+	Masker Masker
+}
+
+// Streams the records of src to dst, masking the specified columns (see
+// MaskColumns()).  The first record of src must be a header, which is written
+// as is.
+func Mask(dst *Writer, src io.Reader, masks ...ColumnMask) error {
+	return Copy(dst, src, MaskColumns(true, masks...))
+}
+
+// Returns a Transform for Copy() that masks the specified columns of each
+// record, so that PII doesn't leave the process that reads the input.  Empty
+// fields are left empty.  If hasHeader is true, the first record is treated as
+// a header and left as is.
+func MaskColumns(hasHeader bool, masks ...ColumnMask) Transform {
+	var bufs [][]byte // a buffer per masked field of the current record
+	return func(i int, record []Field) ([]Field, error) {
+		if hasHeader && i == 1 {
+			return record, nil
+		}
+
+		b := 0
+		for _, mask := range masks {
+			for _, j := range mask.Columns {
+				if j < 0 || j >= len(record) || record[j].IsEmpty() {
+					continue
+				}
+				if b == len(bufs) {
+					bufs = append(bufs, nil)
+				}
+				bufs[b] = mask.Masker(bufs[b][:0], record[j].data)
+				record[j] = NewField(bufs[b])
+				b++
+			}
+		}
+		return record, nil
+	}
+}
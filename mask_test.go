@@ -0,0 +1,49 @@
+package hastycsv
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	in := strings.NewReader("email,name,ssn,zip\nbill@x.com,Bill,123-45-6789,94107\nmary@y.com,Zoë Smith,,10001\nbill@x.com,Bill,987,94110\n")
+	key := []byte("secret")
+
+	var buf bytes.Buffer
+	err := Mask(NewWriter(&buf), in,
+		ColumnMask{Columns: []int{0}, Masker: HashMask(key)},
+		ColumnMask{Columns: []int{1, 3}, Masker: TruncateMask(3)},
+		ColumnMask{Columns: []int{2}, Masker: RedactMask("***")})
+	assert.Nil(t, err)
+
+	bill, mary := hmacHex(key, "bill@x.com"), hmacHex(key, "mary@y.com")
+	assert.Equal(t, "email,name,ssn,zip\n"+
+		bill+",Bil,***,941\n"+
+		mary+",Zoë,,100\n"+
+		bill+",Bil,***,941\n", buf.String())
+}
+
+func TestMaskColumns(t *testing.T) {
+	in := strings.NewReader("a|b\n")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Comma = '|'
+	// Masking the same column twice applies both masks
+	assert.Nil(t, Copy(w, in, MaskColumns(false,
+		ColumnMask{Columns: []int{0, 1}, Masker: RedactMask("xyz")},
+		ColumnMask{Columns: []int{1}, Masker: TruncateMask(1)})))
+	assert.Equal(t, "xyz|x\n", buf.String())
+}
+
+// Test helper
+func hmacHex(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
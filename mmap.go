@@ -0,0 +1,21 @@
+package hastycsv
+
+// Reads records from the specified CSV file like ReadFile(), but memory-maps
+// the file and parses it directly from the mapping instead of copying it
+// through a read buffer.  Each Field's byte slice points into the mapping.
+//
+// The mapping is released when ReadFileMmap() returns, so fields must not be
+// retained beyond the nextRecord callback (copy them, e.g. via String(), if
+// needed).  On platforms without mmap support, the file is read into memory
+// instead.
+func ReadFileMmap(csvFilePath string, comma byte, nextRecord Next) error {
+	data, unmap, err := mapFile(csvFilePath)
+	if err != nil {
+		return err
+	}
+	defer unmap()
+
+	r := NewReader()
+	r.Comma = comma
+	return r.ReadBytes(data, nextRecord)
+}
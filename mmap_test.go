@@ -0,0 +1,60 @@
+package hastycsv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFileMmap(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "people.csv")
+	require.Nil(t, ioutil.WriteFile(path, []byte("MARY|jones|35\r\nbill|anderson|40"), 0644))
+
+	received := []string{}
+	err := ReadFileMmap(path, '|', func(i int, rec []Field) error {
+		assert.Equal(t, 3, len(rec))
+		received = append(received, rec[0].ToLower().String()+":"+rec[2].String())
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mary:35", "bill:40"}, received)
+
+	// In-place edits of fields must not modify the file.
+	data, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	assert.Equal(t, "MARY|jones|35\r\nbill|anderson|40", string(data))
+}
+
+func TestReadFileMmap_emptyFile(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "empty.csv")
+	require.Nil(t, ioutil.WriteFile(path, nil, 0644))
+
+	err := ReadFileMmap(path, ',', func(i int, rec []Field) error {
+		assert.Fail(t, "unexpected record")
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func TestReadFileMmap_errors(t *testing.T) {
+	err := ReadFileMmap("NONEXISTENT_FILE.TXT", ',', func(i int, rec []Field) error { return nil })
+	assert.NotNil(t, err)
+
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "bad.csv")
+	require.Nil(t, ioutil.WriteFile(path, []byte("a,b\nc\n"), 0644))
+	err = ReadFileMmap(path, ',', func(i int, rec []Field) error { return nil })
+	assert.EqualError(t, err, `Line 2: Expected []b to contain 2 fields using delimiter ',': "c"`)
+}
@@ -0,0 +1,7 @@
+//go:build !race
+
+package hastycsv
+
+// True if the race detector is enabled, which makes allocation counts
+// unreliable.
+const raceEnabled = false
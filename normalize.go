@@ -0,0 +1,84 @@
+package hastycsv
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// An io.Reader that normalizes the line endings and trailing whitespace of an
+// underlying reader.
+type normalizingReader struct {
+	r       *bufio.Reader
+	chunk   []byte
+	out     []byte // normalized output
+	pos     int    // the position within out of the output not yet returned
+	spaces  []byte // whitespace that's only output if the line continues
+	started bool   // whether the BOM has been checked for
+	afterCR bool   // whether the last byte read was a '\r'
+	err     error
+}
+
+// Returns an io.Reader that reads from r and normalizes its content into
+// canonical input, for the fast splitter and for comparing files byte by byte:
+// a leading UTF-8 byte order mark is dropped, "\r\n" and lone "\r" line
+// endings are converted to "\n", and spaces and tabs at the end of every line
+// are stripped.  Note that this also applies to the lines of quoted fields
+// that span several lines.
+func NewNormalizingReader(r io.Reader) io.Reader {
+	return &normalizingReader{r: bufio.NewReaderSize(r, 32*1024), chunk: make([]byte, 32*1024)}
+}
+
+// Copies src to dst, normalized as described by NewNormalizingReader(), and
+// returns the number of bytes written.
+func Normalize(dst io.Writer, src io.Reader) (int64, error) {
+	return io.Copy(dst, NewNormalizingReader(src))
+}
+
+func (me *normalizingReader) Read(p []byte) (int, error) {
+	if !me.started {
+		me.started = true
+		if prefix, _ := me.r.Peek(3); bytes.Equal(prefix, []byte("\xEF\xBB\xBF")) {
+			me.r.Discard(3)
+		}
+	}
+
+	for me.pos == len(me.out) && me.err == nil {
+		me.out, me.pos = me.out[:0], 0
+		n, err := me.r.Read(me.chunk)
+		me.normalize(me.chunk[:n])
+		me.err = err
+	}
+
+	n := copy(p, me.out[me.pos:])
+	me.pos += n
+	if n == 0 && len(p) > 0 {
+		return 0, me.err
+	}
+	return n, nil
+}
+
+// Appends the normalized form of data to me.out.
+func (me *normalizingReader) normalize(data []byte) {
+	for _, b := range data {
+		afterCR := me.afterCR
+		me.afterCR = false
+
+		switch b {
+		case ' ', '\t':
+			me.spaces = append(me.spaces, b)
+		case '\r':
+			me.spaces = me.spaces[:0]
+			me.out = append(me.out, '\n')
+			me.afterCR = true
+		case '\n':
+			me.spaces = me.spaces[:0]
+			if !afterCR {
+				me.out = append(me.out, '\n')
+			}
+		default:
+			me.out = append(append(me.out, me.spaces...), b)
+			me.spaces = me.spaces[:0]
+		}
+	}
+}
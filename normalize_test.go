@@ -0,0 +1,55 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestNewNormalizingReader(t *testing.T) {
+	in := "\xEF\xBB\xBFname,age \t\r\nbill, 30\rmary,35  \n\njane,45\t"
+	expected := "name,age\nbill, 30\nmary,35\n\njane,45"
+
+	out, err := ioutil.ReadAll(NewNormalizingReader(strings.NewReader(in)))
+	require.Nil(t, err)
+	assert.Equal(t, expected, string(out))
+
+	// CRLF pairs and whitespace that straddle reads
+	out, err = ioutil.ReadAll(NewNormalizingReader(iotest.OneByteReader(strings.NewReader(in))))
+	require.Nil(t, err)
+	assert.Equal(t, expected, string(out))
+
+	// Only a leading BOM is dropped
+	out, err = ioutil.ReadAll(NewNormalizingReader(strings.NewReader("a\n\xEF\xBB\xBFb\n")))
+	require.Nil(t, err)
+	assert.Equal(t, "a\n\xEF\xBB\xBFb\n", string(out))
+}
+
+func TestNewNormalizingReader_error(t *testing.T) {
+	r := NewNormalizingReader(io.MultiReader(strings.NewReader("a \r"), iotest.ErrReader(io.ErrUnexpectedEOF)))
+	out, err := ioutil.ReadAll(r)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, "a\n", string(out))
+}
+
+func TestNormalize(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := Normalize(&buf, strings.NewReader("a,b \r\nc,d\r\n"))
+	require.Nil(t, err)
+	assert.Equal(t, int64(8), n)
+	assert.Equal(t, "a,b\nc,d\n", buf.String())
+
+	// The normalized input can be parsed by the fast splitter
+	var records []string
+	err = NewReader().Read(NewNormalizingReader(strings.NewReader("x,1 \r\ny,2\r\n")), func(i int, record []Field) error {
+		records = append(records, record[0].String()+record[1].String())
+		return nil
+	})
+	require.Nil(t, err)
+	assert.Equal(t, []string{"x1", "y2"}, records)
+}
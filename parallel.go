@@ -0,0 +1,219 @@
+package hastycsv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Bounds of the size of the chunks that ReadParallel() splits its input into.
+var (
+	minParallelChunkSize int64 = 1 << 20
+	maxParallelChunkSize int64 = 16 << 20
+)
+
+// Returned by a worker's callback wrapper to abandon its chunk once another
+// worker has failed.
+var errParallelReadStopped = errors.New("Parallel read stopped")
+
+// A range of input lines processed by a single ReadParallel() worker.
+type parallelChunk struct {
+	offset  int64
+	length  int64
+	lines   int           // number of lines in this chunk; valid once counted is closed
+	counted chan struct{} // closed once lines has been set
+}
+
+// Reads records from r (whose size in bytes is size) using the specified
+// number of worker goroutines.  If workers < 1, runtime.NumCPU() workers are
+// used.
+//
+// The input is split into chunks at line boundaries, and each chunk is parsed
+// by a worker with its own []Field buffer.  nextRecord is therefore invoked
+// concurrently from multiple goroutines, and records are NOT delivered in
+// input order; i still holds each record's line number.  nextRecord must be
+// safe for concurrent use, and must not retain the fields passed to it.
+//
+// The first error (a parse error or an error returned by nextRecord) stops
+// all workers and is returned.
+func (me *Reader) ReadParallel(r io.ReaderAt, size int64, workers int, nextRecord Next) error {
+	if err := me.reset(); err != nil {
+		return err
+	}
+	defer me.end()
+	if size <= 0 {
+		return nil
+	}
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	// Infer the field count (and read the header) from the first line.
+	firstLineEnd, err := findNewline(r, 0, size)
+	if err != nil {
+		return err
+	}
+	firstLine := make([]byte, firstLineEnd)
+	if _, err := r.ReadAt(firstLine, 0); err != nil && err != io.EOF {
+		return err
+	}
+	firstLine = bytes.TrimSuffix(firstLine, []byte{'\r'})
+
+	dataStart := int64(0)
+	if me.HasHeader {
+		if _, err := me.splitLine(firstLine); err != nil {
+			return err
+		}
+		dataStart = firstLineEnd + 1
+	} else if me.QuoteFallback && bytes.IndexByte(firstLine, '"') >= 0 {
+		fields, err := splitQuoted(firstLine, me.Comma, nil)
+		if err != nil {
+			return fmt.Errorf("Line 1: %w: \"%v\"", err, string(firstLine))
+		}
+		me.initFields(len(fields))
+	} else {
+		me.initFields(bytes.Count(firstLine, []byte{me.Comma}) + 1)
+	}
+	firstRow := me.row
+
+	chunks, err := splitIntoChunks(r, dataStart, size, workers)
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		stopped  int32
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+		atomic.StoreInt32(&stopped, 1)
+	}
+
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			worker := &Reader{Comma: me.Comma, HasHeader: me.HasHeader, QuoteFallback: me.QuoteFallback}
+			worker.header = me.header
+			worker.initFields(len(me.fields))
+			defer worker.end()
+			next := func(i int, fields []Field) error {
+				if atomic.LoadInt32(&stopped) != 0 {
+					return errParallelReadStopped
+				}
+				return nextRecord(i, fields)
+			}
+
+			var buf []byte
+			for k := range jobs {
+				chunk := chunks[k]
+				if int64(cap(buf)) < chunk.length {
+					buf = make([]byte, chunk.length)
+				}
+				data := buf[:chunk.length]
+
+				_, readErr := r.ReadAt(data, chunk.offset)
+				if readErr == io.EOF {
+					readErr = nil
+				}
+				chunk.lines = bytes.Count(data, []byte{'\n'})
+				if len(data) > 0 && data[len(data)-1] != '\n' {
+					chunk.lines++
+				}
+				close(chunk.counted)
+
+				if readErr != nil {
+					fail(readErr)
+					continue
+				}
+
+				// Number this chunk's records after those of all preceding chunks.
+				worker.row = firstRow
+				for j := 0; j < k; j++ {
+					<-chunks[j].counted
+					worker.row += chunks[j].lines
+				}
+
+				worker.clearFieldErr()
+				if err := worker.parseLines(data, next); err != nil && atomic.LoadInt32(&stopped) == 0 {
+					fail(err)
+				}
+			}
+		}()
+	}
+
+	for k := range chunks {
+		if atomic.LoadInt32(&stopped) != 0 {
+			break
+		}
+		jobs <- k
+	}
+	close(jobs)
+	wg.Wait()
+
+	return firstErr
+}
+
+// Splits the byte range [start, size) of r into chunks that end at line
+// boundaries.
+func splitIntoChunks(r io.ReaderAt, start int64, size int64, workers int) ([]*parallelChunk, error) {
+	chunkSize := (size - start) / int64(workers*4)
+	if chunkSize < minParallelChunkSize {
+		chunkSize = minParallelChunkSize
+	} else if chunkSize > maxParallelChunkSize {
+		chunkSize = maxParallelChunkSize
+	}
+
+	chunks := []*parallelChunk{}
+	for offset := start; offset < size; {
+		end := offset + chunkSize
+		if end >= size {
+			end = size
+		} else {
+			newline, err := findNewline(r, end-1, size)
+			if err != nil {
+				return nil, err
+			}
+			end = newline + 1
+			if end > size {
+				end = size
+			}
+		}
+
+		chunks = append(chunks, &parallelChunk{offset: offset, length: end - offset, counted: make(chan struct{})})
+		offset = end
+	}
+
+	return chunks, nil
+}
+
+// Returns the position of the first newline at or after position from, or
+// size if there is none.
+func findNewline(r io.ReaderAt, from int64, size int64) (int64, error) {
+	buf := make([]byte, 64*1024)
+	for pos := from; pos < size; pos += int64(len(buf)) {
+		n, err := r.ReadAt(buf, pos)
+		if idx := bytes.IndexByte(buf[:n], '\n'); idx >= 0 {
+			return pos + int64(idx), nil
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	return size, nil
+}
@@ -0,0 +1,139 @@
+package hastycsv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReader_ReadParallel(t *testing.T) {
+	defer useSmallParallelChunks()()
+
+	lines := []string{}
+	for i := 1; i <= 1000; i++ {
+		lines = append(lines, fmt.Sprintf("name%v|%v", i, i*10))
+	}
+	in := strings.Join(lines, "\n")
+
+	for _, workers := range []int{0, 1, 4} {
+		r := NewReader()
+		r.Comma = '|'
+
+		var mu sync.Mutex
+		received := []string{}
+		err := r.ReadParallel(strings.NewReader(in), int64(len(in)), workers, func(i int, fields []Field) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, fmt.Sprintf("%v:%v|%v", i, fields[0].String(), fields[1].Uint32()))
+			return nil
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, readSequentially(t, r, in), sortedRecords(received))
+	}
+}
+
+func TestReader_ReadParallel_hasHeader(t *testing.T) {
+	defer useSmallParallelChunks()()
+
+	in := "name|age\r\n"
+	for i := 1; i <= 200; i++ {
+		in += fmt.Sprintf("name%v|%v\r\n", i, i)
+	}
+
+	r := NewReader()
+	r.Comma = '|'
+	r.HasHeader = true
+
+	var mu sync.Mutex
+	received := []string{}
+	err := r.ReadParallel(strings.NewReader(in), int64(len(in)), 3, func(i int, fields []Field) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, fmt.Sprintf("%v:%v|%v", i, fields[0].String(), fields[1].Uint32()))
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"name", "age"}, r.Header())
+	assert.Equal(t, 200, len(received))
+	assert.Equal(t, readSequentially(t, r, in), sortedRecords(received))
+}
+
+func TestReader_ReadParallel_emptyInput(t *testing.T) {
+	r := NewReader()
+	err := r.ReadParallel(strings.NewReader(""), 0, 4, func(i int, fields []Field) error {
+		t.Fatal("Callback should not be invoked")
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func TestReader_ReadParallel_parsingError(t *testing.T) {
+	defer useSmallParallelChunks()()
+
+	lines := []string{}
+	for i := 1; i <= 500; i++ {
+		lines = append(lines, fmt.Sprintf("a|%v", i))
+	}
+	lines[299] = "a"
+	in := strings.Join(lines, "\n")
+
+	r := NewReader()
+	r.Comma = '|'
+	err := r.ReadParallel(strings.NewReader(in), int64(len(in)), 4, func(i int, fields []Field) error {
+		return nil
+	})
+	assert.EqualError(t, err, `Line 300: Expected []b to contain 2 fields using delimiter '|': "a"`)
+}
+
+func TestReader_ReadParallel_callbackError(t *testing.T) {
+	defer useSmallParallelChunks()()
+
+	lines := []string{}
+	for i := 1; i <= 500; i++ {
+		lines = append(lines, fmt.Sprintf("a|%v", i))
+	}
+	in := strings.Join(lines, "\n")
+
+	r := NewReader()
+	r.Comma = '|'
+	err := r.ReadParallel(strings.NewReader(in), int64(len(in)), 4, func(i int, fields []Field) error {
+		if fields[1].Uint32() == 123 {
+			return fmt.Errorf("Bad record")
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "Line 123: Bad record")
+}
+
+// Test helper that forces ReadParallel() to split small inputs into many
+// chunks.  Returns a function that restores the original chunk size bounds.
+func useSmallParallelChunks() func() {
+	origMin, origMax := minParallelChunkSize, maxParallelChunkSize
+	minParallelChunkSize, maxParallelChunkSize = 64, 64
+	return func() {
+		minParallelChunkSize, maxParallelChunkSize = origMin, origMax
+	}
+}
+
+// Test helper that reads in using Reader.Read() and returns each record in the
+// same format used by the ReadParallel() tests.
+func readSequentially(t *testing.T, r *Reader, in string) []string {
+	records := []string{}
+	err := r.Read(strings.NewReader(in), func(i int, fields []Field) error {
+		records = append(records, fmt.Sprintf("%v:%v|%v", i, fields[0].String(), fields[1].Uint32()))
+		return nil
+	})
+	assert.Nil(t, err)
+	return sortedRecords(records)
+}
+
+// Test helper
+func sortedRecords(records []string) []string {
+	sort.Strings(records)
+	return records
+}
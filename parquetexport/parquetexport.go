@@ -0,0 +1,129 @@
+// Package parquetexport converts CSV input into Apache Parquet files.
+//
+// Records are parsed according to a hastycsv.Schema into Arrow record batches
+// (see package arrowbatch), and each batch is written out as its own Parquet
+// row group, so memory use is bounded by the row group size rather than by the
+// size of the input.
+package parquetexport
+
+import (
+	"io"
+	"os"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/memory"
+	"github.com/apache/arrow/go/v12/parquet"
+	"github.com/apache/arrow/go/v12/parquet/compress"
+	"github.com/apache/arrow/go/v12/parquet/pqarrow"
+	"github.com/cet001/hastycsv"
+	"github.com/cet001/hastycsv/arrowbatch"
+)
+
+// The number of rows per row group used by Write() if none is specified.
+const DefaultRowGroupSize = arrowbatch.DefaultBatchSize
+
+type config struct {
+	rowGroupSize int
+	compression  compress.Compression
+	mem          memory.Allocator
+}
+
+// Configures Write() and ConvertFile().
+type Option func(cfg *config)
+
+// Returns an Option that sets the maximum number of rows per row group.
+func WithRowGroupSize(rows int) Option {
+	return func(cfg *config) {
+		cfg.rowGroupSize = rows
+	}
+}
+
+// Returns an Option that sets the compression codec used for every column
+// (Snappy by default).
+func WithCompression(codec compress.Compression) Option {
+	return func(cfg *config) {
+		cfg.compression = codec
+	}
+}
+
+// Returns an Option that makes Write() allocate memory from mem instead of
+// memory.DefaultAllocator.
+func WithAllocator(mem memory.Allocator) Option {
+	return func(cfg *config) {
+		cfg.mem = mem
+	}
+}
+
+// Reads records from r using reader, parses them according to schema, and
+// writes them to w as a Parquet file.  Rows are buffered in memory only until
+// a row group is full (see WithRowGroupSize()), at which point the row group
+// is written to w.
+func Write(reader *hastycsv.Reader, r io.Reader, schema *hastycsv.Schema, w io.Writer, options ...Option) error {
+	cfg := config{rowGroupSize: DefaultRowGroupSize, compression: compress.Codecs.Snappy, mem: memory.DefaultAllocator}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	arrowSchema, err := arrowbatch.ArrowSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	props := parquet.NewWriterProperties(
+		parquet.WithAllocator(cfg.mem),
+		parquet.WithCompression(cfg.compression),
+		parquet.WithCreatedBy("hastycsv"),
+	)
+	arrowProps := pqarrow.NewArrowWriterProperties(pqarrow.WithAllocator(cfg.mem), pqarrow.WithStoreSchema())
+	fw, err := pqarrow.NewFileWriter(arrowSchema, nopCloser{w}, props, arrowProps)
+	if err != nil {
+		return err
+	}
+
+	err = arrowbatch.Read(reader, r, schema, cfg.rowGroupSize, cfg.mem, func(batch arrow.Record) error {
+		// Write() starts a new row group for every batch.
+		return fw.Write(batch)
+	})
+	if closeErr := fw.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Converts the CSV file at csvPath into a Parquet file at parquetPath using
+// Write().  Compressed CSV files are decompressed transparently, as by
+// hastycsv.ReadFile().  If the conversion fails, parquetPath is removed.
+func ConvertFile(csvPath string, comma byte, hasHeader bool, schema *hastycsv.Schema, parquetPath string, options ...Option) error {
+	in, err := hastycsv.OpenFile(csvPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(parquetPath)
+	if err != nil {
+		return err
+	}
+
+	reader := hastycsv.NewReader()
+	reader.Comma = comma
+	reader.HasHeader = hasHeader
+
+	err = Write(reader, in, schema, out, options...)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(parquetPath)
+	}
+	return err
+}
+
+// Keeps the parquet writer from closing the caller's io.Writer.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,108 @@
+package parquetexport
+
+import (
+	"bytes"
+	"context"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/memory"
+	"github.com/apache/arrow/go/v12/parquet/file"
+	"github.com/apache/arrow/go/v12/parquet/pqarrow"
+	"github.com/cet001/hastycsv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testSchema = hastycsv.NewSchema(
+	hastycsv.StringColumn("name"),
+	hastycsv.Uint32Column("age"),
+	hastycsv.Float64Column("score"),
+)
+
+const testInput = "" +
+	"name|age|score\n" +
+	"bill|30|1.5\n" +
+	"mary||2\n" +
+	"joe|50|3.25\n"
+
+func TestWrite(t *testing.T) {
+	reader := hastycsv.NewReader()
+	reader.Comma = '|'
+	reader.HasHeader = true
+
+	var out bytes.Buffer
+	err := Write(reader, strings.NewReader(testInput), testSchema, &out, WithRowGroupSize(2))
+	require.Nil(t, err)
+
+	pf, err := file.NewParquetReader(bytes.NewReader(out.Bytes()))
+	require.Nil(t, err)
+	defer pf.Close()
+
+	assert.Equal(t, 2, pf.NumRowGroups())
+	assert.Equal(t, int64(3), pf.NumRows())
+
+	fr, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	require.Nil(t, err)
+	table, err := fr.ReadTable(context.Background())
+	require.Nil(t, err)
+	defer table.Release()
+
+	assert.Equal(t, "age", table.Schema().Field(1).Name)
+	names := table.Column(0).Data().Chunk(0).(*array.String)
+	assert.Equal(t, "bill", names.Value(0))
+	assert.Equal(t, "mary", names.Value(1))
+	assert.True(t, table.Column(1).Data().Chunk(0).IsNull(1))
+	assert.Equal(t, 3.25, table.Column(2).Data().Chunk(0).(*array.Float64).Value(2))
+}
+
+func TestWrite_parsingError(t *testing.T) {
+	reader := hastycsv.NewReader()
+	reader.Comma = '|'
+	reader.HasHeader = true
+
+	var out bytes.Buffer
+	err := Write(reader, strings.NewReader("name|age|score\nbill|x|1\n"), testSchema, &out)
+	assert.EqualError(t, err, `Line 2, column 2: Can't parse column "age" as uint32: "x"`)
+}
+
+func TestConvertFile(t *testing.T) {
+	dir := makeTempDir(t)
+	csvPath := filepath.Join(dir, "in.csv")
+	parquetPath := filepath.Join(dir, "out.parquet")
+	require.Nil(t, ioutil.WriteFile(csvPath, []byte(testInput), 0644))
+
+	err := ConvertFile(csvPath, '|', true, testSchema, parquetPath)
+	require.Nil(t, err)
+
+	f, err := os.Open(parquetPath)
+	require.Nil(t, err)
+	defer f.Close()
+	pf, err := file.NewParquetReader(f)
+	require.Nil(t, err)
+	defer pf.Close()
+	assert.Equal(t, int64(3), pf.NumRows())
+}
+
+func TestConvertFile_removesOutputOnError(t *testing.T) {
+	dir := makeTempDir(t)
+	csvPath := filepath.Join(dir, "in.csv")
+	parquetPath := filepath.Join(dir, "out.parquet")
+	require.Nil(t, ioutil.WriteFile(csvPath, []byte("name|age|score\nbill|x|1\n"), 0644))
+
+	err := ConvertFile(csvPath, '|', true, testSchema, parquetPath)
+	assert.NotNil(t, err)
+	_, err = os.Stat(parquetPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// Test helper
+func makeTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "parquetexport")
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
@@ -0,0 +1,177 @@
+package hastycsv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Writes records in PostgreSQL's COPY text format, e.g. for streaming into
+// "COPY table FROM STDIN".
+//
+// Backslashes, line breaks, tabs and the Delimiter are escaped with
+// backslashes, and nulls are written as the Null marker (\N by default), so
+// any value can be written without corrupting the output.
+type CopyWriter struct {
+	// Delimiter is the column delimiter.  It must match the DELIMITER option of
+	// the COPY command.
+	// It is set to tab ('\t') by NewCopyWriter.
+	Delimiter byte
+
+	// Null is the string written for null values.  It must match the NULL
+	// option of the COPY command.
+	// It is set to `\N` by NewCopyWriter.
+	Null string
+
+	w   *bufio.Writer
+	enc *Encoder // formats struct fields for Encode()
+	buf []byte
+}
+
+// Returns a new CopyWriter that writes tab-delimited rows to w.
+func NewCopyWriter(w io.Writer) *CopyWriter {
+	return &CopyWriter{
+		Delimiter: '\t',
+		Null:      `\N`,
+		w:         bufio.NewWriterSize(w, 32*1024),
+		enc:       NewEncoder(nil),
+	}
+}
+
+// Writes a single row made up of the specified values.  None of them is
+// written as null.
+func (me *CopyWriter) Write(record []string) error {
+	me.buf = me.buf[:0]
+	for i, s := range record {
+		if i > 0 {
+			me.buf = append(me.buf, me.Delimiter)
+		}
+		me.buf = me.appendEscaped(me.buf, s)
+	}
+	return me.writeLine()
+}
+
+// Writes a single row made up of Fields (e.g. fields received from a Reader).
+// Empty fields are written as empty strings, not nulls; use WriteRecord() to
+// write them as nulls.
+func (me *CopyWriter) WriteFields(record []Field) error {
+	me.buf = me.buf[:0]
+	for i, field := range record {
+		if i > 0 {
+			me.buf = append(me.buf, me.Delimiter)
+		}
+		me.buf = me.appendEscaped(me.buf, field.unsafeString())
+	}
+	return me.writeLine()
+}
+
+// Writes a record parsed by Reader.ReadWithSchema() as a single row.  Null
+// (empty) columns are written as nulls, and every other column is written as
+// the raw text of its field.
+func (me *CopyWriter) WriteRecord(record *Record) error {
+	me.buf = me.buf[:0]
+	for col := range record.fields {
+		if col > 0 {
+			me.buf = append(me.buf, me.Delimiter)
+		}
+		if record.IsNull(col) {
+			me.buf = append(me.buf, me.Null...)
+		} else {
+			me.buf = me.appendEscaped(me.buf, record.fields[col].unsafeString())
+		}
+	}
+	return me.writeLine()
+}
+
+// Writes the specified struct (or pointer to a struct) as a single row.
+// Struct fields are formatted as by Encoder, including its `csv` struct tags,
+// except that nil pointer fields are written as nulls.  All values passed to
+// the same CopyWriter must be of the same type.
+func (me *CopyWriter) Encode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return fmt.Errorf("Can't encode a nil %v", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+
+	if err := me.enc.prepare(rv.Type()); err != nil {
+		return err
+	}
+
+	me.buf = me.buf[:0]
+	for i := range me.enc.columns {
+		col := &me.enc.columns[i]
+		if i > 0 {
+			me.buf = append(me.buf, me.Delimiter)
+		}
+
+		fv := rv.Field(col.index)
+		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			me.buf = append(me.buf, me.Null...)
+			continue
+		}
+
+		text, err := col.encode(me.enc.buf[:0], fv)
+		if err != nil {
+			return fmt.Errorf("Can't encode field %v: %w", col.name, err)
+		}
+		me.enc.buf = text
+		me.buf = me.appendEscaped(me.buf, Field{data: text}.unsafeString())
+	}
+	return me.writeLine()
+}
+
+// Writes each element of the specified slice (or array) of structs as a row.
+func (me *CopyWriter) EncodeAll(slice interface{}) error {
+	rv := reflect.ValueOf(slice)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return fmt.Errorf("Expected a slice of structs, but got %T", slice)
+	}
+
+	for i := 0; i < rv.Len(); i++ {
+		if err := me.Encode(rv.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes any buffered data to the underlying io.Writer.
+func (me *CopyWriter) Flush() error {
+	return me.w.Flush()
+}
+
+func (me *CopyWriter) writeLine() error {
+	if me.Delimiter == '\\' || me.Delimiter == '\r' || me.Delimiter == '\n' {
+		return fmt.Errorf(`Delimiter cannot be \, \r or \n`)
+	}
+
+	me.w.Write(me.buf)
+	return me.w.WriteByte('\n')
+}
+
+// Appends s to buf, escaping the characters that COPY's text format treats
+// specially.
+func (me *CopyWriter) appendEscaped(buf []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; ch {
+		case '\\':
+			buf = append(buf, '\\', '\\')
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			if ch == me.Delimiter {
+				buf = append(buf, '\\')
+			}
+			buf = append(buf, ch)
+		}
+	}
+	return buf
+}
@@ -0,0 +1,94 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCopyWriter_Write(t *testing.T) {
+	var out bytes.Buffer
+	w := NewCopyWriter(&out)
+
+	require.Nil(t, w.Write([]string{"plain", `back\slash`, "tab\there", "new\nline", "cr\rhere", `\N`, ""}))
+	require.Nil(t, w.Flush())
+
+	assert.Equal(t, "plain\tback\\\\slash\ttab\\there\tnew\\nline\tcr\\rhere\t\\\\N\t\n", out.String())
+}
+
+func TestCopyWriter_Write_customDelimiter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewCopyWriter(&out)
+	w.Delimiter = '|'
+
+	require.Nil(t, w.Write([]string{"a|b", "c\td"}))
+	require.Nil(t, w.Flush())
+	assert.Equal(t, "a\\|b|c\\td\n", out.String())
+}
+
+func TestCopyWriter_Write_invalidDelimiter(t *testing.T) {
+	w := NewCopyWriter(&bytes.Buffer{})
+	w.Delimiter = '\\'
+	assert.EqualError(t, w.Write([]string{"a"}), `Delimiter cannot be \, \r or \n`)
+}
+
+func TestCopyWriter_WriteFields(t *testing.T) {
+	var out bytes.Buffer
+	w := NewCopyWriter(&out)
+
+	require.Nil(t, w.WriteFields([]Field{makeField(`a\b`), makeField("")}))
+	require.Nil(t, w.Flush())
+	assert.Equal(t, "a\\\\b\t\n", out.String())
+}
+
+func TestCopyWriter_WriteRecord(t *testing.T) {
+	var out bytes.Buffer
+	w := NewCopyWriter(&out)
+
+	r := NewReader()
+	r.Comma = '|'
+	schema := NewSchema(StringColumn("name"), Uint32Column("age"))
+	err := r.ReadWithSchema(strings.NewReader("bill|30\n\t|\n"), schema, func(i int, record *Record) error {
+		return w.WriteRecord(record)
+	})
+	require.Nil(t, err)
+	require.Nil(t, w.Flush())
+
+	assert.Equal(t, "bill\t30\n\\t\t\\N\n", out.String())
+}
+
+func TestCopyWriter_EncodeAll(t *testing.T) {
+	type Person struct {
+		Name    string
+		Age     *int
+		Joined  time.Time `csv:"joined,layout=2006-01-02"`
+		Score   float64   `csv:",prec=1"`
+		Ignored string    `csv:"-"`
+	}
+
+	age := 30
+	people := []Person{
+		{Name: "bill\tjr", Age: &age, Joined: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), Score: 1.25},
+		{Name: "mary", Joined: time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC), Score: 2},
+	}
+
+	var out bytes.Buffer
+	w := NewCopyWriter(&out)
+	require.Nil(t, w.EncodeAll(people))
+	require.Nil(t, w.Flush())
+
+	assert.Equal(t, ""+
+		"bill\\tjr\t30\t2020-01-02\t1.2\n"+
+		"mary\t\\N\t2021-03-04\t2.0\n", out.String())
+}
+
+func TestCopyWriter_Encode_errors(t *testing.T) {
+	w := NewCopyWriter(&bytes.Buffer{})
+
+	var nilPtr *struct{ A string }
+	assert.EqualError(t, w.Encode(nilPtr), "Can't encode a nil *struct { A string }")
+	assert.EqualError(t, w.EncodeAll(5), "Expected a slice of structs, but got int")
+}
@@ -0,0 +1,115 @@
+package hastycsv
+
+import (
+	"fmt"
+	"io"
+)
+
+// Converts long-format src, which holds a record per key, metric and value,
+// into wide format: a record per distinct key, with a column per distinct
+// metric holding the key's value for that metric, or an empty field if it has
+// none.  Keys and metrics are written in order of first appearance.  Columns
+// are identified by name, so the first record of src must be a header.  A key
+// may have only one value per metric.  dst is flushed once all records have
+// been written.
+//
+// The whole output is held in memory, since the metrics aren't known until all
+// of src has been read.
+func Pivot(dst *Writer, src io.Reader, keyColumn, metricColumn, valueColumn string) error {
+	r := NewReader()
+	r.Comma = dst.Comma
+	r.HasHeader = true
+
+	var indexes []int
+	metrics := map[string]int{}
+	header := []string{keyColumn}
+	keys := map[string]int{}
+	var rows [][]string // the key, then its value for each metric
+
+	err := r.Read(src, func(i int, record []Field) error {
+		if indexes == nil {
+			names := r.Header()
+			indexes = make([]int, 3)
+			for i, name := range []string{keyColumn, metricColumn, valueColumn} {
+				if indexes[i] = indexOfString(names, name); indexes[i] < 0 {
+					return fmt.Errorf(`Column "%v" not found in header`, name)
+				}
+			}
+		}
+
+		key, metric := record[indexes[0]], record[indexes[1]]
+		m, exists := metrics[metric.unsafeString()]
+		if !exists {
+			m = len(header)
+			metrics[metric.String()] = m
+			header = append(header, metric.String())
+		}
+		k, exists := keys[key.unsafeString()]
+		if !exists {
+			k = len(rows)
+			keys[key.String()] = k
+			rows = append(rows, []string{key.String()})
+		}
+
+		row := rows[k]
+		for len(row) <= m {
+			row = append(row, "")
+		}
+		if row[m] != "" {
+			return fmt.Errorf(`Duplicate value for key "%v" and metric "%v"`, key.unsafeString(), metric.unsafeString())
+		}
+		row[m] = record[indexes[2]].String()
+		rows[k] = row
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if indexes != nil {
+		if err := dst.Write(header); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		for len(row) < len(header) {
+			row = append(row, "")
+		}
+		if err := dst.Write(row); err != nil {
+			return err
+		}
+	}
+	return dst.Flush()
+}
+
+// Writes the transpose of src to dst: the i-th record written holds the i-th
+// field of every record of src, including its header, if any.  dst is flushed
+// once all records have been written.  The whole input is held in memory, so
+// Transpose() is only meant for small files.
+func Transpose(dst *Writer, src io.Reader) error {
+	r := NewReader()
+	r.Comma = dst.Comma
+
+	arena := NewArena(DefaultArenaBlockSize)
+	var records [][]Field
+	err := r.Read(src, func(i int, record []Field) error {
+		records = append(records, arena.Retain(record))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(records) > 0 {
+		column := make([]Field, len(records))
+		for i := range records[0] {
+			for j, record := range records {
+				column[j] = record[i]
+			}
+			if err := dst.WriteFields(column); err != nil {
+				return err
+			}
+		}
+	}
+	return dst.Flush()
+}
@@ -0,0 +1,44 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestPivot(t *testing.T) {
+	in := "date,metric,value,source\n" +
+		"2021-01-01,clicks,10,web\n" +
+		"2021-01-01,views,100,web\n" +
+		"2021-01-02,views,120,app\n" +
+		"2021-01-02,sales,3,app\n"
+
+	var out bytes.Buffer
+	require.Nil(t, Pivot(NewWriter(&out), strings.NewReader(in), "date", "metric", "value"))
+	assert.Equal(t, "date,clicks,views,sales\n"+
+		"2021-01-01,10,100,\n"+
+		"2021-01-02,,120,3\n", out.String())
+}
+
+func TestPivot_errors(t *testing.T) {
+	var out bytes.Buffer
+	err := Pivot(NewWriter(&out), strings.NewReader("k,m,v\na,x,1\na,x,2\n"), "k", "m", "v")
+	assert.Equal(t, `Line 3: Duplicate value for key "a" and metric "x"`, err.Error())
+
+	err = Pivot(NewWriter(&out), strings.NewReader("k,m,v\na,x,1\n"), "k", "metric", "v")
+	assert.Contains(t, err.Error(), `Column "metric" not found in header`)
+}
+
+func TestTranspose(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	w.Comma = '|'
+	require.Nil(t, Transpose(w, strings.NewReader("name|age|city\nalice|30|paris\nbob|25|rome\n")))
+	assert.Equal(t, "name|alice|bob\nage|30|25\ncity|paris|rome\n", out.String())
+
+	out.Reset()
+	require.Nil(t, Transpose(NewWriter(&out), strings.NewReader("")))
+	assert.Equal(t, "", out.String())
+}
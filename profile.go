@@ -0,0 +1,122 @@
+package hastycsv
+
+import (
+	"context"
+	"io"
+	"runtime/trace"
+	"time"
+)
+
+// Timings of a Read() call, collected if Reader.CollectProfile is set.
+type Profile struct {
+	// Records is the number of records passed to the callback.
+	Records int64
+
+	// Bytes is the number of bytes read from the input.
+	Bytes int64
+
+	// ReadTime is the time spent waiting for the input's io.Reader.
+	ReadTime time.Duration
+
+	// SplitTime is the time spent finding lines and splitting them into
+	// fields.
+	SplitTime time.Duration
+
+	// CallbackTime is the time spent in the Next callback.
+	CallbackTime time.Duration
+
+	// TotalTime is the duration of the Read() call.
+	TotalTime time.Duration
+}
+
+// Returns the number of records processed per second.
+func (me Profile) RecordsPerSecond() float64 {
+	if me.TotalTime <= 0 {
+		return 0
+	}
+	return float64(me.Records) / me.TotalTime.Seconds()
+}
+
+// Returns the timings collected by the most recent Read() call, if
+// CollectProfile was set.
+func (me *Reader) Profile() Profile {
+	return me.profile
+}
+
+// An io.Reader that records the time spent in, and the bytes returned by, an
+// underlying io.Reader.
+type profiledReader struct {
+	r      io.Reader
+	reader *Reader
+	ctx    context.Context
+}
+
+func (me *profiledReader) Read(p []byte) (int, error) {
+	defer me.reader.startRegion(me.ctx, "hastycsv.io").end()
+
+	start := time.Now()
+	n, err := me.r.Read(p)
+	me.reader.profile.ReadTime += time.Since(start)
+	me.reader.profile.Bytes += int64(n)
+	return n, err
+}
+
+// The instrumented version of Read().
+func (me *Reader) readProfiled(r io.Reader, nextRecord Next) error {
+	start := time.Now()
+	me.profile = Profile{}
+	defer func() {
+		me.profile.TotalTime = time.Since(start)
+		me.profile.SplitTime -= me.profile.ReadTime // next() includes the time spent reading
+	}()
+
+	ctx := context.Background()
+	if err := me.begin(&profiledReader{r: r, reader: me, ctx: ctx}); err != nil {
+		return err
+	}
+	defer me.end()
+
+	for {
+		splitStart := time.Now()
+		region := me.startRegion(ctx, "hastycsv.split")
+		fields, err := me.next()
+		region.end()
+		me.profile.SplitTime += time.Since(splitStart)
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		callbackStart := time.Now()
+		region = me.startRegion(ctx, "hastycsv.callback")
+		callbackErr := nextRecord(me.row, fields)
+		region.end()
+		me.profile.CallbackTime += time.Since(callbackStart)
+		me.profile.Records++
+
+		if err := me.recordError(callbackErr); err != nil {
+			return err
+		}
+	}
+}
+
+// A runtime/trace region that may be disabled.
+type traceRegion struct {
+	region *trace.Region
+}
+
+// Starts a trace region if TraceRegions is set and tracing is enabled.
+func (me *Reader) startRegion(ctx context.Context, name string) traceRegion {
+	if me.TraceRegions && trace.IsEnabled() {
+		return traceRegion{region: trace.StartRegion(ctx, name)}
+	}
+	return traceRegion{}
+}
+
+func (me traceRegion) end() {
+	if me.region != nil {
+		me.region.End()
+	}
+}
@@ -0,0 +1,59 @@
+package hastycsv
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"runtime/trace"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReader_Read_collectProfile(t *testing.T) {
+	in := "a|1\nb|2\nc|3\n"
+
+	r := NewReader()
+	r.Comma = '|'
+	r.CollectProfile = true
+
+	err := r.Read(strings.NewReader(in), func(i int, fields []Field) error {
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	})
+
+	assert.Nil(t, err)
+	profile := r.Profile()
+	assert.Equal(t, int64(3), profile.Records)
+	assert.Equal(t, int64(len(in)), profile.Bytes)
+	assert.True(t, profile.CallbackTime >= 6*time.Millisecond)
+	assert.True(t, profile.TotalTime >= profile.CallbackTime+profile.ReadTime+profile.SplitTime)
+	assert.True(t, profile.RecordsPerSecond() > 0)
+
+	err = r.Read(strings.NewReader("a|1\nb|2\n"), func(i int, fields []Field) error {
+		return fmt.Errorf("Stop")
+	})
+	assert.EqualError(t, err, "Line 1: Stop")
+	assert.Equal(t, int64(1), r.Profile().Records)
+}
+
+func TestReader_Read_traceRegions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := trace.Start(buf); err != nil {
+		t.Skipf("Tracing is unavailable: %v", err)
+	}
+
+	r := NewReader()
+	r.TraceRegions = true
+	count := 0
+	err := r.Read(strings.NewReader("a,1\nb,2\n"), func(i int, fields []Field) error {
+		count++
+		return nil
+	})
+	trace.Stop()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.True(t, buf.Len() > 0)
+	assert.Equal(t, Profile{}, NewReader().Profile())
+}
@@ -0,0 +1,208 @@
+// Package protorecord encodes CSV records as protocol buffer messages, e.g. for
+// publishing them to Kafka.
+//
+// It's a separate module so that the core hastycsv package remains free of
+// third-party dependencies.
+package protorecord
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/cet001/hastycsv"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const timestampName protoreflect.FullName = "google.protobuf.Timestamp"
+
+// Converts the value of a (non-null) record column to the value of a message
+// field.
+type converter func(record *hastycsv.Record, col int) (protoreflect.Value, error)
+
+// A hastycsv.RecordEncoder that encodes each record as a protocol buffer
+// message of a user-supplied type.
+//
+// Each schema column is stored in the message field of the same name (or JSON
+// name); columns that have no such field are ignored, and null columns leave
+// their field unset.  Fields of message type google.protobuf.Timestamp can
+// hold TimeType columns, and string and bytes fields can hold columns of any
+// type (as their raw text).
+type Encoder struct {
+	prototype protoreflect.Message
+	schema    *hastycsv.Schema // the schema that fields and converters were built for
+	fields    []protoreflect.FieldDescriptor
+	convert   []converter
+	options   proto.MarshalOptions
+}
+
+// Returns a new Encoder that encodes records as messages of the same type as
+// prototype.  Use it with a hastycsv.BinaryWriter whose LengthPrefixed flag is
+// set to write a stream of messages.
+func NewEncoder(prototype proto.Message) *Encoder {
+	return &Encoder{prototype: prototype.ProtoReflect()}
+}
+
+// Appends the wire encoding of record to buf.
+func (me *Encoder) AppendRecord(buf []byte, record *hastycsv.Record) ([]byte, error) {
+	msg, err := me.Message(record)
+	if err != nil {
+		return buf, err
+	}
+	return me.options.MarshalAppend(buf, msg)
+}
+
+// Returns record converted to a new message.
+func (me *Encoder) Message(record *hastycsv.Record) (proto.Message, error) {
+	if schema := record.Schema(); schema != me.schema {
+		if err := me.prepare(schema); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := me.prototype.New()
+	for col, fd := range me.fields {
+		if fd == nil || record.IsNull(col) {
+			continue
+		}
+
+		v, err := me.convert[col](record, col)
+		if err != nil {
+			return nil, fmt.Errorf(`Can't store column "%v" in field %v: %v`, me.schema.Columns[col].Name, fd.Name(), err)
+		}
+		msg.Set(fd, v)
+	}
+	return msg.Interface(), nil
+}
+
+// Maps the columns of schema to message fields.
+func (me *Encoder) prepare(schema *hastycsv.Schema) error {
+	desc := me.prototype.Descriptor()
+	fields := make([]protoreflect.FieldDescriptor, len(schema.Columns))
+	convert := make([]converter, len(schema.Columns))
+
+	for col, column := range schema.Columns {
+		fd := desc.Fields().ByName(protoreflect.Name(column.Name))
+		if fd == nil {
+			fd = desc.Fields().ByJSONName(column.Name)
+		}
+		if fd == nil {
+			continue
+		}
+		if fd.IsList() || fd.IsMap() {
+			return fmt.Errorf(`Can't store column "%v" in repeated field %v`, column.Name, fd.Name())
+		}
+
+		c := converterFor(me.prototype, column.Type, fd)
+		if c == nil {
+			return fmt.Errorf(`Can't store %v column "%v" in %v field %v`, column.Type, column.Name, kindName(fd), fd.Name())
+		}
+		fields[col] = fd
+		convert[col] = c
+	}
+
+	me.schema = schema
+	me.fields = fields
+	me.convert = convert
+	return nil
+}
+
+// Returns the converter from columns of type t to values of field fd of
+// prototype's message type, or nil if the two are incompatible.
+func converterFor(prototype protoreflect.Message, t hastycsv.ColumnType, fd protoreflect.FieldDescriptor) converter {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return func(record *hastycsv.Record, col int) (protoreflect.Value, error) {
+			return protoreflect.ValueOfString(record.String(col)), nil
+		}
+	case protoreflect.BytesKind:
+		return func(record *hastycsv.Record, col int) (protoreflect.Value, error) {
+			return protoreflect.ValueOfBytes(record.Field(col).Bytes()), nil
+		}
+	case protoreflect.MessageKind:
+		if t == hastycsv.TimeType && fd.Message().FullName() == timestampName {
+			// Built through the field's own descriptor rather than as a
+			// timestamppb.Timestamp, so that this also works with dynamic messages.
+			seconds := fd.Message().Fields().ByName("seconds")
+			nanos := fd.Message().Fields().ByName("nanos")
+			return func(record *hastycsv.Record, col int) (protoreflect.Value, error) {
+				ts := record.Time(col)
+				v := prototype.NewField(fd)
+				v.Message().Set(seconds, protoreflect.ValueOfInt64(ts.Unix()))
+				v.Message().Set(nanos, protoreflect.ValueOfInt32(int32(ts.Nanosecond())))
+				return v, nil
+			}
+		}
+		return nil
+	}
+
+	switch t {
+	case hastycsv.Uint32Type, hastycsv.Int64Type:
+		integer := func(record *hastycsv.Record, col int) int64 {
+			if t == hastycsv.Uint32Type {
+				return int64(record.Uint32(col))
+			}
+			return record.Int64(col)
+		}
+		return integerConverter(fd, integer)
+	case hastycsv.Float32Type, hastycsv.Float64Type:
+		switch fd.Kind() {
+		case protoreflect.FloatKind:
+			return func(record *hastycsv.Record, col int) (protoreflect.Value, error) {
+				return protoreflect.ValueOfFloat32(record.Float32(col)), nil
+			}
+		case protoreflect.DoubleKind:
+			return func(record *hastycsv.Record, col int) (protoreflect.Value, error) {
+				return protoreflect.ValueOfFloat64(record.Float64(col)), nil
+			}
+		}
+	case hastycsv.BoolType:
+		if fd.Kind() == protoreflect.BoolKind {
+			return func(record *hastycsv.Record, col int) (protoreflect.Value, error) {
+				return protoreflect.ValueOfBool(record.Bool(col)), nil
+			}
+		}
+	}
+	return nil
+}
+
+// Returns a converter that stores integer values in an integer field of any
+// size, failing if a value is out of the field's range.
+func integerConverter(fd protoreflect.FieldDescriptor, integer func(record *hastycsv.Record, col int) int64) converter {
+	var min, max int64
+	var valueOf func(i int64) protoreflect.Value
+
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		min, max = math.MinInt32, math.MaxInt32
+		valueOf = func(i int64) protoreflect.Value { return protoreflect.ValueOfInt32(int32(i)) }
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		min, max = math.MinInt64, math.MaxInt64
+		valueOf = protoreflect.ValueOfInt64
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		min, max = 0, math.MaxUint32
+		valueOf = func(i int64) protoreflect.Value { return protoreflect.ValueOfUint32(uint32(i)) }
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		min, max = 0, math.MaxInt64
+		valueOf = func(i int64) protoreflect.Value { return protoreflect.ValueOfUint64(uint64(i)) }
+	default:
+		return nil
+	}
+
+	return func(record *hastycsv.Record, col int) (protoreflect.Value, error) {
+		i := integer(record, col)
+		if i < min || i > max {
+			return protoreflect.Value{}, fmt.Errorf("%v is out of range for %v", i, fd.Kind())
+		}
+		return valueOf(i), nil
+	}
+}
+
+// Returns the name of the type of field fd, e.g. "int32" or
+// "google.protobuf.Timestamp".
+func kindName(fd protoreflect.FieldDescriptor) string {
+	if fd.Kind() == protoreflect.MessageKind {
+		return string(fd.Message().FullName())
+	}
+	return fd.Kind().String()
+}
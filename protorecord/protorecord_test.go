@@ -0,0 +1,157 @@
+package protorecord
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/cet001/hastycsv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"strings"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	prototype := newPersonMessage(t)
+	schema := hastycsv.NewSchema(
+		hastycsv.StringColumn("name"),
+		hastycsv.Uint32Column("age"),
+		hastycsv.Float64Column("score"),
+		hastycsv.BoolColumn("active"),
+		hastycsv.TimeColumn("joined", "2006-01-02"),
+		hastycsv.StringColumn("unmapped"),
+		hastycsv.Int64Column("balance"),
+	)
+
+	var out bytes.Buffer
+	w := hastycsv.NewBinaryWriter(&out, NewEncoder(prototype))
+	w.LengthPrefixed = true
+
+	in := "" +
+		"bill|30|1.5|true|1970-01-02|x|-7\n" +
+		"mary||2|false||y|8\n"
+	readWithSchema(t, in, schema, func(record *hastycsv.Record) error {
+		return w.WriteRecord(record)
+	})
+	require.Nil(t, w.Flush())
+
+	messages := []protoreflect.Message{}
+	data := out.Bytes()
+	for len(data) > 0 {
+		n, k := binary.Uvarint(data)
+		msg := prototype.ProtoReflect().New()
+		require.Nil(t, proto.Unmarshal(data[k:k+int(n)], msg.Interface()))
+		messages = append(messages, msg)
+		data = data[k+int(n):]
+	}
+	require.Equal(t, 2, len(messages))
+
+	fields := prototype.ProtoReflect().Descriptor().Fields()
+	bill := messages[0]
+	assert.Equal(t, "bill", bill.Get(fields.ByName("name")).String())
+	assert.Equal(t, int64(30), bill.Get(fields.ByName("age")).Int())
+	assert.Equal(t, 1.5, bill.Get(fields.ByName("score")).Float())
+	assert.Equal(t, true, bill.Get(fields.ByName("active")).Bool())
+	assert.Equal(t, "-7", bill.Get(fields.ByName("balance")).String())
+	joined := bill.Get(fields.ByName("joined")).Message()
+	assert.Equal(t, int64(86400), joined.Get(joined.Descriptor().Fields().ByName("seconds")).Int())
+
+	mary := messages[1]
+	assert.False(t, mary.Has(fields.ByName("age")))
+	assert.False(t, mary.Has(fields.ByName("joined")))
+}
+
+func TestEncoder_incompatibleColumn(t *testing.T) {
+	schema := hastycsv.NewSchema(hastycsv.BoolColumn("age"))
+	readWithSchema(t, "true", schema, func(record *hastycsv.Record) error {
+		_, err := NewEncoder(newPersonMessage(t)).Message(record)
+		assert.EqualError(t, err, `Can't store bool column "age" in int32 field age`)
+		return nil
+	})
+}
+
+func TestEncoder_outOfRange(t *testing.T) {
+	schema := hastycsv.NewSchema(hastycsv.Uint32Column("age"))
+	readWithSchema(t, "3000000000", schema, func(record *hastycsv.Record) error {
+		_, err := NewEncoder(newPersonMessage(t)).Message(record)
+		assert.EqualError(t, err, `Can't store column "age" in field age: 3000000000 is out of range for int32`)
+		return nil
+	})
+}
+
+func TestEncoder_generatedTimestamp(t *testing.T) {
+	schema := hastycsv.NewSchema(hastycsv.Int64Column("seconds"), hastycsv.Uint32Column("nanos"))
+	readWithSchema(t, "5|7", schema, func(record *hastycsv.Record) error {
+		msg, err := NewEncoder(&timestamppb.Timestamp{}).Message(record)
+		require.Nil(t, err)
+		assert.Equal(t, int64(5), msg.(*timestamppb.Timestamp).Seconds)
+		assert.Equal(t, int32(7), msg.(*timestamppb.Timestamp).Nanos)
+		return nil
+	})
+}
+
+// Test helper that reads '|'-delimited input according to schema.
+func readWithSchema(t *testing.T, in string, schema *hastycsv.Schema, next func(record *hastycsv.Record) error) {
+	r := hastycsv.NewReader()
+	r.Comma = '|'
+	err := r.ReadWithSchema(strings.NewReader(in), schema, func(i int, record *hastycsv.Record) error {
+		return next(record)
+	})
+	require.Nil(t, err)
+}
+
+// Test helper that returns a dynamic message of the following type:
+//
+//	message Person {
+//	  string name = 1;
+//	  int32 age = 2;
+//	  double score = 3;
+//	  bool active = 4;
+//	  google.protobuf.Timestamp joined = 5;
+//	  string balance = 6;
+//	}
+func newPersonMessage(t *testing.T) proto.Message {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:     typ.Enum(),
+		}
+	}
+
+	joined := field("joined", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE)
+	joined.TypeName = proto.String(".google.protobuf.Timestamp")
+
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("person.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Person"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				field("age", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+				field("score", 3, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE),
+				field("active", 4, descriptorpb.FieldDescriptorProto_TYPE_BOOL),
+				joined,
+				field("balance", 6, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			},
+		}},
+	}
+
+	// Make sure that timestamp.proto is registered.
+	_ = timestamppb.Timestamp{}
+
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	require.Nil(t, err)
+	return dynamicpb.NewMessage(fd.Messages().ByName("Person"))
+}
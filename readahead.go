@@ -0,0 +1,104 @@
+package hastycsv
+
+import (
+	"io"
+	"sync"
+)
+
+// An io.ReadCloser that reads from an underlying reader on a background
+// goroutine, so that the next buffer is being read while the current one is
+// consumed.
+type readAheadReader struct {
+	full      chan readAheadBuffer // buffers read from the underlying reader
+	empty     chan []byte          // consumed buffers, ready to be refilled
+	done      chan struct{}
+	closeOnce sync.Once
+	filling   sync.WaitGroup // waits for fill() to return
+
+	buf  []byte // buffer currently being consumed
+	data []byte // unconsumed part of buf
+	err  error  // error that accompanied buf
+}
+
+type readAheadBuffer struct {
+	data []byte
+	err  error
+}
+
+// Returns an io.ReadCloser that reads ahead from r on a background goroutine
+// using two buffers of the specified size, which overlaps I/O with parsing
+// when reading from slow media such as network filesystems.
+//
+// Close() must be called once reading is done (even if r was not read to the
+// end) to stop the background goroutine.  It waits for a pending read from r
+// to return, so that r can safely be closed afterwards, but it does not close
+// r itself.
+func NewReadAheadReader(r io.Reader, bufferSize int) io.ReadCloser {
+	if bufferSize < 1 {
+		bufferSize = 32 * 1024
+	}
+
+	me := &readAheadReader{
+		full:  make(chan readAheadBuffer, 2),
+		empty: make(chan []byte, 2),
+		done:  make(chan struct{}),
+	}
+	me.empty <- make([]byte, bufferSize)
+	me.empty <- make([]byte, bufferSize)
+
+	me.filling.Add(1)
+	go me.fill(r)
+	return me
+}
+
+// Reads from r into empty buffers until r returns an error (such as io.EOF)
+// or the reader is closed.
+func (me *readAheadReader) fill(r io.Reader) {
+	defer me.filling.Done()
+	for {
+		var buf []byte
+		select {
+		case buf = <-me.empty:
+		case <-me.done:
+			return
+		}
+
+		n, err := r.Read(buf)
+
+		select {
+		case me.full <- readAheadBuffer{data: buf[:n], err: err}:
+		case <-me.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (me *readAheadReader) Read(p []byte) (int, error) {
+	for len(me.data) == 0 {
+		if me.err != nil {
+			return 0, me.err
+		}
+		if me.buf != nil {
+			me.empty <- me.buf[:cap(me.buf)]
+		}
+
+		next := <-me.full
+		me.buf, me.data, me.err = next.data, next.data, next.err
+	}
+
+	n := copy(p, me.data)
+	me.data = me.data[n:]
+	return n, nil
+}
+
+// Stops the background goroutine, and waits for it to exit.
+func (me *readAheadReader) Close() error {
+	me.closeOnce.Do(func() {
+		close(me.done)
+	})
+	me.filling.Wait()
+	return nil
+}
@@ -0,0 +1,112 @@
+package hastycsv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestNewReadAheadReader(t *testing.T) {
+	data := strings.Repeat("0123456789", 1000)
+
+	for _, bufferSize := range []int{0, 1, 7, 64, 100000} {
+		in := NewReadAheadReader(strings.NewReader(data), bufferSize)
+		out, err := ioutil.ReadAll(iotest.OneByteReader(in))
+		assert.Nil(t, err)
+		assert.Equal(t, data, string(out), "bufferSize=%v", bufferSize)
+		assert.Nil(t, in.Close())
+	}
+}
+
+func TestNewReadAheadReader_error(t *testing.T) {
+	in := NewReadAheadReader(io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(fmt.Errorf("Disk error"))), 2)
+	defer in.Close()
+
+	out, err := ioutil.ReadAll(in)
+	assert.EqualError(t, err, "Disk error")
+	assert.Equal(t, "abc", string(out))
+}
+
+func TestNewReadAheadReader_closeEarly(t *testing.T) {
+	in := NewReadAheadReader(bytes.NewReader(make([]byte, 1000)), 10)
+	buf := make([]byte, 5)
+	_, err := in.Read(buf)
+	assert.Nil(t, err)
+	assert.Nil(t, in.Close())
+	assert.Nil(t, in.Close())
+}
+
+func TestNewReadAheadReader_closeWaits(t *testing.T) {
+	src := &slowReader{}
+	in := NewReadAheadReader(src, 10)
+	buf := make([]byte, 5)
+	_, err := in.Read(buf)
+	assert.Nil(t, err)
+
+	assert.Nil(t, in.Close())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&src.reading))
+}
+
+func TestReadFile_readAheadStopsEarly(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	compressed := &bytes.Buffer{}
+	gz := gzip.NewWriter(compressed)
+	for i := 0; i < 100000; i++ {
+		fmt.Fprintf(gz, "name%v,%v\n", i, i)
+	}
+	require.Nil(t, gz.Close())
+
+	path := filepath.Join(dir, "people.csv.gz")
+	require.Nil(t, ioutil.WriteFile(path, compressed.Bytes(), 0644))
+
+	err := ReadFile(path, ',', func(i int, rec []Field) error {
+		return fmt.Errorf("Abort!")
+	})
+	assert.EqualError(t, err, "Line 1: Abort!")
+}
+
+// Test helper: an endless reader whose reads take a while.
+type slowReader struct {
+	reading int32
+}
+
+func (me *slowReader) Read(p []byte) (int, error) {
+	atomic.StoreInt32(&me.reading, 1)
+	defer atomic.StoreInt32(&me.reading, 0)
+	time.Sleep(10 * time.Millisecond)
+	return len(p), nil
+}
+
+func TestReader_Read_readAhead(t *testing.T) {
+	lines := []string{}
+	for i := 1; i <= 1000; i++ {
+		lines = append(lines, fmt.Sprintf("name%v|%v", i, i))
+	}
+
+	in := NewReadAheadReader(strings.NewReader(strings.Join(lines, "\n")), 100)
+	defer in.Close()
+
+	r := NewReader()
+	r.Comma = '|'
+	count := 0
+	err := r.Read(in, func(i int, fields []Field) error {
+		count++
+		assert.Equal(t, uint32(i), fields[1].Uint32())
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1000, count)
+}
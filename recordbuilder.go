@@ -0,0 +1,144 @@
+package hastycsv
+
+import (
+	"strconv"
+	"time"
+)
+
+// Assembles a record field by field into a reusable buffer, and then writes
+// it to a Writer.
+//
+// The Add methods can be chained:
+//
+//	err := b.AddString(name).AddUint32(age).AddTime(born, "2006-01-02").Write()
+//
+// Errors (e.g. a value that contains the Writer's delimiter) don't interrupt
+// the chain.  Instead, the first error is remembered and returned by Write(),
+// which discards the offending record.
+type RecordBuilder struct {
+	w          *Writer
+	buf        []byte
+	fieldStart int // offset of the current field within buf
+	fieldCount int
+	err        error
+}
+
+// Returns a new RecordBuilder that writes records to the specified Writer.
+func NewRecordBuilder(w *Writer) *RecordBuilder {
+	return &RecordBuilder{w: w}
+}
+
+// Appends a string field.
+func (me *RecordBuilder) AddString(s string) *RecordBuilder {
+	me.beginField()
+	me.buf = append(me.buf, s...)
+	return me.endField()
+}
+
+// Appends a field containing the specified bytes.
+func (me *RecordBuilder) AddBytes(b []byte) *RecordBuilder {
+	me.beginField()
+	me.buf = append(me.buf, b...)
+	return me.endField()
+}
+
+// Appends a copy of the specified Field (e.g. a field received from a Reader).
+func (me *RecordBuilder) AddField(field Field) *RecordBuilder {
+	return me.AddBytes(field.data)
+}
+
+// Appends a uint32 field.
+func (me *RecordBuilder) AddUint32(i uint32) *RecordBuilder {
+	me.beginField()
+	me.buf = strconv.AppendUint(me.buf, uint64(i), 10)
+	return me.endField()
+}
+
+// Appends a uint64 field.
+func (me *RecordBuilder) AddUint64(i uint64) *RecordBuilder {
+	me.beginField()
+	me.buf = strconv.AppendUint(me.buf, i, 10)
+	return me.endField()
+}
+
+// Appends an int64 field.
+func (me *RecordBuilder) AddInt64(i int64) *RecordBuilder {
+	me.beginField()
+	me.buf = strconv.AppendInt(me.buf, i, 10)
+	return me.endField()
+}
+
+// Appends a float32 field using the specified number of digits after the
+// decimal point.  A precision of -1 uses the smallest number of digits
+// necessary to represent the value exactly.
+func (me *RecordBuilder) AddFloat32(f float32, prec int) *RecordBuilder {
+	me.beginField()
+	me.buf = strconv.AppendFloat(me.buf, float64(f), 'f', prec, 32)
+	return me.endField()
+}
+
+// Appends a float64 field using the specified number of digits after the
+// decimal point.  A precision of -1 uses the smallest number of digits
+// necessary to represent the value exactly.
+func (me *RecordBuilder) AddFloat64(f float64, prec int) *RecordBuilder {
+	me.beginField()
+	me.buf = strconv.AppendFloat(me.buf, f, 'f', prec, 64)
+	return me.endField()
+}
+
+// Appends a bool field ("true" or "false").
+func (me *RecordBuilder) AddBool(b bool) *RecordBuilder {
+	me.beginField()
+	me.buf = strconv.AppendBool(me.buf, b)
+	return me.endField()
+}
+
+// Appends a time field formatted with the specified layout (see time.Format).
+func (me *RecordBuilder) AddTime(t time.Time, layout string) *RecordBuilder {
+	me.beginField()
+	me.buf = t.AppendFormat(me.buf, layout)
+	return me.endField()
+}
+
+// Appends an empty field.
+func (me *RecordBuilder) AddEmpty() *RecordBuilder {
+	me.beginField()
+	return me.endField()
+}
+
+// Writes the assembled record to the Writer and resets the builder so that
+// it can assemble the next record.  Returns the first error encountered while
+// assembling the record, in which case nothing is written.
+func (me *RecordBuilder) Write() error {
+	err := me.err
+	if err == nil {
+		err = me.w.writeLine(me.buf, me.fieldCount)
+	}
+
+	me.Reset()
+	return err
+}
+
+// Discards the record assembled so far.
+func (me *RecordBuilder) Reset() {
+	me.buf = me.buf[:0]
+	me.fieldCount = 0
+	me.err = nil
+}
+
+func (me *RecordBuilder) beginField() {
+	if me.fieldCount > 0 {
+		me.buf = append(me.buf, me.w.Comma)
+	}
+	me.fieldStart = len(me.buf)
+}
+
+// Validates the field that was just appended to the buffer.
+func (me *RecordBuilder) endField() *RecordBuilder {
+	if me.err == nil {
+		me.err = me.w.checkField(me.fieldCount, Field{data: me.buf[me.fieldStart:]}.unsafeString())
+	}
+
+	me.fieldCount++
+	return me
+}
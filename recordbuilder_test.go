@@ -0,0 +1,74 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordBuilder(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Comma = '|'
+	b := NewRecordBuilder(w)
+
+	born := time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC)
+	err := b.AddString("bill").
+		AddUint32(30).
+		AddFloat32(154.5, -1).
+		AddFloat64(1.0/3, 2).
+		AddTime(born, "2006-01-02").
+		AddBool(true).
+		AddInt64(-7).
+		AddUint64(1 << 40).
+		AddEmpty().
+		AddBytes([]byte("xyz")).
+		Write()
+	assert.Nil(t, err)
+
+	assert.Nil(t, b.AddString("mary").AddUint32(35).Write())
+	assert.Nil(t, w.Flush())
+
+	assert.Equal(t, "bill|30|154.5|0.33|1990-03-04|true|-7|1099511627776||xyz\nmary|35\n", buf.String())
+}
+
+func TestRecordBuilder_AddField(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	b := NewRecordBuilder(w)
+
+	err := NewReader().Read(strings.NewReader("a,1\nb,2"), func(i int, fields []Field) error {
+		return b.AddField(fields[1]).AddField(fields[0]).AddUint32(fields[1].Uint32() * 10).Write()
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, w.Flush())
+	assert.Equal(t, "1,a,10\n2,b,20\n", buf.String())
+}
+
+func TestRecordBuilder_invalidField(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	b := NewRecordBuilder(w)
+
+	err := b.AddString("ok").AddString("a,b").AddString("also ok").Write()
+	assert.EqualError(t, err, `Field 1 contains the delimiter ',' or a line break: "a,b"`)
+
+	// The builder is reset after a failed Write(), and the bad record is discarded.
+	assert.Nil(t, b.AddString("next").Write())
+	assert.Nil(t, w.Flush())
+	assert.Equal(t, "next\n", buf.String())
+}
+
+func TestRecordBuilder_Reset(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	b := NewRecordBuilder(w)
+
+	b.AddString("discarded").AddString("a\nb")
+	b.Reset()
+	assert.Nil(t, b.AddString("kept").Write())
+	assert.Nil(t, w.Flush())
+	assert.Equal(t, "kept\n", buf.String())
+}
@@ -0,0 +1,94 @@
+package hastycsv
+
+import (
+	"io"
+)
+
+type remapConfig struct {
+	renames  map[string]string
+	defaults map[string]string
+	fill     string
+}
+
+// Configures Remap().
+type RemapOption func(cfg *remapConfig)
+
+// Returns a RemapOption that renames the input columns named by the keys of
+// renames to the corresponding values, before they are matched against the
+// target columns.
+func WithRenames(renames map[string]string) RemapOption {
+	return func(cfg *remapConfig) {
+		cfg.renames = renames
+	}
+}
+
+// Returns a RemapOption that sets the value of the target columns that are
+// missing from the input ("" by default).
+func WithMissingValue(value string) RemapOption {
+	return func(cfg *remapConfig) {
+		cfg.fill = value
+	}
+}
+
+// Returns a RemapOption that sets the value of specific target columns that
+// are missing from the input, overriding WithMissingValue().
+func WithColumnDefaults(defaults map[string]string) RemapOption {
+	return func(cfg *remapConfig) {
+		cfg.defaults = defaults
+	}
+}
+
+// Streams the records of src to dst with their columns renamed and reordered
+// to match columns, the target header.  The first record of src must be a
+// header; its columns are renamed according to WithRenames(), and then matched
+// by name against the target columns.  Input columns that aren't targeted are
+// dropped, and targeted columns that are missing from the input are filled
+// with a default value.  If columns is nil, the (renamed) input columns are
+// kept in their original order.  The target header is written first.
+func Remap(dst *Writer, src io.Reader, columns []string, options ...RemapOption) error {
+	cfg := &remapConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	var indexes []int // the input position of each target column, or -1
+	var fills []Field // the default value of each target column
+	var remapped []Field
+	return Copy(dst, src, func(i int, record []Field) ([]Field, error) {
+		if indexes == nil {
+			header := make([]string, len(record))
+			for j := range record {
+				header[j] = record[j].String()
+				if name, exists := cfg.renames[header[j]]; exists {
+					header[j] = name
+				}
+			}
+			if columns == nil {
+				columns = header
+			}
+
+			indexes = make([]int, len(columns))
+			fills = make([]Field, len(columns))
+			remapped = make([]Field, len(columns))
+			for j, name := range columns {
+				indexes[j] = indexOfString(header, name)
+				remapped[j] = NewField([]byte(name))
+				fill, exists := cfg.defaults[name]
+				if !exists {
+					fill = cfg.fill
+				}
+				fills[j] = NewField([]byte(fill))
+			}
+			return remapped, nil
+		}
+
+		for j, index := range indexes {
+			if index < 0 {
+				remapped[j] = fills[j]
+			} else {
+				remapped[j] = record[index]
+			}
+		}
+		return remapped, nil
+	})
+}
@@ -0,0 +1,29 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestRemap(t *testing.T) {
+	in := "Full Name,Age,Internal\nbill,30,x\nmary,35,y\n"
+
+	var buf bytes.Buffer
+	err := Remap(NewWriter(&buf), strings.NewReader(in), []string{"id", "name", "age", "country"},
+		WithRenames(map[string]string{"Full Name": "name", "Age": "age"}),
+		WithMissingValue("n/a"),
+		WithColumnDefaults(map[string]string{"country": "US"}))
+	assert.Nil(t, err)
+	assert.Equal(t, "id,name,age,country\nn/a,bill,30,US\nn/a,mary,35,US\n", buf.String())
+}
+
+func TestRemap_renameOnly(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Comma = '|'
+	err := Remap(w, strings.NewReader("a|b\n1|2\n"), nil, WithRenames(map[string]string{"b": "beta"}))
+	assert.Nil(t, err)
+	assert.Equal(t, "a|beta\n1|2\n", buf.String())
+}
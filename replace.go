@@ -0,0 +1,81 @@
+package hastycsv
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+)
+
+// A rewrite applied by ReplaceValues() to the fields of chosen columns.
+type Replacement struct {
+	// Columns holds the (0-based) indexes of the columns whose fields are
+	// rewritten, or nil to rewrite every column.
+	Columns []int
+
+	// Old is the value of the fields that are replaced by New, if Pattern is
+	// nil.  Only fields that are entirely equal to Old are replaced.
+	Old string
+
+	// Pattern, if set, is a regular expression whose matches within fields are
+	// replaced by New, in which $1, ${name} etc. refer to submatches (see
+	// regexp.Regexp.Expand()).
+	Pattern *regexp.Regexp
+
+	New string
+}
+
+// Streams the records of src to dst, rewriting their values as specified (see
+// ReplaceValues()).  The first record of src must be a header, which is
+// written as is.
+func Replace(dst *Writer, src io.Reader, replacements ...Replacement) error {
+	return Copy(dst, src, ReplaceValues(true, replacements...))
+}
+
+// Returns a Transform for Copy() that rewrites the fields of each record with
+// the specified replacements, applied in order, so that a field rewritten by a
+// replacement is seen by the next one.  If hasHeader is true, the first record
+// is treated as a header and left as is.
+func ReplaceValues(hasHeader bool, replacements ...Replacement) Transform {
+	olds := make([][]byte, len(replacements))
+	news := make([][]byte, len(replacements))
+	for i, r := range replacements {
+		olds[i], news[i] = []byte(r.Old), []byte(r.New)
+	}
+
+	return func(i int, record []Field) ([]Field, error) {
+		if hasHeader && i == 1 {
+			return record, nil
+		}
+
+		for k, r := range replacements {
+			if r.Columns == nil {
+				for j := range record {
+					record[j] = r.apply(record[j], olds[k], news[k])
+				}
+				continue
+			}
+			for _, j := range r.Columns {
+				if j >= 0 && j < len(record) {
+					record[j] = r.apply(record[j], olds[k], news[k])
+				}
+			}
+		}
+		return record, nil
+	}
+}
+
+// Returns field, rewritten by this replacement.  The rewritten field doesn't
+// share its data with field.
+func (me *Replacement) apply(field Field, old, new []byte) Field {
+	if me.Pattern == nil {
+		if bytes.Equal(field.data, old) {
+			return NewField(new)
+		}
+		return field
+	}
+
+	if !me.Pattern.Match(field.data) {
+		return field
+	}
+	return NewField(me.Pattern.ReplaceAll(field.data, new))
+}
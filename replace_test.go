@@ -0,0 +1,31 @@
+package hastycsv
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestReplace(t *testing.T) {
+	in := strings.NewReader("code,label,note\nXX,Legacy-A,XX\nB1,legacy-b,ok\nXX,new,XX\n")
+
+	var buf bytes.Buffer
+	err := Replace(NewWriter(&buf), in,
+		Replacement{Columns: []int{0}, Old: "XX", New: "UNKNOWN"},
+		Replacement{Columns: []int{1}, Pattern: regexp.MustCompile(`(?i)^legacy-(\w)$`), New: "label-$1"},
+		Replacement{Pattern: regexp.MustCompile(`UNKNOWN`), New: "n/a"})
+	assert.Nil(t, err)
+	assert.Equal(t, "code,label,note\nn/a,label-A,XX\nB1,label-b,ok\nn/a,new,XX\n", buf.String())
+}
+
+func TestReplaceValues(t *testing.T) {
+	in := strings.NewReader("a|b\nb|a\n")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Comma = '|'
+	assert.Nil(t, Copy(w, in, ReplaceValues(false, Replacement{Old: "a", New: "z"})))
+	assert.Equal(t, "z|b\nb|z\n", buf.String())
+}
@@ -0,0 +1,144 @@
+package hastycsv
+
+import (
+	"fmt"
+	"io"
+)
+
+// The state of the RFC 4180 splitter used for lines that contain quotes when
+// Reader.QuoteFallback is set.
+type quoteState struct {
+	joined            []byte  // a record that spans several lines, joined by joinQuotedLines()
+	unquoted          []byte  // copy of a quoted line, unquoted in place by splitQuotedLine()
+	fields            []Field // fields of the line most recently split by splitQuotedLine()
+	continuationLines int     // lines consumed by the current record beyond its first
+}
+
+// Returns line, extended by as many of the following lines as needed to
+// terminate a quoted field that contains line breaks.  If line has no such
+// field, it's returned as is.
+func (me *Reader) joinQuotedLines(line []byte) ([]byte, error) {
+	if !endsInQuotedField(line, me.Comma, false) {
+		return line, nil
+	}
+
+	// line is only valid until the next readLine(), so it's copied.
+	q := &me.quoting
+	q.joined = append(q.joined[:0], line...)
+	for {
+		next, err := me.lines.readLine()
+		if err == io.EOF {
+			// Let splitQuotedLine() report the unterminated field.
+			return q.joined, nil
+		} else if err != nil {
+			return nil, &ReadError{err}
+		}
+
+		q.continuationLines++
+		q.joined = append(q.joined, '\n')
+		q.joined = append(q.joined, next...)
+		if !endsInQuotedField(next, me.Comma, true) {
+			return q.joined, nil
+		}
+	}
+}
+
+// Returns true if a quoted field is still open at the end of line, given
+// whether one was open at its start.  As in splitQuoted(), only a quote at the
+// start of a field opens a quoted field, so quotes inside unquoted fields
+// don't count.
+func endsInQuotedField(line []byte, delim byte, inQuotes bool) bool {
+	fieldStart := !inQuotes
+	for i := 0; i < len(line); i++ {
+		switch ch := line[i]; {
+		case inQuotes:
+			if ch == '"' {
+				if i+1 < len(line) && line[i+1] == '"' {
+					i++ // escaped quote
+				} else {
+					inQuotes = false
+				}
+			}
+		case ch == delim:
+			fieldStart = true
+			continue
+		case ch == '"' && fieldStart:
+			inQuotes = true
+		}
+		fieldStart = false
+	}
+	return inQuotes
+}
+
+// Like splitLine(), but splits b according to RFC 4180: a field may be
+// enclosed in double quotes, in which case it may contain the delimiter and
+// line breaks, and a double quote within it is escaped by doubling it.
+// Quotes inside unquoted fields are kept as is.
+//
+// b is copied before being unquoted, so the record's fields don't point into
+// b.
+func (me *Reader) splitQuotedLine(b []byte) (bool, error) {
+	me.row++
+
+	q := &me.quoting
+	q.unquoted = append(q.unquoted[:0], b...)
+	fields, err := splitQuoted(q.unquoted, me.Comma, q.fields[:0])
+	q.fields = fields
+	if err != nil {
+		return false, fmt.Errorf("Line %v: %w%v", me.row, err, quoteRaw(b, me.ErrorRawLength))
+	}
+
+	if me.fields == nil {
+		me.initFields(len(fields))
+	} else if len(fields) != len(me.fields) {
+		return false, me.fieldCountError(b, len(fields))
+	}
+	for i := range fields {
+		me.fields[i].data = fields[i].data
+	}
+
+	return !me.takeHeader(), nil
+}
+
+// Splits buf into fields according to RFC 4180 and appends them to fields.
+// Quoted fields are unquoted in place, so the fields point into buf.
+func splitQuoted(buf []byte, delim byte, fields []Field) ([]Field, error) {
+	r, w := 0, 0 // read and write positions within buf
+	for {
+		start := w
+		if r < len(buf) && buf[r] == '"' {
+			r++
+			for {
+				if r == len(buf) {
+					return fields, fmt.Errorf("Field %v has no closing quote", len(fields))
+				}
+				if buf[r] == '"' {
+					if r+1 < len(buf) && buf[r+1] == '"' {
+						r++ // escaped quote
+					} else {
+						r++
+						break
+					}
+				}
+				buf[w] = buf[r]
+				w++
+				r++
+			}
+			if r < len(buf) && buf[r] != delim {
+				return fields, fmt.Errorf("Field %v has unexpected character '%v' after its closing quote", len(fields), string(buf[r]))
+			}
+		} else {
+			for r < len(buf) && buf[r] != delim {
+				buf[w] = buf[r]
+				w++
+				r++
+			}
+		}
+
+		fields = append(fields, Field{data: buf[start:w:w]})
+		if r == len(buf) {
+			return fields, nil
+		}
+		r++ // skip the delimiter
+	}
+}
@@ -0,0 +1,162 @@
+package hastycsv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestReader_QuoteFallback(t *testing.T) {
+	in := "" +
+		"name,quote,age\n" +
+		"bill,plain,30\n" +
+		`"mary, jr.","she said ""hi""",40` + "\n" +
+		`joe,"multi` + "\n" + `line, ""field""` + "\n" + `end",50` + "\n" +
+		`ann,"",60` + "\n" +
+		`bob,a"b,70` + "\n"
+
+	r := NewReader()
+	r.QuoteFallback = true
+	r.HasHeader = true
+
+	records := []string{}
+	err := r.Read(strings.NewReader(in), func(i int, fields []Field) error {
+		records = append(records, fmt.Sprintf("%v:%q|%q|%v", i, fields[0].String(), fields[1].String(), fields[2].Uint32()))
+		return nil
+	})
+
+	require.Nil(t, err)
+	assert.Equal(t, []string{"name", "quote", "age"}, r.Header())
+	assert.Equal(t, []string{
+		`2:"bill"|"plain"|30`,
+		`3:"mary, jr."|"she said \"hi\""|40`,
+		`4:"joe"|"multi\nline, \"field\"\nend"|50`,
+		`7:"ann"|""|60`,
+		`8:"bob"|"a\"b"|70`,
+	}, records)
+}
+
+func TestReader_QuoteFallback_strayQuote(t *testing.T) {
+	in := "" +
+		`tv,42" screen,100` + "\n" +
+		`radio,plain,20` + "\n" +
+		`joe,"multi` + "\n" + `line with a "" quote` + "\n" + `end",50` + "\n" +
+		`cable,6" long,5` + "\n"
+
+	r := NewReader()
+	r.QuoteFallback = true
+
+	records := []string{}
+	err := r.Read(strings.NewReader(in), func(i int, fields []Field) error {
+		records = append(records, fmt.Sprintf("%v:%q|%q|%v", i, fields[0].String(), fields[1].String(), fields[2].Uint32()))
+		return nil
+	})
+
+	require.Nil(t, err)
+	assert.Equal(t, []string{
+		`1:"tv"|"42\" screen"|100`,
+		`2:"radio"|"plain"|20`,
+		`3:"joe"|"multi\nline with a \" quote\nend"|50`,
+		`6:"cable"|"6\" long"|5`,
+	}, records)
+}
+
+func TestEndsInQuotedField(t *testing.T) {
+	assert.False(t, endsInQuotedField([]byte(`a,b"c,d`), ',', false))
+	assert.False(t, endsInQuotedField([]byte(`a,"b,c",d`), ',', false))
+	assert.True(t, endsInQuotedField([]byte(`a,"b`), ',', false))
+	assert.True(t, endsInQuotedField([]byte(`a,"b""`), ',', false))
+	assert.False(t, endsInQuotedField([]byte(`b",c`), ',', true))
+	assert.True(t, endsInQuotedField([]byte(`b"",c`), ',', true))
+	assert.False(t, endsInQuotedField([]byte(`x"y`), ',', true))
+}
+
+func TestReader_QuoteFallback_matchesEncodingCSV(t *testing.T) {
+	in := "" +
+		`a,"b,c",d` + "\n" +
+		`"",",",""""` + "\n" +
+		`x,y,z` + "\n" +
+		`"1` + "\n" + `2",3,"4"` + "\n"
+
+	expected, err := csv.NewReader(strings.NewReader(in)).ReadAll()
+	require.Nil(t, err)
+
+	r := NewReader()
+	r.QuoteFallback = true
+	actual := [][]string{}
+	err = r.Read(strings.NewReader(in), func(i int, fields []Field) error {
+		record := make([]string, len(fields))
+		for k := range fields {
+			record[k] = fields[k].String()
+		}
+		actual = append(actual, record)
+		return nil
+	})
+
+	require.Nil(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestReader_QuoteFallback_disabled(t *testing.T) {
+	r := NewReader()
+	err := r.Read(strings.NewReader(`"a,b",c`), func(i int, fields []Field) error {
+		assert.Equal(t, []string{`"a`, `b"`, "c"}, []string{fields[0].String(), fields[1].String(), fields[2].String()})
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func TestReader_QuoteFallback_errors(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected string
+	}{
+		{"a,b\n\"x\ny,z\n", "Line 2: Field 0 has no closing quote: \"\"x\ny,z\""},
+		{"a,b\n\"x\"y,z\n", `Line 2: Field 0 has unexpected character 'y' after its closing quote: ""x"y,z"`},
+		{"a,b\n\"x\",y,z\n", `Line 2: Expected []b to contain 2 fields using delimiter ',': ""x",y,z"`},
+	}
+
+	for _, testCase := range testCases {
+		r := NewReader()
+		r.QuoteFallback = true
+		err := r.Read(strings.NewReader(testCase.in), func(i int, fields []Field) error { return nil })
+		assert.EqualError(t, err, testCase.expected)
+	}
+}
+
+func TestReader_QuoteFallback_ReadBytes(t *testing.T) {
+	data := []byte("a,\"b,\"\"c\"\"\"\nd,e\n")
+	r := NewReader()
+	r.QuoteFallback = true
+
+	records := []string{}
+	err := r.ReadBytes(data, func(i int, fields []Field) error {
+		records = append(records, fields[0].String()+"|"+fields[1].String())
+		return nil
+	})
+
+	require.Nil(t, err)
+	assert.Equal(t, []string{`a|b,"c"`, "d|e"}, records)
+	assert.Equal(t, "a,\"b,\"\"c\"\"\"\nd,e\n", string(data), "input must not be unquoted in place")
+}
+
+func TestSplitQuoted(t *testing.T) {
+	toStrings := func(fields []Field) []string {
+		s := []string{}
+		for _, field := range fields {
+			s = append(s, field.String())
+		}
+		return s
+	}
+
+	fields, err := splitQuoted([]byte(`"a""b",,"",c,`), ',', nil)
+	require.Nil(t, err)
+	assert.Equal(t, []string{`a"b`, "", "", "c", ""}, toStrings(fields))
+
+	fields, err = splitQuoted([]byte(`"x|y"|z`), '|', nil)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"x|y", "z"}, toStrings(fields))
+}
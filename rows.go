@@ -0,0 +1,91 @@
+package hastycsv
+
+import (
+	"io"
+)
+
+// Supplies rows that have already been split into fields, e.g. the rows of a
+// spreadsheet, to Reader.ReadRows().
+type RowSource interface {
+	// Returns the fields of the next row, along with the row's number (which
+	// takes the place of the line number).  Returns io.EOF once all rows have
+	// been read.  The returned slices only need to remain valid until the next
+	// call.
+	NextRow() (row int, fields [][]byte, err error)
+}
+
+// Reads rows from src and passes them to nextRecord as records, just as Read()
+// does for lines of CSV: the header is handled according to HasHeader, every
+// row must contain as many fields as the first one, and parse errors raised
+// by the record's fields stop the read.
+//
+// Each row's fields, joined by Comma, take the place of its raw line: they're
+// checked against MinLineLength and MaxLineLength, compared by DuplicateRows,
+// fed to RowDigest, and returned by LastRaw().  Digest receives each such
+// line followed by a newline.
+func (me *Reader) ReadRows(src RowSource, nextRecord Next) (err error) {
+	defer func() {
+		me.err = err
+	}()
+	if me.RecoverPanics {
+		nextRecord = me.recovering(nextRecord)
+	}
+	if err := me.reset(); err != nil {
+		return err
+	}
+	defer me.end()
+	if me.Digest != nil {
+		me.Digest.Reset()
+	}
+
+	var line []byte
+	for {
+		row, values, err := src.NextRow()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		line = appendRow(line[:0], values, me.Comma)
+		me.raw = line
+		if me.Digest != nil {
+			me.Digest.Write(line)
+			me.Digest.Write([]byte{'\n'})
+		}
+		me.hashRow(line)
+
+		me.row = row - 1 // checkLineLength() counts the row
+		if err := me.checkLineLength(line); err != nil {
+			return err
+		}
+		me.row = row
+
+		if me.fields == nil {
+			me.initFields(len(values))
+		} else if len(values) != len(me.fields) {
+			return me.fieldCountError(line, len(values))
+		}
+		for i := range values {
+			me.fields[i].data = values[i]
+		}
+
+		if me.takeHeader() || me.skipDuplicate(line) {
+			continue
+		}
+		if err := me.recordError(nextRecord(me.row, me.fields)); err != nil {
+			return err
+		}
+	}
+}
+
+// Appends the fields of a row to line, separated by comma.
+func appendRow(line []byte, values [][]byte, comma byte) []byte {
+	for i, value := range values {
+		if i > 0 {
+			line = append(line, comma)
+		}
+		line = append(line, value...)
+	}
+	return line
+}
@@ -0,0 +1,88 @@
+package hastycsv
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestReader_ReadRows(t *testing.T) {
+	src := &sliceRowSource{rows: []int{1, 2, 5}, values: [][]string{{"name", "age"}, {"bill", "30"}, {"mary", "35"}}}
+
+	r := NewReader()
+	r.HasHeader = true
+
+	received := []string{}
+	err := r.ReadRows(src, func(i int, fields []Field) error {
+		received = append(received, fmt.Sprintf("%v:%v=%v", i, fields[0].String(), fields[1].Uint32()))
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"name", "age"}, r.Header())
+	assert.Equal(t, []string{"2:bill=30", "5:mary=35"}, received)
+}
+
+func TestReader_ReadRows_errors(t *testing.T) {
+	r := NewReader()
+
+	src := &sliceRowSource{rows: []int{1, 2}, values: [][]string{{"a", "b"}, {"c"}}}
+	err := r.ReadRows(src, func(i int, fields []Field) error { return nil })
+	assert.EqualError(t, err, `Line 2: Expected []b to contain 2 fields using delimiter ',': "c"`)
+
+	src = &sliceRowSource{rows: []int{3}, values: [][]string{{"x"}}}
+	err = r.ReadRows(src, func(i int, fields []Field) error {
+		fields[0].Uint32()
+		return nil
+	})
+	assert.EqualError(t, err, `Line 3, column 1: Can't parse field as uint32: "x" contains non-numeric character 'x'`)
+}
+
+func TestReader_ReadRows_options(t *testing.T) {
+	newSource := func() *sliceRowSource {
+		return &sliceRowSource{rows: []int{1, 2, 3}, values: [][]string{{"bill", "30"}, {"mary", "35"}, {"bill", "30"}}}
+	}
+
+	r := NewReader()
+	r.DuplicateRows = SkipDuplicateRows
+	r.Digest = sha256.New()
+	received := []int{}
+	err := r.ReadRows(newSource(), func(i int, fields []Field) error {
+		received = append(received, i)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 2}, received)
+	assert.Equal(t, 1, r.DuplicateRowCount())
+	assert.Equal(t, "bill,30", string(r.LastRaw()))
+	assert.Nil(t, r.VerifyDigest(fmt.Sprintf("%x", sha256.Sum256([]byte("bill,30\nmary,35\nbill,30\n")))))
+
+	r = NewReader()
+	r.MaxLineLength = 6
+	err = r.ReadRows(newSource(), func(i int, fields []Field) error { return nil })
+	assert.EqualError(t, err, "Line 1: Length of 7 bytes exceeds the maximum of 6")
+	assert.Equal(t, err, r.Err())
+	assert.Equal(t, 1, r.Line())
+}
+
+// A RowSource that returns predefined rows.
+type sliceRowSource struct {
+	rows   []int
+	values [][]string
+	next   int
+}
+
+func (me *sliceRowSource) NextRow() (int, [][]byte, error) {
+	if me.next == len(me.rows) {
+		return 0, nil, io.EOF
+	}
+
+	fields := [][]byte{}
+	for _, s := range me.values[me.next] {
+		fields = append(fields, []byte(s))
+	}
+	me.next++
+	return me.rows[me.next-1], fields, nil
+}
@@ -0,0 +1,108 @@
+package hastycsv
+
+import (
+	"bufio"
+	"io"
+	"math/rand"
+	"sort"
+)
+
+// Returns a uniform random sample of k records of src, read in a single pass
+// using a default Reader with HasHeader set (see Reader.SampleRows()).  The
+// first row returned is the header.
+func SampleRows(src io.Reader, k int, seed int64) ([][]string, error) {
+	r := NewReader()
+	r.HasHeader = true
+	return r.SampleRows(src, k, seed)
+}
+
+// Returns a uniform random sample of k records of src (or all of them, if src
+// has fewer), in input order, preceded by the header if HasHeader is set.
+// Records are sampled in a single pass using reservoir sampling, so only k
+// records are held in memory.  The sample is determined by seed: the same
+// input and seed always yield the same sample.
+func (me *Reader) SampleRows(src io.Reader, k int, seed int64) ([][]string, error) {
+	rows, err := me.sample(src, k, seed, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var sample [][]string
+	if me.header != nil {
+		sample = append(sample, append([]string{}, me.header...))
+	}
+	for _, row := range rows {
+		sample = append(sample, row.fields)
+	}
+	return sample, nil
+}
+
+// Same as SampleRows(), but writes the sample to dst as CSV.  Records are
+// written as their raw line, like Filter().
+func (me *Reader) WriteSample(src io.Reader, dst io.Writer, k int, seed int64) error {
+	rows, err := me.sample(src, k, seed, false)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriterSize(dst, 32*1024)
+	me.writeHeader(w)
+	for _, row := range rows {
+		w.Write(row.raw)
+		w.WriteByte('\n')
+	}
+	return w.Flush()
+}
+
+// A record held in a sampling reservoir.
+type sampledRow struct {
+	index  int      // the record's position among the records of the input
+	raw    []byte   // the record's raw line, unless its fields are kept
+	fields []string // the record's fields, if kept
+}
+
+// Samples k records of src with Algorithm R, and returns them in input order.
+// If keepFields is true, the records' fields are kept instead of their raw
+// lines.
+func (me *Reader) sample(src io.Reader, k int, seed int64, keepFields bool) ([]sampledRow, error) {
+	if k < 0 {
+		k = 0
+	}
+	rng := rand.New(rand.NewSource(seed))
+	rows := make([]sampledRow, 0, k)
+	count := 0
+
+	err := me.Read(src, func(i int, record []Field) error {
+		slot := count
+		if count >= k {
+			// Replace a random row with probability k/(count+1).
+			if slot = rng.Intn(count + 1); slot >= k {
+				count++
+				return nil
+			}
+		} else {
+			rows = append(rows, sampledRow{})
+		}
+
+		row := &rows[slot]
+		row.index = count
+		if keepFields {
+			row.fields = make([]string, len(record))
+			for j := range record {
+				row.fields[j] = record[j].String()
+			}
+		} else {
+			row.raw = append(row.raw[:0], me.raw...)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].index < rows[j].index
+	})
+	return rows, nil
+}
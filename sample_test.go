@@ -0,0 +1,85 @@
+package hastycsv
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestSampleRows(t *testing.T) {
+	in := makeSampleInput(100)
+
+	sample, err := SampleRows(strings.NewReader(in), 10, 42)
+	require.Nil(t, err)
+	require.Equal(t, 11, len(sample))
+	assert.Equal(t, []string{"id", "name"}, sample[0])
+
+	// Records are distinct, and in input order
+	prev := -1
+	for _, row := range sample[1:] {
+		var id int
+		fmt.Sscan(row[0], &id)
+		assert.True(t, id > prev, row)
+		assert.Equal(t, fmt.Sprintf("name%v", id), row[1])
+		prev = id
+	}
+
+	// The same seed yields the same sample
+	again, err := SampleRows(strings.NewReader(in), 10, 42)
+	require.Nil(t, err)
+	assert.Equal(t, sample, again)
+
+	other, err := SampleRows(strings.NewReader(in), 10, 7)
+	require.Nil(t, err)
+	assert.NotEqual(t, sample, other)
+
+	// Small inputs are returned whole
+	all, err := SampleRows(strings.NewReader(makeSampleInput(3)), 10, 42)
+	require.Nil(t, err)
+	assert.Equal(t, [][]string{{"id", "name"}, {"0", "name0"}, {"1", "name1"}, {"2", "name2"}}, all)
+}
+
+func TestSampleRows_uniform(t *testing.T) {
+	in := makeSampleInput(10)
+
+	// Each record should be picked about k/n = 30% of the time
+	picks := make([]int, 10)
+	for seed := int64(0); seed < 2000; seed++ {
+		sample, err := SampleRows(strings.NewReader(in), 3, seed)
+		require.Nil(t, err)
+		for _, row := range sample[1:] {
+			var id int
+			fmt.Sscan(row[0], &id)
+			picks[id]++
+		}
+	}
+	for id, n := range picks {
+		assert.InDelta(t, 600, n, 100, "record %v", id)
+	}
+}
+
+func TestReader_WriteSample(t *testing.T) {
+	r := NewReader()
+	r.Comma = '|'
+
+	var buf bytes.Buffer
+	require.Nil(t, r.WriteSample(strings.NewReader("a|1\r\nb|2\nc|3\n"), &buf, 5, 1))
+	assert.Equal(t, "a|1\nb|2\nc|3\n", buf.String())
+
+	buf.Reset()
+	require.Nil(t, r.WriteSample(strings.NewReader("a|1\nb|2\nc|3\n"), &buf, 2, 1))
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+}
+
+// Test helper
+func makeSampleInput(records int) string {
+	var b strings.Builder
+	b.WriteString("id,name\n")
+	for i := 0; i < records; i++ {
+		fmt.Fprintf(&b, "%v,name%v\n", i, i)
+	}
+	return b.String()
+}
@@ -0,0 +1,451 @@
+package hastycsv
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Identifies the data type of a Schema column.
+type ColumnType int
+
+const (
+	StringType ColumnType = iota
+	Uint32Type
+	Int64Type
+	Float32Type
+	Float64Type
+	BoolType
+	TimeType
+)
+
+var columnTypeNames = []string{"string", "uint32", "int64", "float32", "float64", "bool", "time"}
+
+// Returns the name of this column type, e.g. "uint32".
+func (me ColumnType) String() string {
+	if me < 0 || int(me) >= len(columnTypeNames) {
+		return fmt.Sprintf("ColumnType(%d)", int(me))
+	}
+	return columnTypeNames[me]
+}
+
+// Describes a single column of a Schema.
+type Column struct {
+	Name string
+	Type ColumnType
+
+	// Layout is the time.Parse() layout of a TimeType column.
+	Layout string
+
+	// Validators are the rules that the column's fields must satisfy.  See
+	// Validate().
+	Validators []Validator
+}
+
+// Returns a column of type StringType.
+func StringColumn(name string) Column {
+	return Column{Name: name, Type: StringType}
+}
+
+// Returns a column of type Uint32Type.
+func Uint32Column(name string) Column {
+	return Column{Name: name, Type: Uint32Type}
+}
+
+// Returns a column of type Int64Type.
+func Int64Column(name string) Column {
+	return Column{Name: name, Type: Int64Type}
+}
+
+// Returns a column of type Float32Type.
+func Float32Column(name string) Column {
+	return Column{Name: name, Type: Float32Type}
+}
+
+// Returns a column of type Float64Type.
+func Float64Column(name string) Column {
+	return Column{Name: name, Type: Float64Type}
+}
+
+// Returns a column of type BoolType.
+func BoolColumn(name string) Column {
+	return Column{Name: name, Type: BoolType}
+}
+
+// Returns a column of type TimeType whose values are parsed using the
+// specified time.Parse() layout.
+func TimeColumn(name string, layout string) Column {
+	return Column{Name: name, Type: TimeType, Layout: layout}
+}
+
+// Declares the names and types of the columns of a CSV file, so that
+// Reader.ReadWithSchema() can parse each record into typed values.
+//
+// If the Reader has a header (see Reader.HasHeader), schema columns are
+// matched to the header's columns by name, and the input may contain columns
+// that are not part of the schema.  Otherwise, the Nth schema column describes
+// the Nth field of each record.
+type Schema struct {
+	Columns []Column
+
+	// OnViolation determines how records that violate column validators are
+	// handled (StopOnViolation by default).
+	OnViolation ViolationPolicy
+
+	// UniqueKey, if set, names the column (or columns, for a composite key)
+	// whose values must be unique across records.  Records whose key repeats
+	// that of an earlier record are treated as violations (see
+	// ValidationError.DuplicateOf).  Records with an empty key column are not
+	// checked.
+	UniqueKey []string
+
+	// MaxInvalidRecords, if positive, aborts reads that tolerate invalid
+	// records (under the SkipViolations and FlagViolations policies, and in
+	// Reader.Validate() and LoadIntoDB() with SkipInvalidRecords) once more
+	// than this many records have failed to parse or violated validators.
+	MaxInvalidRecords int
+
+	// MaxInvalidRatio, if positive, likewise aborts such reads once the
+	// fraction of invalid records exceeds it, e.g. 0.05 for 5%.  To avoid
+	// failing on the first few records, the ratio is only checked once at
+	// least 100 records have been read, and again at the end of the read.
+	MaxInvalidRatio float64
+
+	// StrictHeader requires the header to list exactly the schema's columns,
+	// in the same order (see HeaderOrderError), for consumers that rely on
+	// column positions.  Without a header, records must have exactly as many
+	// fields as the schema has columns.
+	StrictHeader bool
+}
+
+// Returns a new Schema made up of the specified columns.
+func NewSchema(columns ...Column) *Schema {
+	return &Schema{Columns: columns}
+}
+
+// Returns the index of the column with the specified name, or -1 if the
+// schema has no such column.
+func (me *Schema) ColumnIndex(name string) int {
+	for i, col := range me.Columns {
+		if col.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the position within each input record of every schema column.
+func (me *Schema) positions(header []string, fieldCount int) ([]int, error) {
+	positions := make([]int, len(me.Columns))
+
+	if header == nil {
+		if fieldCount < len(me.Columns) {
+			return nil, fmt.Errorf("Schema has %v columns, but the input has only %v", len(me.Columns), fieldCount)
+		} else if me.StrictHeader && fieldCount > len(me.Columns) {
+			return nil, fmt.Errorf("Schema has %v columns, but the input has %v", len(me.Columns), fieldCount)
+		}
+		for i := range positions {
+			positions[i] = i
+		}
+		return positions, nil
+	}
+
+	if me.StrictHeader {
+		if err := me.checkHeaderOrder(header); err != nil {
+			return nil, err
+		}
+	}
+
+	headerIndexes := make(map[string]int, len(header))
+	for i, name := range header {
+		if _, exists := headerIndexes[name]; !exists {
+			headerIndexes[name] = i
+		}
+	}
+
+	for i, col := range me.Columns {
+		pos, ok := headerIndexes[col.Name]
+		if !ok {
+			return nil, fmt.Errorf(`Column "%v" not found in header`, col.Name)
+		}
+		positions[i] = pos
+	}
+	return positions, nil
+}
+
+// Returns a HeaderOrderError if header doesn't list exactly the schema's
+// columns, in order.
+func (me *Schema) checkHeaderOrder(header []string) error {
+	expected := make([]string, len(me.Columns))
+	for i, col := range me.Columns {
+		expected[i] = col.Name
+	}
+
+	if !equalStrings(header, expected) {
+		return &HeaderOrderError{Expected: expected, Actual: header}
+	}
+	return nil
+}
+
+// Definition of a callback function that serves as a sequential iterator over
+// records parsed according to a Schema.  Reader.ReadWithSchema() will stop
+// reading the input records if this function returns an error.
+type NextRecord func(i int, record *Record) error
+
+// A record whose fields have been parsed into the types declared by a Schema.
+//
+// Values are accessed by schema column index (see Schema.ColumnIndex()).  An
+// accessor called for a column of a different type returns the zero value.
+// Empty fields are treated as nulls: they are not parsed, and their typed
+// value is the zero value.
+type Record struct {
+	schema     *Schema
+	fields     []Field
+	values     []value
+	violations []ValidationError
+	layouts    []observedLayout // the layouts matched by IsTime() validators
+}
+
+type observedLayout struct {
+	col    int
+	layout string
+}
+
+type value struct {
+	null bool
+	u    uint32
+	i    int64
+	f    float64
+	b    bool
+	t    time.Time
+}
+
+// Returns the Schema that this record was parsed with.
+func (me *Record) Schema() *Schema {
+	return me.schema
+}
+
+// Returns the raw Field of the specified column.
+func (me *Record) Field(col int) Field {
+	return me.fields[col]
+}
+
+// Returns true if the specified column's field is empty.
+func (me *Record) IsNull(col int) bool {
+	return me.values[col].null
+}
+
+// Returns the value of the specified column as a string.  This works for
+// columns of every type.
+func (me *Record) String(col int) string {
+	return me.fields[col].String()
+}
+
+// Returns the value of the specified Uint32Type column.
+func (me *Record) Uint32(col int) uint32 {
+	return me.values[col].u
+}
+
+// Returns the value of the specified Int64Type column.
+func (me *Record) Int64(col int) int64 {
+	return me.values[col].i
+}
+
+// Returns the value of the specified Float32Type column.
+func (me *Record) Float32(col int) float32 {
+	return float32(me.values[col].f)
+}
+
+// Returns the value of the specified Float64Type column.
+func (me *Record) Float64(col int) float64 {
+	return me.values[col].f
+}
+
+// Returns the value of the specified BoolType column.
+func (me *Record) Bool(col int) bool {
+	return me.values[col].b
+}
+
+// Returns the value of the specified TimeType column.
+func (me *Record) Time(col int) time.Time {
+	return me.values[col].t
+}
+
+// Parses the field of the specified column into its typed value.  Returns a
+// *ParseError, whose Line and Column are left for the caller to fill in, if the
+// field can't be parsed.
+func (me *Record) parse(col int) error {
+	field := me.fields[col]
+	column := &me.schema.Columns[col]
+	v := &me.values[col]
+	*v = value{null: field.IsEmpty()}
+	if v.null {
+		return nil
+	}
+
+	var err error
+	code := noParseError
+	switch column.Type {
+	case StringType:
+	case Uint32Type:
+		v.u, code = parseUint32(field.data)
+	case Int64Type:
+		v.i, err = strconv.ParseInt(field.unsafeString(), 10, 64)
+	case Float32Type:
+		v.f, err = strconv.ParseFloat(field.unsafeString(), 32)
+	case Float64Type:
+		v.f, err = strconv.ParseFloat(field.unsafeString(), 64)
+	case BoolType:
+		v.b, err = strconv.ParseBool(field.unsafeString())
+	case TimeType:
+		v.t, err = parseTime(field, column.Layout)
+	default:
+		err = fmt.Errorf("Unsupported column type %v", column.Type)
+	}
+
+	if err != nil || code != noParseError {
+		return &ParseError{
+			ColumnName: column.Name,
+			Type:       column.Type.String(),
+			Kind:       parseErrorKind(code, err),
+			Raw:        append([]byte(nil), field.data...),
+			Err:        err,
+			schema:     true,
+		}
+	}
+	return nil
+}
+
+// Parses a field as a time.  The field is converted to a regular (copied)
+// string, since the parsed time may retain parts of it (e.g. a zone name).
+func parseTime(field Field, layout string) (time.Time, error) {
+	return time.Parse(layout, field.String())
+}
+
+// Reads records from r and parses each one according to the specified
+// schema before passing it to nextRecord.  The *Record passed to nextRecord is
+// reused for every record.
+//
+// Parse errors and violations of column validators (see Column.Validate())
+// identify the offending line and column, e.g. `Line 43, column 5: Can't parse
+// column "price" as float32: "n/a"`.  How violations are handled depends on
+// schema.OnViolation; ValidationReport() summarizes them once the read is done.
+func (me *Reader) ReadWithSchema(r io.Reader, schema *Schema, nextRecord NextRecord) error {
+	return me.readWithSchema(r, schema, nil, nextRecord)
+}
+
+// Same as ReadWithSchema(), except that if onParseError is not nil, records
+// whose fields can't be parsed or validated are passed to it (as an error identifying the
+// line) instead of stopping the read.  Reading continues unless onParseError
+// returns an error, or the schema's limits on invalid records are exceeded.
+func (me *Reader) readWithSchema(r io.Reader, schema *Schema, onParseError func(err error) error, nextRecord NextRecord) error {
+	me.validation = ValidationReport{Passed: true}
+	keys, err := newUniqueKeys(schema)
+	if err != nil {
+		return err
+	}
+	record := &Record{
+		schema: schema,
+		fields: make([]Field, len(schema.Columns)),
+		values: make([]value, len(schema.Columns)),
+	}
+
+	var positions []int
+	records, invalid := 0, 0
+	skip := func(i int, err error) error {
+		invalid++
+		if err := schema.checkInvalid(invalid, records, false); err != nil {
+			return err
+		}
+		if err == nil || onParseError == nil {
+			return nil
+		}
+		return onParseError(atLine(i, err))
+	}
+
+	err = me.Read(r, func(i int, fields []Field) error {
+		if positions == nil {
+			var err error
+			if positions, err = schema.positions(me.header, len(fields)); err != nil {
+				return err
+			}
+		}
+
+		records++
+		var parseErrs ParseErrors
+		for col, pos := range positions {
+			record.fields[col] = fields[pos]
+			if err := record.parse(col); err != nil {
+				parseErr := err.(*ParseError)
+				parseErr.Column = pos + 1
+				if parseErrs = append(parseErrs, parseErr); !me.CollectFieldErrors {
+					break
+				}
+			}
+		}
+		if len(parseErrs) > 0 {
+			var err error = parseErrs
+			if len(parseErrs) == 1 {
+				err = parseErrs[0]
+			}
+			if onParseError == nil {
+				return err
+			}
+			return skip(i, err)
+		}
+
+		record.validate(i)
+		if keys != nil {
+			keys.check(record, i)
+		}
+		me.validation.add(record)
+		if len(record.violations) > 0 {
+			switch schema.OnViolation {
+			case StopOnViolation:
+				violation := record.violations[0]
+				if onParseError == nil {
+					return &violation
+				}
+				return skip(i, &violation)
+			case SkipViolations:
+				return skip(i, nil)
+			default:
+				if err := skip(i, nil); err != nil {
+					return err
+				}
+			}
+		} else if records == minRecordsForInvalidRatio {
+			// The ratio may have been exceeded by earlier records.
+			if err := schema.checkInvalid(invalid, records, false); err != nil {
+				return err
+			}
+		}
+
+		return nextRecord(i, record)
+	})
+	if err != nil {
+		return err
+	}
+	return schema.checkInvalid(invalid, records, true)
+}
+
+// The number of records that must have been read before MaxInvalidRatio is
+// enforced during a read (it's always enforced at the end).
+const minRecordsForInvalidRatio = 100
+
+// Returns an error if invalid, the number of invalid records among the first
+// records, exceeds the schema's limits.  final is true once all records have
+// been read.
+func (me *Schema) checkInvalid(invalid, records int, final bool) error {
+	if me.MaxInvalidRecords > 0 && invalid > me.MaxInvalidRecords {
+		return fmt.Errorf("Too many invalid records: more than %v", me.MaxInvalidRecords)
+	}
+
+	if me.MaxInvalidRatio > 0 && (final || records >= minRecordsForInvalidRatio) && records > 0 {
+		if ratio := float64(invalid) / float64(records); ratio > me.MaxInvalidRatio {
+			return fmt.Errorf("Too many invalid records: %v of %v (%.1f%%, limit %.1f%%)", invalid, records, ratio*100, me.MaxInvalidRatio*100)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package hastycsv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestColumnType_String(t *testing.T) {
+	assert.Equal(t, "string", StringType.String())
+	assert.Equal(t, "uint32", Uint32Type.String())
+	assert.Equal(t, "time", TimeType.String())
+	assert.Equal(t, "ColumnType(99)", ColumnType(99).String())
+}
+
+func TestSchema_ColumnIndex(t *testing.T) {
+	schema := NewSchema(StringColumn("name"), Uint32Column("age"))
+	assert.Equal(t, 0, schema.ColumnIndex("name"))
+	assert.Equal(t, 1, schema.ColumnIndex("age"))
+	assert.Equal(t, -1, schema.ColumnIndex("weight"))
+}
+
+func TestReader_ReadWithSchema(t *testing.T) {
+	schema := NewSchema(
+		StringColumn("name"),
+		Uint32Column("age"),
+		Int64Column("balance"),
+		Float32Column("weight"),
+		Float64Column("score"),
+		BoolColumn("active"),
+		TimeColumn("born", "2006-01-02"),
+	)
+
+	in := strings.NewReader(`bill|30|-120|154.5|0.125|true|1990-03-04
+mary||||||`)
+
+	r := NewReader()
+	r.Comma = '|'
+	records := []string{}
+	err := r.ReadWithSchema(in, schema, func(i int, rec *Record) error {
+		assert.Equal(t, schema, rec.Schema())
+		records = append(records, fmt.Sprintf("%v:%v,%v,%v,%v,%v,%v,%v,%v",
+			i, rec.String(0), rec.Uint32(1), rec.Int64(2), rec.Float32(3), rec.Float64(4), rec.Bool(5),
+			rec.Time(6).Format("2006-01-02"), rec.IsNull(1)))
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"1:bill,30,-120,154.5,0.125,true,1990-03-04,false",
+		"2:mary,0,0,0,0,false,0001-01-01,true",
+	}, records)
+}
+
+func TestReader_ReadWithSchema_header(t *testing.T) {
+	schema := NewSchema(Float32Column("price"), StringColumn("product"))
+	in := strings.NewReader("id,product,price\n1,apple,0.5\n2,pear,n/a")
+
+	r := NewReader()
+	r.HasHeader = true
+	prices := map[string]float32{}
+	err := r.ReadWithSchema(in, schema, func(i int, rec *Record) error {
+		prices[rec.String(1)] = rec.Float32(0)
+		assert.Equal(t, rec.String(1), rec.Field(1).String())
+		return nil
+	})
+
+	assert.EqualError(t, err, `Line 3, column 3: Can't parse column "price" as float32: "n/a"`)
+	assert.Equal(t, map[string]float32{"apple": 0.5}, prices)
+}
+
+func TestReader_ReadWithSchema_errors(t *testing.T) {
+	r := NewReader()
+	r.HasHeader = true
+	err := r.ReadWithSchema(strings.NewReader("a,b\n1,2"), NewSchema(Uint32Column("c")), func(i int, rec *Record) error { return nil })
+	assert.EqualError(t, err, `Line 2: Column "c" not found in header`)
+
+	r = NewReader()
+	err = r.ReadWithSchema(strings.NewReader("1,2"), NewSchema(Uint32Column("a"), Uint32Column("b"), Uint32Column("c")), func(i int, rec *Record) error { return nil })
+	assert.EqualError(t, err, "Line 1: Schema has 3 columns, but the input has only 2")
+
+	err = r.ReadWithSchema(strings.NewReader("2019-13-45"), NewSchema(TimeColumn("t", "2006-01-02")), func(i int, rec *Record) error { return nil })
+	assert.EqualError(t, err, `Line 1, column 1: Can't parse column "t" as time: "2019-13-45"`)
+
+	err = r.ReadWithSchema(strings.NewReader("1"), NewSchema(Uint32Column("a")), func(i int, rec *Record) error {
+		assert.Equal(t, time.Time{}, rec.Time(0))
+		return fmt.Errorf("Abort!")
+	})
+	assert.EqualError(t, err, "Line 1: Abort!")
+}
+
+func TestReader_ReadWithSchema_strictHeader(t *testing.T) {
+	schema := NewSchema(StringColumn("id"), StringColumn("name"), Uint32Column("age"))
+	schema.StrictHeader = true
+	next := func(i int, rec *Record) error { return nil }
+
+	r := NewReader()
+	r.HasHeader = true
+	assert.Nil(t, r.ReadWithSchema(strings.NewReader("id,name,age\n1,bill,30\n"), schema, next))
+
+	err := r.ReadWithSchema(strings.NewReader("id,age,name\n1,30,bill\n"), schema, next)
+	assert.EqualError(t, err, `Line 2: Header doesn't match the expected columns: column 2 is "age", expected "name"; column 3 is "name", expected "age"`)
+
+	var orderErr *HeaderOrderError
+	assert.True(t, errors.As(err, &orderErr))
+	assert.Equal(t, []string{"id", "age", "name"}, orderErr.Actual)
+
+	err = r.ReadWithSchema(strings.NewReader("id,name,age,zip\n1,bill,30,12345\n"), schema, next)
+	assert.EqualError(t, err, `Line 2: Header doesn't match the expected columns: column 4 "zip" is unexpected`)
+
+	err = r.ReadWithSchema(strings.NewReader("id,name\n1,bill\n"), schema, next)
+	assert.EqualError(t, err, `Line 2: Header doesn't match the expected columns: column 3 is missing, expected "age"`)
+
+	r.HasHeader = false
+	err = r.ReadWithSchema(strings.NewReader("1,bill,30,12345\n"), schema, next)
+	assert.EqualError(t, err, "Line 1: Schema has 3 columns, but the input has 4")
+}
@@ -0,0 +1,430 @@
+package hastycsv
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// The number of bytes of records that Sort() holds in memory (and sorts) at a
+// time if none is specified.
+const DefaultSortRunSize = 64 << 20
+
+// A column that Sort() orders records by.
+type SortKey struct {
+	// Column identifies the column by its Name, which is looked up in the
+	// header, and determines how its values are compared: bytewise for
+	// StringType, chronologically for TimeType (parsed using Layout), and
+	// numerically for the other types.  Empty fields sort before any value,
+	// i.e. first, or last if Descending.
+	Column Column
+
+	// Index identifies the column by its (0-based) position instead, for input
+	// without a header.  It's only used if Column.Name is "".
+	Index int
+
+	// Descending reverses the order of the column's values.
+	Descending bool
+}
+
+type sortConfig struct {
+	reader  *Reader
+	runSize int64
+	tempDir string
+}
+
+// Configures Sort().
+type SortOption func(cfg *sortConfig)
+
+// Returns a SortOption that makes Sort() parse its input using the specified
+// Reader, e.g. to clear HasHeader.  By default, a Reader returned by
+// NewReader() with the Writer's Comma delimiter and HasHeader set to true is
+// used.
+func WithSortReader(reader *Reader) SortOption {
+	return func(cfg *sortConfig) {
+		cfg.reader = reader
+	}
+}
+
+// Returns a SortOption that sets how many bytes of records are sorted in
+// memory before being spilled to a temporary file (DefaultSortRunSize by
+// default).  Memory use is a small multiple of this.
+func WithSortRunSize(bytes int64) SortOption {
+	return func(cfg *sortConfig) {
+		cfg.runSize = bytes
+	}
+}
+
+// Returns a SortOption that sets the directory where Sort() creates its
+// temporary files (os.TempDir() by default).
+func WithSortTempDir(dir string) SortOption {
+	return func(cfg *sortConfig) {
+		cfg.tempDir = dir
+	}
+}
+
+// Streams the records of src to dst, sorted by the specified keys (the first
+// key first, then the second for records with equal first keys, and so on).
+// Records with equal keys keep their input order.  If the input has a header,
+// it is written first.  dst is flushed once all records have been written.
+//
+// Inputs too large to sort in memory are sorted externally: runs of records
+// are sorted in memory, spilled to temporary files, and then merged.  Records
+// are written as their raw line, so they needn't be re-encoded.
+func Sort(dst *Writer, src io.Reader, keys []SortKey, options ...SortOption) error {
+	cfg := &sortConfig{runSize: DefaultSortRunSize}
+	for _, option := range options {
+		option(cfg)
+	}
+	if cfg.reader == nil {
+		cfg.reader = NewReader()
+		cfg.reader.Comma = dst.Comma
+		cfg.reader.HasHeader = true
+	}
+
+	s := &sorter{
+		keys:    keys,
+		schema:  &Schema{Columns: make([]Column, len(keys))},
+		arena:   NewArena(DefaultArenaBlockSize),
+		reader:  cfg.reader,
+		tempDir: cfg.tempDir,
+	}
+	for i, key := range keys {
+		s.schema.Columns[i] = key.Column
+	}
+	defer s.removeRuns()
+
+	err := cfg.reader.Read(src, func(i int, record []Field) error {
+		if s.indexes == nil {
+			if err := s.start(dst, record); err != nil {
+				return err
+			}
+		}
+		if err := s.add(record); err != nil {
+			return err
+		}
+		if s.arena.Size() >= cfg.runSize {
+			return s.spill()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if header := cfg.reader.Header(); s.indexes == nil && header != nil {
+		// The input holds no records, so start() wasn't called.
+		if err := dst.Write(header); err != nil {
+			return err
+		}
+	}
+
+	if len(s.runs) == 0 {
+		// Everything fit in memory.
+		s.sortRecords()
+		for _, rec := range s.records {
+			if err := dst.writeLine(rec.fields[0].data, s.fieldCount); err != nil {
+				return err
+			}
+		}
+	} else {
+		if err := s.spill(); err != nil {
+			return err
+		}
+		if err := s.merge(dst); err != nil {
+			return err
+		}
+	}
+
+	return dst.Flush()
+}
+
+// The state of a Sort() call.
+type sorter struct {
+	keys       []SortKey
+	schema     *Schema // a column per key, used to parse the keys
+	indexes    []int   // the position of each key within records
+	fieldCount int
+	reader     *Reader
+	tempDir    string
+
+	arena   *Arena       // the raw lines and key fields of the current run
+	records []sortRecord // the records of the current run
+	values  []value      // the parsed keys of the current run's records
+	keyRec  Record       // used to parse keys
+	scratch []Field
+	runs    []*os.File
+}
+
+// A record of the current run.  fields[0] is the record's raw line and
+// fields[1:] are its key fields; its parsed keys are values[v:v+len(keys)].
+type sortRecord struct {
+	fields []Field
+	v      int
+}
+
+// Resolves the positions of the keys and writes the header, if any, given the
+// first record.
+func (me *sorter) start(dst *Writer, first []Field) error {
+	me.fieldCount = len(first)
+	me.indexes = make([]int, len(me.keys))
+	for i, key := range me.keys {
+		if key.Column.Name == "" {
+			me.indexes[i] = key.Index
+		} else if header := me.reader.Header(); header == nil {
+			return fmt.Errorf(`Can't sort by column "%v": the input has no header`, key.Column.Name)
+		} else if me.indexes[i] = indexOfString(header, key.Column.Name); me.indexes[i] < 0 {
+			return fmt.Errorf(`Column "%v" not found in header`, key.Column.Name)
+		}
+
+		if me.indexes[i] < 0 || me.indexes[i] >= len(first) {
+			return fmt.Errorf("Column index %v is out of range for records with %v fields", me.indexes[i], len(first))
+		}
+	}
+
+	me.keyRec = Record{schema: me.schema, fields: make([]Field, len(me.keys)), values: make([]value, len(me.keys))}
+	if header := me.reader.Header(); header != nil {
+		return dst.Write(header)
+	}
+	return nil
+}
+
+// Parses the keys of record into me.keyRec.
+func (me *sorter) parseKeys(record []Field) error {
+	for i, index := range me.indexes {
+		me.keyRec.fields[i] = record[index]
+		if err := me.keyRec.parse(i); err != nil {
+			err.(*ParseError).Column = index + 1
+			return err
+		}
+	}
+	return nil
+}
+
+// Adds the current record of the Reader to the current run.
+func (me *sorter) add(record []Field) error {
+	if err := me.parseKeys(record); err != nil {
+		return err
+	}
+
+	me.scratch = append(append(me.scratch[:0], NewField(me.reader.raw)), me.keyRec.fields...)
+	me.records = append(me.records, sortRecord{fields: me.arena.Retain(me.scratch), v: len(me.values)})
+	me.values = append(me.values, me.keyRec.values...)
+	return nil
+}
+
+func (me *sorter) sortRecords() {
+	n := len(me.keys)
+	sort.SliceStable(me.records, func(i, j int) bool {
+		a, b := &me.records[i], &me.records[j]
+		return me.compare(a.fields[1:], me.values[a.v:a.v+n], b.fields[1:], me.values[b.v:b.v+n]) < 0
+	})
+}
+
+// Compares two records by their key fields and parsed keys.
+func (me *sorter) compare(aFields []Field, aValues []value, bFields []Field, bValues []value) int {
+	for i := range me.keys {
+		c := compareValues(me.schema.Columns[i].Type, aFields[i], &aValues[i], bFields[i], &bValues[i])
+		if me.keys[i].Descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareValues(typ ColumnType, aField Field, a *value, bField Field, b *value) int {
+	if a.null || b.null {
+		return compareBools(!a.null, !b.null)
+	}
+
+	switch typ {
+	case Uint32Type:
+		return compareOrdered(a.u, b.u)
+	case Int64Type:
+		return compareOrdered(a.i, b.i)
+	case Float32Type, Float64Type:
+		return compareOrdered(a.f, b.f)
+	case BoolType:
+		return compareBools(a.b, b.b)
+	case TimeType:
+		if a.t.Before(b.t) {
+			return -1
+		} else if a.t.After(b.t) {
+			return 1
+		}
+		return 0
+	}
+	return bytes.Compare(aField.data, bField.data)
+}
+
+// Returns the index of the first occurrence of s in values, or -1.
+func indexOfString(values []string, s string) int {
+	for i, v := range values {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func compareOrdered[T uint32 | int64 | float64](a, b T) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// Orders false before true.
+func compareBools(a, b bool) int {
+	if a == b {
+		return 0
+	} else if a {
+		return 1
+	}
+	return -1
+}
+
+// Sorts the current run and writes it to a temporary file.
+func (me *sorter) spill() error {
+	me.sortRecords()
+
+	f, err := os.CreateTemp(me.tempDir, "hastycsv-sort-*")
+	if err != nil {
+		return err
+	}
+	me.runs = append(me.runs, f)
+
+	w := bufio.NewWriterSize(f, 32*1024)
+	for _, rec := range me.records {
+		w.Write(rec.fields[0].data)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	me.arena.Free()
+	me.records = me.records[:0]
+	me.values = me.values[:0]
+	return nil
+}
+
+// Merges the sorted runs into dst.
+func (me *sorter) merge(dst *Writer) error {
+	h := &runHeap{sorter: me}
+	for i, f := range me.runs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		r := NewReader()
+		r.Comma = me.reader.Comma
+		r.QuoteFallback = me.reader.QuoteFallback
+		run := &sortRun{index: i, reader: r, keys: Record{
+			schema: me.schema,
+			fields: make([]Field, len(me.keys)),
+			values: make([]value, len(me.keys)),
+		}}
+		if err := r.begin(f); err != nil {
+			return err
+		}
+		defer r.end()
+
+		if ok, err := me.advance(run); err != nil {
+			return err
+		} else if ok {
+			h.runs = append(h.runs, run)
+		}
+	}
+	heap.Init(h)
+
+	for len(h.runs) > 0 {
+		run := h.runs[0]
+		if err := dst.writeLine(run.reader.raw, me.fieldCount); err != nil {
+			return err
+		}
+
+		if ok, err := me.advance(run); err != nil {
+			return err
+		} else if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return nil
+}
+
+// Reads the next record of run and parses its keys.  Returns false once the
+// run is exhausted.
+func (me *sorter) advance(run *sortRun) (bool, error) {
+	record, err := run.reader.next()
+	if err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	for i, index := range me.indexes {
+		run.keys.fields[i] = record[index]
+		if err := run.keys.parse(i); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (me *sorter) removeRuns() {
+	for _, f := range me.runs {
+		f.Close()
+		os.Remove(f.Name())
+	}
+}
+
+// A sorted run being merged, positioned at its current record.
+type sortRun struct {
+	index  int
+	reader *Reader
+	keys   Record // the key fields of the current record
+}
+
+// Orders runs by their current record, and then by their index so that the
+// merge is stable.
+type runHeap struct {
+	sorter *sorter
+	runs   []*sortRun
+}
+
+func (me *runHeap) Len() int {
+	return len(me.runs)
+}
+
+func (me *runHeap) Less(i, j int) bool {
+	a, b := me.runs[i], me.runs[j]
+	if c := me.sorter.compare(a.keys.fields, a.keys.values, b.keys.fields, b.keys.values); c != 0 {
+		return c < 0
+	}
+	return a.index < b.index
+}
+
+func (me *runHeap) Swap(i, j int) {
+	me.runs[i], me.runs[j] = me.runs[j], me.runs[i]
+}
+
+func (me *runHeap) Push(x interface{}) {
+	me.runs = append(me.runs, x.(*sortRun))
+}
+
+func (me *runHeap) Pop() interface{} {
+	run := me.runs[len(me.runs)-1]
+	me.runs = me.runs[:len(me.runs)-1]
+	return run
+}
@@ -0,0 +1,106 @@
+package hastycsv
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSort(t *testing.T) {
+	in := "name,dept,salary\nbill,eng,100\nmary,ops,90\njoe,eng,\nann,eng,120\nsue,ops,90\n"
+	keys := []SortKey{
+		{Column: StringColumn("dept")},
+		{Column: Uint32Column("salary"), Descending: true},
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, Sort(NewWriter(&buf), strings.NewReader(in), keys))
+	assert.Equal(t, "name,dept,salary\nann,eng,120\nbill,eng,100\njoe,eng,\nmary,ops,90\nsue,ops,90\n", buf.String())
+}
+
+func TestSort_headerOnly(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, Sort(NewWriter(&buf), strings.NewReader("name,dept,salary\n"), []SortKey{{Column: StringColumn("dept")}}))
+	assert.Equal(t, "name,dept,salary\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, Sort(NewWriter(&buf), strings.NewReader(""), []SortKey{{Column: StringColumn("dept")}}))
+	assert.Equal(t, "", buf.String())
+}
+
+func TestSort_emptyFields(t *testing.T) {
+	in := "id,salary\n1,20\n2,\n3,10\n"
+
+	var buf bytes.Buffer
+	assert.Nil(t, Sort(NewWriter(&buf), strings.NewReader(in), []SortKey{{Column: Uint32Column("salary")}}))
+	assert.Equal(t, "id,salary\n2,\n3,10\n1,20\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, Sort(NewWriter(&buf), strings.NewReader(in), []SortKey{{Column: Uint32Column("salary"), Descending: true}}))
+	assert.Equal(t, "id,salary\n1,20\n3,10\n2,\n", buf.String())
+}
+
+func TestSort_noHeader(t *testing.T) {
+	r := NewReader()
+	r.Comma = '|'
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Comma = '|'
+
+	in := "b|2021-03-01\na|2020-12-31\nc|2021-01-15\n"
+	err := Sort(w, strings.NewReader(in), []SortKey{{Column: TimeColumn("", "2006-01-02"), Index: 1}}, WithSortReader(r))
+	assert.Nil(t, err)
+	assert.Equal(t, "a|2020-12-31\nc|2021-01-15\nb|2021-03-01\n", buf.String())
+}
+
+func TestSort_external(t *testing.T) {
+	tmpDir := makeTempDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	rnd := rand.New(rand.NewSource(1))
+	values := make([]int, 1000)
+	lines := []string{"id,value"}
+	for i := range values {
+		values[i] = rnd.Intn(100)
+		lines = append(lines, fmt.Sprintf("%v,%v", i, values[i]))
+	}
+
+	var buf bytes.Buffer
+	err := Sort(NewWriter(&buf), strings.NewReader(strings.Join(lines, "\n")), []SortKey{{Column: Int64Column("value")}},
+		WithSortRunSize(256), WithSortTempDir(tmpDir))
+	require.Nil(t, err)
+
+	// The sort must be stable, i.e. ids of equal values stay in input order.
+	expected := []string{"id,value"}
+	ids := make([]int, len(values))
+	for i := range ids {
+		ids[i] = i
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return values[ids[i]] < values[ids[j]] })
+	for _, id := range ids {
+		expected = append(expected, fmt.Sprintf("%v,%v", id, values[id]))
+	}
+	assert.Equal(t, strings.Join(expected, "\n")+"\n", buf.String())
+
+	assert.Equal(t, []string{}, listDir(t, tmpDir), "temporary files must be removed")
+}
+
+func TestSort_errors(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := Sort(NewWriter(&buf), strings.NewReader("a,b\n1,2\n"), []SortKey{{Column: StringColumn("c")}})
+	assert.EqualError(t, err, `Line 2: Column "c" not found in header`)
+
+	err = Sort(NewWriter(&buf), strings.NewReader("a,b\n1,2\nx,3\n"), []SortKey{{Column: Uint32Column("a")}})
+	assert.EqualError(t, err, `Line 3, column 1: Can't parse column "a" as uint32: "x"`)
+
+	err = Sort(NewWriter(&buf), strings.NewReader("1,2\n"), []SortKey{{Column: StringColumn("a")}}, WithSortReader(NewReader()))
+	assert.EqualError(t, err, `Line 1: Can't sort by column "a": the input has no header`)
+}
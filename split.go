@@ -0,0 +1,254 @@
+package hastycsv
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"os"
+)
+
+type splitConfig struct {
+	reader    *Reader
+	fixed     bool   // true for round-robin and key hash splits
+	shards    int    // the number of shards, for round-robin and key hash splits
+	keyColumn string // the key column, for key hash splits
+	maxRows   int
+	maxBytes  int64
+}
+
+// Configures Split().
+type SplitOption func(cfg *splitConfig)
+
+// Returns a SplitOption that makes Split() parse its input using the specified
+// Reader, e.g. to set its Comma delimiter.  By default, a Reader returned by
+// NewReader() with HasHeader set to true is used.
+func WithSplitReader(reader *Reader) SplitOption {
+	return func(cfg *splitConfig) {
+		cfg.reader = reader
+	}
+}
+
+// Returns a SplitOption that makes Split() deal the records out to the
+// specified number of shards in turn.
+func WithRoundRobin(shards int) SplitOption {
+	return func(cfg *splitConfig) {
+		cfg.fixed = true
+		cfg.shards = shards
+		cfg.keyColumn = ""
+	}
+}
+
+// Returns a SplitOption that makes Split() assign each record to one of the
+// specified number of shards by hashing its value of the specified column,
+// so that all records with the same value end up in the same shard.  The
+// assignment is deterministic across runs.
+func WithKeyHash(column string, shards int) SplitOption {
+	return func(cfg *splitConfig) {
+		cfg.fixed = true
+		cfg.shards = shards
+		cfg.keyColumn = column
+	}
+}
+
+// Returns a SplitOption that makes Split() start a new shard once the current
+// one holds the specified number of records.
+func WithMaxShardRows(rows int) SplitOption {
+	return func(cfg *splitConfig) {
+		cfg.maxRows = rows
+	}
+}
+
+// Returns a SplitOption that makes Split() start a new shard before the
+// current one would exceed the specified number of bytes of records (not
+// counting the header).  A shard always holds at least one record.
+func WithMaxShardBytes(bytes int64) SplitOption {
+	return func(cfg *splitConfig) {
+		cfg.maxBytes = bytes
+	}
+}
+
+// Splits the records of src into shard files, whose paths are built by
+// formatting the shard's (0-based) number with pathFormat, e.g.
+// "out/part-%04d.csv".  If the input has a header, it is written at the start
+// of every shard.  Returns the paths of the shards, in order.
+//
+// Records are assigned to shards according to the options: WithRoundRobin()
+// and WithKeyHash() create a fixed number of shards, while WithMaxShardRows()
+// and WithMaxShardBytes() fill one shard after the other.  The shards are
+// removed if an error occurs.
+func Split(src io.Reader, pathFormat string, options ...SplitOption) ([]string, error) {
+	cfg := &splitConfig{}
+	for _, option := range options {
+		option(cfg)
+	}
+	if cfg.reader == nil {
+		cfg.reader = NewReader()
+		cfg.reader.HasHeader = true
+	}
+	if cfg.fixed && cfg.shards < 1 {
+		return nil, fmt.Errorf("Number of shards must be at least 1, but is %v", cfg.shards)
+	}
+	if !cfg.fixed && cfg.maxRows <= 0 && cfg.maxBytes <= 0 {
+		return nil, fmt.Errorf("No split strategy specified")
+	}
+
+	s := &splitter{reader: cfg.reader, cfg: cfg, pathFormat: pathFormat}
+	err := cfg.reader.Read(src, func(i int, record []Field) error {
+		if s.fieldCount == 0 {
+			if err := s.start(record); err != nil {
+				return err
+			}
+		}
+
+		shard, err := s.shardOf(record)
+		if err != nil {
+			return err
+		}
+		return s.write(shard, record)
+	})
+	if err == nil && s.fieldCount == 0 && cfg.fixed {
+		// The input has no records; create the (empty) shards anyway.
+		err = s.start(nil)
+	}
+	if err == nil {
+		err = s.closeAll()
+	}
+
+	if err != nil {
+		s.closeAll()
+		for _, path := range s.paths {
+			os.Remove(path)
+		}
+		return nil, err
+	}
+	return s.paths, nil
+}
+
+// The state of a Split() call.
+type splitter struct {
+	reader     *Reader
+	cfg        *splitConfig
+	pathFormat string
+	fieldCount int
+	keyIndex   int
+	keyHash    hash.Hash64
+
+	paths  []string
+	shards []*splitShard // the open shards
+	next   int           // the next shard, for round-robin splits
+}
+
+type splitShard struct {
+	f     *os.File
+	w     *Writer
+	rows  int
+	bytes int64
+}
+
+// Locates the key column and, for fixed numbers of shards, creates the shards,
+// given the first record.
+func (me *splitter) start(first []Field) error {
+	me.fieldCount = len(first)
+
+	if me.cfg.keyColumn != "" {
+		header := me.reader.Header()
+		if header == nil {
+			return fmt.Errorf(`Can't split by column "%v": the input has no header`, me.cfg.keyColumn)
+		}
+		if me.keyIndex = indexOfString(header, me.cfg.keyColumn); me.keyIndex < 0 {
+			return fmt.Errorf(`Column "%v" not found in header`, me.cfg.keyColumn)
+		}
+		me.keyHash = fnv.New64a()
+	}
+
+	for i := 0; i < me.cfg.shards; i++ {
+		if err := me.create(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the index within me.shards of the shard of record.
+func (me *splitter) shardOf(record []Field) (int, error) {
+	switch {
+	case me.cfg.keyColumn != "":
+		me.keyHash.Reset()
+		me.keyHash.Write(record[me.keyIndex].data)
+		return int(me.keyHash.Sum64() % uint64(me.cfg.shards)), nil
+
+	case me.cfg.fixed:
+		shard := me.next
+		me.next = (me.next + 1) % me.cfg.shards
+		return shard, nil
+	}
+
+	// Fill one shard after the other.
+	last := len(me.shards) - 1
+	if last >= 0 {
+		shard := me.shards[last]
+		fullRows := me.cfg.maxRows > 0 && shard.rows >= me.cfg.maxRows
+		fullBytes := me.cfg.maxBytes > 0 && shard.rows > 0 && shard.bytes+int64(len(me.reader.raw))+1 > me.cfg.maxBytes
+		if !fullRows && !fullBytes {
+			return last, nil
+		}
+		if err := me.close(shard); err != nil {
+			return 0, err
+		}
+	}
+	if err := me.create(); err != nil {
+		return 0, err
+	}
+	return len(me.shards) - 1, nil
+}
+
+func (me *splitter) write(index int, record []Field) error {
+	shard := me.shards[index]
+	shard.rows++
+	shard.bytes += int64(len(me.reader.raw)) + 1
+	return shard.w.writeLine(me.reader.raw, len(record))
+}
+
+// Creates the next shard and writes the header to it.
+func (me *splitter) create() error {
+	path := fmt.Sprintf(me.pathFormat, len(me.paths))
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	me.paths = append(me.paths, path)
+
+	shard := &splitShard{f: f, w: NewWriter(f)}
+	shard.w.Comma = me.reader.Comma
+	me.shards = append(me.shards, shard)
+
+	if header := me.reader.Header(); header != nil {
+		return shard.w.Write(header)
+	}
+	return nil
+}
+
+func (me *splitter) close(shard *splitShard) error {
+	if shard.f == nil {
+		return nil
+	}
+
+	err := shard.w.Flush()
+	if closeErr := shard.f.Close(); err == nil {
+		err = closeErr
+	}
+	shard.f = nil
+	return err
+}
+
+// Closes every open shard, and returns the first error.
+func (me *splitter) closeAll() error {
+	var firstErr error
+	for _, shard := range me.shards {
+		if err := me.close(shard); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,98 @@
+package hastycsv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplit_roundRobin(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	in := "id,name\n1,a\n2,b\n3,c\n4,d\n5,e\n"
+	paths, err := Split(strings.NewReader(in), filepath.Join(dir, "part-%02d.csv"), WithRoundRobin(3))
+
+	require.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "part-00.csv"), filepath.Join(dir, "part-01.csv"), filepath.Join(dir, "part-02.csv")}, paths)
+	assert.Equal(t, []string{"id,name\n1,a\n4,d\n", "id,name\n2,b\n5,e\n", "id,name\n3,c\n"}, readShards(t, paths))
+}
+
+func TestSplit_keyHash(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	in := "id,region\n1,east\n2,west\n3,east\n4,north\n5,west\n"
+	paths, err := Split(strings.NewReader(in), filepath.Join(dir, "part-%d.csv"), WithKeyHash("region", 2))
+	require.Nil(t, err)
+	require.Equal(t, 2, len(paths))
+
+	// Every region must end up in a single shard
+	regions := map[string]int{}
+	total := 0
+	for shard, content := range readShards(t, paths) {
+		lines := strings.Split(strings.TrimSpace(content), "\n")
+		assert.Equal(t, "id,region", lines[0])
+		for _, line := range lines[1:] {
+			region := strings.Split(line, ",")[1]
+			if prev, exists := regions[region]; exists {
+				assert.Equal(t, prev, shard, region)
+			}
+			regions[region] = shard
+			total++
+		}
+	}
+	assert.Equal(t, 5, total)
+}
+
+func TestSplit_maxRowsAndBytes(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	in := "id,name\n1,a\n2,b\n3,c\n4,dddddd\n5,e\n"
+
+	paths, err := Split(strings.NewReader(in), filepath.Join(dir, "rows-%d.csv"), WithMaxShardRows(2))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"id,name\n1,a\n2,b\n", "id,name\n3,c\n4,dddddd\n", "id,name\n5,e\n"}, readShards(t, paths))
+
+	paths, err = Split(strings.NewReader(in), filepath.Join(dir, "bytes-%d.csv"), WithMaxShardBytes(8))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"id,name\n1,a\n2,b\n", "id,name\n3,c\n", "id,name\n4,dddddd\n", "id,name\n5,e\n"}, readShards(t, paths))
+}
+
+func TestSplit_errors(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	_, err := Split(strings.NewReader("a\n1\n"), filepath.Join(dir, "%d.csv"))
+	assert.EqualError(t, err, "No split strategy specified")
+
+	_, err = Split(strings.NewReader("a,b\n1,2\n3\n"), filepath.Join(dir, "%d.csv"), WithRoundRobin(2))
+	assert.EqualError(t, err, `Line 3: Expected []b to contain 2 fields using delimiter ',': "3"`)
+	assert.Equal(t, []string{}, listDir(t, dir), "shards must be removed on error")
+
+	_, err = Split(strings.NewReader("a,b\n1,2\n"), filepath.Join(dir, "%d.csv"), WithKeyHash("c", 2))
+	assert.EqualError(t, err, `Line 2: Column "c" not found in header`)
+
+	_, err = Split(strings.NewReader("a,b\n1,2\n"), filepath.Join(dir, "%d.csv"), WithKeyHash("a", 0), WithMaxShardRows(1))
+	assert.EqualError(t, err, "Number of shards must be at least 1, but is 0")
+
+	_, err = Split(strings.NewReader("a,b\n1,2\n"), filepath.Join(dir, "%d.csv"), WithRoundRobin(0), WithMaxShardRows(1))
+	assert.EqualError(t, err, "Number of shards must be at least 1, but is 0")
+	assert.Equal(t, []string{}, listDir(t, dir))
+}
+
+// Test helper
+func readShards(t *testing.T, paths []string) []string {
+	contents := make([]string, len(paths))
+	for i, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		require.Nil(t, err)
+		contents[i] = string(data)
+	}
+	return contents
+}
@@ -0,0 +1,190 @@
+package hastycsv
+
+import (
+	"fmt"
+	"hash/maphash"
+	"io"
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+// Precision of the HyperLogLog sketches used to estimate distinct counts: 2^12
+// registers, for a standard error of about 1.6%.
+const hllPrecision = 12
+
+// A profile of a CSV input, as computed by Reader.ReadWithStats().
+type Stats struct {
+	// Records is the number of records read (excluding the header).
+	Records int64
+
+	Columns []ColumnStats
+}
+
+// Statistics of a single column.
+type ColumnStats struct {
+	// Name is the column's name in the header, or "column<N>" if the input has
+	// no header.
+	Name string
+
+	// Nulls is the number of empty fields.
+	Nulls int64
+
+	// Numeric is true if every non-empty field parsed as a number, in which
+	// case Min, Max and Mean describe the column's values.
+	Numeric bool
+	Min     float64
+	Max     float64
+	Mean    float64
+
+	// Distinct is an estimate of the number of distinct non-empty values.
+	Distinct uint64
+
+	// MinLength and MaxLength are the shortest and longest field lengths, in
+	// bytes.
+	MinLength int
+	MaxLength int
+
+	// LengthHistogram[0] counts empty fields, and LengthHistogram[k] counts
+	// fields whose length is in [2^(k-1), 2^k).
+	LengthHistogram []int64
+}
+
+// Accumulates the statistics of a single column.
+type columnStatsCollector struct {
+	stats    ColumnStats
+	nonEmpty int64
+	sum      float64
+	hash     maphash.Hash
+	hll      [1 << hllPrecision]uint8
+}
+
+func newColumnStatsCollector(name string, seed maphash.Seed) *columnStatsCollector {
+	c := &columnStatsCollector{stats: ColumnStats{Name: name, Numeric: true, MinLength: math.MaxInt32}}
+	c.hash.SetSeed(seed)
+	return c
+}
+
+func (me *columnStatsCollector) add(field Field) {
+	s := &me.stats
+
+	n := len(field.data)
+	if n < s.MinLength {
+		s.MinLength = n
+	}
+	if n > s.MaxLength {
+		s.MaxLength = n
+	}
+	bucket := bits.Len(uint(n))
+	for len(s.LengthHistogram) <= bucket {
+		s.LengthHistogram = append(s.LengthHistogram, 0)
+	}
+	s.LengthHistogram[bucket]++
+
+	if n == 0 {
+		s.Nulls++
+		return
+	}
+	me.nonEmpty++
+
+	if s.Numeric {
+		if v, err := strconv.ParseFloat(field.unsafeString(), 64); err != nil {
+			s.Numeric = false
+		} else {
+			if me.nonEmpty == 1 || v < s.Min {
+				s.Min = v
+			}
+			if me.nonEmpty == 1 || v > s.Max {
+				s.Max = v
+			}
+			me.sum += v
+		}
+	}
+
+	me.hash.Reset()
+	me.hash.Write(field.data)
+	h := me.hash.Sum64()
+	register := h >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(h<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > me.hll[register] {
+		me.hll[register] = rank
+	}
+}
+
+// Returns the final statistics of this column.
+func (me *columnStatsCollector) report() ColumnStats {
+	s := me.stats
+	if s.MinLength == math.MaxInt32 {
+		s.MinLength = 0
+	}
+	if me.nonEmpty == 0 || !s.Numeric {
+		s.Numeric = false
+		s.Min, s.Max = 0, 0
+	} else {
+		s.Mean = me.sum / float64(me.nonEmpty)
+	}
+	s.Distinct = me.estimateDistinct()
+	return s
+}
+
+// Returns the HyperLogLog estimate of the number of distinct values.
+func (me *columnStatsCollector) estimateDistinct() uint64 {
+	const m = float64(len(me.hll))
+
+	sum := 0.0
+	zeros := 0
+	for _, rank := range me.hll {
+		sum += 1 / float64(uint64(1)<<rank)
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 { // small range correction (linear counting)
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// Reads records from r like Read(), and returns a profile of each column
+// (min/max/mean of numeric columns, null count, distinct count estimate and
+// field length histogram), computed in the same pass.  nextRecord may be nil
+// if the records are only being profiled.
+func (me *Reader) ReadWithStats(r io.Reader, nextRecord Next) (*Stats, error) {
+	stats := &Stats{}
+	seed := maphash.MakeSeed()
+	var columns []*columnStatsCollector
+
+	err := me.Read(r, func(i int, fields []Field) error {
+		if columns == nil {
+			columns = make([]*columnStatsCollector, len(fields))
+			for j := range columns {
+				name := fmt.Sprintf("column%v", j+1)
+				if me.header != nil {
+					name = me.header[j]
+				}
+				columns[j] = newColumnStatsCollector(name, seed)
+			}
+		}
+
+		stats.Records++
+		for j, field := range fields {
+			columns[j].add(field)
+		}
+
+		if nextRecord != nil {
+			return nextRecord(i, fields)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Columns = make([]ColumnStats, len(columns))
+	for j, c := range columns {
+		stats.Columns[j] = c.report()
+	}
+	return stats, nil
+}
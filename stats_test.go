@@ -0,0 +1,71 @@
+package hastycsv
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestReader_ReadWithStats(t *testing.T) {
+	in := strings.NewReader("name|age|score\nbill|30|1.5\nmary||x\njoe|40|2.5\nbill|50|")
+
+	r := NewReader()
+	r.Comma = '|'
+	r.HasHeader = true
+
+	records := 0
+	stats, err := r.ReadWithStats(in, func(i int, fields []Field) error {
+		records++
+		return nil
+	})
+
+	require.Nil(t, err)
+	assert.Equal(t, 4, records)
+	assert.Equal(t, int64(4), stats.Records)
+	require.Equal(t, 3, len(stats.Columns))
+
+	name := stats.Columns[0]
+	assert.Equal(t, "name", name.Name)
+	assert.False(t, name.Numeric)
+	assert.Equal(t, int64(0), name.Nulls)
+	assert.Equal(t, uint64(3), name.Distinct)
+	assert.Equal(t, 3, name.MinLength)
+	assert.Equal(t, 4, name.MaxLength)
+	assert.Equal(t, []int64{0, 0, 1, 3}, name.LengthHistogram)
+
+	age := stats.Columns[1]
+	assert.True(t, age.Numeric)
+	assert.Equal(t, int64(1), age.Nulls)
+	assert.Equal(t, 30.0, age.Min)
+	assert.Equal(t, 50.0, age.Max)
+	assert.Equal(t, 40.0, age.Mean)
+	assert.Equal(t, 0, age.MinLength)
+	assert.Equal(t, []int64{1, 0, 3}, age.LengthHistogram)
+
+	score := stats.Columns[2]
+	assert.False(t, score.Numeric)
+	assert.Equal(t, 0.0, score.Min)
+	assert.Equal(t, int64(1), score.Nulls)
+}
+
+func TestReader_ReadWithStats_distinctEstimate(t *testing.T) {
+	buf := &bytes.Buffer{}
+	for i := 0; i < 100000; i++ {
+		fmt.Fprintf(buf, "%v,%v\n", i%20000, i%7)
+	}
+
+	stats, err := NewReader().ReadWithStats(buf, nil)
+	require.Nil(t, err)
+	assert.Equal(t, "column1", stats.Columns[0].Name)
+	assert.InEpsilon(t, 20000, float64(stats.Columns[0].Distinct), 0.05)
+	assert.Equal(t, uint64(7), stats.Columns[1].Distinct)
+	assert.InDelta(t, 3.0, stats.Columns[1].Mean, 0.001)
+}
+
+func TestReader_ReadWithStats_error(t *testing.T) {
+	_, err := NewReader().ReadWithStats(strings.NewReader("a,b\nc"), nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,61 @@
+package hastycsv
+
+import (
+	"sync"
+)
+
+// A Writer wrapper that can safely be shared by multiple goroutines.
+//
+// Calls are serialized with a mutex, so each record is written as a whole and
+// never interleaves with records written by other goroutines.  The order of
+// records written concurrently is, of course, not defined.
+type SyncWriter struct {
+	mu sync.Mutex
+	w  *Writer
+}
+
+// Returns a new SyncWriter that serializes access to the specified Writer.
+// The Writer must not be used directly while it's wrapped by the SyncWriter.
+func NewSyncWriter(w *Writer) *SyncWriter {
+	return &SyncWriter{w: w}
+}
+
+// Writes a single record.  See Writer.Write().
+func (me *SyncWriter) Write(record []string) error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	return me.w.Write(record)
+}
+
+// Writes a single record made up of Fields.  See Writer.WriteFields().
+//
+// Fields received from a Reader must not be modified by the Reader's callback
+// while this call is in progress.
+func (me *SyncWriter) WriteFields(record []Field) error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	return me.w.WriteFields(record)
+}
+
+// Invokes the specified function with exclusive access to the underlying
+// Writer, so that a group of records can be written without other goroutines'
+// records being interleaved with them.
+func (me *SyncWriter) Do(fn func(w *Writer) error) error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	return fn(me.w)
+}
+
+// Writes any buffered data to the underlying io.Writer.  See Writer.Flush().
+func (me *SyncWriter) Flush() error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	return me.w.Flush()
+}
+
+// Flushes and closes the underlying Writer.  See Writer.Close().
+func (me *SyncWriter) Close() error {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	return me.w.Close()
+}
@@ -0,0 +1,72 @@
+package hastycsv
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSyncWriter_concurrentWrites(t *testing.T) {
+	const goroutines = 8
+	const recordsPerGoroutine = 1000
+
+	var buf bytes.Buffer
+	w := NewSyncWriter(NewWriter(&buf))
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < recordsPerGoroutine; i++ {
+				assert.Nil(t, w.Write([]string{fmt.Sprintf("g%v", g), fmt.Sprintf("r%v", i), "some-padding-value"}))
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.Nil(t, w.Close())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	assert.Equal(t, goroutines*recordsPerGoroutine, len(lines))
+
+	expectedLines := []string{}
+	for g := 0; g < goroutines; g++ {
+		for i := 0; i < recordsPerGoroutine; i++ {
+			expectedLines = append(expectedLines, fmt.Sprintf("g%v,r%v,some-padding-value", g, i))
+		}
+	}
+	sort.Strings(expectedLines)
+	sort.Strings(lines)
+	assert.Equal(t, expectedLines, lines)
+}
+
+func TestSyncWriter_Do(t *testing.T) {
+	var buf bytes.Buffer
+	csvWriter := NewWriter(&buf)
+	csvWriter.Comma = '|'
+	w := NewSyncWriter(csvWriter)
+
+	err := w.Do(func(w *Writer) error {
+		w.Write([]string{"a", "b"})
+		return w.Write([]string{"c", "d"})
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, w.Flush())
+	assert.Equal(t, "a|b\nc|d\n", buf.String())
+}
+
+func TestSyncWriter_WriteFields(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewSyncWriter(NewWriter(&buf))
+
+	err := NewReader().Read(strings.NewReader("a,b\nc,d"), func(i int, fields []Field) error {
+		return w.WriteFields(fields)
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, w.Flush())
+	assert.Equal(t, "a,b\nc,d\n", buf.String())
+}
@@ -0,0 +1,123 @@
+package hastycsv
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The parsed contents of a `csv:"..."` struct tag.
+//
+// The tag's first element is the column name; the remaining comma-separated
+// elements are options, either bare flags ("required") or key/value pairs
+// ("prec=2").  Option values cannot contain commas.
+type fieldTag struct {
+	name     string
+	position int  // column index if the tag's name is a number (e.g. `csv:"2"`), otherwise -1
+	skip     bool // true if the field is tagged `csv:"-"` or is unexported
+	options  map[string]string
+}
+
+// Parses the `csv` tag of the specified struct field.  Fields without a tag
+// (or with an empty name in their tag) are named after the Go field itself.
+func parseFieldTag(sf reflect.StructField) fieldTag {
+	tag := fieldTag{position: -1}
+
+	if sf.PkgPath != "" { // unexported field
+		tag.skip = true
+		return tag
+	}
+
+	parts := strings.Split(sf.Tag.Get("csv"), ",")
+	tag.name = strings.TrimSpace(parts[0])
+	if tag.name == "-" && len(parts) == 1 {
+		tag.skip = true
+		return tag
+	}
+	if tag.name == "" {
+		tag.name = sf.Name
+	}
+	if position, err := strconv.Atoi(tag.name); err == nil && position >= 0 {
+		tag.position = position
+	}
+
+	for _, option := range parts[1:] {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		if tag.options == nil {
+			tag.options = map[string]string{}
+		}
+		if idx := strings.IndexByte(option, '='); idx != -1 {
+			tag.options[option[:idx]] = option[idx+1:]
+		} else {
+			tag.options[option] = ""
+		}
+	}
+
+	return tag
+}
+
+// Returns the value of the specified tag option, and whether it was present.
+func (me fieldTag) option(name string) (string, bool) {
+	value, ok := me.options[name]
+	return value, ok
+}
+
+// A (possibly nested) struct field that's mapped to a CSV column.
+type structField struct {
+	name  string // column name, prefixed with the names of enclosing struct fields
+	path  string // Go field path, e.g. "Address.City"
+	index []int  // index sequence for reflect.Value.FieldByIndex()
+	typ   reflect.Type
+	tag   fieldTag
+}
+
+// Returns the fields of struct type t that map to CSV columns, in declaration
+// order.  Fields of embedded structs are promoted to the outer struct, and the
+// fields of nested struct fields are flattened into dotted column names.  Struct
+// types that are decoded as a single value (time.Time and types implementing
+// encoding.TextUnmarshaler) are not flattened.
+func flattenStruct(t reflect.Type) []structField {
+	return appendStructFields(nil, t, "", "", nil)
+}
+
+func appendStructFields(fields []structField, t reflect.Type, namePrefix string, pathPrefix string, index []int) []structField {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		tag := parseFieldTag(sf)
+
+		if isFlattenedStruct(sf.Type) {
+			if sf.Anonymous && sf.Tag.Get("csv") == "" {
+				// Embedded struct: promote its fields (even if its type is unexported).
+				fields = appendStructFields(fields, sf.Type, namePrefix, pathPrefix+sf.Name+".", fieldIndex)
+				continue
+			}
+			if !tag.skip {
+				fields = appendStructFields(fields, sf.Type, namePrefix+tag.name+".", pathPrefix+sf.Name+".", fieldIndex)
+			}
+			continue
+		}
+
+		if tag.skip {
+			continue
+		}
+
+		fields = append(fields, structField{
+			name:  namePrefix + tag.name,
+			path:  pathPrefix + sf.Name,
+			index: fieldIndex,
+			typ:   sf.Type,
+			tag:   tag,
+		})
+	}
+
+	return fields
+}
+
+// Returns true if t is a struct type whose fields map to separate columns.
+func isFlattenedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
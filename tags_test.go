@@ -0,0 +1,63 @@
+package hastycsv
+
+import (
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFieldTag(t *testing.T) {
+	type Record struct {
+		Untagged   string
+		Named      string `csv:"name"`
+		Options    string `csv:"score,prec=2, required"`
+		NoName     string `csv:",layout=2006-01-02"`
+		Skipped    string `csv:"-"`
+		Dash       string `csv:"-,"`
+		Position   string `csv:"3,required"`
+		unexported string
+	}
+
+	rt := reflect.TypeOf(Record{})
+	tags := make([]fieldTag, rt.NumField())
+	for i := range tags {
+		tags[i] = parseFieldTag(rt.Field(i))
+	}
+
+	assert.Equal(t, fieldTag{name: "Untagged", position: -1}, tags[0])
+	assert.Equal(t, fieldTag{name: "name", position: -1}, tags[1])
+	assert.Equal(t, fieldTag{name: "score", position: -1, options: map[string]string{"prec": "2", "required": ""}}, tags[2])
+	assert.Equal(t, fieldTag{name: "NoName", position: -1, options: map[string]string{"layout": "2006-01-02"}}, tags[3])
+	assert.True(t, tags[4].skip)
+	assert.Equal(t, fieldTag{name: "-", position: -1}, tags[5])
+	assert.Equal(t, fieldTag{name: "3", position: 3, options: map[string]string{"required": ""}}, tags[6])
+	assert.True(t, tags[7].skip)
+
+	value, ok := tags[2].option("prec")
+	assert.True(t, ok)
+	assert.Equal(t, "2", value)
+	_, ok = tags[2].option("layout")
+	assert.False(t, ok)
+}
+
+func TestFlattenStruct(t *testing.T) {
+	type Record struct {
+		decoderTestAuditInfo
+		Home decoderTestAddress `csv:"home"`
+		Born time.Time
+	}
+
+	names := []string{}
+	paths := []string{}
+	for _, sf := range flattenStruct(reflect.TypeOf(Record{})) {
+		names = append(names, sf.name)
+		paths = append(paths, sf.path)
+	}
+
+	assert.Equal(t, []string{"created_by", "version", "home.street", "home.city", "home.geo.lat", "home.geo.lon", "Born"}, names)
+	assert.Equal(t, []string{
+		"decoderTestAuditInfo.CreatedBy", "decoderTestAuditInfo.Version",
+		"Home.Street", "Home.City", "Home.Geo.Lat", "Home.Geo.Lon", "Born",
+	}, paths)
+}
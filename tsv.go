@@ -0,0 +1,18 @@
+package hastycsv
+
+// Returns a new Reader for tab-separated values (TSV).
+//
+// Like any Reader, it accepts both "\n" and "\r\n" line endings (including a
+// final "\r" that isn't followed by a newline), so no stray carriage return
+// ends up in the last field of a record.  A trailing tab denotes an empty last
+// field.
+func NewTSVReader() *Reader {
+	return &Reader{
+		Comma: '\t',
+	}
+}
+
+// Reads records from the specified TSV file (see ReadFile()).
+func ReadTSVFile(tsvFilePath string, nextRecord Next) error {
+	return ReadFile(tsvFilePath, '\t', nextRecord)
+}
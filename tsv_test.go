@@ -0,0 +1,45 @@
+package hastycsv
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewTSVReader(t *testing.T) {
+	r := NewTSVReader()
+	assert.Equal(t, byte('\t'), r.Comma)
+
+	in := strings.NewReader("name\tcomment\r\nbill\tsome, text\r\nmary\t\r\njoe\tlast\r")
+	r.HasHeader = true
+	received := []string{}
+	err := r.Read(in, func(i int, fields []Field) error {
+		received = append(received, fmt.Sprintf("%v=%q", fields[0].String(), fields[1].String()))
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`bill="some, text"`, `mary=""`, `joe="last"`}, received)
+	assert.Equal(t, []string{"name", "comment"}, r.Header())
+}
+
+func TestReadTSVFile(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "people.tsv")
+	require.Nil(t, ioutil.WriteFile(path, []byte("mary\t35\nbill\t40\n"), 0644))
+
+	ages := []uint32{}
+	err := ReadTSVFile(path, func(i int, fields []Field) error {
+		ages = append(ages, fields[1].Uint32())
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint32{35, 40}, ages)
+}
@@ -0,0 +1,66 @@
+package hastycsv
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tracks the Schema.UniqueKey of the records read by ReadWithSchema(), and
+// flags records whose key was already seen.
+type uniqueKeys struct {
+	columns []int
+	name    string         // the key's column names, joined by commas
+	lines   map[string]int // key => line of the first record with that key
+	buf     []byte         // the encoded key of the current record
+	value   []byte         // the printable key of the current record
+}
+
+// Returns a tracker for schema.UniqueKey, or nil if the schema has no key.
+func newUniqueKeys(schema *Schema) (*uniqueKeys, error) {
+	if len(schema.UniqueKey) == 0 {
+		return nil, nil
+	}
+
+	keys := &uniqueKeys{
+		columns: make([]int, len(schema.UniqueKey)),
+		name:    strings.Join(schema.UniqueKey, ","),
+		lines:   map[string]int{},
+	}
+	for i, name := range schema.UniqueKey {
+		if keys.columns[i] = schema.ColumnIndex(name); keys.columns[i] < 0 {
+			return nil, fmt.Errorf(`Unique key column "%v" not found in schema`, name)
+		}
+	}
+	return keys, nil
+}
+
+// Records the key of the specified (validated) record, and adds a violation
+// to the record if the key was already seen.  Records with an empty key
+// column are not checked.
+func (me *uniqueKeys) check(record *Record, line int) {
+	for _, col := range me.columns {
+		if record.values[col].null {
+			return
+		}
+	}
+	me.buf = appendKey(me.buf[:0], record.fields, me.columns)
+
+	if first, exists := me.lines[string(me.buf)]; exists {
+		me.value = me.value[:0]
+		for i, col := range me.columns {
+			if i > 0 {
+				me.value = append(me.value, ',')
+			}
+			me.value = append(me.value, record.fields[col].data...)
+		}
+		record.violations = append(record.violations, ValidationError{
+			Line:        line,
+			Column:      me.name,
+			Rule:        "is unique",
+			Value:       string(me.value),
+			DuplicateOf: first,
+		})
+		return
+	}
+	me.lines[string(me.buf)] = line
+}
@@ -0,0 +1,45 @@
+package hastycsv
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestReader_ReadWithSchema_uniqueKey(t *testing.T) {
+	schema := NewSchema(StringColumn("id"), StringColumn("region"), Uint32Column("qty"))
+	schema.UniqueKey = []string{"id"}
+
+	r := NewReader()
+	r.HasHeader = true
+
+	in := "id,region,qty\na,east,1\nb,east,2\na,west,3\n,east,4\n,east,5\n"
+	err := r.ReadWithSchema(strings.NewReader(in), schema, func(i int, record *Record) error { return nil })
+	assert.EqualError(t, err, `Line 4: Column "id" violates rule is unique: "a" (duplicate of line 2)`)
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, 2, validationErr.DuplicateOf)
+
+	// Composite key
+	schema.UniqueKey = []string{"id", "region"}
+	schema.OnViolation = FlagViolations
+	duplicates := []string{}
+	in = "id,region,qty\na,east,1\na,west,2\nae,ast,3\na,east,4\nae,ast,5\n"
+	err = r.ReadWithSchema(strings.NewReader(in), schema, func(i int, record *Record) error {
+		for _, v := range record.Violations() {
+			duplicates = append(duplicates, fmt.Sprintf("%v=%v:%v", v.Line, v.DuplicateOf, v.Value))
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"5=2:a,east", "6=4:ae,ast"}, duplicates)
+	assert.Equal(t, map[string]int{"id,region": 2}, r.ValidationReport().ColumnViolations)
+
+	schema.UniqueKey = []string{"sku"}
+	err = r.ReadWithSchema(strings.NewReader(in), schema, func(i int, record *Record) error { return nil })
+	assert.EqualError(t, err, `Unique key column "sku" not found in schema`)
+}
@@ -0,0 +1,131 @@
+package hastycsv
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Configures how ReadURL() fetches its input.
+type URLOption func(cfg *urlConfig)
+
+type urlConfig struct {
+	client  *http.Client
+	timeout time.Duration
+	header  http.Header
+}
+
+// Returns a URLOption that makes ReadURL() use the specified HTTP client
+// instead of http.DefaultClient.
+func WithHTTPClient(client *http.Client) URLOption {
+	return func(cfg *urlConfig) {
+		cfg.client = client
+	}
+}
+
+// Returns a URLOption that limits the duration of the whole request,
+// including reading the response body.
+func WithTimeout(timeout time.Duration) URLOption {
+	return func(cfg *urlConfig) {
+		cfg.timeout = timeout
+	}
+}
+
+// Returns a URLOption that adds a header (e.g. "Authorization") to the request.
+func WithRequestHeader(key string, value string) URLOption {
+	return func(cfg *urlConfig) {
+		cfg.header.Add(key, value)
+	}
+}
+
+// The error returned by ReadURL() if the server doesn't respond with a 2xx
+// status code.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (me *HTTPError) Error() string {
+	return fmt.Sprintf("GET %v failed: %v", me.URL, me.Status)
+}
+
+// Streams the CSV document at the specified HTTP(S) URL and invokes
+// nextRecord with each record.
+//
+// The response is decompressed if the server applies gzip Content-Encoding,
+// or otherwise if a Decompressor is registered for the extension of the URL's
+// path (e.g. ".gz"; see RegisterDecompressor()).  A response with a non-2xx status
+// code results in an *HTTPError.
+func ReadURL(ctx context.Context, rawURL string, comma byte, nextRecord Next, options ...URLOption) error {
+	cfg := &urlConfig{client: http.DefaultClient, header: http.Header{}}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range cfg.header {
+		req.Header[key] = values
+	}
+	// Requesting gzip explicitly means that the response is decompressed here
+	// rather than by the http.Transport, whatever client is used.  An
+	// Accept-Encoding set by the caller is left alone.
+	if req.Header.Get("Accept-Encoding") == "" {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+
+	resp, err := cfg.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &HTTPError{URL: rawURL, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	var body io.Reader = resp.Body
+	decoded := resp.Uncompressed // decompressed by the http.Transport
+	if !decoded && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return fmt.Errorf("Can't decompress response: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+		decoded = true
+	}
+	// A compressed file served with a matching Content-Encoding has already
+	// been decompressed.
+	if u, err := url.Parse(rawURL); err == nil && !decoded {
+		if decompress := decompressorFor(u.Path); decompress != nil {
+			dr, err := decompress(body)
+			if err != nil {
+				return fmt.Errorf("Can't decompress %v: %w", rawURL, err)
+			}
+			defer dr.Close()
+			body = dr
+		}
+	}
+
+	in := NewReadAheadReader(body, 32*1024)
+	defer in.Close()
+
+	r := NewReader()
+	r.Comma = comma
+	return r.Read(in, nextRecord)
+}
@@ -0,0 +1,134 @@
+package hastycsv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "Bearer xyz", req.Header.Get("Authorization"))
+
+		switch req.URL.Path {
+		case "/people.csv":
+			w.Write([]byte("mary|35\nbill|40\n"))
+		case "/encoded.csv":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipBytes(t, "mary|35\nbill|40\n"))
+		case "/people.csv.gz":
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Write(gzipBytes(t, "mary|35\nbill|40\n"))
+		case "/encoded.csv.gz":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipBytes(t, "mary|35\nbill|40\n"))
+		case "/identity.csv":
+			assert.Equal(t, "identity", req.Header.Get("Accept-Encoding"))
+			w.Write([]byte("mary|35\nbill|40\n"))
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/people.csv", "/encoded.csv", "/people.csv.gz", "/encoded.csv.gz"} {
+		names := []string{}
+		err := ReadURL(context.Background(), server.URL+path, '|', func(i int, fields []Field) error {
+			names = append(names, fields[0].String())
+			return nil
+		}, WithRequestHeader("Authorization", "Bearer xyz"), WithHTTPClient(server.Client()))
+
+		assert.Nil(t, err, path)
+		assert.Equal(t, []string{"mary", "bill"}, names, path)
+	}
+
+	// Decompressed by the transport, rather than by ReadURL()
+	names := []string{}
+	err := ReadURL(context.Background(), server.URL+"/encoded.csv.gz", '|', func(i int, fields []Field) error {
+		names = append(names, fields[0].String())
+		return nil
+	}, WithRequestHeader("Authorization", "Bearer xyz"), WithHTTPClient(&http.Client{Transport: decodingTransport{}}))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mary", "bill"}, names)
+
+	// A caller-supplied Accept-Encoding is kept
+	err = ReadURL(context.Background(), server.URL+"/identity.csv", '|', func(i int, fields []Field) error {
+		return nil
+	}, WithRequestHeader("Authorization", "Bearer xyz"), WithRequestHeader("Accept-Encoding", "identity"))
+	assert.Nil(t, err)
+
+	err = ReadURL(context.Background(), server.URL+"/missing.csv", '|', func(i int, fields []Field) error {
+		return nil
+	}, WithRequestHeader("Authorization", "Bearer xyz"))
+	var httpErr *HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	assert.EqualError(t, err, "GET "+server.URL+"/missing.csv failed: 404 Not Found")
+}
+
+// Test helper: an http.RoundTripper that decompresses gzip-encoded responses,
+// as http.Transport does when it requests gzip itself.
+type decodingTransport struct{}
+
+func (me decodingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil || resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp, err
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{gz, resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+func TestReadURL_timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("a,1\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-req.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	err := ReadURL(context.Background(), server.URL, ',', func(i int, fields []Field) error {
+		return nil
+	}, WithTimeout(50*time.Millisecond))
+
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < 4*time.Second)
+}
+
+func TestReadURL_invalidURL(t *testing.T) {
+	err := ReadURL(context.Background(), "://bad", ',', func(i int, fields []Field) error { return nil })
+	assert.NotNil(t, err)
+}
+
+// Test helper
+func gzipBytes(t *testing.T, s string) []byte {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	_, err := gz.Write([]byte(s))
+	require.Nil(t, err)
+	require.Nil(t, gz.Close())
+	return buf.Bytes()
+}
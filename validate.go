@@ -0,0 +1,390 @@
+package hastycsv
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+)
+
+// A rule that the fields of a Schema column must satisfy.  Validators are
+// attached to columns with Column.Validate(), and are evaluated by
+// Reader.ReadWithSchema() once all fields of a record have been parsed.
+// Except for Required(), validators are not applied to empty (null) fields.
+type Validator struct {
+	// Rule describes the validator in errors, e.g. `matches "^[0-9]{5}$"`.
+	Rule string
+
+	check       func(record *Record, col int) bool
+	checkNulls  bool // whether check is also applied to null fields
+	countValues bool // whether violations are counted by value in the report
+}
+
+// Returns a Validator that accepts fields that match the specified regular
+// expression.  Fields are matched as bytes, so this doesn't allocate.
+func Matches(re *regexp.Regexp) Validator {
+	return Validator{
+		Rule: fmt.Sprintf("matches %q", re.String()),
+		check: func(record *Record, col int) bool {
+			return re.Match(record.fields[col].data)
+		},
+	}
+}
+
+// Returns a Validator that accepts fields for which check returns true.  rule
+// describes the check in errors.  The bytes passed to check must not be
+// retained.
+func Satisfies(rule string, check func(field []byte) bool) Validator {
+	return Validator{
+		Rule: rule,
+		check: func(record *Record, col int) bool {
+			return check(record.fields[col].data)
+		},
+	}
+}
+
+// Returns a Validator that rejects empty fields.
+func Required() Validator {
+	return Validator{
+		Rule: "is required",
+		check: func(record *Record, col int) bool {
+			return !record.values[col].null
+		},
+		checkNulls: true,
+	}
+}
+
+// Returns a Validator that accepts numeric values between min and max
+// (inclusive), e.g. InRange(0, 150) for an age column.  It compares the parsed
+// value of Uint32Type, Int64Type, Float32Type and Float64Type columns, and
+// rejects every field of other column types.
+func InRange(min, max float64) Validator {
+	return numericValidator(fmt.Sprintf("is between %v and %v", min, max), func(n float64) bool {
+		return n >= min && n <= max
+	})
+}
+
+// Returns a Validator that accepts numeric values that are at least min (see
+// InRange()).
+func AtLeast(min float64) Validator {
+	return numericValidator(fmt.Sprintf("is at least %v", min), func(n float64) bool {
+		return n >= min
+	})
+}
+
+// Returns a Validator that accepts numeric values that are at most max (see
+// InRange()).
+func AtMost(max float64) Validator {
+	return numericValidator(fmt.Sprintf("is at most %v", max), func(n float64) bool {
+		return n <= max
+	})
+}
+
+func numericValidator(rule string, check func(n float64) bool) Validator {
+	return Validator{
+		Rule: rule,
+		check: func(record *Record, col int) bool {
+			n, ok := record.number(col)
+			return ok && check(n)
+		},
+	}
+}
+
+// Returns a Validator that accepts the specified values only.  The values that
+// are rejected are counted in ValidationReport.UnknownValues.
+func OneOf(values ...string) Validator {
+	allowed := make(map[string]bool, len(values))
+	for _, v := range values {
+		allowed[v] = true
+	}
+
+	return Validator{
+		Rule: fmt.Sprintf("is one of %q", values),
+		check: func(record *Record, col int) bool {
+			return allowed[string(record.fields[col].data)]
+		},
+		countValues: true,
+	}
+}
+
+// Same as OneOf(), except that values are compared case-insensitively.
+func OneOfFold(values ...string) Validator {
+	allowed := make([][]byte, len(values))
+	for i, v := range values {
+		allowed[i] = []byte(v)
+	}
+
+	return Validator{
+		Rule: fmt.Sprintf("is one of %q (ignoring case)", values),
+		check: func(record *Record, col int) bool {
+			for _, v := range allowed {
+				if bytes.EqualFold(record.fields[col].data, v) {
+					return true
+				}
+			}
+			return false
+		},
+		countValues: true,
+	}
+}
+
+// Returns a Validator that accepts fields that can be parsed as a time using
+// at least one of the specified time.Parse() layouts.  The first layout that
+// each field matches is counted in ValidationReport.TimeLayouts, which shows
+// whether a column mixes several formats.
+func IsTime(layouts ...string) Validator {
+	return Validator{
+		Rule: fmt.Sprintf("is a time in one of the layouts %q", layouts),
+		check: func(record *Record, col int) bool {
+			// The parsed time is discarded, so the field needn't be copied.
+			s := record.fields[col].unsafeString()
+			for _, layout := range layouts {
+				if _, err := time.Parse(layout, s); err == nil {
+					record.layouts = append(record.layouts, observedLayout{col: col, layout: layout})
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// Returns a copy of this column with the specified validators added, e.g.
+//
+//	StringColumn("zip").Validate(Required(), Matches(regexp.MustCompile(`^[0-9]{5}$`)))
+func (me Column) Validate(validators ...Validator) Column {
+	me.Validators = append(append([]Validator{}, me.Validators...), validators...)
+	return me
+}
+
+// Identifies how Reader.ReadWithSchema() handles records that violate
+// validators.
+type ViolationPolicy int
+
+const (
+	// Stop reading at the first violation and return it as an error.
+	StopOnViolation ViolationPolicy = iota
+
+	// Don't pass records with violations to the NextRecord callback.
+	SkipViolations
+
+	// Pass records with violations to the NextRecord callback, which can
+	// retrieve them with Record.Violations().
+	FlagViolations
+)
+
+// Describes a field that violates one of its column's validators.  Under the
+// StopOnViolation policy, it's returned by Reader.ReadWithSchema() wrapped in
+// an error that identifies the line; use errors.As() to retrieve it.
+type ValidationError struct {
+	Line   int    // the line number of the record
+	Column string // the name of the schema column
+	Rule   string // the Rule of the violated Validator
+	Value  string // the offending field
+
+	// DuplicateOf is the line of the first record with the same key, for
+	// violations of Schema.UniqueKey (whose Column and Value are the key's
+	// column names and values, joined by commas).
+	DuplicateOf int
+
+	countValue bool
+}
+
+func (me *ValidationError) Error() string {
+	if me.DuplicateOf > 0 {
+		return fmt.Sprintf(`Column "%v" violates rule %v: "%v" (duplicate of line %v)`, me.Column, me.Rule, me.Value, me.DuplicateOf)
+	}
+	return fmt.Sprintf(`Column "%v" violates rule %v: "%v"`, me.Column, me.Rule, me.Value)
+}
+
+// The maximum number of sample violations kept by ValidationReport for each
+// rule.
+const MaxViolationSamples = 5
+
+// Summarizes the violations found by the most recent Reader.ReadWithSchema()
+// call.  It can be marshaled to JSON, e.g. to act on it in a data-quality
+// gate.
+type ValidationReport struct {
+	// True if no record violated a rule.
+	Passed bool `json:"passed"`
+
+	// The number of records that were validated.
+	Records int `json:"records"`
+
+	// The number of records with at least one violation.
+	InvalidRecords int `json:"invalidRecords"`
+
+	// The number of records whose fields couldn't be parsed, and the first
+	// (up to MaxViolationSamples) parse errors.  These are only collected by
+	// Reader.Validate(), since ReadWithSchema() stops at the first parse
+	// error.
+	ParseErrors       int      `json:"parseErrors,omitempty"`
+	ParseErrorSamples []string `json:"parseErrorSamples,omitempty"`
+
+	// The number of violations of each column, by column name.  Columns
+	// without violations are omitted.
+	ColumnViolations map[string]int `json:"columnViolations,omitempty"`
+
+	// The number of times each value rejected by a OneOf() or OneOfFold()
+	// validator occurred, by column name and value.
+	UnknownValues map[string]map[string]int `json:"unknownValues,omitempty"`
+
+	// The number of fields that matched each layout of an IsTime() validator,
+	// by column name and layout.
+	TimeLayouts map[string]map[string]int `json:"timeLayouts,omitempty"`
+
+	// The violations of each rule, in the order in which the rules were first
+	// violated.
+	Rules []RuleReport `json:"rules,omitempty"`
+}
+
+// Summarizes the violations of a single rule of a single column.
+type RuleReport struct {
+	Column     string `json:"column"`
+	Rule       string `json:"rule"`
+	Violations int    `json:"violations"`
+
+	// The first (up to MaxViolationSamples) violations.
+	Samples []ViolationSample `json:"samples"`
+}
+
+// A field that violated a rule.
+type ViolationSample struct {
+	Line  int    `json:"line"`
+	Value string `json:"value"`
+}
+
+// Reads the records of r and checks them against schema (parsing every field
+// and evaluating every validator) without passing them to any callback, as a
+// fast pre-flight check before an expensive load.  Unlike ReadWithSchema(),
+// records that fail to parse or violate validators don't stop the read; they
+// are summarized in the returned report, whatever schema.OnViolation is.
+// Errors that prevent reading further, e.g. a record with the wrong number of
+// fields, are returned along with the report so far.
+func (me *Reader) Validate(r io.Reader, schema *Schema) (ValidationReport, error) {
+	flagging := *schema
+	flagging.OnViolation = FlagViolations
+
+	var parseErrors []string
+	err := me.readWithSchema(r, &flagging, func(err error) error {
+		if len(parseErrors) < MaxViolationSamples {
+			parseErrors = append(parseErrors, err.Error())
+		}
+		me.validation.ParseErrors++
+		me.validation.Passed = false
+		return nil
+	}, func(i int, record *Record) error {
+		return nil
+	})
+
+	me.validation.ParseErrorSamples = parseErrors
+	return me.validation, err
+}
+
+// Returns the summary of the violations found by the most recent
+// ReadWithSchema() call.
+func (me *Reader) ValidationReport() ValidationReport {
+	return me.validation
+}
+
+// Returns the validator violations of the current record, which can only be
+// non-empty under the FlagViolations policy.  The returned slice is reused for
+// every record.
+func (me *Record) Violations() []ValidationError {
+	return me.violations
+}
+
+// Returns the parsed value of the specified numeric column as a float64, or
+// false if the column isn't numeric.
+func (me *Record) number(col int) (float64, bool) {
+	v := &me.values[col]
+	switch me.schema.Columns[col].Type {
+	case Uint32Type:
+		return float64(v.u), true
+	case Int64Type:
+		return float64(v.i), true
+	case Float32Type, Float64Type:
+		return v.f, true
+	}
+	return 0, false
+}
+
+// Evaluates the validators of every column of this (parsed) record, and
+// stores the violations in me.violations.
+func (me *Record) validate(line int) {
+	me.violations = me.violations[:0]
+	me.layouts = me.layouts[:0]
+	for col := range me.schema.Columns {
+		column := &me.schema.Columns[col]
+		for i := range column.Validators {
+			v := &column.Validators[i]
+			if (v.checkNulls || !me.values[col].null) && !v.check(me, col) {
+				me.violations = append(me.violations, ValidationError{
+					Line:       line,
+					Column:     column.Name,
+					Rule:       v.Rule,
+					Value:      me.fields[col].String(),
+					countValue: v.countValues,
+				})
+			}
+		}
+	}
+}
+
+// Adds the violations of record to the report.
+func (me *ValidationReport) add(record *Record) {
+	me.Records++
+	for _, observed := range record.layouts {
+		me.TimeLayouts = addCount(me.TimeLayouts, record.schema.Columns[observed.col].Name, observed.layout)
+	}
+	if len(record.violations) == 0 {
+		return
+	}
+
+	me.Passed = false
+	me.InvalidRecords++
+	if me.ColumnViolations == nil {
+		me.ColumnViolations = map[string]int{}
+	}
+	for i := range record.violations {
+		v := &record.violations[i]
+		me.ColumnViolations[v.Column]++
+		if v.countValue {
+			me.UnknownValues = addCount(me.UnknownValues, v.Column, v.Value)
+		}
+
+		rule := me.rule(v.Column, v.Rule)
+		rule.Violations++
+		if len(rule.Samples) < MaxViolationSamples {
+			rule.Samples = append(rule.Samples, ViolationSample{Line: v.Line, Value: v.Value})
+		}
+	}
+}
+
+// Returns the RuleReport of the specified column and rule, adding it if
+// necessary.  Schemas have few rules, so they're searched linearly.
+func (me *ValidationReport) rule(column, rule string) *RuleReport {
+	for i := range me.Rules {
+		if r := &me.Rules[i]; r.Column == column && r.Rule == rule {
+			return r
+		}
+	}
+	me.Rules = append(me.Rules, RuleReport{Column: column, Rule: rule})
+	return &me.Rules[len(me.Rules)-1]
+}
+
+// Increments counts[column][key], allocating the maps as needed, and returns
+// counts.
+func addCount(counts map[string]map[string]int, column, key string) map[string]map[string]int {
+	if counts == nil {
+		counts = map[string]map[string]int{}
+	}
+	columnCounts := counts[column]
+	if columnCounts == nil {
+		columnCounts = map[string]int{}
+		counts[column] = columnCounts
+	}
+	columnCounts[key]++
+	return counts
+}
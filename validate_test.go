@@ -0,0 +1,317 @@
+package hastycsv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestColumn_Validate(t *testing.T) {
+	zip := regexp.MustCompile(`^[0-9]{5}$`)
+	base := StringColumn("zip")
+	col := base.Validate(Matches(zip))
+
+	assert.Equal(t, 0, len(base.Validators), "Validate() must not modify the original column")
+	require.Equal(t, 1, len(col.Validators))
+	assert.Equal(t, `matches "^[0-9]{5}$"`, col.Validators[0].Rule)
+
+	col = col.Validate(Satisfies("is even", func(field []byte) bool { return true }))
+	assert.Equal(t, 2, len(col.Validators))
+}
+
+func TestReader_ReadWithSchema_validators(t *testing.T) {
+	isShort := Satisfies("is at most 4 bytes long", func(field []byte) bool { return len(field) <= 4 })
+	schema := NewSchema(
+		StringColumn("name").Validate(isShort),
+		StringColumn("zip").Validate(Matches(regexp.MustCompile(`^[0-9]{5}$`))),
+	)
+
+	r := NewReader()
+	r.HasHeader = true
+
+	received := []string{}
+	in := "name,zip\nbill,12345\nmary,\njoe,1234x\n"
+	err := r.ReadWithSchema(strings.NewReader(in), schema, func(i int, record *Record) error {
+		received = append(received, fmt.Sprintf("%v:%v", i, record.String(0)))
+		return nil
+	})
+
+	assert.EqualError(t, err, `Line 4: Column "zip" violates rule matches "^[0-9]{5}$": "1234x"`)
+	assert.Equal(t, []string{"2:bill", "3:mary"}, received, "empty fields are not validated")
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, &ValidationError{Line: 4, Column: "zip", Rule: `matches "^[0-9]{5}$"`, Value: "1234x"}, validationErr)
+
+	err = r.ReadWithSchema(strings.NewReader("name,zip\nbernard,12345\n"), schema, func(i int, record *Record) error { return nil })
+	assert.EqualError(t, err, `Line 2: Column "name" violates rule is at most 4 bytes long: "bernard"`)
+}
+
+func TestReader_ReadWithSchema_validators_noAllocations(t *testing.T) {
+	schema := NewSchema(StringColumn("zip").Validate(Matches(regexp.MustCompile(`^[0-9]{5}$`))))
+	record := &Record{schema: schema, fields: []Field{makeField("12345")}, values: make([]value, 1)}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if record.validate(1); len(record.violations) > 0 {
+			t.Fatal(record.violations[0].Error())
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func TestReader_ReadWithSchema_required(t *testing.T) {
+	schema := NewSchema(
+		StringColumn("name").Validate(Required()),
+		StringColumn("email").Validate(Required()),
+		StringColumn("zip").Validate(Matches(regexp.MustCompile(`^[0-9]{5}$`))),
+	)
+	in := "name,email,zip\nbill,bill@x.com,12345\n,,\nmary,,1234x\njoe,joe@x.com,\n"
+
+	r := NewReader()
+	r.HasHeader = true
+
+	err := r.ReadWithSchema(strings.NewReader(in), schema, func(i int, record *Record) error { return nil })
+	assert.EqualError(t, err, `Line 3: Column "name" violates rule is required: ""`)
+
+	// Reject invalid records
+	schema.OnViolation = SkipViolations
+	received := []int{}
+	err = r.ReadWithSchema(strings.NewReader(in), schema, func(i int, record *Record) error {
+		received = append(received, i)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 5}, received)
+	report := r.ValidationReport()
+	assert.False(t, report.Passed)
+	assert.Equal(t, 4, report.Records)
+	assert.Equal(t, 2, report.InvalidRecords)
+	assert.Equal(t, map[string]int{"name": 1, "email": 2, "zip": 1}, report.ColumnViolations)
+
+	// Flag invalid records
+	schema.OnViolation = FlagViolations
+	flagged := []string{}
+	err = r.ReadWithSchema(strings.NewReader(in), schema, func(i int, record *Record) error {
+		for _, v := range record.Violations() {
+			flagged = append(flagged, fmt.Sprintf("%v:%v %v", v.Line, v.Column, v.Rule))
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"3:name is required",
+		"3:email is required",
+		`4:email is required`,
+		`4:zip matches "^[0-9]{5}$"`,
+	}, flagged)
+	assert.Equal(t, 2, r.ValidationReport().InvalidRecords)
+
+	// The report is reset by every read
+	err = r.ReadWithSchema(strings.NewReader("name,email,zip\nbill,bill@x.com,12345\n"), schema, func(i int, record *Record) error { return nil })
+	assert.Nil(t, err)
+	assert.Equal(t, ValidationReport{Passed: true, Records: 1}, r.ValidationReport())
+}
+
+func TestReader_ReadWithSchema_rangeValidators(t *testing.T) {
+	schema := NewSchema(
+		Uint32Column("age").Validate(InRange(0, 150)),
+		Float64Column("price").Validate(AtLeast(0)),
+		Int64Column("delta").Validate(AtMost(10)),
+		StringColumn("name").Validate(AtLeast(0)),
+	)
+	schema.OnViolation = FlagViolations
+	in := "age,price,delta,name\n30,9.99,-5,\n151,-0.01,10,\n0,0,11,bill\n"
+
+	r := NewReader()
+	r.HasHeader = true
+
+	flagged := []string{}
+	err := r.ReadWithSchema(strings.NewReader(in), schema, func(i int, record *Record) error {
+		for _, v := range record.Violations() {
+			flagged = append(flagged, fmt.Sprintf("%v:%v %v", v.Line, v.Column, v.Rule))
+		}
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"3:age is between 0 and 150",
+		"3:price is at least 0",
+		"4:delta is at most 10",
+		"4:name is at least 0",
+	}, flagged)
+}
+
+func TestReader_ReadWithSchema_oneOf(t *testing.T) {
+	schema := NewSchema(
+		StringColumn("status").Validate(OneOf("active", "closed")),
+		StringColumn("country").Validate(OneOfFold("US", "CA")),
+	)
+	schema.OnViolation = SkipViolations
+	in := "status,country\nactive,us\npending,CA\nclosed,mx\npending,Ca\nActive,\n"
+
+	r := NewReader()
+	r.HasHeader = true
+
+	received := []int{}
+	err := r.ReadWithSchema(strings.NewReader(in), schema, func(i int, record *Record) error {
+		received = append(received, i)
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2}, received)
+	assert.Equal(t, map[string]map[string]int{
+		"status":  {"pending": 2, "Active": 1},
+		"country": {"mx": 1},
+	}, r.ValidationReport().UnknownValues)
+
+	schema.OnViolation = StopOnViolation
+	err = r.ReadWithSchema(strings.NewReader(in), schema, func(i int, record *Record) error { return nil })
+	assert.EqualError(t, err, `Line 3: Column "status" violates rule is one of ["active" "closed"]: "pending"`)
+}
+
+func TestValidationReport_json(t *testing.T) {
+	schema := NewSchema(
+		StringColumn("id").Validate(Required()),
+		Uint32Column("age").Validate(InRange(0, 150)),
+	)
+	schema.OnViolation = FlagViolations
+
+	r := NewReader()
+	r.HasHeader = true
+
+	in := "id,age\na,30\n,200\nc,151\nd,152\ne,153\nf,154\ng,155\n"
+	err := r.ReadWithSchema(strings.NewReader(in), schema, func(i int, record *Record) error { return nil })
+	require.Nil(t, err)
+
+	report := r.ValidationReport()
+	require.Equal(t, 2, len(report.Rules))
+	assert.Equal(t, RuleReport{Column: "id", Rule: "is required", Violations: 1, Samples: []ViolationSample{{Line: 3, Value: ""}}}, report.Rules[0])
+	assert.Equal(t, 6, report.Rules[1].Violations)
+	assert.Equal(t, MaxViolationSamples, len(report.Rules[1].Samples))
+	assert.Equal(t, ViolationSample{Line: 7, Value: "154"}, report.Rules[1].Samples[4])
+
+	data, err := json.Marshal(ValidationReport{
+		Records:          2,
+		InvalidRecords:   1,
+		ColumnViolations: map[string]int{"id": 1},
+		Rules:            report.Rules[:1],
+	})
+	require.Nil(t, err)
+	assert.JSONEq(t, `{
+		"passed": false,
+		"records": 2,
+		"invalidRecords": 1,
+		"columnViolations": {"id": 1},
+		"rules": [{"column": "id", "rule": "is required", "violations": 1, "samples": [{"line": 3, "value": ""}]}]
+	}`, string(data))
+}
+
+func TestReader_Validate(t *testing.T) {
+	schema := NewSchema(
+		StringColumn("name").Validate(Required()),
+		Uint32Column("age").Validate(InRange(0, 150)),
+	)
+
+	r := NewReader()
+	r.HasHeader = true
+
+	in := "name,age\nbill,30\n,35\nmary,x\njoe,200\nann,y\n"
+	report, err := r.Validate(strings.NewReader(in), schema)
+
+	assert.Nil(t, err)
+	assert.False(t, report.Passed)
+	assert.Equal(t, 3, report.Records)
+	assert.Equal(t, 2, report.InvalidRecords)
+	assert.Equal(t, 2, report.ParseErrors)
+	assert.Equal(t, []string{
+		`Line 4, column 2: Can't parse column "age" as uint32: "x"`,
+		`Line 6, column 2: Can't parse column "age" as uint32: "y"`,
+	}, report.ParseErrorSamples)
+	assert.Equal(t, map[string]int{"name": 1, "age": 1}, report.ColumnViolations)
+	assert.Equal(t, StopOnViolation, schema.OnViolation, "Validate() must not modify the schema")
+	assert.Equal(t, report, r.ValidationReport())
+
+	report, err = r.Validate(strings.NewReader("name,age\nbill,30\n"), schema)
+	assert.Nil(t, err)
+	assert.True(t, report.Passed)
+
+	_, err = r.Validate(strings.NewReader("name,age\nbill,30\nmary\n"), schema)
+	assert.EqualError(t, err, `Line 3: Expected []b to contain 2 fields using delimiter ',': "mary"`)
+}
+
+func TestReader_ReadWithSchema_invalidRecordLimits(t *testing.T) {
+	schema := NewSchema(Uint32Column("qty").Validate(AtLeast(1)))
+	schema.OnViolation = SkipViolations
+	schema.MaxInvalidRecords = 2
+
+	r := NewReader()
+	next := func(i int, record *Record) error { return nil }
+
+	assert.Nil(t, r.ReadWithSchema(strings.NewReader("1\n0\n2\n0\n3\n"), schema, next))
+
+	err := r.ReadWithSchema(strings.NewReader("1\n0\n2\n0\n3\n0\n4\n"), schema, next)
+	assert.EqualError(t, err, "Line 6: Too many invalid records: more than 2")
+
+	// Parse errors count as well
+	_, err = r.Validate(strings.NewReader("1\nx\n0\ny\n"), schema)
+	assert.EqualError(t, err, "Line 4: Too many invalid records: more than 2")
+
+	// The ratio is enforced once 100 records have been read...
+	schema.MaxInvalidRecords = 0
+	schema.MaxInvalidRatio = 0.1
+	in := strings.Repeat("0\n", 11) + strings.Repeat("1\n", 200)
+	err = r.ReadWithSchema(strings.NewReader(in), schema, next)
+	assert.EqualError(t, err, "Line 100: Too many invalid records: 11 of 100 (11.0%, limit 10.0%)")
+
+	// ...and at the end of the read
+	err = r.ReadWithSchema(strings.NewReader("1\n0\n1\n"), schema, next)
+	assert.EqualError(t, err, "Too many invalid records: 1 of 3 (33.3%, limit 10.0%)")
+
+	assert.Nil(t, r.ReadWithSchema(strings.NewReader(strings.Repeat("1\n", 9)+"0\n"), schema, next))
+}
+
+func TestReader_ReadWithSchema_isTime(t *testing.T) {
+	schema := NewSchema(
+		StringColumn("id"),
+		StringColumn("date").Validate(IsTime("2006-01-02", "01/02/2006")),
+	)
+	schema.OnViolation = FlagViolations
+	in := "id,date\na,2021-03-04\nb,03/05/2021\nc,2021-03-06\nd,2021-02-30\ne,\n"
+
+	r := NewReader()
+	r.HasHeader = true
+
+	invalid := []string{}
+	err := r.ReadWithSchema(strings.NewReader(in), schema, func(i int, record *Record) error {
+		for _, v := range record.Violations() {
+			invalid = append(invalid, fmt.Sprintf("%v:%v", v.Line, v.Rule))
+		}
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`5:is a time in one of the layouts ["2006-01-02" "01/02/2006"]`}, invalid)
+	assert.Equal(t, map[string]map[string]int{
+		"date": {"2006-01-02": 2, "01/02/2006": 1},
+	}, r.ValidationReport().TimeLayouts)
+}
+
+func TestReader_ReadWithSchema_isTime_noAllocations(t *testing.T) {
+	schema := NewSchema(StringColumn("date").Validate(IsTime("2006-01-02")))
+	record := &Record{schema: schema, fields: []Field{makeField("2021-03-04")}, values: make([]value, 1)}
+	record.validate(1)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if record.validate(1); len(record.violations) > 0 {
+			t.Fatal(record.violations[0].Error())
+		}
+	})
+	assert.Equal(t, 0.0, allocs)
+}
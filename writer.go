@@ -0,0 +1,415 @@
+package hastycsv
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Identifies the compression codec applied to a CSV file.
+type Compression int
+
+const (
+	// Plain, uncompressed CSV.
+	NoCompression Compression = iota
+	// Gzip-compressed CSV.
+	Gzip
+)
+
+// Writes records to a CSV-encoded file or io.Writer.
+//
+// Like the Reader, the Writer is NOT RFC-4180 compliant: it never quotes or
+// escapes anything.  A field that contains the Comma delimiter or a line break
+// is rejected with an error instead of producing output that cannot be read
+// back.
+type Writer struct {
+	// Comma is the field delimiter.
+	// It is set to comma (',') by NewWriter.
+	// Comma cannot be \r or \n.
+	Comma byte
+
+	// WriteBOM indicates whether a UTF-8 byte order mark is written before the
+	// first record.  Excel needs it to recognize UTF-8 encoded CSV files.
+	WriteBOM bool
+
+	// WriteSepHint indicates whether a "sep=" line naming the Comma delimiter
+	// (e.g. "sep=;") is written before the first record.  Excel uses it to
+	// split files whose delimiter differs from the locale's list separator.
+	// Other CSV readers will generally treat it as a regular record.
+	WriteSepHint bool
+
+	w          *bufio.Writer
+	closer     io.Closer // stream owned (and closed) by this Writer, if any
+	fieldCount int       // if > 0, the number of fields every record must contain
+	started    bool      // true once the first record (or preamble) has been written
+}
+
+// Returns a new Writer whose Delimiter is set to the comma character (',').
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		Comma: ',',
+		w:     bufio.NewWriterSize(w, 32*1024),
+	}
+}
+
+// Returns a new Writer that gzip-compresses its output before writing it to w.
+//
+// Close() must be called once all records have been written in order to
+// terminate the gzip stream.  Close() does not close w itself.
+func NewGzipWriter(w io.Writer) *Writer {
+	gz := gzip.NewWriter(w)
+	csvWriter := NewWriter(gz)
+	csvWriter.closer = gz
+	return csvWriter
+}
+
+// Writes a single record.  Output is buffered; call Flush() or Close() to make
+// sure it reaches the underlying io.Writer.
+func (me *Writer) Write(record []string) error {
+	if err := me.checkRecord(len(record)); err != nil {
+		return err
+	}
+
+	for i, s := range record {
+		if err := me.checkField(i, s); err != nil {
+			return err
+		}
+	}
+
+	me.writePreamble()
+	for i, s := range record {
+		if i > 0 {
+			me.w.WriteByte(me.Comma)
+		}
+		me.w.WriteString(s)
+	}
+	return me.w.WriteByte('\n')
+}
+
+// Writes a single record made up of Fields (e.g. fields received from a
+// Reader), without converting them to strings.
+func (me *Writer) WriteFields(record []Field) error {
+	if err := me.checkRecord(len(record)); err != nil {
+		return err
+	}
+
+	for i, field := range record {
+		if err := me.checkField(i, field.unsafeString()); err != nil {
+			return err
+		}
+	}
+
+	me.writePreamble()
+	for i, field := range record {
+		if i > 0 {
+			me.w.WriteByte(me.Comma)
+		}
+		me.w.Write(field.data)
+	}
+	return me.w.WriteByte('\n')
+}
+
+// Writes a record that has already been assembled into a single line (without
+// the trailing newline).  The caller is responsible for having validated each
+// of the line's fields with checkField().
+func (me *Writer) writeLine(line []byte, fieldCount int) error {
+	if err := me.checkRecord(fieldCount); err != nil {
+		return err
+	}
+
+	me.writePreamble()
+	me.w.Write(line)
+	return me.w.WriteByte('\n')
+}
+
+// Writes the optional BOM and "sep=" hint ahead of the first record.
+func (me *Writer) writePreamble() {
+	if me.started {
+		return
+	}
+	me.started = true
+
+	if me.WriteBOM {
+		me.w.WriteString("\xEF\xBB\xBF")
+	}
+	if me.WriteSepHint {
+		me.w.WriteString("sep=")
+		me.w.WriteByte(me.Comma)
+		me.w.WriteByte('\n')
+	}
+}
+
+// Writes any buffered data to the underlying io.Writer.
+func (me *Writer) Flush() error {
+	return me.w.Flush()
+}
+
+// Flushes any buffered data and closes any stream (e.g. gzip) owned by this
+// Writer.  The io.Writer that was passed to the Writer's constructor is not
+// closed.
+func (me *Writer) Close() error {
+	if err := me.Flush(); err != nil {
+		return err
+	}
+
+	if me.closer != nil {
+		return me.closer.Close()
+	}
+	return nil
+}
+
+func (me *Writer) checkRecord(fieldCount int) error {
+	if me.Comma == '\r' || me.Comma == '\n' {
+		return &sentinelError{ErrBadDelimiter, `Comma delimiter cannot be \r or \n`}
+	}
+
+	if me.fieldCount > 0 && fieldCount != me.fieldCount {
+		return &sentinelError{ErrFieldCount, fmt.Sprintf("Expected record to contain %v fields, but it contains %v", me.fieldCount, fieldCount)}
+	}
+	return nil
+}
+
+func (me *Writer) checkField(i int, s string) error {
+	if strings.IndexByte(s, me.Comma) != -1 || strings.IndexByte(s, '\n') != -1 || strings.IndexByte(s, '\r') != -1 {
+		return fmt.Errorf(`Field %v contains the delimiter '%v' or a line break: "%v"`, i, string(me.Comma), s)
+	}
+	return nil
+}
+
+// Configures how WriteFile() creates its output file.
+type FileOption func(cfg *fileConfig)
+
+type fileConfig struct {
+	compression  Compression
+	atomic       bool
+	append       bool
+	verifyHeader bool
+}
+
+// Returns a FileOption that compresses the output file using the specified
+// codec.
+func WithCompression(compression Compression) FileOption {
+	return func(cfg *fileConfig) {
+		cfg.compression = compression
+	}
+}
+
+// Returns a FileOption that makes WriteFile() write to a temporary file in the
+// destination directory and rename it to the destination path only after all
+// records were written and synced to disk.  Readers of the destination path
+// therefore never observe a partially written file, even if the process
+// crashes midway.
+func WithAtomicWrite() FileOption {
+	return func(cfg *fileConfig) {
+		cfg.atomic = true
+	}
+}
+
+// Returns a FileOption that makes WriteFile() append records to the end of the
+// file (creating it if it doesn't exist yet) instead of truncating it.
+//
+// If verifyHeader is true and the file is not empty, the first line of the
+// existing file is split using the Writer's delimiter, and every appended
+// record must contain the same number of fields.  This catches attempts to
+// append records that use a different delimiter or layout.
+//
+// Append mode cannot be combined with WithAtomicWrite().  When combined with
+// WithCompression(Gzip), each WriteFile() call appends a new gzip member to
+// the file, which gzip readers transparently concatenate.
+func WithAppend(verifyHeader bool) FileOption {
+	return func(cfg *fileConfig) {
+		cfg.append = true
+		cfg.verifyHeader = verifyHeader
+	}
+}
+
+// Creates (or truncates) the specified file and passes a Writer for it to the
+// writeRecords callback.  The file is flushed and closed once the callback
+// returns.
+//
+// With WithAppend(), records are appended to the existing file instead of
+// replacing its contents.  With WithAtomicWrite(), records are written to a
+// temporary file that replaces the specified file only once the callback
+// returns successfully, leaving the original file untouched otherwise.
+func WriteFile(csvFilePath string, comma byte, writeRecords func(w *Writer) error, options ...FileOption) error {
+	cfg := fileConfig{}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	if cfg.compression != NoCompression && cfg.compression != Gzip {
+		return fmt.Errorf("Unsupported compression: %v", cfg.compression)
+	}
+
+	if cfg.atomic && cfg.append {
+		return fmt.Errorf("Atomic writes cannot be combined with append mode")
+	}
+
+	if cfg.atomic {
+		return writeFileAtomically(csvFilePath, comma, writeRecords, &cfg)
+	}
+
+	if cfg.append {
+		return appendToFile(csvFilePath, comma, writeRecords, &cfg)
+	}
+
+	f, err := os.Create(csvFilePath)
+	if err != nil {
+		return err
+	}
+
+	if err := writeRecordsToFile(f, comma, writeRecords, &cfg, 0, false); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func writeFileAtomically(csvFilePath string, comma byte, writeRecords func(w *Writer) error, cfg *fileConfig) error {
+	dir, name := filepath.Split(csvFilePath)
+	if dir == "" {
+		dir = "."
+	}
+
+	f, err := os.CreateTemp(dir, "."+name+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpFilePath := f.Name()
+
+	// Give the new file the same permissions as the file it replaces (or the
+	// usual permissions of a newly created file), since CreateTemp() creates
+	// files that are only readable by their owner.
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(csvFilePath); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	err = writeRecordsToFile(f, comma, writeRecords, cfg, 0, false)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFilePath, csvFilePath)
+	}
+
+	if err != nil {
+		os.Remove(tmpFilePath)
+		return err
+	}
+	return nil
+}
+
+func appendToFile(csvFilePath string, comma byte, writeRecords func(w *Writer) error, cfg *fileConfig) error {
+	f, err := os.OpenFile(csvFilePath, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+
+	fieldCount, isEmpty, err := prepareFileForAppend(f, comma, cfg)
+	if err == nil {
+		err = writeRecordsToFile(f, comma, writeRecords, cfg, fieldCount, !isEmpty)
+	}
+
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Inspects a file that is about to be appended to.  Returns the number of
+// fields in its header if the header needs to be verified (otherwise 0) and
+// whether the file is empty, and terminates the file's last line if it lacks
+// a trailing newline.
+func prepareFileForAppend(f *os.File, comma byte, cfg *fileConfig) (int, bool, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, false, err
+	}
+
+	size := fi.Size()
+	if size == 0 {
+		return 0, true, nil
+	}
+
+	fieldCount, err := inspectFileForAppend(f, size, comma, cfg)
+	return fieldCount, false, err
+}
+
+// Does the work of prepareFileForAppend() for a non-empty file.
+func inspectFileForAppend(f *os.File, size int64, comma byte, cfg *fileConfig) (int, error) {
+	if cfg.compression == NoCompression {
+		lastByte := make([]byte, 1)
+		if _, err := f.ReadAt(lastByte, size-1); err != nil {
+			return 0, err
+		}
+		if lastByte[0] != '\n' {
+			if _, err := f.Write([]byte{'\n'}); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if !cfg.verifyHeader {
+		return 0, nil
+	}
+
+	var r io.Reader = io.NewSectionReader(f, 0, size)
+	if cfg.compression == Gzip {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return 0, fmt.Errorf("Error reading header of %v: %w", f.Name(), err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	br := bufio.NewReader(r)
+	header, err := br.ReadBytes('\n')
+	header = bytes.TrimPrefix(header, []byte("\xEF\xBB\xBF"))
+	if err == nil && bytes.HasPrefix(header, []byte("sep=")) {
+		// Skip the Excel delimiter hint written by WriteSepHint.
+		header, err = br.ReadBytes('\n')
+	}
+	if err != nil && err != io.EOF {
+		return 0, fmt.Errorf("Error reading header of %v: %w", f.Name(), err)
+	}
+	header = bytes.TrimRight(header, "\r\n")
+
+	return bytes.Count(header, []byte{comma}) + 1, nil
+}
+
+// Writes records to f using a Writer configured according to cfg.  fieldCount
+// is the number of fields every record must contain (0 = any), and started
+// indicates that f already contains data (so no BOM or "sep=" hint must be
+// written).
+func writeRecordsToFile(f *os.File, comma byte, writeRecords func(w *Writer) error, cfg *fileConfig, fieldCount int, started bool) error {
+	var w *Writer
+	if cfg.compression == Gzip {
+		w = NewGzipWriter(f)
+	} else {
+		w = NewWriter(f)
+	}
+	w.Comma = comma
+	w.fieldCount = fieldCount
+	w.started = started
+
+	if err := writeRecords(w); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
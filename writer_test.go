@@ -0,0 +1,345 @@
+package hastycsv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewWriter(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{})
+	assert.Equal(t, byte(','), w.Comma)
+}
+
+func TestWriter_Write(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Comma = '|'
+
+	assert.Nil(t, w.Write([]string{"bill", "30", "154.5"}))
+	assert.Nil(t, w.Write([]string{"mary", "", "125.1"}))
+	assert.Nil(t, w.Write([]string{"single"}))
+	assert.Nil(t, w.Flush())
+
+	assert.Equal(t, "bill|30|154.5\nmary||125.1\nsingle\n", buf.String())
+}
+
+func TestWriter_Write_invalidField(t *testing.T) {
+	badFields := []string{"a|b", "a\nb", "a\rb"}
+
+	for _, badField := range badFields {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.Comma = '|'
+
+		err := w.Write([]string{"ok", badField})
+		assert.NotNil(t, err, `field=%q`, badField)
+		assert.Nil(t, w.Flush())
+		assert.Equal(t, "", buf.String(), "nothing should be written for a rejected record")
+	}
+}
+
+func TestWriter_Write_InvalidComma(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{})
+
+	for _, invalidCommaChar := range []byte{'\r', '\n'} {
+		w.Comma = invalidCommaChar
+		assert.EqualError(t, w.Write([]string{"a"}), `Comma delimiter cannot be \r or \n`)
+	}
+}
+
+func TestWriter_WriteFields(t *testing.T) {
+	in := strings.NewReader("a|b|c\nd|e|f")
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Comma = ','
+
+	r := NewReader()
+	r.Comma = '|'
+	err := r.Read(in, func(i int, fields []Field) error {
+		return w.WriteFields(fields)
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, w.Flush())
+
+	assert.Equal(t, "a,b,c\nd,e,f\n", buf.String())
+}
+
+func TestWriter_excelPreamble(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Comma = ';'
+	w.WriteBOM = true
+	w.WriteSepHint = true
+
+	assert.Nil(t, w.Write([]string{"name", "city"}))
+	assert.Nil(t, NewRecordBuilder(w).AddString("Zoë").AddString("Zürich").Write())
+	assert.Nil(t, w.Flush())
+
+	assert.Equal(t, "\xEF\xBB\xBFsep=;\nname;city\nZoë;Zürich\n", buf.String())
+}
+
+func TestNewGzipWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewGzipWriter(&buf)
+	assert.Nil(t, w.Write([]string{"a", "b"}))
+	assert.Nil(t, w.Write([]string{"c", "d"}))
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, "a,b\nc,d\n", gunzip(t, buf.Bytes()))
+}
+
+func TestWriteFile(t *testing.T) {
+	tmpDir := makeTempDir(t)
+	defer os.RemoveAll(tmpDir)
+	csvFile := filepath.Join(tmpDir, "out.csv")
+
+	err := WriteFile(csvFile, '|', func(w *Writer) error {
+		w.Write([]string{"mary", "jones", "35"})
+		return w.Write([]string{"bill", "anderson", "40"})
+	})
+	assert.Nil(t, err)
+
+	data, err := ioutil.ReadFile(csvFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "mary|jones|35\nbill|anderson|40\n", string(data))
+}
+
+func TestWriteFile_gzip(t *testing.T) {
+	tmpDir := makeTempDir(t)
+	defer os.RemoveAll(tmpDir)
+	csvFile := filepath.Join(tmpDir, "out.csv.gz")
+
+	err := WriteFile(csvFile, ',', func(w *Writer) error {
+		return w.Write([]string{"a", "b", "c"})
+	}, WithCompression(Gzip))
+	assert.Nil(t, err)
+
+	data, err := ioutil.ReadFile(csvFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "a,b,c\n", gunzip(t, data))
+}
+
+func TestWriteFile_callbackError(t *testing.T) {
+	tmpDir := makeTempDir(t)
+	defer os.RemoveAll(tmpDir)
+
+	err := WriteFile(filepath.Join(tmpDir, "out.csv"), ',', func(w *Writer) error {
+		return fmt.Errorf("Abort!")
+	})
+	assert.EqualError(t, err, "Abort!")
+}
+
+func TestWriteFile_unsupportedCompression(t *testing.T) {
+	tmpDir := makeTempDir(t)
+	defer os.RemoveAll(tmpDir)
+	csvFile := filepath.Join(tmpDir, "out.csv")
+
+	err := WriteFile(csvFile, ',', func(w *Writer) error { return nil }, WithCompression(Compression(99)))
+	assert.NotNil(t, err)
+
+	_, err = os.Stat(csvFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWriteFile_atomic(t *testing.T) {
+	tmpDir := makeTempDir(t)
+	defer os.RemoveAll(tmpDir)
+	csvFile := filepath.Join(tmpDir, "out.csv")
+
+	err := WriteFile(csvFile, ',', func(w *Writer) error {
+		return w.Write([]string{"a", "b", "c"})
+	}, WithAtomicWrite())
+	assert.Nil(t, err)
+
+	data, err := ioutil.ReadFile(csvFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "a,b,c\n", string(data))
+	assert.Equal(t, []string{"out.csv"}, listDir(t, tmpDir), "temp file should have been renamed")
+}
+
+func TestWriteFile_atomic_callbackError(t *testing.T) {
+	tmpDir := makeTempDir(t)
+	defer os.RemoveAll(tmpDir)
+	csvFile := filepath.Join(tmpDir, "out.csv")
+	assert.Nil(t, ioutil.WriteFile(csvFile, []byte("old,data\n"), 0644))
+
+	err := WriteFile(csvFile, ',', func(w *Writer) error {
+		w.Write([]string{"new", "data"})
+		w.Flush()
+		return fmt.Errorf("Abort!")
+	}, WithAtomicWrite())
+	assert.EqualError(t, err, "Abort!")
+
+	// The original file must be left untouched and the temp file cleaned up.
+	data, err := ioutil.ReadFile(csvFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "old,data\n", string(data))
+	assert.Equal(t, []string{"out.csv"}, listDir(t, tmpDir))
+}
+
+func TestWriteFile_atomic_gzip(t *testing.T) {
+	tmpDir := makeTempDir(t)
+	defer os.RemoveAll(tmpDir)
+	csvFile := filepath.Join(tmpDir, "out.csv.gz")
+
+	err := WriteFile(csvFile, ',', func(w *Writer) error {
+		return w.Write([]string{"a", "b"})
+	}, WithAtomicWrite(), WithCompression(Gzip))
+	assert.Nil(t, err)
+
+	data, err := ioutil.ReadFile(csvFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "a,b\n", gunzip(t, data))
+}
+
+func TestWriteFile_append(t *testing.T) {
+	tmpDir := makeTempDir(t)
+	defer os.RemoveAll(tmpDir)
+	csvFile := filepath.Join(tmpDir, "out.csv")
+
+	// The existing file's last line lacks a trailing newline.
+	assert.Nil(t, ioutil.WriteFile(csvFile, []byte("name|age\nmary|35"), 0644))
+
+	for _, name := range []string{"bill", "jane"} {
+		err := WriteFile(csvFile, '|', func(w *Writer) error {
+			return w.Write([]string{name, "40"})
+		}, WithAppend(true))
+		assert.Nil(t, err)
+	}
+
+	data, err := ioutil.ReadFile(csvFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "name|age\nmary|35\nbill|40\njane|40\n", string(data))
+}
+
+func TestWriteFile_append_newFile(t *testing.T) {
+	tmpDir := makeTempDir(t)
+	defer os.RemoveAll(tmpDir)
+	csvFile := filepath.Join(tmpDir, "out.csv")
+
+	err := WriteFile(csvFile, ',', func(w *Writer) error {
+		return w.Write([]string{"a", "b"})
+	}, WithAppend(true))
+	assert.Nil(t, err)
+
+	data, err := ioutil.ReadFile(csvFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "a,b\n", string(data))
+}
+
+func TestWriteFile_append_headerMismatch(t *testing.T) {
+	tmpDir := makeTempDir(t)
+	defer os.RemoveAll(tmpDir)
+	csvFile := filepath.Join(tmpDir, "out.csv")
+	assert.Nil(t, ioutil.WriteFile(csvFile, []byte("name|age\n"), 0644))
+
+	// Wrong delimiter: the existing header is read as a single field.
+	err := WriteFile(csvFile, ',', func(w *Writer) error {
+		return w.Write([]string{"bill", "40"})
+	}, WithAppend(true))
+	assert.EqualError(t, err, "Expected record to contain 1 fields, but it contains 2")
+
+	// Wrong arity.
+	err = WriteFile(csvFile, '|', func(w *Writer) error {
+		return w.Write([]string{"bill", "40", "extra"})
+	}, WithAppend(true))
+	assert.EqualError(t, err, "Expected record to contain 2 fields, but it contains 3")
+
+	// Header verification disabled.
+	err = WriteFile(csvFile, '|', func(w *Writer) error {
+		return w.Write([]string{"bill", "40", "extra"})
+	}, WithAppend(false))
+	assert.Nil(t, err)
+
+	data, err := ioutil.ReadFile(csvFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "name|age\nbill|40|extra\n", string(data))
+}
+
+func TestWriteFile_append_gzip(t *testing.T) {
+	tmpDir := makeTempDir(t)
+	defer os.RemoveAll(tmpDir)
+	csvFile := filepath.Join(tmpDir, "out.csv.gz")
+
+	for _, record := range [][]string{{"name", "age"}, {"mary", "35"}} {
+		err := WriteFile(csvFile, ',', func(w *Writer) error {
+			return w.Write(record)
+		}, WithAppend(true), WithCompression(Gzip))
+		assert.Nil(t, err)
+	}
+
+	err := WriteFile(csvFile, ',', func(w *Writer) error {
+		return w.Write([]string{"bill"})
+	}, WithAppend(true), WithCompression(Gzip))
+	assert.NotNil(t, err)
+
+	data, err := ioutil.ReadFile(csvFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "name,age\nmary,35\n", gunzip(t, data))
+}
+
+func TestWriteFile_append_excelPreamble(t *testing.T) {
+	tmpDir := makeTempDir(t)
+	defer os.RemoveAll(tmpDir)
+	csvFile := filepath.Join(tmpDir, "out.csv")
+
+	for _, record := range [][]string{{"name", "age"}, {"mary", "35"}} {
+		err := WriteFile(csvFile, ';', func(w *Writer) error {
+			w.WriteBOM = true
+			w.WriteSepHint = true
+			return w.Write(record)
+		}, WithAppend(true))
+		assert.Nil(t, err)
+	}
+
+	data, err := ioutil.ReadFile(csvFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "\xEF\xBB\xBFsep=;\nname;age\nmary;35\n", string(data))
+}
+
+func TestWriteFile_appendAndAtomic(t *testing.T) {
+	err := WriteFile("out.csv", ',', func(w *Writer) error { return nil }, WithAppend(false), WithAtomicWrite())
+	assert.NotNil(t, err)
+}
+
+// Test helper
+func listDir(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+
+	names := []string{}
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names
+}
+
+// Test helper
+func makeTempDir(t *testing.T) string {
+	tmpDir, err := ioutil.TempDir("", "hastycsv")
+	if err != nil {
+		assert.FailNow(t, "Error creating temp dir", "%v", err)
+	}
+	return tmpDir
+}
+
+// Test helper
+func gunzip(t *testing.T, data []byte) string {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		assert.FailNow(t, "Error opening gzip stream", "%v", err)
+	}
+	defer gz.Close()
+
+	b, err := ioutil.ReadAll(gz)
+	assert.Nil(t, err)
+	return string(b)
+}
@@ -0,0 +1,198 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// A hastycsv.RowSource that streams the rows of a worksheet.
+type sheetReader struct {
+	d     *xml.Decoder
+	wb    *workbook
+	width int // the number of fields of the first row; 0 until it's been read
+	row   int
+	cells [][]byte
+	buf   []byte // backs cells
+}
+
+func newSheetReader(r io.Reader, wb *workbook) *sheetReader {
+	return &sheetReader{d: xml.NewDecoder(r), wb: wb}
+}
+
+// Returns the next non-blank row.
+func (me *sheetReader) NextRow() (int, [][]byte, error) {
+	for {
+		start, err := me.nextStart("row")
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if r := attr(start, "r"); r != "" {
+			if me.row, err = strconv.Atoi(r); err != nil {
+				return 0, nil, fmt.Errorf(`Invalid row number "%v"`, r)
+			}
+		} else {
+			me.row++
+		}
+
+		values, err := me.readRow()
+		if err != nil {
+			return 0, nil, fmt.Errorf("Row %v: %w", me.row, err)
+		}
+		if isBlank(values) {
+			continue
+		}
+
+		if me.width == 0 {
+			me.width = len(values)
+		}
+		for len(values) < me.width {
+			values = append(values, "")
+		}
+		for len(values) > me.width && values[len(values)-1] == "" {
+			values = values[:len(values)-1]
+		}
+
+		me.buf = me.buf[:0]
+		for _, v := range values {
+			me.buf = append(me.buf, v...)
+		}
+		me.cells = me.cells[:0]
+		offset := 0
+		for _, v := range values {
+			me.cells = append(me.cells, me.buf[offset:offset+len(v):offset+len(v)])
+			offset += len(v)
+		}
+		return me.row, me.cells, nil
+	}
+}
+
+// Skips to the next start element with the specified local name.  Returns
+// io.EOF if there is none.
+func (me *sheetReader) nextStart(name string) (xml.StartElement, error) {
+	for {
+		tok, err := me.d.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == name {
+			return start, nil
+		}
+	}
+}
+
+// Reads the cells of the current row, up to its end element.  Returns the text
+// of each cell, indexed by column.
+func (me *sheetReader) readRow() ([]string, error) {
+	values := []string{}
+	for {
+		tok, err := me.d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if tok.Name.Local != "c" {
+				if err := me.d.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			col := len(values)
+			if ref := attr(tok, "r"); ref != "" {
+				if col, err = columnIndex(ref); err != nil {
+					return nil, err
+				}
+			}
+			value, err := me.readCell(tok)
+			if err != nil {
+				return nil, err
+			}
+
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			values[col] = value
+		case xml.EndElement:
+			return values, nil
+		}
+	}
+}
+
+// Reads a cell element and returns its text.
+func (me *sheetReader) readCell(start xml.StartElement) (string, error) {
+	var cell struct {
+		Value  string   `xml:"v"`
+		Inline string   `xml:"is>t"`
+		Runs   []string `xml:"is>r>t"`
+	}
+	if err := me.d.DecodeElement(&cell, &start); err != nil {
+		return "", err
+	}
+
+	switch attr(start, "t") {
+	case "s":
+		i, err := strconv.Atoi(cell.Value)
+		if err != nil || i < 0 || i >= len(me.wb.sharedStrings) {
+			return "", fmt.Errorf(`Invalid shared string index "%v"`, cell.Value)
+		}
+		return me.wb.sharedStrings[i], nil
+	case "inlineStr":
+		text := cell.Inline
+		for _, run := range cell.Runs {
+			text += run
+		}
+		return text, nil
+	case "b":
+		if cell.Value == "1" {
+			return "true", nil
+		}
+		return "false", nil
+	case "", "n":
+		if style := attr(start, "s"); style != "" && cell.Value != "" {
+			if i, err := strconv.Atoi(style); err == nil && i < len(me.wb.dateStyles) && me.wb.dateStyles[i] {
+				if date, ok := formatDate(cell.Value, me.wb.date1904); ok {
+					return date, nil
+				}
+			}
+		}
+	}
+	return cell.Value, nil
+}
+
+// Returns the value of the specified attribute, or "" if there is none.
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Returns the zero-based column index of a cell reference such as "AB12".
+func columnIndex(ref string) (int, error) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 {
+		return 0, fmt.Errorf(`Invalid cell reference "%v"`, ref)
+	}
+	return col - 1, nil
+}
+
+// Returns true if all of the specified values are empty.
+func isBlank(values []string) bool {
+	for _, v := range values {
+		if v != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,311 @@
+// Package xlsx reads the rows of Excel (.xlsx) worksheets as hastycsv records,
+// so that spreadsheets and CSV files can share the same Next callback.
+//
+// Worksheets are streamed row by row; only the workbook's shared strings and
+// cell styles are held in memory.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cet001/hastycsv"
+)
+
+// Reads the rows of the specified worksheet of the .xlsx file at xlsxFilePath
+// (see Read()).
+func ReadFile(xlsxFilePath string, sheet string, reader *hastycsv.Reader, nextRecord hastycsv.Next) error {
+	f, err := os.Open(xlsxFilePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return Read(f, info.Size(), sheet, reader, nextRecord)
+}
+
+// Reads the rows of the specified worksheet (the first one if sheet is "") of
+// the .xlsx file r, whose size in bytes is size, and passes them to
+// nextRecord using reader (see hastycsv.Reader.ReadRows()).  Records are
+// numbered by their spreadsheet row number.
+//
+// Every row is padded with empty fields to the width of the first row, and
+// blank rows are skipped.  Cells are converted to text as follows:
+//
+//   - Strings and numbers are passed as is, e.g. "1.5".
+//   - Booleans become "true" or "false".
+//   - Numbers formatted as dates become "2006-01-02", or "2006-01-02T15:04:05"
+//     if they include a time of day.
+//   - Formula cells hold the formula's cached result.
+func Read(r io.ReaderAt, size int64, sheet string, reader *hastycsv.Reader, nextRecord hastycsv.Next) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	wb, err := openWorkbook(zr)
+	if err != nil {
+		return err
+	}
+
+	sheetPath, err := wb.sheetPath(sheet)
+	if err != nil {
+		return err
+	}
+	f, err := wb.open(sheetPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return reader.ReadRows(newSheetReader(f, wb), nextRecord)
+}
+
+// Returns the names of the worksheets of the .xlsx file r, in workbook order.
+func SheetNames(r io.ReaderAt, size int64) ([]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	wb, err := openWorkbook(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(wb.sheets))
+	for i, s := range wb.sheets {
+		names[i] = s.Name
+	}
+	return names, nil
+}
+
+// The workbook-level parts of an .xlsx file that are needed to read a sheet.
+type workbook struct {
+	zr            *zip.Reader
+	sheets        []workbookSheet
+	sheetTargets  map[string]string // relationship id => part path
+	sharedStrings []string
+	dateStyles    []bool // whether each cell style (by index) formats a date
+	date1904      bool
+}
+
+type workbookSheet struct {
+	Name string `xml:"name,attr"`
+	RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
+func openWorkbook(zr *zip.Reader) (*workbook, error) {
+	wb := &workbook{zr: zr, sheetTargets: map[string]string{}}
+
+	var wbXML struct {
+		Properties struct {
+			Date1904 string `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []workbookSheet `xml:"sheets>sheet"`
+	}
+	if err := wb.decode("xl/workbook.xml", &wbXML); err != nil {
+		return nil, err
+	}
+	wb.sheets = wbXML.Sheets
+	wb.date1904 = wbXML.Properties.Date1904 == "1" || wbXML.Properties.Date1904 == "true"
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := wb.decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	for _, rel := range rels.Relationships {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = target[1:]
+		} else {
+			target = path.Join("xl", target)
+		}
+		wb.sheetTargets[rel.ID] = target
+	}
+
+	if err := wb.loadSharedStrings(); err != nil {
+		return nil, err
+	}
+	if err := wb.loadStyles(); err != nil {
+		return nil, err
+	}
+	return wb, nil
+}
+
+// Returns the path of the part that holds the specified worksheet.
+func (me *workbook) sheetPath(name string) (string, error) {
+	for _, s := range me.sheets {
+		if name == "" || s.Name == name {
+			target, ok := me.sheetTargets[s.RID]
+			if !ok {
+				return "", fmt.Errorf(`Sheet "%v" has no target part`, s.Name)
+			}
+			return target, nil
+		}
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("Workbook has no sheets")
+	}
+	return "", fmt.Errorf(`Sheet "%v" not found`, name)
+}
+
+// Returns the specified part, or nil if there is no such part.
+func (me *workbook) find(name string) *zip.File {
+	for _, f := range me.zr.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (me *workbook) open(name string) (io.ReadCloser, error) {
+	f := me.find(name)
+	if f == nil {
+		return nil, fmt.Errorf("Part %v not found", name)
+	}
+	return f.Open()
+}
+
+func (me *workbook) decode(name string, v interface{}) error {
+	rc, err := me.open(name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("Can't parse %v: %w", name, err)
+	}
+	return nil
+}
+
+func (me *workbook) loadSharedStrings() error {
+	if me.find("xl/sharedStrings.xml") == nil {
+		return nil
+	}
+
+	// A rich text string is made up of several runs, whose text is concatenated.
+	var sst struct {
+		Items []struct {
+			Text string   `xml:"t"`
+			Runs []string `xml:"r>t"`
+		} `xml:"si"`
+	}
+	if err := me.decode("xl/sharedStrings.xml", &sst); err != nil {
+		return err
+	}
+
+	me.sharedStrings = make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		me.sharedStrings[i] = item.Text + strings.Join(item.Runs, "")
+	}
+	return nil
+}
+
+func (me *workbook) loadStyles() error {
+	if me.find("xl/styles.xml") == nil {
+		return nil
+	}
+
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := me.decode("xl/styles.xml", &styles); err != nil {
+		return err
+	}
+
+	customDateFormats := map[int]bool{}
+	for _, numFmt := range styles.NumFmts {
+		customDateFormats[numFmt.ID] = isDateFormat(numFmt.Code)
+	}
+
+	me.dateStyles = make([]bool, len(styles.CellXfs))
+	for i, xf := range styles.CellXfs {
+		if isDate, ok := customDateFormats[xf.NumFmtID]; ok {
+			me.dateStyles[i] = isDate
+		} else {
+			me.dateStyles[i] = isBuiltInDateFormat(xf.NumFmtID)
+		}
+	}
+	return nil
+}
+
+// Returns true if the specified built-in number format displays a date or a
+// time.
+func isBuiltInDateFormat(id int) bool {
+	return (id >= 14 && id <= 22) || (id >= 45 && id <= 47)
+}
+
+// Returns true if the specified custom number format code displays a date or
+// a time, i.e. if it contains date or time placeholders outside of quoted
+// text, escapes and [bracketed] sections.
+func isDateFormat(code string) bool {
+	for i := 0; i < len(code); i++ {
+		switch ch := code[i]; ch {
+		case '"':
+			for i++; i < len(code) && code[i] != '"'; i++ {
+			}
+		case '[':
+			for i++; i < len(code) && code[i] != ']'; i++ {
+			}
+		case '\\', '_', '*':
+			i++
+		case 'y', 'Y', 'm', 'M', 'd', 'D', 'h', 'H', 's', 'S':
+			return true
+		}
+	}
+	return false
+}
+
+// Day 0 of the 1900 and 1904 date systems.  The 1900 epoch accounts for
+// Excel treating 1900 as a leap year, which makes it correct from March 1900
+// on.
+var (
+	excelEpoch     = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	excelEpoch1904 = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// Converts a cell's date serial number (as text) to a date, or to a date and
+// time if it has a fractional part.
+func formatDate(serial string, date1904 bool) (string, bool) {
+	days, err := strconv.ParseFloat(serial, 64)
+	if err != nil {
+		return "", false
+	}
+
+	epoch := excelEpoch
+	if date1904 {
+		epoch = excelEpoch1904
+	}
+	t := epoch.Add(time.Duration(math.Round(days*24*60*60)) * time.Second)
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+		return t.Format("2006-01-02"), true
+	}
+	return t.Format("2006-01-02T15:04:05"), true
+}
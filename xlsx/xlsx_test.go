@@ -0,0 +1,167 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"github.com/cet001/hastycsv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testWorkbook = `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets>
+    <sheet name="People" sheetId="1" r:id="rId1"/>
+    <sheet name="Other" sheetId="2" r:id="rId2"/>
+  </sheets>
+</workbook>`
+
+const testRels = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+  <Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/>
+</Relationships>`
+
+const testSharedStrings = `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <si><t>name</t></si>
+  <si><t>age</t></si>
+  <si><t>joined</t></si>
+  <si><t>active</t></si>
+  <si><r><t>bi</t></r><r><t>ll</t></r></si>
+  <si><t>mary, jr.</t></si>
+</sst>`
+
+const testStyles = `<?xml version="1.0" encoding="UTF-8"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <numFmts><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd\ hh:mm"/></numFmts>
+  <cellXfs>
+    <xf numFmtId="0"/>
+    <xf numFmtId="14"/>
+    <xf numFmtId="164"/>
+  </cellXfs>
+</styleSheet>`
+
+const testSheet1 = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c><c r="D1" t="s"><v>3</v></c></row>
+    <row r="2"><c r="A2" t="s"><v>4</v></c><c r="B2"><v>30</v></c><c r="C2" s="1"><v>43831</v></c><c r="D2" t="b"><v>1</v></c></row>
+    <row r="4"><c r="A4" t="inlineStr"><is><t>joe</t></is></c><c r="C4" s="2"><v>43831.5</v></c></row>
+    <row r="5"><c r="A5" s="1"/></row>
+    <row r="6"><c r="A6" t="s"><v>5</v></c><c r="B6"><f>B2+5</f><v>35</v></c><c r="D6" t="b"><v>0</v></c></row>
+  </sheetData>
+</worksheet>`
+
+const testSheet2 = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1"><v>1</v></c></row>
+    <row r="2"><c r="A2"><v>x</v></c></row>
+  </sheetData>
+</worksheet>`
+
+func TestRead(t *testing.T) {
+	data := makeTestWorkbook(t)
+
+	reader := hastycsv.NewReader()
+	reader.HasHeader = true
+
+	records := []string{}
+	err := Read(bytes.NewReader(data), int64(len(data)), "", reader, func(i int, fields []hastycsv.Field) error {
+		records = append(records, fmt.Sprintf("%v:%q|%q|%q|%q", i, fields[0].String(), fields[1].String(), fields[2].String(), fields[3].String()))
+		return nil
+	})
+
+	require.Nil(t, err)
+	assert.Equal(t, []string{"name", "age", "joined", "active"}, reader.Header())
+	assert.Equal(t, []string{
+		`2:"bill"|"30"|"2020-01-01"|"true"`,
+		`4:"joe"|""|"2020-01-01T12:00:00"|""`,
+		`6:"mary, jr."|"35"|""|"false"`,
+	}, records)
+}
+
+func TestRead_namedSheet(t *testing.T) {
+	data := makeTestWorkbook(t)
+
+	err := Read(bytes.NewReader(data), int64(len(data)), "Other", hastycsv.NewReader(), func(i int, fields []hastycsv.Field) error {
+		fields[0].Uint32()
+		return nil
+	})
+	assert.EqualError(t, err, `Line 2, column 1: Can't parse field as uint32: "x" contains non-numeric character 'x'`)
+
+	err = Read(bytes.NewReader(data), int64(len(data)), "Missing", hastycsv.NewReader(), func(i int, fields []hastycsv.Field) error { return nil })
+	assert.EqualError(t, err, `Sheet "Missing" not found`)
+}
+
+func TestReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlsx")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	xlsxPath := filepath.Join(dir, "test.xlsx")
+	require.Nil(t, ioutil.WriteFile(xlsxPath, makeTestWorkbook(t), 0644))
+
+	count := 0
+	err = ReadFile(xlsxPath, "Other", hastycsv.NewReader(), func(i int, fields []hastycsv.Field) error {
+		count++
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestSheetNames(t *testing.T) {
+	data := makeTestWorkbook(t)
+	names, err := SheetNames(bytes.NewReader(data), int64(len(data)))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"People", "Other"}, names)
+}
+
+func TestIsDateFormat(t *testing.T) {
+	assert.True(t, isDateFormat("yyyy-mm-dd"))
+	assert.True(t, isDateFormat(`[$-409]h:mm AM/PM`))
+	assert.False(t, isDateFormat("0.00"))
+	assert.False(t, isDateFormat(`"days"0`))
+	assert.False(t, isDateFormat(`[Red]0.00`))
+	assert.False(t, isDateFormat("General"))
+}
+
+func TestColumnIndex(t *testing.T) {
+	for ref, expected := range map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB3": 27} {
+		col, err := columnIndex(ref)
+		require.Nil(t, err)
+		assert.Equal(t, expected, col, ref)
+	}
+
+	_, err := columnIndex("12")
+	assert.EqualError(t, err, `Invalid cell reference "12"`)
+}
+
+// Test helper that returns the bytes of a minimal .xlsx file.
+func makeTestWorkbook(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"xl/workbook.xml":            testWorkbook,
+		"xl/_rels/workbook.xml.rels": testRels,
+		"xl/sharedStrings.xml":       testSharedStrings,
+		"xl/styles.xml":              testStyles,
+		"xl/worksheets/sheet1.xml":   testSheet1,
+		"xl/worksheets/sheet2.xml":   testSheet2,
+	}
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		require.Nil(t, err)
+		_, err = w.Write([]byte(content))
+		require.Nil(t, err)
+	}
+	require.Nil(t, zw.Close())
+	return buf.Bytes()
+}
@@ -0,0 +1,19 @@
+module github.com/cet001/hastycsv/zstd
+
+go 1.18
+
+require (
+	github.com/cet001/hastycsv v0.0.0-20261015103949-e00c2996acd5
+	github.com/klauspost/compress v1.15.15
+	github.com/stretchr/testify v1.8.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// Builds against the hastycsv in this repository; like any replace directive,
+// this is ignored when the module is required by another one.
+replace github.com/cet001/hastycsv => ../
@@ -0,0 +1,51 @@
+// Package zstd adds support for Zstandard-compressed input to hastycsv.
+//
+// It's a separate module so that the core hastycsv package remains free of
+// third-party dependencies.  Importing it also registers a decompressor for
+// the ".zst" extension, so that hastycsv.ReadFile() can read .csv.zst files.
+package zstd
+
+import (
+	"io"
+	"os"
+
+	"github.com/cet001/hastycsv"
+	"github.com/klauspost/compress/zstd"
+)
+
+func init() {
+	hastycsv.RegisterDecompressor(".zst", NewReader)
+}
+
+// Returns a reader that decompresses the zstd stream read from r.  Close()
+// must be called to release the decoder's resources; it does not close r.
+func NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+// Reads records from the specified zstd-compressed CSV file (e.g.
+// "data.csv.zst") like hastycsv.ReadFile().
+func ReadFile(csvFilePath string, comma byte, nextRecord hastycsv.Next) error {
+	f, err := os.Open(csvFilePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zr, err := NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	in := hastycsv.NewReadAheadReader(zr, 32*1024)
+	defer in.Close()
+
+	r := hastycsv.NewReader()
+	r.Comma = comma
+	return r.Read(in, nextRecord)
+}
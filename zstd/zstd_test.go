@@ -0,0 +1,84 @@
+package zstd
+
+import (
+	"bytes"
+	"github.com/cet001/hastycsv"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hastycsv")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "people.csv.zst")
+	require.Nil(t, ioutil.WriteFile(path, compress(t, "mary|35\nbill|40\n"), 0644))
+
+	received := []string{}
+	err = ReadFile(path, '|', func(i int, rec []hastycsv.Field) error {
+		received = append(received, rec[0].String())
+		assert.Equal(t, uint32(30+5*i), rec[1].Uint32())
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mary", "bill"}, received)
+}
+
+func TestReadFile_errors(t *testing.T) {
+	err := ReadFile("NONEXISTENT_FILE.csv.zst", ',', func(i int, rec []hastycsv.Field) error { return nil })
+	assert.NotNil(t, err)
+
+	dir, err := ioutil.TempDir("", "hastycsv")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "plain.csv.zst")
+	require.Nil(t, ioutil.WriteFile(path, []byte("not,compressed\n"), 0644))
+	err = ReadFile(path, ',', func(i int, rec []hastycsv.Field) error { return nil })
+	assert.NotNil(t, err)
+}
+
+func TestNewReader(t *testing.T) {
+	zr, err := NewReader(bytes.NewReader(compress(t, "a,b\n")))
+	require.Nil(t, err)
+	defer zr.Close()
+
+	data, err := ioutil.ReadAll(zr)
+	assert.Nil(t, err)
+	assert.Equal(t, "a,b\n", string(data))
+}
+
+// Test helper
+func compress(t *testing.T, s string) []byte {
+	buf := &bytes.Buffer{}
+	zw, err := zstd.NewWriter(buf)
+	require.Nil(t, err)
+	_, err = zw.Write([]byte(s))
+	require.Nil(t, err)
+	require.Nil(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestReadFile_registeredDecompressor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hastycsv")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "people.csv.zst")
+	require.Nil(t, ioutil.WriteFile(path, compress(t, "mary,35\nbill,40\n"), 0644))
+
+	count := 0
+	err = hastycsv.ReadFile(path, ',', func(i int, rec []hastycsv.Field) error {
+		count++
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+}